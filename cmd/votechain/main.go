@@ -0,0 +1,104 @@
+// Команда votechain проверяет журнал голосов: цепочка хэшей каждой петиции пересчитывается
+// и сверяется с таблицей votes. Код выхода 1, если хотя бы одна цепочка нарушена.
+//
+//	go run ./cmd/votechain -conf configs/config.json
+//	go run ./cmd/votechain -petition 12
+//	go run ./cmd/votechain -backfill   # один раз после обновления: добавить в журнал старые голоса
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"log"
+	"os"
+	"petition_api/internal/app/apiserver"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/services"
+	"petition_api/utils/logger"
+	"time"
+)
+
+var (
+	configPath string
+	petitionID uint
+	backfill   bool
+)
+
+func init() {
+	flag.StringVar(&configPath, "conf", "configs/config.json", "config path")
+	flag.UintVar(&petitionID, "petition", 0, "verify only this petition")
+	flag.BoolVar(&backfill, "backfill", false, "import votes cast before the audit log existed, then verify")
+}
+
+func main() {
+	flag.Parse()
+	// Чтение содержимого JSON-файла
+	file, err := ioutil.ReadFile(configPath)
+	errHandler(err)
+
+	config := apiserver.NewConfig()
+
+	// Распаковка JSON в структуру
+	err = json.Unmarshal(file, config)
+	errHandler(err)
+
+	level, err := logrus.ParseLevel(config.App.LogLevel)
+	errHandler(err)
+	appLogger := &logrus.Logger{Out: os.Stderr, Level: level, Formatter: &logger.CustomFormatter{}}
+
+	db, err := apiserver.ConnectDatabase(config.Database)
+	errHandler(err)
+
+	audit := services.NewVoteAuditService(
+		repository.NewVoteAuditRepository(db, appLogger),
+		repository.NewVoteRepository(db, appLogger),
+		appLogger,
+	)
+
+	if backfill {
+		var imported int
+		if petitionID != 0 {
+			imported, err = audit.Backfill(petitionID, time.Now())
+		} else {
+			imported, err = audit.BackfillAll(time.Now())
+		}
+		errHandler(err)
+		fmt.Printf("imported %d votes\n", imported)
+	}
+
+	var checked, invalid int
+	report := func(r *models.VoteChainReport) {
+		checked++
+		if r.Valid {
+			return
+		}
+		invalid++
+		fmt.Printf("petition %d: INVALID: %s (entries %d, head %s, chain votes %d, table votes %d)\n",
+			r.PetitionID, r.Problem, r.Entries, r.Head, r.ChainVotes, r.TableVotes)
+	}
+	if petitionID != 0 {
+		r, err := audit.VerifyPetition(petitionID)
+		errHandler(err)
+		report(r)
+		if r.Valid {
+			fmt.Printf("petition %d: ok (entries %d, head %s, votes %d)\n", r.PetitionID, r.Entries, r.Head, r.TableVotes)
+		}
+	} else {
+		errHandler(audit.VerifyAll(report))
+	}
+
+	fmt.Printf("checked %d petitions, %d invalid\n", checked, invalid)
+	if invalid > 0 {
+		os.Exit(1)
+	}
+}
+
+func errHandler(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,15 +1,26 @@
 package apiserver
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 	"os"
+	"path/filepath"
 	"petition_api/internal/app/handlers/httpHandlers"
 	"petition_api/internal/app/handlers/websocket"
+	"petition_api/internal/app/jobs"
+	"petition_api/internal/app/mailer"
+	"petition_api/internal/app/models"
 	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/services"
+	"petition_api/internal/app/storage"
+	"petition_api/utils/auth"
 	"petition_api/utils/logger"
+	"strings"
+	"time"
 )
 
 type ApiServer struct {
@@ -35,6 +46,16 @@ func (s *ApiServer) Start() error {
 	}
 	s.logger.Info("Starting API Server...")
 
+	if err := s.config.Validate(); err != nil {
+		s.logger.Error(err)
+		return err
+	}
+
+	if err := auth.LoadKeys(s.config.App.PrivateKeyPath); err != nil {
+		s.logger.Errorf("failed to load private key: %v", err)
+		return err
+	}
+
 	if err := configureDB(s); err != nil {
 		return err
 	}
@@ -46,8 +67,8 @@ func (s *ApiServer) Start() error {
 	s.router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:4200"},
 		AllowMethods:     []string{"POST", "GET", "PUT", "DELETE", "PATCH"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-Token-Mode", "X-Device-Fingerprint", "X-API-Key"},
+		ExposeHeaders:    []string{"Content-Length", "Content-Disposition"},
 		AllowCredentials: true,
 	}))
 
@@ -56,36 +77,414 @@ func (s *ApiServer) Start() error {
 		c.JSON(200, gin.H{"message": "pong"})
 	})
 
+	userRepo := repository.NewUserRepository(s.db, s.logger)
+	sessionRepo := repository.NewSessionRepo(s.db, s.logger)
+	roleRepo := repository.NewRoleRepository(s.db, s.logger)
+	petitionRepo := repository.NewPetitionRepository(s.db, s.logger)
+	commentRepo := repository.NewCommentRepository(s.db, s.logger)
+	voteRepo := repository.NewVoteRepository(s.db, s.logger)
+	voteRepo.ExcludePassiveVoters = s.config.Votes.ExcludePassiveVoters
+	apiKeyRepo := repository.NewAPIKeyRepository(s.db, s.logger)
+	dataExportRepo := repository.NewDataExportRepository(s.db, s.logger)
+	userBanRepo := repository.NewUserBanRepository(s.db, s.logger)
+
+	// Проверка статуса и блокировок пользователей для всех защищенных роутов
+	accountStatus := services.NewAccountStatusService(userRepo, userBanRepo, s.logger)
+
+	// Роли и права по умолчанию
+	if err := roleRepo.Seed(models.DefaultPermissions, models.DefaultRolePermissions); err != nil {
+		s.logger.Errorf("failed to seed roles: %v", err)
+		return err
+	}
+
+	// Периодическая очистка истекших сессий и кэша статусов аккаунтов
+	sessionCleanup := jobs.NewSessionCleanupJob(
+		sessionRepo,
+		accountStatus,
+		time.Duration(s.config.Session.CleanupIntervalMinutes)*time.Minute,
+		s.logger,
+	)
+	sessionCleanup.Start()
+	defer sessionCleanup.Stop()
+
+	// Выгрузка персональных данных
+	dataExporter := services.NewUserDataExportService(
+		userRepo,
+		sessionRepo,
+		petitionRepo,
+		commentRepo,
+		voteRepo,
+		repository.NewVoteFlagRepository(s.db, s.logger),
+		apiKeyRepo,
+		repository.NewNotificationRepository(s.db, s.logger),
+		repository.NewNotificationPreferenceRepository(s.db, s.logger),
+		repository.NewSubscriptionRepository(s.db, s.logger),
+		repository.NewWebhookRepository(s.db, s.logger),
+		userBanRepo,
+		s.logger,
+	)
+	dataExportJob := jobs.NewDataExportJob(
+		dataExportRepo,
+		dataExporter,
+		s.config.Export.Dir,
+		time.Duration(s.config.Export.LinkTTLMinutes)*time.Minute,
+		time.Duration(s.config.Export.WorkerIntervalSeconds)*time.Second,
+		time.Duration(s.config.Export.ProcessingTimeoutMinutes)*time.Minute,
+		s.logger,
+	)
+	dataExportJob.Start()
+	defer dataExportJob.Stop()
+
+	// Хранилище загруженных файлов, аватары раздаются по префиксу storage.base_url
+	fileStorage := storage.NewLocalStorage(s.config.Storage.Dir, s.config.Storage.BaseURL)
+	mountAvatars(s.router, fileStorage, s.config.Storage.BaseURL)
+	models.SetAvatarURLResolver(fileStorage.URL)
+	avatars := services.NewAvatarService(userRepo, fileStorage, s.config.Avatar.MaxSizeKB*1024, s.logger)
+	attachments := services.NewPetitionAttachmentService(
+		repository.NewPetitionAttachmentRepository(s.db, s.logger),
+		fileStorage,
+		services.AttachmentQuota{
+			MaxFileSize:  s.config.Attachments.MaxFileSizeKB * 1024,
+			MaxFiles:     s.config.Attachments.MaxFilesPerPetition,
+			MaxTotalSize: s.config.Attachments.MaxTotalSizeMB * 1024 * 1024,
+		},
+		s.logger,
+	)
+
+	notificationRepo := repository.NewNotificationRepository(s.db, s.logger)
+	subscriptionRepo := repository.NewSubscriptionRepository(s.db, s.logger)
+	webhookRepo := repository.NewWebhookRepository(s.db, s.logger)
+	// Исходящая очередь событий для вебхуков, пишется вместе с изменениями петиций и голосов
+	webhookEventRepo := repository.NewWebhookEventRepository(s.db, s.logger)
+	// Журнал голосов с цепочкой хэшей, пишется вместе с голосами
+	voteAuditRepo := repository.NewVoteAuditRepository(s.db, s.logger)
+
+	// Удаление аккаунтов после периода ожидания
+	accountDeletion := services.NewAccountDeletionService(
+		userRepo,
+		sessionRepo,
+		petitionRepo,
+		commentRepo,
+		voteRepo,
+		voteAuditRepo,
+		apiKeyRepo,
+		webhookRepo,
+		dataExportRepo,
+		notificationRepo,
+		subscriptionRepo,
+		repository.NewNotificationPreferenceRepository(s.db, s.logger),
+		avatars,
+		time.Duration(s.config.Account.DeletionGraceDays)*24*time.Hour,
+		s.logger,
+	)
+	accountDeletionJob := jobs.NewAccountDeletionJob(
+		accountDeletion,
+		time.Duration(s.config.Account.DeletionCheckIntervalMinutes)*time.Minute,
+	)
+	accountDeletionJob.Start()
+	defer accountDeletionJob.Stop()
+
+	// Статусы петиций: вехи и набор подписей, официальные ответы адресатов
+	recipientRepo := repository.NewRecipientRepository(s.db, s.logger)
+	petitionStatuses := services.NewPetitionStatusService(
+		petitionRepo,
+		voteRepo,
+		userRepo,
+		recipientRepo,
+		repository.NewPetitionResponseRepository(s.db, s.logger),
+		repository.NewPetitionMilestoneRepository(s.db, s.logger),
+		webhookEventRepo,
+		s.logger,
+	)
+
+	// Фильтр комментариев и причин подписи
+	contentFilter := services.NewContentFilter(s.config.Moderation.BannedWords)
+	// Условия подписи петиций: возраст, регион, подтвержденный email, возраст аккаунта
+	eligibility := services.NewEligibilityService(
+		repository.NewPetitionEligibilityRepository(s.db, s.logger),
+		userRepo,
+		voteRepo,
+		s.logger,
+	)
+	// Подписи петиций через вебсокет и REST
+	votes := services.NewVoteService(petitionRepo, voteRepo, webhookEventRepo, voteAuditRepo, petitionStatuses, eligibility, accountStatus, contentFilter, s.logger)
+	// Вебсокет для голосов, через него же рассылаются новости петиций
+	voteRoute := websocket.NewVoteWebsocket(voteRepo, userRepo, votes, accountStatus, s.logger)
+	votes.AddNotifier(voteRoute)
+	// Поиск накруток: подозрительные голоса отмечаются для проверки админом
+	voteFraud := services.NewVoteFraudService(
+		repository.NewVoteFlagRepository(s.db, s.logger),
+		voteRepo,
+		votes,
+		services.FraudPolicy{
+			Window:             time.Duration(s.config.Fraud.WindowMinutes) * time.Minute,
+			MaxVotesPerIP:      s.config.Fraud.MaxVotesPerIP,
+			MaxVotesPerSubnet:  s.config.Fraud.MaxVotesPerSubnet,
+			NewAccountAge:      time.Duration(s.config.Fraud.NewAccountHours) * time.Hour,
+			MaxNewAccountVotes: s.config.Fraud.MaxNewAccountVotes,
+		},
+		s.logger,
+	)
+	votes.AddNotifier(voteFraud)
+	// Личный вебсокет пользователя и центр уведомлений
+	notificationSocket := websocket.NewNotificationWebsocket(notificationRepo, accountStatus, s.logger)
+	notifications := services.NewNotificationService(
+		notificationRepo,
+		subscriptionRepo,
+		petitionRepo,
+		commentRepo,
+		notificationSocket,
+		s.logger,
+	)
+
+	// Письма с уведомлениями по настройкам пользователей
+	mailSender, err := s.configureMailer()
+	if err != nil {
+		s.logger.Errorf("failed to configure mailer: %v", err)
+		return err
+	}
+	mailTemplates, err := mailer.NewTemplates()
+	if err != nil {
+		return err
+	}
+	unsubscribeSecret := s.config.Mail.UnsubscribeSecret
+	if unsubscribeSecret == "" {
+		s.logger.Warn("mail.unsubscribe_secret is empty, unsubscribe links will stop working after restart")
+		unsubscribeSecret = randomSecret()
+	}
+	emailDigests := services.NewEmailDigestService(
+		notificationRepo,
+		repository.NewNotificationPreferenceRepository(s.db, s.logger),
+		userRepo,
+		petitionRepo,
+		mailSender,
+		mailTemplates,
+		services.NewUnsubscribeSigner(unsubscribeSecret),
+		s.config.Mail.BaseURL,
+		services.DigestSchedule{Hour: s.config.Mail.DigestHour, WeeklyDay: time.Weekday(s.config.Mail.WeeklyDay)},
+		s.logger,
+	)
+	// Подтверждение email по ссылке из письма. Ключ подписи выводится из секрета отписки отдельно для этих ссылок
+	emailVerification := services.NewEmailVerificationService(
+		userRepo,
+		mailSender,
+		mailTemplates,
+		services.NewUnsubscribeSigner(unsubscribeSecret),
+		s.config.Mail.BaseURL,
+		time.Duration(s.config.Mail.VerificationTTLHours)*time.Hour,
+		s.logger,
+	)
+	emailDigestJob := jobs.NewEmailDigestJob(emailDigests, time.Duration(s.config.Mail.IntervalSeconds)*time.Second)
+	emailDigestJob.Start()
+	defer emailDigestJob.Stop()
+
+	// Доставка событий на вебхуки интеграций
+	webhooks := services.NewWebhookService(
+		webhookRepo,
+		webhookEventRepo,
+		repository.NewWebhookDeliveryRepository(s.db, s.logger),
+		services.NewWebhookHTTPClient(
+			time.Duration(s.config.Webhooks.TimeoutSeconds)*time.Second,
+			s.config.Webhooks.AllowPrivateURLs,
+		),
+		services.WebhookPolicy{
+			MaxPerUser:  s.config.Webhooks.MaxPerUser,
+			MaxAttempts: s.config.Webhooks.MaxAttempts,
+			RetryBase:   time.Duration(s.config.Webhooks.RetryBaseSeconds) * time.Second,
+			RetryMax:    time.Duration(s.config.Webhooks.RetryMaxMinutes) * time.Minute,
+			Timeout:     time.Duration(s.config.Webhooks.TimeoutSeconds) * time.Second,
+			Retention:   time.Duration(s.config.Webhooks.RetentionDays) * 24 * time.Hour,
+		},
+		s.logger,
+	)
+	webhookJob := jobs.NewWebhookDeliveryJob(webhooks, time.Duration(s.config.Webhooks.IntervalSeconds)*time.Second)
+	webhookJob.Start()
+	defer webhookJob.Stop()
+
+	petitionStatuses.AddNotifier(services.NewLogStatusNotifier(s.logger))
+	petitionStatuses.AddNotifier(voteRoute)
+	petitionStatuses.AddNotifier(notifications)
+
 	// Создание роутов для юзера
 	userRoutes := httpHandlers.NewUserModelRoute(
-		repository.NewUserRepository(s.db, s.logger),
-		repository.NewSessionRepo(s.db, s.logger),
+		userRepo,
+		petitionRepo,
+		voteRepo,
+		sessionRepo,
+		roleRepo,
+		repository.NewUserAuditRepository(s.db, s.logger),
+		userBanRepo,
+		accountStatus,
+		accountDeletion,
+		s.config.Session.MaxPerUser,
 		s.logger)
 
 	userRoutes.BindUserToRoute(s.router.Group("/user"))
 
+	// Роуты для подтверждения email
+	emailRoutes := httpHandlers.NewUserEmailRoute(emailVerification, accountStatus, s.logger)
+
+	emailRoutes.BindEmailToRoute(s.router.Group("/user"))
+
+	// Роуты для ролей и прав
+	roleRoutes := httpHandlers.NewRoleModelRoute(roleRepo, accountStatus, s.logger)
+
+	roleRoutes.BindRoleToRoute(s.router.Group("/role"))
+
+	// Роуты для выгрузки персональных данных
+	dataExportRoutes := httpHandlers.NewDataExportModelRoute(
+		dataExportRepo,
+		dataExporter,
+		s.config.Export.SyncMaxRecords,
+		accountStatus,
+		s.logger,
+	)
+
+	dataExportRoutes.BindDataExportToRoute(s.router.Group("/user"))
+
+	// Роуты для аватара
+	avatarRoutes := httpHandlers.NewAvatarModelRoute(avatars, accountStatus, s.logger)
+
+	avatarRoutes.BindAvatarToRoute(s.router.Group("/user/me/avatar"))
+
+	// Роуты для персональных API ключей
+	apiKeyRoutes := httpHandlers.NewAPIKeyModelRoute(apiKeyRepo, accountStatus, s.logger)
+
+	apiKeyRoutes.BindAPIKeyToRoute(s.router.Group("/user/me/api-keys"))
+
+	// Роуты для вебхуков
+	webhookRoutes := httpHandlers.NewWebhookModelRoute(webhooks, accountStatus, s.logger)
+
+	webhookRoutes.BindWebhookToRoute(s.router.Group("/user/me/webhooks"))
+
 	// Роуты для петиций
 	petitionRoutes := httpHandlers.NewPetitionModelRoute(
-		repository.NewPetitionRepository(s.db, s.logger),
+		petitionRepo,
+		voteRepo,
+		apiKeyRepo,
+		roleRepo,
+		repository.NewPetitionRevisionRepository(s.db, s.logger),
+		attachments,
+		recipientRepo,
+		petitionStatuses,
+		webhookEventRepo,
+		accountStatus,
 		s.logger,
 	)
 
 	petitionRoutes.BindPetitionToRoute(s.router.Group("/petition"))
 
-	// Роуты для комментов
-	commentRoutes := httpHandlers.NewCommentModelRoute(
-		repository.NewCommentRepository(s.db, s.logger),
+	// Роуты для обложек и документов петиций
+	attachmentRoutes := httpHandlers.NewPetitionAttachmentRoute(
+		attachments,
+		petitionRepo,
+		roleRepo,
+		accountStatus,
 		s.logger,
 	)
 
-	commentRoutes.BindCommentToRoute(s.router.Group("/comment"))
+	attachmentRoutes.BindAttachmentToRoute(s.router.Group("/petition"))
+
+	// Роуты для новостей петиций
+	newsRoutes := httpHandlers.NewPetitionNewsRoute(
+		repository.NewPetitionNewsRepository(s.db, s.logger),
+		petitionRepo,
+		roleRepo,
+		voteRoute,
+		notifications,
+		accountStatus,
+		s.logger,
+	)
+
+	newsRoutes.BindNewsToRoute(s.router.Group("/petition"))
+
+	// Роуты для подписок на петиции
+	subscriptionRoutes := httpHandlers.NewPetitionSubscriptionRoute(subscriptionRepo, petitionRepo, accountStatus, s.logger)
+
+	subscriptionRoutes.BindSubscriptionToRoute(s.router.Group("/petition"))
+	subscriptionRoutes.BindMySubscriptionsToRoute(s.router.Group("/user/me/subscriptions"))
+
+	// Роуты для центра уведомлений
+	notificationRoutes := httpHandlers.NewNotificationModelRoute(notifications, emailDigests, accountStatus, s.logger)
+
+	notificationRoutes.BindNotificationToRoute(s.router.Group("/user/me/notifications"))
+	notificationRoutes.BindUnsubscribeToRoute(s.router.Group("/notifications"))
+	notificationSocket.AddToRoute(s.router.Group("/user/me/notifications"))
+
+	// Роуты для официальных ответов адресатов
+	responseRoutes := httpHandlers.NewPetitionResponseRoute(
+		petitionStatuses,
+		petitionRepo,
+		roleRepo,
+		accountStatus,
+		s.logger,
+	)
+
+	responseRoutes.BindResponseToRoute(s.router.Group("/petition"))
+
+	// Роуты для подписи петиций через REST и причин подписи
+	voteRoutes := httpHandlers.NewPetitionVoteRoute(votes, petitionRepo, userRepo, accountStatus, s.logger)
+
+	voteRoutes.BindVoteToRoute(s.router.Group("/petition"))
+
+	// Роуты для проверки подозрительных голосов
+	voteFlagRoutes := httpHandlers.NewVoteFlagRoute(voteFraud, roleRepo, accountStatus, s.logger)
 
-	// Вебсокет для голосов
-	voteRoute := websocket.NewVoteWebsocket(
-		repository.NewVoteRepository(s.db, s.logger),
+	voteFlagRoutes.BindVoteFlagToRoute(s.router.Group("/vote/flags"))
+
+	// Роуты для условий подписи петиций
+	eligibilityRoutes := httpHandlers.NewPetitionEligibilityRoute(eligibility, petitionRepo, roleRepo, accountStatus, s.logger)
+
+	eligibilityRoutes.BindEligibilityToRoute(s.router.Group("/petition"))
+
+	// Роуты для публичной проверки журнала голосов
+	voteAuditRoutes := httpHandlers.NewVoteAuditRoute(
+		services.NewVoteAuditService(voteAuditRepo, voteRepo, s.logger),
+		petitionRepo,
+		s.logger,
+	)
+
+	voteAuditRoutes.BindVoteAuditToRoute(s.router.Group("/petition"))
+
+	// Публичный список подписей и выгрузка списка подписантов для адресата
+	signatureRoutes := httpHandlers.NewPetitionSignatureRoute(
+		services.NewSignatureExportService(voteRepo, userRepo, roleRepo, s.logger),
+		petitionRepo,
+		voteRepo,
+		apiKeyRepo,
+		accountStatus,
+		s.logger,
+	)
+
+	signatureRoutes.BindSignatureToRoute(s.router.Group("/petition"))
+
+	// Роуты для справочника адресатов
+	recipientRoutes := httpHandlers.NewRecipientModelRoute(
+		recipientRepo,
+		userRepo,
+		petitionRepo,
+		roleRepo,
+		repository.NewUserAuditRepository(s.db, s.logger),
+		apiKeyRepo,
+		accountStatus,
+		s.logger,
+	)
+
+	recipientRoutes.BindRecipientToRoute(s.router.Group("/recipient"))
+
+	// Роуты для комментов
+	commentRoutes := httpHandlers.NewCommentModelRoute(
+		commentRepo,
+		userRepo,
+		roleRepo,
+		notifications,
+		contentFilter,
+		accountStatus,
 		s.logger,
 	)
 
+	commentRoutes.BindCommentToRoute(s.router.Group("/comment"))
+
 	voteRoute.AddToRoute(s.router.Group("/vote"))
 
 	s.logger.Info("API Server started!")
@@ -97,6 +496,30 @@ func (s *ApiServer) Start() error {
 	return nil
 }
 
+// configureMailer Выбирает способ отправки писем по mail.driver
+func (s *ApiServer) configureMailer() (mailer.Mailer, error) {
+	cfg := s.config.Mail
+	if cfg.Driver == "smtp" {
+		return mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From), nil
+	}
+	return mailer.NewFileMailer(cfg.FileDir, cfg.From)
+}
+
+// mountAvatars Раздает статикой только папку аватаров. Вложения петиций лежат в том же хранилище,
+// но отдаются через /petition/:id/attachments с Content-Disposition и проверкой петиции
+func mountAvatars(router *gin.Engine, files *storage.LocalStorage, baseURL string) {
+	router.Static(strings.TrimSuffix(baseURL, "/")+"/"+services.AvatarKeyPrefix, filepath.Join(files.Dir(), services.AvatarKeyPrefix))
+}
+
+// randomSecret Случайный ключ, если ключ не задан в конфиге
+func randomSecret() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
 // configureLogger конфигурирует логгер
 func (s *ApiServer) configureLogger() error {
 	level, err := logrus.ParseLevel(s.config.App.LogLevel)
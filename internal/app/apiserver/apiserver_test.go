@@ -0,0 +1,32 @@
+package apiserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"petition_api/internal/app/storage"
+	"strings"
+	"testing"
+)
+
+func TestMountAvatarsServesOnlyAvatars(t *testing.T) {
+	files := storage.NewLocalStorage(t.TempDir(), "/files")
+	require.NoError(t, files.Put("avatars/1/abc_64.jpg", strings.NewReader("avatar"), "image/jpeg"))
+	require.NoError(t, files.Put("petitions/1/doc.pdf", strings.NewReader("document"), "application/pdf"))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	mountAvatars(router, files, "/files")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, files.URL("avatars/1/abc_64.jpg"), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "avatar", w.Body.String())
+
+	// Вложения петиций статикой не раздаются
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, files.URL("petitions/1/doc.pdf"), nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -1,14 +1,29 @@
 package apiserver
 
+import "fmt"
+
 type Config struct {
-	App      AppConfig      `json:"app"`
-	Database DatabaseConfig `json:"database"`
+	App         AppConfig         `json:"app"`
+	Database    DatabaseConfig    `json:"database"`
+	Session     SessionConfig     `json:"session"`
+	Export      ExportConfig      `json:"export"`
+	Account     AccountConfig     `json:"account"`
+	Votes       VotesConfig       `json:"votes"`
+	Storage     StorageConfig     `json:"storage"`
+	Avatar      AvatarConfig      `json:"avatar"`
+	Attachments AttachmentsConfig `json:"attachments"`
+	Mail        MailConfig        `json:"mail"`
+	Webhooks    WebhooksConfig    `json:"webhooks"`
+	Moderation  ModerationConfig  `json:"moderation"`
+	Fraud       FraudConfig       `json:"fraud"`
 }
 
 type AppConfig struct {
 	BindAddr string `json:"bind_addr"`
 	BindPort string `json:"bind_port"`
 	LogLevel string `json:"log_level"`
+	// PrivateKeyPath Путь к RSA ключу для подписи JWT токенов
+	PrivateKeyPath string `json:"private_key_path"`
 }
 
 type DatabaseConfig struct {
@@ -19,13 +34,139 @@ type DatabaseConfig struct {
 	DatabaseName string `json:"database"`
 }
 
+// SessionConfig Настройки refresh сессий пользователей
+type SessionConfig struct {
+	// MaxPerUser Максимальное число активных сессий у одного пользователя, самые старые вытесняются
+	MaxPerUser int `json:"max_per_user"`
+	// CleanupIntervalMinutes Как часто удалять истекшие сессии из базы
+	CleanupIntervalMinutes int `json:"cleanup_interval_minutes"`
+}
+
+// ExportConfig Настройки выгрузки персональных данных
+type ExportConfig struct {
+	// Dir Папка для готовых архивов
+	Dir string `json:"dir"`
+	// LinkTTLMinutes Сколько живет ссылка на скачивание архива
+	LinkTTLMinutes int `json:"link_ttl_minutes"`
+	// SyncMaxRecords Аккаунты с большим числом записей выгружаются в фоне
+	SyncMaxRecords int64 `json:"sync_max_records"`
+	// WorkerIntervalSeconds Как часто фоновая задача проверяет очередь выгрузок
+	WorkerIntervalSeconds int `json:"worker_interval_seconds"`
+	// ProcessingTimeoutMinutes Через сколько минут незавершенная сборка архива начинается заново
+	ProcessingTimeoutMinutes int `json:"processing_timeout_minutes"`
+}
+
+// AccountConfig Настройки удаления аккаунтов
+type AccountConfig struct {
+	// DeletionGraceDays Сколько дней пользователь может отменить удаление аккаунта
+	DeletionGraceDays int `json:"deletion_grace_days"`
+	// DeletionCheckIntervalMinutes Как часто проверять аккаунты, которые пора удалить
+	DeletionCheckIntervalMinutes int `json:"deletion_check_interval_minutes"`
+}
+
+// VotesConfig Настройки подсчета голосов
+type VotesConfig struct {
+	// ExcludePassiveVoters Не учитывать в публичных счетчиках голоса отключенных пользователей
+	ExcludePassiveVoters bool `json:"exclude_passive_voters"`
+}
+
+// StorageConfig Настройки хранилища загруженных файлов
+type StorageConfig struct {
+	// Dir Папка для файлов
+	Dir string `json:"dir"`
+	// BaseURL Префикс, по которому сервер раздает файлы из папки
+	BaseURL string `json:"base_url"`
+}
+
+// AvatarConfig Настройки аватаров пользователей
+type AvatarConfig struct {
+	// MaxSizeKB Максимальный размер загружаемого изображения в килобайтах
+	MaxSizeKB int64 `json:"max_size_kb"`
+}
+
+// AttachmentsConfig Ограничения на обложки и документы петиций
+type AttachmentsConfig struct {
+	// MaxFileSizeKB Максимальный размер одного файла в килобайтах
+	MaxFileSizeKB int64 `json:"max_file_size_kb"`
+	// MaxFilesPerPetition Сколько документов можно прикрепить к одной петиции (обложка не считается)
+	MaxFilesPerPetition int64 `json:"max_files_per_petition"`
+	// MaxTotalSizeMB Общий размер документов одной петиции в мегабайтах
+	MaxTotalSizeMB int64 `json:"max_total_size_mb"`
+}
+
+// MailConfig Настройки писем с уведомлениями
+type MailConfig struct {
+	// Driver Способ отправки: smtp или file (письма сохраняются в FileDir)
+	Driver  string `json:"driver"`
+	From    string `json:"from"`
+	FileDir string `json:"file_dir"`
+	// SMTPHost, SMTPPort, SMTPUsername, SMTPPassword Настройки SMTP сервера для driver = smtp
+	SMTPHost     string `json:"smtp_host"`
+	SMTPPort     string `json:"smtp_port"`
+	SMTPUsername string `json:"smtp_username"`
+	SMTPPassword string `json:"smtp_password"`
+	// BaseURL Адрес сервиса для ссылок в письмах
+	BaseURL string `json:"base_url"`
+	// UnsubscribeSecret Ключ подписи ссылок отписки и подтверждения email. Если пустой, генерируется при запуске
+	UnsubscribeSecret string `json:"unsubscribe_secret"`
+	// VerificationTTLHours Сколько часов действует ссылка подтверждения email
+	VerificationTTLHours int `json:"verification_ttl_hours"`
+	// DigestHour Час отправки сводок
+	DigestHour int `json:"digest_hour"`
+	// WeeklyDay День недели еженедельной сводки: 0 - воскресенье, 1 - понедельник
+	WeeklyDay int `json:"weekly_day"`
+	// IntervalSeconds Как часто проверять новые уведомления для писем
+	IntervalSeconds int `json:"interval_seconds"`
+}
+
+// WebhooksConfig Настройки исходящих вебхуков
+type WebhooksConfig struct {
+	// MaxPerUser Сколько вебхуков может создать один пользователь
+	MaxPerUser int `json:"max_per_user"`
+	// MaxAttempts Сколько попыток доставки до перевода в мертвые
+	MaxAttempts int `json:"max_attempts"`
+	// RetryBaseSeconds Пауза после первой неудачной попытки, дальше удваивается
+	RetryBaseSeconds int `json:"retry_base_seconds"`
+	// RetryMaxMinutes Наибольшая пауза между попытками
+	RetryMaxMinutes int `json:"retry_max_minutes"`
+	// TimeoutSeconds Сколько ждать ответа получателя
+	TimeoutSeconds int `json:"timeout_seconds"`
+	// RetentionDays Сколько дней хранить журнал завершенных доставок
+	RetentionDays int `json:"retention_days"`
+	// AllowPrivateURLs Разрешить доставку на локальные и внутренние адреса, например при разработке
+	AllowPrivateURLs bool `json:"allow_private_urls"`
+	// IntervalSeconds Как часто проверять очередь событий
+	IntervalSeconds int `json:"interval_seconds"`
+}
+
+// ModerationConfig Настройки фильтра комментариев и причин подписи
+type ModerationConfig struct {
+	// BannedWords Запрещенные слова, "слово*" запрещает все слова с этим началом
+	BannedWords []string `json:"banned_words"`
+}
+
+// FraudConfig Пороги поиска накруток подписей. Нулевой порог отключает проверку
+type FraudConfig struct {
+	// WindowMinutes За сколько минут считаются голоса с одного адреса или от новых аккаунтов
+	WindowMinutes int `json:"window_minutes"`
+	// MaxVotesPerIP Сколько голосов за петицию с одного IP за окно не вызывают подозрений
+	MaxVotesPerIP int `json:"max_votes_per_ip"`
+	// MaxVotesPerSubnet То же для подсети /24 (IPv4) или /64 (IPv6)
+	MaxVotesPerSubnet int `json:"max_votes_per_subnet"`
+	// NewAccountHours Аккаунт считается новым, если подписал раньше, чем через столько часов после регистрации
+	NewAccountHours int `json:"new_account_hours"`
+	// MaxNewAccountVotes Сколько голосов новых аккаунтов за петицию за окно не вызывают подозрений
+	MaxNewAccountVotes int `json:"max_new_account_votes"`
+}
+
 // NewConfig Возвращает конфигураций по умолчанию
 func NewConfig() *Config {
 	return &Config{
 		App: AppConfig{
-			BindAddr: "0.0.0.0",
-			BindPort: "8080",
-			LogLevel: "debug",
+			BindAddr:       "0.0.0.0",
+			BindPort:       "8080",
+			LogLevel:       "debug",
+			PrivateKeyPath: "configs/private_key.pem",
 		},
 		Database: DatabaseConfig{
 			Host:     "localhost",
@@ -33,5 +174,81 @@ func NewConfig() *Config {
 			Username: "root",
 			Password: "root",
 		},
+		Session: SessionConfig{
+			MaxPerUser:             5,
+			CleanupIntervalMinutes: 60,
+		},
+		Export: ExportConfig{
+			Dir:                      "exports",
+			LinkTTLMinutes:           60,
+			SyncMaxRecords:           1000,
+			WorkerIntervalSeconds:    10,
+			ProcessingTimeoutMinutes: 30,
+		},
+		Account: AccountConfig{
+			DeletionGraceDays:            14,
+			DeletionCheckIntervalMinutes: 60,
+		},
+		Storage: StorageConfig{
+			Dir:     "uploads",
+			BaseURL: "/files",
+		},
+		Avatar: AvatarConfig{
+			MaxSizeKB: 5120,
+		},
+		Attachments: AttachmentsConfig{
+			MaxFileSizeKB:       10240,
+			MaxFilesPerPetition: 10,
+			MaxTotalSizeMB:      50,
+		},
+		Mail: MailConfig{
+			Driver:               "file",
+			From:                 "noreply@petition.local",
+			FileDir:              "mail",
+			SMTPPort:             "587",
+			BaseURL:              "http://localhost:8080",
+			VerificationTTLHours: 48,
+			DigestHour:           9,
+			WeeklyDay:            1,
+			IntervalSeconds:      60,
+		},
+		Webhooks: WebhooksConfig{
+			MaxPerUser:       10,
+			MaxAttempts:      8,
+			RetryBaseSeconds: 30,
+			RetryMaxMinutes:  360,
+			TimeoutSeconds:   10,
+			RetentionDays:    30,
+			IntervalSeconds:  5,
+		},
+		Fraud: FraudConfig{
+			WindowMinutes:      10,
+			MaxVotesPerIP:      5,
+			MaxVotesPerSubnet:  20,
+			NewAccountHours:    24,
+			MaxNewAccountVotes: 10,
+		},
+	}
+}
+
+// Validate Проверяет интервалы фоновых задач: нулевой или отрицательный интервал
+// (например, 0 в файле конфигурации) уронил бы сервер при запуске таймера
+func (c *Config) Validate() error {
+	intervals := []struct {
+		name  string
+		value int
+	}{
+		{"session.cleanup_interval_minutes", c.Session.CleanupIntervalMinutes},
+		{"export.worker_interval_seconds", c.Export.WorkerIntervalSeconds},
+		{"export.processing_timeout_minutes", c.Export.ProcessingTimeoutMinutes},
+		{"account.deletion_check_interval_minutes", c.Account.DeletionCheckIntervalMinutes},
+		{"mail.interval_seconds", c.Mail.IntervalSeconds},
+		{"webhooks.interval_seconds", c.Webhooks.IntervalSeconds},
+	}
+	for _, interval := range intervals {
+		if interval.value <= 0 {
+			return fmt.Errorf("config: %s must be positive, got %d", interval.name, interval.value)
+		}
 	}
+	return nil
 }
@@ -0,0 +1,30 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestConfigValidateIntervals(t *testing.T) {
+	assert.NoError(t, NewConfig().Validate())
+
+	// Нулевой интервал из файла конфигурации отклоняется до запуска задач
+	for _, file := range []string{
+		`{"session": {"cleanup_interval_minutes": 0}}`,
+		`{"export": {"worker_interval_seconds": 0}}`,
+		`{"export": {"processing_timeout_minutes": -1}}`,
+		`{"account": {"deletion_check_interval_minutes": 0}}`,
+		`{"mail": {"interval_seconds": 0}}`,
+		`{"webhooks": {"interval_seconds": 0}}`,
+	} {
+		config := NewConfig()
+		assert.NoError(t, json.Unmarshal([]byte(file), config))
+		assert.Error(t, config.Validate(), file)
+	}
+
+	// Незаданные поля берутся из значений по умолчанию
+	config := NewConfig()
+	assert.NoError(t, json.Unmarshal([]byte(`{"session": {"max_per_user": 3}}`), config))
+	assert.NoError(t, config.Validate())
+}
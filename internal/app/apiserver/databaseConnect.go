@@ -9,28 +9,33 @@ import (
 )
 
 func configureDB(s *ApiServer) error {
+	gormDb, err := ConnectDatabase(s.config.Database)
+	if err != nil {
+		s.logger.Errorf("failed to connect to database: %v", err)
+		return err
+	}
+
+	s.db = gormDb
+	return nil
+}
+
+// ConnectDatabase Подключается к серверу базы, создает базу, если ее нет, и обновляет таблицы.
+// Используется сервером и консольными командами
+func ConnectDatabase(config DatabaseConfig) (*gorm.DB, error) {
 	// Mysql in openserver 6.0.0
 	connectionString := fmt.Sprintf("%s:%s@tcp(%s:%s)/?charset=utf8mb4&parseTime=True&loc=Local",
-		s.config.Database.Username,
-		s.config.Database.Password,
-		s.config.Database.Host,
-		s.config.Database.Port,
+		config.Username,
+		config.Password,
+		config.Host,
+		config.Port,
 	)
 	db, err := gorm.Open(mysql.Open(connectionString), &gorm.Config{})
 	if err != nil {
-		s.logger.Errorf("failed to connect to MySQL server: %v", err)
-		return err
+		return nil, fmt.Errorf("failed to connect to MySQL server: %w", err)
 	}
 
 	// Создаем базу данных, если ее нет
-	gormDb, err := createDatabaseIfNotExistsAndConnect(db, s.config.Database)
-	if err != nil {
-		s.logger.Errorf("failed to connect to database: %v", err)
-		return err
-	}
-
-	s.db = gormDb
-	return nil
+	return createDatabaseIfNotExistsAndConnect(db, config)
 }
 
 // createDatabaseIfNotExistsAndConnect Создает базу если нет в сервере базы
@@ -51,62 +56,64 @@ func createDatabaseIfNotExistsAndConnect(db *gorm.DB, config DatabaseConfig) (*g
 		}
 	}(sqlDb)
 
-	// Тут два сценария:
-	// 1) Если есть база (Valid) то подключаемся и возвращаем gormDb
-	// 2) Если нет, то создаем базу и таблицу, подключаемся к этой базе и вернем подключение к базе
-	if result.Valid {
-		// Если база существует подключемся к нему
-		connectionString := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-			config.Username,
-			config.Password,
-			config.Host,
-			config.Port,
-			config.DatabaseName,
-		)
-		gormDB, err := gorm.Open(mysql.Open(connectionString), &gorm.Config{})
-		if err != nil {
-			return nil, err
-		}
-
-		return gormDB, nil // База данных уже существует
-	} else {
-		// Тут происходит если нет базы данных и нужно создать и вернуть
+	// Если базы нет, то создаем ее
+	if !result.Valid {
 		err = db.Exec(fmt.Sprintf("CREATE DATABASE %s", config.DatabaseName)).Error
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		// Если база существует подключемся к нему
-		connectionString := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-			config.Username,
-			config.Password,
-			config.Host,
-			config.Port,
-			config.DatabaseName,
-		)
-		gormDb, err := gorm.Open(mysql.Open(connectionString), &gorm.Config{})
-		if err != nil {
-			return nil, err
-		}
+	// Подключаемся к базе
+	connectionString := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+		config.Username,
+		config.Password,
+		config.Host,
+		config.Port,
+		config.DatabaseName,
+	)
+	gormDb, err := gorm.Open(mysql.Open(connectionString), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
 
-		// Создаем необходимые таблицы
-		err = gormDb.AutoMigrate(
-			models.UserModel{},
-			models.RefreshSession{},
-			models.Petition{},
-			models.Comment{},
-			models.Vote{},
-		)
-		if err != nil {
-			return nil, err
-		}
+	// Создаем и обновляем необходимые таблицы при каждом запуске,
+	// чтобы новые поля и таблицы появлялись и в уже существующей базе
+	if err := migrate(gormDb); err != nil {
+		return nil, err
+	}
 
-		// Создать уникальный индекс чтобы не было дважды голосовать в одну петицию
-		err = gormDb.Exec("CREATE UNIQUE INDEX idx_user_petition ON votes(user_id, petition_id)").Error
-		if err != nil {
-			return nil, err
-		}
+	return gormDb, nil
+}
 
-		return gormDb, nil
-	}
+// migrate Создает и обновляет таблицы
+func migrate(db *gorm.DB) error {
+	return db.AutoMigrate(
+		models.UserModel{},
+		models.RefreshSession{},
+		models.Petition{},
+		models.Comment{},
+		models.Vote{},
+		models.APIKey{},
+		models.Permission{},
+		models.Role{},
+		models.UserAuditLog{},
+		models.DataExport{},
+		models.UserBan{},
+		models.PetitionAttachment{},
+		models.PetitionRevision{},
+		models.PetitionNews{},
+		models.Recipient{},
+		models.PetitionResponse{},
+		models.Notification{},
+		models.PetitionSubscription{},
+		models.NotificationPreference{},
+		models.PetitionMilestone{},
+		models.Webhook{},
+		models.WebhookEvent{},
+		models.WebhookDelivery{},
+		models.PetitionEligibility{},
+		models.VoteFlag{},
+		models.VoteAuditEntry{},
+	)
 }
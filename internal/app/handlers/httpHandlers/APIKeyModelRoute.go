@@ -0,0 +1,114 @@
+package httpHandlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/middleware"
+	"petition_api/utils/auth"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type APIKeyModelRoute struct {
+	repo     repository.APIKeyRepository
+	accounts middleware.AccountChecker
+	logger   *logrus.Logger
+}
+
+// NewAPIKeyModelRoute создает роут для персональных API ключей
+func NewAPIKeyModelRoute(repo repository.APIKeyRepository, accounts middleware.AccountChecker, logger *logrus.Logger) *APIKeyModelRoute {
+	return &APIKeyModelRoute{repo: repo, accounts: accounts, logger: logger}
+}
+
+func (ar *APIKeyModelRoute) BindAPIKeyToRoute(route *gin.RouterGroup) {
+	authMiddleware := middleware.NewAuthMiddleware(ar.logger, ar.accounts)
+
+	route.POST("", authMiddleware, ar.createAPIKey)
+	route.GET("", authMiddleware, ar.getAPIKeys)
+	route.DELETE("/:id", authMiddleware, ar.revokeAPIKey)
+}
+
+func (ar *APIKeyModelRoute) createAPIKey(c *gin.Context) {
+	var input models.APIKeyCreate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		ar.logger.Errorf("Invalid request body: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	rawKey, prefix, secretHash, err := auth.GenerateAPIKey()
+	if err != nil {
+		ar.logger.Errorf("Error generating api key: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	key := models.APIKey{
+		UserID:     c.Value("ID").(uint),
+		Name:       input.Name,
+		Prefix:     prefix,
+		SecretHash: secretHash,
+		Scopes:     strings.Join(uniqueStrings(input.Scopes), ","),
+	}
+	if input.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, input.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := ar.repo.Create(&key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	// Полный ключ возвращается только один раз
+	view := models.NewAPIKeyView(&key)
+	view.Key = rawKey
+	c.JSON(http.StatusCreated, view)
+}
+
+func (ar *APIKeyModelRoute) getAPIKeys(c *gin.Context) {
+	keys, err := ar.repo.GetAllByUserID(c.Value("ID").(uint))
+	if err != nil {
+		ar.logger.Errorf("Error getting api keys: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get API keys"})
+		return
+	}
+
+	views := make([]models.APIKeyView, 0, len(keys))
+	for i := range keys {
+		views = append(views, models.NewAPIKeyView(&keys[i]))
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+func (ar *APIKeyModelRoute) revokeAPIKey(c *gin.Context) {
+	keyID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	if err := ar.repo.Revoke(uint(keyID), c.Value("ID").(uint)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// uniqueStrings убирает повторы, сохраняя порядок
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,79 @@
+package httpHandlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"petition_api/internal/app/services"
+	"petition_api/middleware"
+)
+
+// multipartOverhead Запас на заголовки multipart формы сверх размера самого файла
+const multipartOverhead = 64 << 10
+
+type AvatarModelRoute struct {
+	avatars  *services.AvatarService
+	accounts middleware.AccountChecker
+	logger   *logrus.Logger
+}
+
+// NewAvatarModelRoute создает роут для загрузки аватара текущего пользователя
+func NewAvatarModelRoute(avatars *services.AvatarService, accounts middleware.AccountChecker, logger *logrus.Logger) *AvatarModelRoute {
+	return &AvatarModelRoute{avatars: avatars, accounts: accounts, logger: logger}
+}
+
+func (ar *AvatarModelRoute) BindAvatarToRoute(route *gin.RouterGroup) {
+	authMiddleware := middleware.NewAuthMiddleware(ar.logger, ar.accounts)
+
+	route.PUT("", authMiddleware, ar.uploadAvatar)
+	route.DELETE("", authMiddleware, ar.deleteAvatar)
+}
+
+// uploadAvatar Принимает изображение в поле avatar multipart формы и возвращает ссылки на миниатюры
+func (ar *AvatarModelRoute) uploadAvatar(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, ar.avatars.MaxSize()+multipartOverhead)
+
+	fileHeader, err := c.FormFile("avatar")
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrAvatarTooLarge.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Avatar file is required in the 'avatar' form field"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read avatar file"})
+		return
+	}
+	defer file.Close()
+
+	urls, err := ar.avatars.Upload(c.Value("ID").(uint), file)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAvatarTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrAvatarUnsupported), errors.Is(err, services.ErrAvatarDimensions):
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		default:
+			ar.logger.Errorf("Failed to upload avatar: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload avatar"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"avatar": urls})
+}
+
+// deleteAvatar Удаляет аватар текущего пользователя
+func (ar *AvatarModelRoute) deleteAvatar(c *gin.Context) {
+	if err := ar.avatars.Delete(c.Value("ID").(uint)); err != nil {
+		ar.logger.Errorf("Failed to delete avatar: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete avatar"})
+		return
+	}
+	c.Status(http.StatusOK)
+}
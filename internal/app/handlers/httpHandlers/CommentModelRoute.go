@@ -6,22 +6,28 @@ import (
 	"net/http"
 	"petition_api/internal/app/models"
 	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/services"
 	"petition_api/middleware"
 	"strconv"
 )
 
 type CommentModelRoute struct {
-	repo   repository.CommentRepository
-	logger *logrus.Logger
+	repo          repository.CommentRepository
+	users         repository.UserRepository
+	roles         repository.RoleRepository
+	notifications *services.NotificationService
+	filter        *services.ContentFilter
+	accounts      middleware.AccountChecker
+	logger        *logrus.Logger
 }
 
 // NewCommentModelRoute создает новый роут для комментариев
-func NewCommentModelRoute(repo repository.CommentRepository, logger *logrus.Logger) *CommentModelRoute {
-	return &CommentModelRoute{repo: repo, logger: logger}
+func NewCommentModelRoute(repo repository.CommentRepository, users repository.UserRepository, roles repository.RoleRepository, notifications *services.NotificationService, filter *services.ContentFilter, accounts middleware.AccountChecker, logger *logrus.Logger) *CommentModelRoute {
+	return &CommentModelRoute{repo: repo, users: users, roles: roles, notifications: notifications, filter: filter, accounts: accounts, logger: logger}
 }
 
 func (cr *CommentModelRoute) BindCommentToRoute(route *gin.RouterGroup) {
-	authMiddleware := middleware.NewAuthMiddleware(cr.logger)
+	authMiddleware := middleware.NewAuthMiddleware(cr.logger, cr.accounts)
 
 	route.POST("", authMiddleware, cr.createComment)
 	route.GET("", cr.getComments)
@@ -32,17 +38,34 @@ func (cr *CommentModelRoute) BindCommentToRoute(route *gin.RouterGroup) {
 func (cr *CommentModelRoute) createComment(c *gin.Context) {
 	var comment models.Comment
 	if err := c.ShouldBindJSON(&comment); err != nil {
-		cr.logger.Error("Invalid request body: %v", err)
+		cr.logger.Errorf("Invalid request body: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
+	if err := cr.filter.Check(comment.Content); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Автор комментария - текущий пользователь
+	comment.UserID = c.Value("ID").(uint)
+	// Отвечать можно только на комментарий той же петиции
+	if comment.ParentID != nil {
+		parent, err := cr.repo.GetByID(*comment.ParentID)
+		if err != nil || parent.PetitionID != comment.PetitionID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent comment not found"})
+			return
+		}
+	}
 
 	newCommentID, err := cr.repo.Create(&comment)
 	if err != nil {
-		cr.logger.Error("Error creating comment: %v", err)
+		cr.logger.Errorf("Error creating comment: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comment"})
 		return
 	}
+	if err := cr.notifications.CommentCreated(&comment); err != nil {
+		cr.logger.Errorf("Failed to notify about comment %d: %v", newCommentID, err)
+	}
 
 	c.JSON(http.StatusCreated, gin.H{"id": newCommentID})
 }
@@ -59,10 +82,11 @@ func (cr *CommentModelRoute) getComments(c *gin.Context) {
 
 	comments, err := cr.repo.GetAll(page, pageSize)
 	if err != nil {
-		cr.logger.Error("Error getting comments: %v", err)
+		cr.logger.Errorf("Error getting comments: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get comments"})
 		return
 	}
+	cr.fillAuthorAvatars(comments)
 
 	c.JSON(http.StatusOK, comments)
 }
@@ -79,8 +103,10 @@ func (cr *CommentModelRoute) getCommentByID(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
 		return
 	}
+	comments := []models.Comment{*comment}
+	cr.fillAuthorAvatars(comments)
 
-	c.JSON(http.StatusOK, comment)
+	c.JSON(http.StatusOK, comments[0])
 }
 
 func (cr *CommentModelRoute) deleteComment(c *gin.Context) {
@@ -90,11 +116,40 @@ func (cr *CommentModelRoute) deleteComment(c *gin.Context) {
 		return
 	}
 
+	comment, err := cr.repo.GetByID(uint(commentID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+
+	// Удалить комментарий может автор или пользователь с правом comment.delete.any
+	if comment.UserID != c.Value("ID").(uint) && !middleware.HasPermission(c, &cr.roles, models.PermCommentDeleteAny) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Doesn't have access"})
+		return
+	}
+
 	if err := cr.repo.DeleteByID(uint(commentID)); err != nil {
-		cr.logger.Error("Error deleting comment: %v", err)
+		cr.logger.Errorf("Error deleting comment: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
 		return
 	}
 
 	c.Status(http.StatusOK)
 }
+
+// fillAuthorAvatars Добавляет к комментариям ссылки на аватары авторов одним запросом к базе
+func (cr *CommentModelRoute) fillAuthorAvatars(comments []models.Comment) {
+	ids := make([]uint, 0, len(comments))
+	for _, comment := range comments {
+		ids = append(ids, comment.UserID)
+	}
+	keys, err := cr.users.GetAvatarKeys(ids)
+	if err != nil {
+		// Комментарии без аватаров лучше, чем ошибка
+		cr.logger.Errorf("Failed to get comment author avatars: %v", err)
+		return
+	}
+	for i := range comments {
+		comments[i].AuthorAvatar = models.NewAvatarURLs(keys[comments[i].UserID])
+	}
+}
@@ -0,0 +1,128 @@
+package httpHandlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/services"
+	"petition_api/middleware"
+	"strconv"
+	"time"
+)
+
+type DataExportModelRoute struct {
+	repo     repository.DataExportRepository
+	exporter *services.UserDataExportService
+	// syncMaxRecords Аккаунты с большим числом записей выгружаются в фоне
+	syncMaxRecords int64
+	accounts       middleware.AccountChecker
+	logger         *logrus.Logger
+}
+
+// NewDataExportModelRoute создает роут для выгрузки персональных данных
+func NewDataExportModelRoute(repo repository.DataExportRepository, exporter *services.UserDataExportService, syncMaxRecords int64, accounts middleware.AccountChecker, logger *logrus.Logger) *DataExportModelRoute {
+	return &DataExportModelRoute{repo: repo, exporter: exporter, syncMaxRecords: syncMaxRecords, accounts: accounts, logger: logger}
+}
+
+// BindDataExportToRoute Привязывает роуты к группе /user
+func (er *DataExportModelRoute) BindDataExportToRoute(route *gin.RouterGroup) {
+	authMiddleware := middleware.NewAuthMiddleware(er.logger, er.accounts)
+
+	route.GET("/me/export", authMiddleware, er.exportData)
+	route.GET("/me/export/:id", authMiddleware, er.getExportStatus)
+	// Ссылка на скачивание не требует авторизации, доступ дает секретный токен с ограниченным сроком
+	route.GET("/export/download/:token", er.downloadExport)
+}
+
+// exportData Отдает ZIP архив сразу, а для больших аккаунтов (или с ?async=true) ставит выгрузку в очередь
+func (er *DataExportModelRoute) exportData(c *gin.Context) {
+	userID := c.Value("ID").(uint)
+
+	async := c.Query("async") == "true"
+	if !async {
+		count, err := er.exporter.CountRecords(userID)
+		if err != nil {
+			er.logger.Errorf("Error counting user records: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export data"})
+			return
+		}
+		async = count > er.syncMaxRecords
+	}
+
+	if !async {
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user_%d_export_%s.zip"`,
+			userID, time.Now().Format("20060102")))
+		c.Status(http.StatusOK)
+		if err := er.exporter.WriteArchive(c.Writer, userID); err != nil {
+			// Заголовки уже отправлены, поэтому только логируем
+			er.logger.Errorf("Error writing data export for user %d: %v", userID, err)
+		}
+		return
+	}
+
+	// Не создаем новую задачу, если предыдущая еще не готова
+	export, err := er.repo.GetActiveByUserID(userID)
+	if err != nil {
+		er.logger.Errorf("Error getting active data export: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export data"})
+		return
+	}
+	if export == nil {
+		export = &models.DataExport{UserID: userID, Status: models.ExportStatusPending}
+		if err := er.repo.Create(export); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export data"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusAccepted, er.exportView(c, export))
+}
+
+func (er *DataExportModelRoute) getExportStatus(c *gin.Context) {
+	exportID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export ID"})
+		return
+	}
+
+	export, err := er.repo.GetByID(uint(exportID), c.Value("ID").(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, er.exportView(c, export))
+}
+
+func (er *DataExportModelRoute) downloadExport(c *gin.Context) {
+	export, err := er.repo.GetByToken(c.Param("token"))
+	if err != nil || export.ExpiresAt == nil || time.Now().After(*export.ExpiresAt) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Download link is invalid or expired"})
+		return
+	}
+
+	c.FileAttachment(export.FilePath, fmt.Sprintf("user_%d_export_%s.zip",
+		export.UserID, export.CreatedAt.Format("20060102")))
+}
+
+// exportView Статус выгрузки и ссылка на скачивание, когда архив готов
+func (er *DataExportModelRoute) exportView(c *gin.Context, export *models.DataExport) gin.H {
+	view := gin.H{
+		"id":         export.ID,
+		"status":     export.Status,
+		"status_url": fmt.Sprintf("/user/me/export/%d", export.ID),
+		"created_at": export.CreatedAt,
+	}
+	if export.Status == models.ExportStatusReady && export.ExpiresAt != nil && time.Now().Before(*export.ExpiresAt) {
+		view["download_url"] = "/user/export/download/" + export.Token
+		view["expires_at"] = export.ExpiresAt
+	}
+	if export.Status == models.ExportStatusFailed {
+		view["error"] = export.Error
+	}
+	return view
+}
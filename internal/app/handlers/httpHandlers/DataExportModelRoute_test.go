@@ -0,0 +1,194 @@
+package httpHandlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"path/filepath"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/services"
+	"petition_api/internal/app/testutil"
+	"testing"
+	"time"
+)
+
+func newDataExportRouteTest(t *testing.T, syncMaxRecords int64) (*gorm.DB, *gin.Engine) {
+	testutil.SetAuthKey(t)
+
+	db := testutil.OpenDB(t,
+		models.UserModel{},
+		models.RefreshSession{},
+		models.Petition{},
+		models.Comment{},
+		models.Vote{},
+		models.VoteFlag{},
+		models.APIKey{},
+		models.DataExport{},
+		models.Notification{},
+		models.NotificationPreference{},
+		models.PetitionSubscription{},
+		models.Webhook{},
+		models.UserBan{},
+	)
+
+	logger := testutil.Logger()
+	exportRepo := repository.NewDataExportRepository(db, logger)
+	exporter := services.NewUserDataExportService(
+		repository.NewUserRepository(db, logger),
+		repository.NewSessionRepo(db, logger),
+		repository.NewPetitionRepository(db, logger),
+		repository.NewCommentRepository(db, logger),
+		repository.NewVoteRepository(db, logger),
+		repository.NewVoteFlagRepository(db, logger),
+		repository.NewAPIKeyRepository(db, logger),
+		repository.NewNotificationRepository(db, logger),
+		repository.NewNotificationPreferenceRepository(db, logger),
+		repository.NewSubscriptionRepository(db, logger),
+		repository.NewWebhookRepository(db, logger),
+		repository.NewUserBanRepository(db, logger),
+		logger,
+	)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewDataExportModelRoute(exportRepo, exporter, syncMaxRecords, nil, logger).BindDataExportToRoute(router.Group("/user"))
+	return db, router
+}
+
+// readArchive Распаковывает ZIP архив в отображение имя файла - содержимое
+func readArchive(t *testing.T, body []byte) map[string]string {
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		reader.Close()
+		files[file.Name] = string(content)
+	}
+	return files
+}
+
+func TestDataExportSyncArchive(t *testing.T) {
+	db, router := newDataExportRouteTest(t, 10)
+	user := models.UserModel{Login: "aigerim", Password: "x", Email: "aigerim@mail.kz", Role: models.RoleUser, Status: models.StatusActive}
+	require.NoError(t, db.Create(&user).Error)
+	other := models.UserModel{Login: "other", Password: "x", Email: "other@mail.kz", Role: models.RoleUser, Status: models.StatusActive}
+	require.NoError(t, db.Create(&other).Error)
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", UserID: user.ID}
+	require.NoError(t, db.Create(&petition).Error)
+	require.NoError(t, db.Create(&models.Comment{Content: "Поддерживаю", UserID: user.ID, Login: user.Login, PetitionID: petition.ID}).Error)
+	require.NoError(t, db.Create(&models.Comment{Content: "Чужой комментарий", UserID: other.ID, Login: other.Login, PetitionID: petition.ID}).Error)
+	vote := models.Vote{Login: user.Login, UserID: user.ID, PetitionID: petition.ID, Visibility: models.VoteVisibilityHidden,
+		Reason: "Нужен парк", IP: "10.0.0.7", Subnet: "10.0.0.0/24", UA: "Firefox"}
+	require.NoError(t, db.Create(&vote).Error)
+	require.NoError(t, db.Create(&models.VoteFlag{VoteID: vote.ID, Reason: models.VoteFlagSameIP, PetitionID: petition.ID, UserID: user.ID,
+		Login: user.Login, Details: "5 votes", IP: vote.IP, UA: vote.UA, Status: models.VoteFlagPending, CreatedAt: time.Now()}).Error)
+	reviewedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, reason := range []string{models.VoteFlagSameSubnet, models.VoteFlagNewAccounts} {
+		require.NoError(t, db.Create(&models.VoteFlag{VoteID: vote.ID + 100, Reason: reason, PetitionID: petition.ID, UserID: user.ID,
+			Login: user.Login, Details: "12 votes in 5 minutes", IP: "10.0.0.9", UA: "Firefox", Status: models.VoteFlagInvalidated,
+			ReviewedAt: &reviewedAt, CreatedAt: time.Now()}).Error)
+	}
+	require.NoError(t, db.Create(&models.Notification{UserID: user.ID, Type: models.NotificationPetitionComment, PetitionID: petition.ID, Text: "Новый комментарий"}).Error)
+	require.NoError(t, db.Create(&models.NotificationPreference{UserID: user.ID, EventType: models.NotificationPetitionComment, Mode: models.DeliveryOff}).Error)
+	require.NoError(t, db.Create(&models.Webhook{UserID: user.ID, URL: "https://example.kz/hook", Secret: "webhook-secret", Events: models.WebhookVoteCreated, Active: true}).Error)
+	require.NoError(t, db.Create(&models.UserBan{UserID: user.ID, BannedByID: other.ID, Reason: "Спам"}).Error)
+	require.NoError(t, db.Create(&models.RefreshSession{UserID: user.ID, RefreshToken: "secret-refresh", UA: "Firefox", IP: "10.0.0.1",
+		Fingerprint: "fp", ExpiresIn: time.Now().Add(time.Hour).Unix()}).Error)
+
+	w := petitionRequest(router, http.MethodGet, "/user/me/export", "", user.ID, models.RoleUser)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+
+	files := readArchive(t, w.Body.Bytes())
+	for _, name := range []string{"profile.json", "sessions.json", "petitions.json", "comments.json", "votes.json", "invalidated_votes.json", "api_keys.json",
+		"notifications.json", "notification_settings.json", "webhooks.json", "bans.json",
+		"sessions.csv", "petitions.csv", "comments.csv", "votes.csv"} {
+		assert.Contains(t, files, name)
+	}
+	assert.Contains(t, files["profile.json"], "aigerim@mail.kz")
+	assert.Contains(t, files["petitions.csv"], "Построить парк")
+	assert.Contains(t, files["comments.json"], "Поддерживаю")
+	assert.NotContains(t, files["comments.json"], "Чужой комментарий")
+	assert.Contains(t, files["sessions.csv"], "Firefox")
+	// Refresh токен в архив не попадает
+	assert.NotContains(t, files["sessions.json"], "secret-refresh")
+
+	// Вместе с подписью выгружается, откуда и как подписали
+	for _, value := range []string{"10.0.0.7", "10.0.0.0/24", "Firefox", "Нужен парк", models.VoteVisibilityHidden} {
+		assert.Contains(t, files["votes.json"], value)
+		assert.Contains(t, files["votes.csv"], value)
+	}
+	// Из отметок антифрода выгружается только факт и дата признания голоса недействительным
+	assert.NotContains(t, files, "vote_flags.json")
+	var invalidated []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(files["invalidated_votes.json"]), &invalidated))
+	require.Len(t, invalidated, 1)
+	assert.Equal(t, map[string]interface{}{"vote_id": float64(vote.ID + 100), "petition_id": float64(petition.ID),
+		"invalidated_at": "2026-03-01T12:00:00Z"}, invalidated[0])
+	assert.Contains(t, files["notifications.json"], "Новый комментарий")
+	assert.Contains(t, files["notification_settings.json"], `"`+models.NotificationPetitionComment+`": "`+models.DeliveryOff+`"`)
+	assert.Contains(t, files["webhooks.json"], "https://example.kz/hook")
+	assert.NotContains(t, files["webhooks.json"], "webhook-secret")
+	assert.Contains(t, files["bans.json"], "Спам")
+
+	var count int64
+	db.Model(&models.DataExport{}).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestDataExportAsyncQueue(t *testing.T) {
+	db, router := newDataExportRouteTest(t, 1)
+	user := models.UserModel{Login: "aigerim", Password: "x", Role: models.RoleUser, Status: models.StatusActive}
+	require.NoError(t, db.Create(&user).Error)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, db.Create(&models.Petition{Title: fmt.Sprintf("Петиция %d", i), UserID: user.ID}).Error)
+	}
+
+	// Записей больше порога, поэтому выгрузка уходит в очередь
+	w := petitionRequest(router, http.MethodGet, "/user/me/export", "", user.ID, models.RoleUser)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var queued struct {
+		ID        uint   `json:"id"`
+		Status    string `json:"status"`
+		StatusURL string `json:"status_url"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &queued))
+	assert.Equal(t, models.ExportStatusPending, queued.Status)
+	assert.Equal(t, fmt.Sprintf("/user/me/export/%d", queued.ID), queued.StatusURL)
+
+	// Пока выгрузка не готова, повторный запрос возвращает ее же
+	w = petitionRequest(router, http.MethodGet, "/user/me/export?async=true", "", user.ID, models.RoleUser)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	assert.Contains(t, w.Body.String(), fmt.Sprintf(`"id":%d`, queued.ID))
+	var count int64
+	db.Model(&models.DataExport{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// Чужую выгрузку посмотреть нельзя
+	w = petitionRequest(router, http.MethodGet, queued.StatusURL, "", user.ID+1, models.RoleUser)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	expiresAt := time.Now().Add(time.Hour)
+	path := filepath.Join(t.TempDir(), "export.zip")
+	require.NoError(t, db.Model(&models.DataExport{}).Where("id = ?", queued.ID).Updates(map[string]interface{}{
+		"status": models.ExportStatusReady, "token": "download-token", "file_path": path, "expires_at": expiresAt,
+	}).Error)
+	w = petitionRequest(router, http.MethodGet, queued.StatusURL, "", user.ID, models.RoleUser)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"download_url":"/user/export/download/download-token"`)
+
+	w = petitionRequest(router, http.MethodGet, "/user/export/download/wrong-token", "", 0, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,155 @@
+package httpHandlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"petition_api/internal/app/models"
+	"petition_api/internal/app/services"
+	"petition_api/middleware"
+	"strconv"
+)
+
+type NotificationModelRoute struct {
+	notifications *services.NotificationService
+	digests       *services.EmailDigestService
+	accounts      middleware.AccountChecker
+	logger        *logrus.Logger
+}
+
+// NewNotificationModelRoute создает роут для центра уведомлений пользователя
+func NewNotificationModelRoute(notifications *services.NotificationService, digests *services.EmailDigestService, accounts middleware.AccountChecker, logger *logrus.Logger) *NotificationModelRoute {
+	return &NotificationModelRoute{notifications: notifications, digests: digests, accounts: accounts, logger: logger}
+}
+
+func (nr *NotificationModelRoute) BindNotificationToRoute(route *gin.RouterGroup) {
+	authMiddleware := middleware.NewAuthMiddleware(nr.logger, nr.accounts)
+
+	route.GET("", authMiddleware, nr.getNotifications)
+	route.GET("/unread-count", authMiddleware, nr.getUnreadCount)
+	route.PUT("/read", authMiddleware, nr.markRead)
+	route.PUT("/read-all", authMiddleware, nr.markAllRead)
+	route.PUT("/:id/read", authMiddleware, nr.markOneRead)
+	route.GET("/preferences", authMiddleware, nr.getPreferences)
+	route.PUT("/preferences", authMiddleware, nr.updatePreferences)
+}
+
+// BindUnsubscribeToRoute Отписка по ссылке из письма, работает без авторизации.
+// POST нужен почтовым клиентам, которые отписывают в один клик по заголовку List-Unsubscribe-Post
+func (nr *NotificationModelRoute) BindUnsubscribeToRoute(route *gin.RouterGroup) {
+	route.GET("/unsubscribe", nr.unsubscribe)
+	route.POST("/unsubscribe", nr.unsubscribe)
+}
+
+// getNotifications Уведомления текущего пользователя, ?unread=true - только непрочитанные
+func (nr *NotificationModelRoute) getNotifications(c *gin.Context) {
+	var filter models.NotificationFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	page, err := nr.notifications.List(c.Value("ID").(uint), filter)
+	if err != nil {
+		nr.logger.Errorf("Error getting notifications: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notifications"})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+func (nr *NotificationModelRoute) getUnreadCount(c *gin.Context) {
+	unread, err := nr.notifications.UnreadCount(c.Value("ID").(uint))
+	if err != nil {
+		nr.logger.Errorf("Error counting notifications: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count notifications"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"unread": unread})
+}
+
+// markRead Отмечает прочитанными уведомления из списка {"ids": [...]}
+func (nr *NotificationModelRoute) markRead(c *gin.Context) {
+	var input models.NotificationReadInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	nr.respondMarked(c, input.IDs)
+}
+
+func (nr *NotificationModelRoute) markOneRead(c *gin.Context) {
+	notificationID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+	nr.respondMarked(c, []uint{uint(notificationID)})
+}
+
+func (nr *NotificationModelRoute) markAllRead(c *gin.Context) {
+	nr.respondMarked(c, nil)
+}
+
+// getPreferences Язык писем и режимы по типам событий: instant, daily, weekly или off
+func (nr *NotificationModelRoute) getPreferences(c *gin.Context) {
+	settings, err := nr.digests.GetSettings(c.Value("ID").(uint))
+	if err != nil {
+		nr.logger.Errorf("Error getting notification preferences: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get preferences"})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+func (nr *NotificationModelRoute) updatePreferences(c *gin.Context) {
+	var input models.NotificationSettings
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	settings, err := nr.digests.UpdateSettings(c.Value("ID").(uint), input)
+	if errors.Is(err, services.ErrInvalidNotificationSettings) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		nr.logger.Errorf("Error updating notification preferences: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update preferences"})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// unsubscribe Выключает письма по подписанной ссылке ?user=&type=&token=
+func (nr *NotificationModelRoute) unsubscribe(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Query("user"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unsubscribe link"})
+		return
+	}
+	eventType := c.Query("type")
+
+	err = nr.digests.Unsubscribe(uint(userID), eventType, c.Query("token"))
+	if errors.Is(err, services.ErrInvalidUnsubscribeToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unsubscribe link"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"unsubscribed": eventType})
+}
+
+func (nr *NotificationModelRoute) respondMarked(c *gin.Context, ids []uint) {
+	marked, err := nr.notifications.MarkRead(c.Value("ID").(uint), ids)
+	if err != nil {
+		nr.logger.Errorf("Error marking notifications as read: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark notifications as read"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"marked": marked})
+}
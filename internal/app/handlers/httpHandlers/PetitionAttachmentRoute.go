@@ -0,0 +1,195 @@
+package httpHandlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"mime"
+	"net/http"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/services"
+	"petition_api/internal/app/storage"
+	"petition_api/middleware"
+	"strconv"
+	"strings"
+)
+
+type PetitionAttachmentRoute struct {
+	attachments *services.PetitionAttachmentService
+	petitions   repository.PetitionRepository
+	roles       repository.RoleRepository
+	accounts    middleware.AccountChecker
+	logger      *logrus.Logger
+}
+
+// NewPetitionAttachmentRoute создает роут для обложек и документов петиций
+func NewPetitionAttachmentRoute(attachments *services.PetitionAttachmentService, petitions repository.PetitionRepository, roles repository.RoleRepository, accounts middleware.AccountChecker, logger *logrus.Logger) *PetitionAttachmentRoute {
+	return &PetitionAttachmentRoute{attachments: attachments, petitions: petitions, roles: roles, accounts: accounts, logger: logger}
+}
+
+func (ar *PetitionAttachmentRoute) BindAttachmentToRoute(route *gin.RouterGroup) {
+	authMiddleware := middleware.NewAuthMiddleware(ar.logger, ar.accounts)
+
+	route.POST("/:id/attachments", authMiddleware, ar.uploadAttachment)
+	route.GET("/:id/attachments", ar.getAttachments)
+	route.GET("/:id/attachments/:attachmentID", ar.downloadAttachment)
+	route.DELETE("/:id/attachments/:attachmentID", authMiddleware, ar.deleteAttachment)
+}
+
+// uploadAttachment Прикрепляет к петиции файл из поля file multipart формы. Вид вложения передается в поле kind
+func (ar *PetitionAttachmentRoute) uploadAttachment(c *gin.Context) {
+	petition, ok := ar.petitionForChange(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, ar.attachments.MaxFileSize()+multipartOverhead)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrAttachmentTooLarge.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required in the 'file' form field"})
+		return
+	}
+	kind := c.DefaultPostForm("kind", models.AttachmentKindDocument)
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	attachment, err := ar.attachments.Add(petition.ID, c.Value("ID").(uint), kind, fileHeader.Filename, file)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAttachmentTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrAttachmentType), errors.Is(err, services.ErrCoverNotImage):
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrAttachmentQuota):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrAttachmentKind):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			ar.logger.Errorf("Failed to upload attachment: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload attachment"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// getAttachments Возвращает список вложений петиции
+func (ar *PetitionAttachmentRoute) getAttachments(c *gin.Context) {
+	petitionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid petition ID"})
+		return
+	}
+	if _, err := ar.petitions.GetByID(uint(petitionID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Petition not found"})
+		return
+	}
+
+	attachments, err := ar.attachments.GetAll(uint(petitionID))
+	if err != nil {
+		ar.logger.Errorf("Error getting attachments: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get attachments"})
+		return
+	}
+	c.JSON(http.StatusOK, attachments)
+}
+
+// downloadAttachment Отдает файл вложения. Изображения показываются в браузере, документы скачиваются
+func (ar *PetitionAttachmentRoute) downloadAttachment(c *gin.Context) {
+	attachment, ok := ar.attachmentFromPath(c)
+	if !ok {
+		return
+	}
+
+	file, err := ar.attachments.Open(attachment)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment file not found"})
+			return
+		}
+		ar.logger.Errorf("Failed to open attachment %d: %v", attachment.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open attachment"})
+		return
+	}
+	defer file.Close()
+
+	disposition := "attachment"
+	if strings.HasPrefix(attachment.ContentType, "image/") {
+		disposition = "inline"
+	}
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, file, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}),
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "public, max-age=86400",
+	})
+}
+
+// deleteAttachment Удаляет вложение петиции
+func (ar *PetitionAttachmentRoute) deleteAttachment(c *gin.Context) {
+	if _, ok := ar.petitionForChange(c); !ok {
+		return
+	}
+	attachment, ok := ar.attachmentFromPath(c)
+	if !ok {
+		return
+	}
+
+	if err := ar.attachments.Delete(attachment); err != nil {
+		ar.logger.Errorf("Failed to delete attachment %d: %v", attachment.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete attachment"})
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// petitionForChange Возвращает петицию из пути, если текущий пользователь ее автор или модератор.
+// Иначе сам отвечает клиенту ошибкой
+func (ar *PetitionAttachmentRoute) petitionForChange(c *gin.Context) (*models.Petition, bool) {
+	petitionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid petition ID"})
+		return nil, false
+	}
+	petition, err := ar.petitions.GetByID(uint(petitionID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Petition not found"})
+		return nil, false
+	}
+	if c.Value("ID").(uint) != petition.UserID && !middleware.HasPermission(c, &ar.roles, models.PermPetitionModerate) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Doesn't have access"})
+		return nil, false
+	}
+	return petition, true
+}
+
+// attachmentFromPath Возвращает вложение по ID петиции и вложения из пути
+func (ar *PetitionAttachmentRoute) attachmentFromPath(c *gin.Context) (*models.PetitionAttachment, bool) {
+	petitionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid petition ID"})
+		return nil, false
+	}
+	attachmentID, err := strconv.ParseUint(c.Param("attachmentID"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+		return nil, false
+	}
+	attachment, err := ar.attachments.Get(uint(petitionID), uint(attachmentID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return nil, false
+	}
+	return attachment, true
+}
@@ -0,0 +1,143 @@
+package httpHandlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/services"
+	"petition_api/middleware"
+	"strconv"
+	"time"
+)
+
+type PetitionEligibilityRoute struct {
+	eligibility *services.EligibilityService
+	petitions   repository.PetitionRepository
+	roles       repository.RoleRepository
+	accounts    middleware.AccountChecker
+	logger      *logrus.Logger
+}
+
+// NewPetitionEligibilityRoute создает роут для условий подписи петиций
+func NewPetitionEligibilityRoute(eligibility *services.EligibilityService, petitions repository.PetitionRepository, roles repository.RoleRepository, accounts middleware.AccountChecker, logger *logrus.Logger) *PetitionEligibilityRoute {
+	return &PetitionEligibilityRoute{eligibility: eligibility, petitions: petitions, roles: roles, accounts: accounts, logger: logger}
+}
+
+func (er *PetitionEligibilityRoute) BindEligibilityToRoute(route *gin.RouterGroup) {
+	authMiddleware := middleware.NewAuthMiddleware(er.logger, er.accounts)
+
+	route.GET("/:id/eligibility", er.getEligibility)
+	route.PUT("/:id/eligibility", authMiddleware, er.setEligibility)
+	route.DELETE("/:id/eligibility", authMiddleware, er.deleteEligibility)
+	route.GET("/:id/eligibility/me", authMiddleware, er.checkEligibility)
+}
+
+// getEligibility Условия подписи петиции. Без условий возвращаются нулевые значения
+func (er *PetitionEligibilityRoute) getEligibility(c *gin.Context) {
+	petition, ok := er.loadPetition(c)
+	if !ok {
+		return
+	}
+	eligibility, err := er.eligibility.Get(petition.ID)
+	if err != nil {
+		er.logger.Errorf("Error getting eligibility of petition %d: %v", petition.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get eligibility rules"})
+		return
+	}
+	if eligibility == nil {
+		eligibility = &models.PetitionEligibility{PetitionID: petition.ID, Regions: []string{}}
+	}
+	c.JSON(http.StatusOK, eligibility)
+}
+
+// setEligibility Задает условия подписи. Доступно автору петиции и модераторам, пока петицию не подписывали
+func (er *PetitionEligibilityRoute) setEligibility(c *gin.Context) {
+	petition, ok := er.loadEditablePetition(c)
+	if !ok {
+		return
+	}
+	var input models.PetitionEligibilityInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	eligibility, err := er.eligibility.Set(petition.ID, input)
+	if err != nil {
+		er.eligibilityError(c, err, "Failed to set eligibility rules")
+		return
+	}
+	c.JSON(http.StatusOK, eligibility)
+}
+
+// deleteEligibility Снимает условия подписи
+func (er *PetitionEligibilityRoute) deleteEligibility(c *gin.Context) {
+	petition, ok := er.loadEditablePetition(c)
+	if !ok {
+		return
+	}
+	if err := er.eligibility.Delete(petition.ID); err != nil {
+		er.eligibilityError(c, err, "Failed to delete eligibility rules")
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// checkEligibility Может ли текущий пользователь подписать петицию и какие условия не выполнены
+func (er *PetitionEligibilityRoute) checkEligibility(c *gin.Context) {
+	petition, ok := er.loadPetition(c)
+	if !ok {
+		return
+	}
+	violations, err := er.eligibility.Check(c.Value("ID").(uint), petition.ID, time.Now())
+	if err != nil {
+		er.logger.Errorf("Error checking eligibility for petition %d: %v", petition.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check eligibility"})
+		return
+	}
+	c.JSON(http.StatusOK, models.EligibilityCheck{Eligible: len(violations) == 0, Reasons: violations})
+}
+
+// eligibilityError Переводит ошибки изменения условий в ответ. message - ответ на непредвиденную ошибку
+func (er *PetitionEligibilityRoute) eligibilityError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrUnknownRegion):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrEligibilityLocked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		er.logger.Errorf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// loadEditablePetition Находит петицию по :id и проверяет, что текущий пользователь - автор или модератор
+func (er *PetitionEligibilityRoute) loadEditablePetition(c *gin.Context) (*models.Petition, bool) {
+	petition, ok := er.loadPetition(c)
+	if !ok {
+		return nil, false
+	}
+	if c.Value("ID").(uint) != petition.UserID && !middleware.HasPermission(c, &er.roles, models.PermPetitionModerate) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Doesn't have access"})
+		return nil, false
+	}
+	return petition, true
+}
+
+// loadPetition Находит петицию по :id или отвечает ошибкой
+func (er *PetitionEligibilityRoute) loadPetition(c *gin.Context) (*models.Petition, bool) {
+	petitionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid petition ID"})
+		return nil, false
+	}
+	petition, err := er.petitions.GetByID(uint(petitionID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Petition not found"})
+		return nil, false
+	}
+	return petition, true
+}
@@ -1,32 +1,60 @@
 package httpHandlers
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 	"net/http"
 	"petition_api/internal/app/models"
 	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/services"
 	"petition_api/middleware"
 	"strconv"
 )
 
+var (
+	// errPetitionTextLocked Автор правит текст петиции, которую уже подписали
+	errPetitionTextLocked = errors.New("petition text is locked after the first signature")
+	// errEditReasonRequired Правка подписанной петиции без причины
+	errEditReasonRequired = errors.New("reason is required to edit a signed petition")
+)
+
+// recentReasonsCount Сколько причин подписи показывается вместе с петицией
+const recentReasonsCount = 3
+
 type PetitionModelRoute struct {
-	repo   repository.PetitionRepository
-	logger *logrus.Logger
+	repo        repository.PetitionRepository
+	voteRepo    repository.VoteRepository
+	apiKeys     repository.APIKeyRepository
+	roles       repository.RoleRepository
+	revisions   repository.PetitionRevisionRepository
+	attachments *services.PetitionAttachmentService
+	recipients  repository.RecipientRepository
+	statuses    *services.PetitionStatusService
+	events      repository.WebhookEventRepository
+	accounts    middleware.AccountChecker
+	logger      *logrus.Logger
 }
 
 // NewPetitionModelRoute создает новую роут
-func NewPetitionModelRoute(repo repository.PetitionRepository, logger *logrus.Logger) *PetitionModelRoute {
-	return &PetitionModelRoute{repo: repo, logger: logger}
+func NewPetitionModelRoute(repo repository.PetitionRepository, voteRepo repository.VoteRepository, apiKeys repository.APIKeyRepository, roles repository.RoleRepository, revisions repository.PetitionRevisionRepository, attachments *services.PetitionAttachmentService, recipients repository.RecipientRepository, statuses *services.PetitionStatusService, events repository.WebhookEventRepository, accounts middleware.AccountChecker, logger *logrus.Logger) *PetitionModelRoute {
+	return &PetitionModelRoute{repo: repo, voteRepo: voteRepo, apiKeys: apiKeys, roles: roles, revisions: revisions, attachments: attachments, recipients: recipients, statuses: statuses, events: events, accounts: accounts, logger: logger}
 }
 
 func (pr *PetitionModelRoute) BindPetitionToRoute(route *gin.RouterGroup) {
 
-	authMiddleware := middleware.NewAuthMiddleware(pr.logger)
+	authMiddleware := middleware.NewAuthMiddleware(pr.logger, pr.accounts)
+	// Счетчик подписей доступен партнерам по API ключу со скоупом votes:read и вошедшим пользователям
+	votesReadKey := middleware.NewAPIKeyMiddleware(&pr.apiKeys, pr.accounts, pr.logger, models.ScopeVotesRead)
 
 	route.POST("", authMiddleware, pr.createPetition)
 	route.GET("", pr.getPetitions)
 	route.GET("/:id", pr.getPetitionByID)
+	route.GET("/:id/votes/count", votesReadKey, pr.getVoteCount)
+	route.GET("/:id/revisions", pr.getRevisions)
+	route.GET("/:id/revisions/diff", pr.getRevisionDiff)
+	route.GET("/:id/revisions/:number", pr.getRevision)
 	route.PUT("/:id", authMiddleware, pr.updatePetition)
 	route.DELETE("/:id", authMiddleware, pr.deletePetition)
 }
@@ -34,19 +62,40 @@ func (pr *PetitionModelRoute) BindPetitionToRoute(route *gin.RouterGroup) {
 func (pr *PetitionModelRoute) createPetition(c *gin.Context) {
 	var petition models.Petition
 	if err := c.ShouldBindJSON(&petition); err != nil {
-		pr.logger.Error("Invalid request body: %v", err)
+		pr.logger.Errorf("Invalid request body: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
+	// Автор петиции - текущий пользователь
+	petition.UserID = c.Value("ID").(uint)
+	// Статус меняется только сервером
+	petition.Status = models.PetitionStatusOpen
+	petition.TargetReachedAt = nil
+	if petition.RecipientID != nil {
+		if !pr.applyRecipient(c, &petition, *petition.RecipientID) {
+			return
+		}
+	}
 
-	newPetition, err := pr.repo.Create(&petition)
+	// Петиция, ее первая версия и событие для вебхуков сохраняются вместе
+	err := pr.repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := pr.repo.CreateTx(tx, &petition); err != nil {
+			return err
+		}
+		revision := models.NewPetitionRevision(&petition, 1, petition.UserID, "")
+		if err := pr.revisions.CreateTx(tx, &revision); err != nil {
+			return err
+		}
+		return pr.events.CreateTx(tx, models.WebhookPetitionCreated, petition)
+	})
 	if err != nil {
-		pr.logger.Error("Error creating petition: %v", err)
+		pr.logger.Errorf("Error creating petition: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create petition"})
 		return
 	}
+	pr.logger.Info("Petition created. ID: ", petition.ID)
 
-	c.JSON(http.StatusCreated, newPetition)
+	c.JSON(http.StatusCreated, petition)
 }
 
 func (pr *PetitionModelRoute) getPetitions(c *gin.Context) {
@@ -61,10 +110,11 @@ func (pr *PetitionModelRoute) getPetitions(c *gin.Context) {
 
 	petitions, err := pr.repo.GetAll(page, pageSize)
 	if err != nil {
-		pr.logger.Error("Error getting petitions: %v", err)
+		pr.logger.Errorf("Error getting petitions: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get petitions"})
 		return
 	}
+	pr.fillCoverURLs(petitions)
 
 	c.JSON(http.StatusOK, petitions)
 }
@@ -81,40 +131,79 @@ func (pr *PetitionModelRoute) getPetitionByID(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Petition not found"})
 		return
 	}
+	petitions := []models.Petition{*petition}
+	pr.fillCoverURLs(petitions)
+	// Официальный ответ адресата показывается вместе с петицией
+	if petitions[0].Response, err = pr.statuses.GetResponse(petition.ID); err != nil {
+		pr.logger.Errorf("Failed to get response of petition %d: %v", petition.ID, err)
+	}
+	if petitions[0].Milestones, err = pr.statuses.GetMilestones(petition.ID); err != nil {
+		pr.logger.Errorf("Failed to get milestones of petition %d: %v", petition.ID, err)
+	}
+	reasons, _, err := pr.voteRepo.GetReasons(petition.ID, 1, recentReasonsCount)
+	if err != nil {
+		pr.logger.Errorf("Failed to get reasons of petition %d: %v", petition.ID, err)
+	}
+	for i := range reasons {
+		petitions[0].RecentReasons = append(petitions[0].RecentReasons, reasons[i].Public())
+	}
 
-	c.JSON(http.StatusOK, petition)
+	c.JSON(http.StatusOK, petitions[0])
 }
 
-func (pr *PetitionModelRoute) updatePetition(c *gin.Context) {
+func (pr *PetitionModelRoute) getVoteCount(c *gin.Context) {
 	petitionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid petition ID"})
 		return
 	}
 
-	// Начало транзакции
-	tx := pr.repo.DB.Begin()
-	if tx.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+	if _, err := pr.repo.GetByID(uint(petitionID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Petition not found"})
+		return
+	}
+
+	count, err := pr.voteRepo.GetCountVoteByPetitionID(uint(petitionID))
+	if err != nil {
+		pr.logger.Errorf("Error getting vote count: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get vote count"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"petition_id": petitionID, "vote_count": count})
+}
+
+// updatePetition Обновляет петицию и сохраняет новую версию текста.
+// После первой подписи текст может менять только модератор с указанием причины
+func (pr *PetitionModelRoute) updatePetition(c *gin.Context) {
+	petitionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid petition ID"})
 		return
 	}
 
 	// Получаем текущую петицию из базы данных
 	petition, err := pr.repo.GetByID(uint(petitionID))
 	if err != nil {
-		tx.Rollback()
 		c.JSON(http.StatusNotFound, gin.H{"error": "Petition not found"})
 		return
 	}
 
+	// Изменять петицию может автор или пользователь с правом petition.moderate
+	isModerator := middleware.HasPermission(c, &pr.roles, models.PermPetitionModerate)
+	if c.Value("ID").(uint) != petition.UserID && !isModerator {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Doesn't have access"})
+		return
+	}
+
 	// Привязываем только те поля, которые нужно обновить
 	var updateData models.PetitionUpdate
 	if err := c.ShouldBindJSON(&updateData); err != nil {
-		tx.Rollback()
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
+	before := *petition
 	// Обновляем только указанные поля
 	if updateData.Title != "" {
 		petition.Title = updateData.Title
@@ -125,28 +214,89 @@ func (pr *PetitionModelRoute) updatePetition(c *gin.Context) {
 	if updateData.TargetByVote != 0 {
 		petition.TargetByVote = updateData.TargetByVote
 	}
-	if updateData.CurrentVotes != 0 {
-		petition.CurrentVotes = updateData.CurrentVotes
-	}
 	if updateData.Recipient != "" {
 		petition.Recipient = updateData.Recipient
 	}
+	if updateData.RecipientID != 0 {
+		if !pr.applyRecipient(c, petition, updateData.RecipientID) {
+			return
+		}
+	}
 
-	// Обновляем петицию в базе данных в рамках транзакции
-	updatedPetition, err := pr.repo.UpdateTx(tx, petition)
-	if err != nil {
-		tx.Rollback()
+	textChanged := petition.Title != before.Title ||
+		petition.Description != before.Description ||
+		petition.TargetByVote != before.TargetByVote ||
+		petition.Recipient != before.Recipient ||
+		!sameRecipient(petition.RecipientID, before.RecipientID)
+	// Петиция и ее новая версия сохраняются в одной транзакции.
+	// Строка петиции блокируется, чтобы первая подпись не появилась между проверкой подписей и сохранением текста
+	editorID := c.Value("ID").(uint)
+	err = pr.repo.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := pr.repo.GetForUpdateTx(tx, petition.ID); err != nil {
+			return err
+		}
+		if textChanged {
+			signed, err := pr.voteRepo.HasVotesTx(tx, petition.ID)
+			if err != nil {
+				return err
+			}
+			// Подписавшие должны быть уверены, что текст не поменяли после их подписи
+			if signed && !isModerator {
+				return errPetitionTextLocked
+			}
+			if signed && updateData.Reason == "" {
+				return errEditReasonRequired
+			}
+		}
+		if _, err := pr.repo.UpdateTx(tx, petition); err != nil {
+			return err
+		}
+		if !textChanged {
+			return nil
+		}
+		last, err := pr.revisions.LastNumberTx(tx, petition.ID)
+		if err != nil {
+			return err
+		}
+		// У петиций, созданных до истории версий, сначала сохраняется исходный текст
+		if last == 0 {
+			original := models.NewPetitionRevision(&before, 1, before.UserID, "")
+			original.CreatedAt = before.CreatedAt
+			if err := pr.revisions.CreateTx(tx, &original); err != nil {
+				return err
+			}
+			last = 1
+		}
+		revision := models.NewPetitionRevision(petition, last+1, editorID, updateData.Reason)
+		return pr.revisions.CreateTx(tx, &revision)
+	})
+	switch {
+	case errors.Is(err, errPetitionTextLocked):
+		c.JSON(http.StatusConflict, gin.H{"error": "Petition text is locked after the first signature"})
+		return
+	case errors.Is(err, errEditReasonRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reason is required to edit a signed petition"})
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Petition not found"})
+		return
+	case err != nil:
+		pr.logger.Errorf("Error updating petition %d: %v", petition.ID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update petition"})
 		return
 	}
-
-	// Фиксируем транзакцию
-	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
-		return
+	// После уменьшения цели петиция может сразу оказаться набравшей подписи
+	if petition.TargetByVote < before.TargetByVote {
+		if _, err := pr.statuses.CheckTarget(petition.ID); err != nil {
+			pr.logger.Errorf("Failed to check target of petition %d: %v", petition.ID, err)
+		}
+	}
+	// Статус и счетчики могли поменяться, пока петицию редактировали, поэтому отдаем ее из базы
+	if updated, err := pr.repo.GetByID(petition.ID); err == nil {
+		petition = updated
 	}
 
-	c.JSON(http.StatusOK, updatedPetition)
+	c.JSON(http.StatusOK, petition)
 }
 
 func (pr *PetitionModelRoute) deletePetition(c *gin.Context) {
@@ -156,10 +306,70 @@ func (pr *PetitionModelRoute) deletePetition(c *gin.Context) {
 		return
 	}
 
+	petition, err := pr.repo.GetByID(uint(petitionID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Petition not found"})
+		return
+	}
+
+	// Удалять петицию может автор или пользователь с правом petition.moderate
+	if !pr.canModerate(c, petition) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Doesn't have access"})
+		return
+	}
+
 	if err := pr.repo.DeleteByID(uint(petitionID)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete petition"})
 		return
 	}
+	// Файлы удаленной петиции больше не нужны
+	if err := pr.attachments.DeleteAllForPetition(uint(petitionID)); err != nil {
+		pr.logger.Errorf("Failed to delete attachments of petition %d: %v", petitionID, err)
+	}
 
 	c.Status(http.StatusOK)
 }
+
+// canModerate Петицией управляет ее автор или пользователь с правом petition.moderate
+func (pr *PetitionModelRoute) canModerate(c *gin.Context, petition *models.Petition) bool {
+	if c.Value("ID").(uint) == petition.UserID {
+		return true
+	}
+	return middleware.HasPermission(c, &pr.roles, models.PermPetitionModerate)
+}
+
+// applyRecipient Привязывает петицию к адресату из справочника, иначе сам отвечает клиенту ошибкой
+func (pr *PetitionModelRoute) applyRecipient(c *gin.Context, petition *models.Petition, recipientID uint) bool {
+	recipient, err := pr.recipients.GetByID(recipientID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Recipient not found"})
+		return false
+	}
+	petition.RecipientID = &recipient.ID
+	petition.Recipient = recipient.Organisation
+	return true
+}
+
+// sameRecipient Указывают ли обе ссылки на одного адресата
+func sameRecipient(a *uint, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// fillCoverURLs Добавляет к петициям ссылки на обложки одним запросом к базе
+func (pr *PetitionModelRoute) fillCoverURLs(petitions []models.Petition) {
+	ids := make([]uint, 0, len(petitions))
+	for _, petition := range petitions {
+		ids = append(ids, petition.ID)
+	}
+	urls, err := pr.attachments.CoverURLs(ids)
+	if err != nil {
+		pr.logger.Errorf("Failed to get petition covers: %v", err)
+		return
+	}
+	for i := range petitions {
+		petitions[i].CoverURL = urls[petitions[i].ID]
+	}
+}
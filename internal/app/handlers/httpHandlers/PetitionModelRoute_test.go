@@ -0,0 +1,163 @@
+package httpHandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/services"
+	"petition_api/internal/app/storage"
+	"petition_api/internal/app/testutil"
+	"petition_api/utils/auth"
+	"testing"
+)
+
+func newPetitionRouteTest(t *testing.T) (*gorm.DB, *gin.Engine) {
+	testutil.SetAuthKey(t)
+
+	db := testutil.OpenDB(t,
+		models.Petition{},
+		models.PetitionRevision{},
+		models.PetitionAttachment{},
+		models.Vote{},
+		models.Permission{},
+		models.Role{},
+		models.UserModel{},
+		models.Recipient{},
+		models.PetitionResponse{},
+		models.PetitionMilestone{},
+		models.WebhookEvent{},
+	)
+
+	logger := testutil.Logger()
+	roleRepo := repository.NewRoleRepository(db, logger)
+	if err := roleRepo.Seed(models.DefaultPermissions, models.DefaultRolePermissions); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	petitionRepo := repository.NewPetitionRepository(db, logger)
+	voteRepo := repository.NewVoteRepository(db, logger)
+	recipientRepo := repository.NewRecipientRepository(db, logger)
+	NewPetitionModelRoute(
+		petitionRepo,
+		voteRepo,
+		repository.NewAPIKeyRepository(db, logger),
+		roleRepo,
+		repository.NewPetitionRevisionRepository(db, logger),
+		services.NewPetitionAttachmentService(
+			repository.NewPetitionAttachmentRepository(db, logger),
+			storage.NewLocalStorage(t.TempDir(), "/files"),
+			services.AttachmentQuota{MaxFileSize: 1024, MaxFiles: 1, MaxTotalSize: 1024},
+			logger,
+		),
+		recipientRepo,
+		services.NewPetitionStatusService(
+			petitionRepo,
+			voteRepo,
+			repository.NewUserRepository(db, logger),
+			recipientRepo,
+			repository.NewPetitionResponseRepository(db, logger),
+			repository.NewPetitionMilestoneRepository(db, logger),
+			repository.NewWebhookEventRepository(db, logger),
+			logger,
+		),
+		repository.NewWebhookEventRepository(db, logger),
+		nil,
+		logger,
+	).BindPetitionToRoute(router.Group("/petition"))
+	return db, router
+}
+
+func petitionRequest(router *gin.Engine, method string, path string, body string, userID uint, role string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if userID != 0 {
+		token, _ := auth.CreateAccessToken(userID, role)
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestPetitionRevisionsAndLock(t *testing.T) {
+	db, router := newPetitionRouteTest(t)
+
+	w := petitionRequest(router, http.MethodPost, "/petition", `{"title":"Парк","description":"Построить парк\nна Абая","target_by_vote":100}`, 1, models.RoleUser)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	// Вместе с петицией в очередь вебхуков попадает событие
+	var event models.WebhookEvent
+	assert.NoError(t, db.Where("type = ?", models.WebhookPetitionCreated).First(&event).Error)
+	assert.Contains(t, event.Payload, `"title":"Парк"`)
+
+	// Пока подписей нет, автор свободно меняет текст
+	w = petitionRequest(router, http.MethodPut, "/petition/1", `{"description":"Построить парк\nна Сатпаева"}`, 1, models.RoleUser)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	db.Create(&models.Vote{Login: "voter", UserID: 2, PetitionID: 1})
+
+	w = petitionRequest(router, http.MethodPut, "/petition/1", `{"title":"Сквер"}`, 1, models.RoleUser)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = petitionRequest(router, http.MethodPut, "/petition/1", `{"title":"Сквер"}`, 3, models.RoleModerator)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = petitionRequest(router, http.MethodPut, "/petition/1", `{"title":"Сквер","reason":"опечатка"}`, 3, models.RoleModerator)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var revisions []models.PetitionRevision
+	w = petitionRequest(router, http.MethodGet, "/petition/1/revisions", "", 0, "")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &revisions))
+	if assert.Len(t, revisions, 3) {
+		assert.Equal(t, "Сквер", revisions[2].Title)
+		assert.Equal(t, uint(3), revisions[2].EditorID)
+		assert.Equal(t, "опечатка", revisions[2].Reason)
+	}
+
+	var diff models.PetitionRevisionDiff
+	w = petitionRequest(router, http.MethodGet, "/petition/1/revisions/diff?from=1&to=2", "", 0, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &diff))
+	assert.Len(t, diff.Description, 3)
+	assert.Equal(t, "delete", diff.Description[1].Op)
+	assert.Equal(t, "на Абая", diff.Description[1].Text)
+
+	w = petitionRequest(router, http.MethodGet, "/petition/1/revisions/7", "", 0, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Счетчик голосов правкой не меняется
+	w = petitionRequest(router, http.MethodPut, "/petition/1", `{"current_votes":500}`, 1, models.RoleUser)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var stored models.Petition
+	assert.NoError(t, db.First(&stored, 1).Error)
+	assert.Zero(t, stored.CurrentVotes)
+}
+
+func TestPetitionRecentReasons(t *testing.T) {
+	db, router := newPetitionRouteTest(t)
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", TargetByVote: 10, UserID: 1}
+	db.Create(&petition)
+	for i, reason := range []string{"Первая", "Вторая", "", "Третья", "Четвертая"} {
+		voter := models.UserModel{Login: fmt.Sprintf("voter%d", i), Role: models.RoleUser, Status: models.StatusActive}
+		db.Create(&voter)
+		db.Create(&models.Vote{Login: voter.Login, UserID: voter.ID, PetitionID: petition.ID,
+			Visibility: models.VoteVisibilityPublic, Reason: reason})
+	}
+
+	w := petitionRequest(router, http.MethodGet, fmt.Sprintf("/petition/%d", petition.ID), "", 0, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body models.Petition
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	// Три последние причины, новые первыми
+	reasons := make([]string, 0, len(body.RecentReasons))
+	for _, signature := range body.RecentReasons {
+		reasons = append(reasons, signature.Reason)
+	}
+	assert.Equal(t, []string{"Четвертая", "Третья", "Вторая"}, reasons)
+}
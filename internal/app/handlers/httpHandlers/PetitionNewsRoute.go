@@ -0,0 +1,189 @@
+package httpHandlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/services"
+	"petition_api/middleware"
+	"strconv"
+)
+
+// PetitionBroadcaster Рассылает сообщения клиентам, подключенным к вебсокету петиции
+type PetitionBroadcaster interface {
+	BroadcastToPetition(petitionID uint, messageType string, payload interface{})
+}
+
+type PetitionNewsRoute struct {
+	repo          repository.PetitionNewsRepository
+	petitions     repository.PetitionRepository
+	roles         repository.RoleRepository
+	broadcaster   PetitionBroadcaster
+	notifications *services.NotificationService
+	accounts      middleware.AccountChecker
+	logger        *logrus.Logger
+}
+
+// NewPetitionNewsRoute создает роут для новостей петиций
+func NewPetitionNewsRoute(repo repository.PetitionNewsRepository, petitions repository.PetitionRepository, roles repository.RoleRepository, broadcaster PetitionBroadcaster, notifications *services.NotificationService, accounts middleware.AccountChecker, logger *logrus.Logger) *PetitionNewsRoute {
+	return &PetitionNewsRoute{repo: repo, petitions: petitions, roles: roles, broadcaster: broadcaster, notifications: notifications, accounts: accounts, logger: logger}
+}
+
+func (nr *PetitionNewsRoute) BindNewsToRoute(route *gin.RouterGroup) {
+	authMiddleware := middleware.NewAuthMiddleware(nr.logger, nr.accounts)
+
+	route.POST("/:id/news", authMiddleware, nr.createNews)
+	route.GET("/:id/news", nr.getNews)
+	route.GET("/:id/news/:newsID", nr.getNewsByID)
+	route.PUT("/:id/news/:newsID", authMiddleware, nr.updateNews)
+	route.DELETE("/:id/news/:newsID", authMiddleware, nr.deleteNews)
+}
+
+// createNews Публикует новость петиции и рассылает ее подключенным к вебсокету петиции
+func (nr *PetitionNewsRoute) createNews(c *gin.Context) {
+	petition, ok := nr.petitionFromPath(c)
+	if !ok {
+		return
+	}
+	// Новости пишет только автор петиции
+	if c.Value("ID").(uint) != petition.UserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the petition author can post news"})
+		return
+	}
+
+	var input models.PetitionNewsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	news := models.PetitionNews{
+		PetitionID: petition.ID,
+		AuthorID:   petition.UserID,
+		Title:      input.Title,
+		Content:    input.Content,
+	}
+	if err := nr.repo.Create(&news); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create news"})
+		return
+	}
+	nr.broadcaster.BroadcastToPetition(petition.ID, "petition_news", news)
+	if err := nr.notifications.NewsPublished(&news); err != nil {
+		nr.logger.Errorf("Failed to notify about petition news %d: %v", news.ID, err)
+	}
+
+	c.JSON(http.StatusCreated, news)
+}
+
+// getNews Возвращает новости петиции по страницам
+func (nr *PetitionNewsRoute) getNews(c *gin.Context) {
+	petition, ok := nr.petitionFromPath(c)
+	if !ok {
+		return
+	}
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.Query("pageSize"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	news, err := nr.repo.GetAllByPetitionID(petition.ID, page, pageSize)
+	if err != nil {
+		nr.logger.Errorf("Error getting petition news: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get news"})
+		return
+	}
+	c.JSON(http.StatusOK, news)
+}
+
+func (nr *PetitionNewsRoute) getNewsByID(c *gin.Context) {
+	news, ok := nr.newsFromPath(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, news)
+}
+
+// updateNews Изменяет новость. Доступно только автору петиции
+func (nr *PetitionNewsRoute) updateNews(c *gin.Context) {
+	news, ok := nr.newsFromPath(c)
+	if !ok {
+		return
+	}
+	if c.Value("ID").(uint) != news.AuthorID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the petition author can edit news"})
+		return
+	}
+
+	var input models.PetitionNewsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	news.Title = input.Title
+	news.Content = input.Content
+	if err := nr.repo.Update(news); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update news"})
+		return
+	}
+
+	c.JSON(http.StatusOK, news)
+}
+
+// deleteNews Удаляет новость. Доступно автору петиции и модераторам
+func (nr *PetitionNewsRoute) deleteNews(c *gin.Context) {
+	news, ok := nr.newsFromPath(c)
+	if !ok {
+		return
+	}
+	if c.Value("ID").(uint) != news.AuthorID && !middleware.HasPermission(c, &nr.roles, models.PermPetitionModerate) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Doesn't have access"})
+		return
+	}
+
+	if err := nr.repo.DeleteByID(news.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete news"})
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// petitionFromPath Возвращает петицию по ID из пути, иначе сам отвечает клиенту ошибкой
+func (nr *PetitionNewsRoute) petitionFromPath(c *gin.Context) (*models.Petition, bool) {
+	petitionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid petition ID"})
+		return nil, false
+	}
+	petition, err := nr.petitions.GetByID(uint(petitionID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Petition not found"})
+		return nil, false
+	}
+	return petition, true
+}
+
+// newsFromPath Возвращает новость по ID петиции и новости из пути
+func (nr *PetitionNewsRoute) newsFromPath(c *gin.Context) (*models.PetitionNews, bool) {
+	petitionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid petition ID"})
+		return nil, false
+	}
+	newsID, err := strconv.ParseUint(c.Param("newsID"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid news ID"})
+		return nil, false
+	}
+	news, err := nr.repo.GetByID(uint(petitionID), uint(newsID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "News not found"})
+		return nil, false
+	}
+	return news, true
+}
@@ -0,0 +1,103 @@
+package httpHandlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/services"
+	"petition_api/internal/app/testutil"
+	"testing"
+)
+
+type fakeBroadcaster struct {
+	messages []string
+}
+
+func (b *fakeBroadcaster) BroadcastToPetition(petitionID uint, messageType string, payload interface{}) {
+	b.messages = append(b.messages, messageType)
+}
+
+// fakePusher Запоминает, каким пользователям ушли сообщения личного канала
+type fakePusher struct {
+	users []uint
+}
+
+func (p *fakePusher) PushToUser(userID uint, messageType string, payload interface{}) {
+	if messageType == "notification" {
+		p.users = append(p.users, userID)
+	}
+}
+
+func TestPetitionNews(t *testing.T) {
+	testutil.SetAuthKey(t)
+
+	db := testutil.OpenDB(t,
+		models.Petition{},
+		models.PetitionNews{},
+		models.Permission{},
+		models.Role{},
+		models.Vote{},
+		models.Comment{},
+		models.Notification{},
+		models.PetitionSubscription{},
+	)
+	logger := testutil.Logger()
+	roleRepo := repository.NewRoleRepository(db, logger)
+	if err := roleRepo.Seed(models.DefaultPermissions, models.DefaultRolePermissions); err != nil {
+		t.Fatal(err)
+	}
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", UserID: 1}
+	assert.NoError(t, db.Create(&petition).Error)
+	// Подписавший и подписчик получают уведомление о новости, автор - нет
+	db.Create(&models.Vote{UserID: 4, PetitionID: petition.ID, Login: "signer"})
+	db.Create(&models.PetitionSubscription{UserID: 5, PetitionID: petition.ID})
+
+	broadcaster := &fakeBroadcaster{}
+	pusher := &fakePusher{}
+	petitionRepo := repository.NewPetitionRepository(db, logger)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewPetitionNewsRoute(
+		repository.NewPetitionNewsRepository(db, logger),
+		petitionRepo,
+		roleRepo,
+		broadcaster,
+		services.NewNotificationService(
+			repository.NewNotificationRepository(db, logger),
+			repository.NewSubscriptionRepository(db, logger),
+			petitionRepo,
+			repository.NewCommentRepository(db, logger),
+			pusher,
+			logger,
+		),
+		nil,
+		logger,
+	).BindNewsToRoute(router.Group("/petition"))
+
+	// Чужой пользователь не может публиковать новости
+	w := petitionRequest(router, http.MethodPost, "/petition/1/news", `{"title":"Итоги","content":"Встреча с акиматом"}`, 2, models.RoleUser)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, broadcaster.messages)
+
+	w = petitionRequest(router, http.MethodPost, "/petition/1/news", `{"title":"Итоги","content":"Встреча с акиматом"}`, 1, models.RoleUser)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, []string{"petition_news"}, broadcaster.messages)
+	assert.ElementsMatch(t, []uint{4, 5}, pusher.users)
+
+	w = petitionRequest(router, http.MethodGet, "/petition/1/news", "", 0, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Встреча с акиматом")
+
+	w = petitionRequest(router, http.MethodPut, "/petition/1/news/1", `{"title":"Итоги","content":"Перенесли"}`, 2, models.RoleUser)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = petitionRequest(router, http.MethodPut, "/petition/1/news/1", `{"title":"Итоги","content":"Перенесли"}`, 1, models.RoleUser)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Модератор может удалить чужую новость
+	w = petitionRequest(router, http.MethodDelete, "/petition/1/news/1", "", 3, models.RoleModerator)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = petitionRequest(router, http.MethodGet, "/petition/1/news/1", "", 0, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,89 @@
+package httpHandlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/services"
+	"petition_api/middleware"
+	"strconv"
+)
+
+type PetitionResponseRoute struct {
+	statuses  *services.PetitionStatusService
+	petitions repository.PetitionRepository
+	roles     repository.RoleRepository
+	accounts  middleware.AccountChecker
+	logger    *logrus.Logger
+}
+
+// NewPetitionResponseRoute создает роут для официальных ответов адресатов
+func NewPetitionResponseRoute(statuses *services.PetitionStatusService, petitions repository.PetitionRepository, roles repository.RoleRepository, accounts middleware.AccountChecker, logger *logrus.Logger) *PetitionResponseRoute {
+	return &PetitionResponseRoute{statuses: statuses, petitions: petitions, roles: roles, accounts: accounts, logger: logger}
+}
+
+func (rr *PetitionResponseRoute) BindResponseToRoute(route *gin.RouterGroup) {
+	authMiddleware := middleware.NewAuthMiddleware(rr.logger, rr.accounts)
+	respondMiddleware := middleware.RequirePermission(&rr.roles, rr.logger, models.PermPetitionRespond)
+
+	route.GET("/:id/response", rr.getResponse)
+	route.POST("/:id/response", authMiddleware, respondMiddleware, rr.createResponse)
+}
+
+func (rr *PetitionResponseRoute) getResponse(c *gin.Context) {
+	petitionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid petition ID"})
+		return
+	}
+
+	response, err := rr.statuses.GetResponse(uint(petitionID))
+	if err != nil {
+		rr.logger.Errorf("Error getting response of petition %d: %v", petitionID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get response"})
+		return
+	}
+	if response == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Response not found"})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// createResponse Публикует официальный ответ адресата и меняет статус петиции
+func (rr *PetitionResponseRoute) createResponse(c *gin.Context) {
+	petitionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid petition ID"})
+		return
+	}
+	petition, err := rr.petitions.GetByID(uint(petitionID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Petition not found"})
+		return
+	}
+
+	var input models.PetitionResponseInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	_, err = rr.statuses.Respond(petition, c.Value("ID").(uint), input)
+	switch {
+	case errors.Is(err, services.ErrNotPetitionRecipient):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Petition is not addressed to your organisation"})
+		return
+	case errors.Is(err, services.ErrPetitionAlreadyAnswered):
+		c.JSON(http.StatusConflict, gin.H{"error": "Petition already has an official response"})
+		return
+	case err != nil:
+		rr.logger.Errorf("Failed to respond to petition %d: %v", petition.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish response"})
+		return
+	}
+	c.JSON(http.StatusCreated, petition)
+}
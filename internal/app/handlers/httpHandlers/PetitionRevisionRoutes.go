@@ -0,0 +1,104 @@
+// История версий петиции вынесена из PetitionModelRoute, чтобы файл не разрастался
+
+package httpHandlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"petition_api/internal/app/models"
+	"strconv"
+)
+
+// getRevisions Возвращает все версии текста петиции
+func (pr *PetitionModelRoute) getRevisions(c *gin.Context) {
+	petitionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid petition ID"})
+		return
+	}
+	if _, err := pr.repo.GetByID(uint(petitionID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Petition not found"})
+		return
+	}
+
+	revisions, err := pr.revisions.GetAllByPetitionID(uint(petitionID))
+	if err != nil {
+		pr.logger.Errorf("Error getting petition revisions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get revisions"})
+		return
+	}
+	c.JSON(http.StatusOK, revisions)
+}
+
+// getRevision Возвращает одну версию петиции по номеру
+func (pr *PetitionModelRoute) getRevision(c *gin.Context) {
+	petitionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid petition ID"})
+		return
+	}
+	number, err := strconv.ParseUint(c.Param("number"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number"})
+		return
+	}
+
+	revision, err := pr.revisions.GetByNumber(uint(petitionID), uint(number))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+	c.JSON(http.StatusOK, revision)
+}
+
+// getRevisionDiff Возвращает построчную разницу между версиями ?from= и ?to=.
+// Без параметров сравнивается первая версия с последней
+func (pr *PetitionModelRoute) getRevisionDiff(c *gin.Context) {
+	petitionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid petition ID"})
+		return
+	}
+
+	revisions, err := pr.revisions.GetAllByPetitionID(uint(petitionID))
+	if err != nil {
+		pr.logger.Errorf("Error getting petition revisions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get revisions"})
+		return
+	}
+	if len(revisions) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Petition has no revisions"})
+		return
+	}
+
+	from, ok := revisionByQuery(c, revisions, "from", &revisions[0])
+	if !ok {
+		return
+	}
+	to, ok := revisionByQuery(c, revisions, "to", &revisions[len(revisions)-1])
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewPetitionRevisionDiff(from, to))
+}
+
+// revisionByQuery Находит версию по номеру из параметра запроса, без параметра возвращает fallback
+func revisionByQuery(c *gin.Context, revisions []models.PetitionRevision, param string, fallback *models.PetitionRevision) (*models.PetitionRevision, bool) {
+	value := c.Query(param)
+	if value == "" {
+		return fallback, true
+	}
+	number, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number in '" + param + "'"})
+		return nil, false
+	}
+	for i := range revisions {
+		if revisions[i].Number == uint(number) {
+			return &revisions[i], true
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+	return nil, false
+}
@@ -0,0 +1,127 @@
+package httpHandlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/services"
+	"petition_api/middleware"
+	"strconv"
+	"time"
+)
+
+type PetitionSignatureRoute struct {
+	exporter  *services.SignatureExportService
+	petitions repository.PetitionRepository
+	votes     repository.VoteRepository
+	apiKeys   repository.APIKeyRepository
+	accounts  middleware.AccountChecker
+	logger    *logrus.Logger
+}
+
+// NewPetitionSignatureRoute создает роут для списков подписантов петиций
+func NewPetitionSignatureRoute(exporter *services.SignatureExportService, petitions repository.PetitionRepository, votes repository.VoteRepository, apiKeys repository.APIKeyRepository, accounts middleware.AccountChecker, logger *logrus.Logger) *PetitionSignatureRoute {
+	return &PetitionSignatureRoute{exporter: exporter, petitions: petitions, votes: votes, apiKeys: apiKeys, accounts: accounts, logger: logger}
+}
+
+func (sr *PetitionSignatureRoute) BindSignatureToRoute(route *gin.RouterGroup) {
+	// Полный список подписантов адресат забирает по API ключу со скоупом votes:read или после входа
+	votesReadKey := middleware.NewAPIKeyMiddleware(&sr.apiKeys, sr.accounts, sr.logger, models.ScopeVotesRead)
+
+	route.GET("/:id/signatures", sr.getSignatures)
+	route.GET("/:id/signatures/export", votesReadKey, sr.exportSignatures)
+}
+
+// getSignatures Публичный список подписей с учетом выбора подписавших: имя, аноним или только в счетчике
+func (sr *PetitionSignatureRoute) getSignatures(c *gin.Context) {
+	petitionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid petition ID"})
+		return
+	}
+	var filter models.PublicSignatureFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = 20
+	}
+	if _, err := sr.petitions.GetByID(uint(petitionID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Petition not found"})
+		return
+	}
+
+	signatures, total, err := sr.votes.GetListedSignatures(uint(petitionID), filter.Page, filter.PageSize)
+	if err != nil {
+		sr.logger.Errorf("Error getting signatures of petition %d: %v", petitionID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get signatures"})
+		return
+	}
+	items := make([]models.PublicSignature, 0, len(signatures))
+	for i := range signatures {
+		items = append(items, signatures[i].Public())
+	}
+	c.JSON(http.StatusOK, models.PublicSignaturePage{
+		Items:    items,
+		Total:    total,
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+	})
+}
+
+// exportSignatures Отдает список подписантов файлом: ?format=csv (по умолчанию) или ?format=pdf.
+// Доступно автору петиции, администраторам и представителям адресата
+func (sr *PetitionSignatureRoute) exportSignatures(c *gin.Context) {
+	petitionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid petition ID"})
+		return
+	}
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "pdf" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be csv or pdf"})
+		return
+	}
+	petition, err := sr.petitions.GetByID(uint(petitionID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Petition not found"})
+		return
+	}
+
+	userID := c.Value("ID").(uint)
+	allowed, err := sr.exporter.CanExport(petition, userID)
+	if err != nil {
+		sr.logger.Errorf("Error checking signature export access to petition %d: %v", petition.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export signatures"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author, administrators and the recipient can export signatures"})
+		return
+	}
+
+	sr.logger.Infof("User %d exported signatures of petition %d as %s", userID, petition.ID, format)
+	now := time.Now()
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="petition_%d_signatures_%s.%s"`,
+		petition.ID, now.Format("20060102"), format))
+	if format == "pdf" {
+		c.Header("Content-Type", "application/pdf")
+		c.Status(http.StatusOK)
+		err = sr.exporter.WritePDF(c.Writer, petition, now)
+	} else {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		err = sr.exporter.WriteCSV(c.Writer, petition)
+	}
+	if err != nil {
+		// Заголовки уже отправлены, поэтому только логируем
+		sr.logger.Errorf("Error writing signatures of petition %d: %v", petition.ID, err)
+	}
+}
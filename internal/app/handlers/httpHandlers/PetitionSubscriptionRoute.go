@@ -0,0 +1,99 @@
+package httpHandlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/middleware"
+	"strconv"
+)
+
+type PetitionSubscriptionRoute struct {
+	repo      repository.SubscriptionRepository
+	petitions repository.PetitionRepository
+	accounts  middleware.AccountChecker
+	logger    *logrus.Logger
+}
+
+// NewPetitionSubscriptionRoute создает роут для подписок на петиции
+func NewPetitionSubscriptionRoute(repo repository.SubscriptionRepository, petitions repository.PetitionRepository, accounts middleware.AccountChecker, logger *logrus.Logger) *PetitionSubscriptionRoute {
+	return &PetitionSubscriptionRoute{repo: repo, petitions: petitions, accounts: accounts, logger: logger}
+}
+
+func (sr *PetitionSubscriptionRoute) BindSubscriptionToRoute(route *gin.RouterGroup) {
+	authMiddleware := middleware.NewAuthMiddleware(sr.logger, sr.accounts)
+
+	route.GET("/:id/subscription", authMiddleware, sr.getSubscription)
+	route.PUT("/:id/subscription", authMiddleware, sr.subscribe)
+	route.DELETE("/:id/subscription", authMiddleware, sr.unsubscribe)
+}
+
+// BindMySubscriptionsToRoute Список подписок текущего пользователя
+func (sr *PetitionSubscriptionRoute) BindMySubscriptionsToRoute(route *gin.RouterGroup) {
+	authMiddleware := middleware.NewAuthMiddleware(sr.logger, sr.accounts)
+
+	route.GET("", authMiddleware, sr.getMySubscriptions)
+}
+
+func (sr *PetitionSubscriptionRoute) getSubscription(c *gin.Context) {
+	petitionID, ok := sr.petitionIDFromPath(c)
+	if !ok {
+		return
+	}
+	subscribed, err := sr.repo.Exists(c.Value("ID").(uint), petitionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get subscription"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"petition_id": petitionID, "subscribed": subscribed})
+}
+
+// subscribe Подписывает на петицию. Повторная подписка не ошибка
+func (sr *PetitionSubscriptionRoute) subscribe(c *gin.Context) {
+	petitionID, ok := sr.petitionIDFromPath(c)
+	if !ok {
+		return
+	}
+	if err := sr.repo.Create(c.Value("ID").(uint), petitionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to subscribe"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"petition_id": petitionID, "subscribed": true})
+}
+
+func (sr *PetitionSubscriptionRoute) unsubscribe(c *gin.Context) {
+	petitionID, ok := sr.petitionIDFromPath(c)
+	if !ok {
+		return
+	}
+	if err := sr.repo.Delete(c.Value("ID").(uint), petitionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"petition_id": petitionID, "subscribed": false})
+}
+
+func (sr *PetitionSubscriptionRoute) getMySubscriptions(c *gin.Context) {
+	subscriptions, err := sr.repo.GetByUserID(c.Value("ID").(uint))
+	if err != nil {
+		sr.logger.Errorf("Error getting subscriptions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get subscriptions"})
+		return
+	}
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+// petitionIDFromPath Возвращает ID существующей петиции из пути, иначе сам отвечает клиенту ошибкой
+func (sr *PetitionSubscriptionRoute) petitionIDFromPath(c *gin.Context) (uint, bool) {
+	petitionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid petition ID"})
+		return 0, false
+	}
+	if _, err := sr.petitions.GetByID(uint(petitionID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Petition not found"})
+		return 0, false
+	}
+	return uint(petitionID), true
+}
@@ -0,0 +1,177 @@
+package httpHandlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/services"
+	"petition_api/middleware"
+	"strconv"
+)
+
+type PetitionVoteRoute struct {
+	votes     *services.VoteService
+	petitions repository.PetitionRepository
+	users     repository.UserRepository
+	accounts  middleware.AccountChecker
+	logger    *logrus.Logger
+}
+
+// NewPetitionVoteRoute создает роут для подписи петиций через REST
+func NewPetitionVoteRoute(votes *services.VoteService, petitions repository.PetitionRepository, users repository.UserRepository, accounts middleware.AccountChecker, logger *logrus.Logger) *PetitionVoteRoute {
+	return &PetitionVoteRoute{votes: votes, petitions: petitions, users: users, accounts: accounts, logger: logger}
+}
+
+func (vr *PetitionVoteRoute) BindVoteToRoute(route *gin.RouterGroup) {
+	authMiddleware := middleware.NewAuthMiddleware(vr.logger, vr.accounts)
+
+	route.POST("/:id/vote", authMiddleware, vr.vote)
+	route.DELETE("/:id/vote", authMiddleware, vr.unvote)
+	route.PUT("/:id/vote/reason", authMiddleware, vr.updateReason)
+	route.DELETE("/:id/vote/reason", authMiddleware, vr.deleteReason)
+	route.GET("/:id/reasons", vr.getReasons)
+}
+
+// vote Подписывает петицию от имени текущего пользователя
+func (vr *PetitionVoteRoute) vote(c *gin.Context) {
+	petition, ok := vr.loadPetition(c)
+	if !ok {
+		return
+	}
+	var input models.VoteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	user, err := vr.users.GetByID(c.Value("ID").(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	vote := models.Vote{
+		Login:      user.Login,
+		UserID:     user.ID,
+		PetitionID: petition.ID,
+		Visibility: input.Visibility,
+		Reason:     input.Reason,
+		IP:         c.ClientIP(),
+		UA:         c.Request.UserAgent(),
+	}
+	if err := vr.votes.Vote(&vote); err != nil {
+		vr.voteError(c, err, "Failed to sign petition")
+		return
+	}
+	c.JSON(http.StatusCreated, vote)
+}
+
+// unvote Отзывает подпись текущего пользователя
+func (vr *PetitionVoteRoute) unvote(c *gin.Context) {
+	petition, ok := vr.loadPetition(c)
+	if !ok {
+		return
+	}
+	if err := vr.votes.Unvote(c.Value("ID").(uint), petition.ID); err != nil {
+		vr.voteError(c, err, "Failed to remove signature")
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// updateReason Меняет причину подписи текущего пользователя
+func (vr *PetitionVoteRoute) updateReason(c *gin.Context) {
+	petition, ok := vr.loadPetition(c)
+	if !ok {
+		return
+	}
+	var input models.VoteReasonInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	vote, err := vr.votes.UpdateReason(c.Value("ID").(uint), petition.ID, input.Reason)
+	if err != nil {
+		vr.voteError(c, err, "Failed to update reason")
+		return
+	}
+	c.JSON(http.StatusOK, vote)
+}
+
+// deleteReason Удаляет причину, подпись остается
+func (vr *PetitionVoteRoute) deleteReason(c *gin.Context) {
+	petition, ok := vr.loadPetition(c)
+	if !ok {
+		return
+	}
+	if _, err := vr.votes.UpdateReason(c.Value("ID").(uint), petition.ID, ""); err != nil {
+		vr.voteError(c, err, "Failed to delete reason")
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// getReasons Причины подписи из публичного списка, новые первыми
+func (vr *PetitionVoteRoute) getReasons(c *gin.Context) {
+	petition, ok := vr.loadPetition(c)
+	if !ok {
+		return
+	}
+	var filter models.PublicSignatureFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	page, err := vr.votes.Reasons(petition.ID, filter)
+	if err != nil {
+		vr.logger.Errorf("Error getting reasons of petition %d: %v", petition.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get reasons"})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// voteError Переводит ошибки подписи в ответ. message - ответ на непредвиденную ошибку
+func (vr *PetitionVoteRoute) voteError(c *gin.Context, err error, message string) {
+	var ineligible *services.IneligibleError
+	switch {
+	case errors.As(err, &ineligible):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "reasons": ineligible.Violations})
+	case errors.Is(err, services.ErrAccountBanned),
+		errors.Is(err, services.ErrAccountPassive),
+		errors.Is(err, services.ErrAccountNotFound):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidVisibility),
+		errors.Is(err, services.ErrReasonTooLong),
+		errors.Is(err, services.ErrContentRejected):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPetitionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Petition not found"})
+	case errors.Is(err, services.ErrAlreadyVoted), errors.Is(err, services.ErrPetitionClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrVoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Vote not found"})
+	default:
+		vr.logger.Errorf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// loadPetition Находит петицию по :id или отвечает ошибкой
+func (vr *PetitionVoteRoute) loadPetition(c *gin.Context) (*models.Petition, bool) {
+	petitionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid petition ID"})
+		return nil, false
+	}
+	petition, err := vr.petitions.GetByID(uint(petitionID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Petition not found"})
+		return nil, false
+	}
+	return petition, true
+}
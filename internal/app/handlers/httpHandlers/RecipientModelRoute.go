@@ -0,0 +1,280 @@
+package httpHandlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"net/http"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/middleware"
+	"strconv"
+)
+
+type RecipientModelRoute struct {
+	repo      repository.RecipientRepository
+	users     repository.UserRepository
+	petitions repository.PetitionRepository
+	roles     repository.RoleRepository
+	audit     repository.UserAuditRepository
+	apiKeys   repository.APIKeyRepository
+	accounts  middleware.AccountChecker
+	logger    *logrus.Logger
+}
+
+// NewRecipientModelRoute создает роут для справочника адресатов петиций
+func NewRecipientModelRoute(repo repository.RecipientRepository, users repository.UserRepository, petitions repository.PetitionRepository, roles repository.RoleRepository, audit repository.UserAuditRepository, apiKeys repository.APIKeyRepository, accounts middleware.AccountChecker, logger *logrus.Logger) *RecipientModelRoute {
+	return &RecipientModelRoute{repo: repo, users: users, petitions: petitions, roles: roles, audit: audit, apiKeys: apiKeys, accounts: accounts, logger: logger}
+}
+
+func (rr *RecipientModelRoute) BindRecipientToRoute(route *gin.RouterGroup) {
+	authMiddleware := middleware.NewAuthMiddleware(rr.logger, rr.accounts)
+	// Справочником адресатов и их аккаунтами управляют те же админы, что и пользователями
+	manageMiddleware := middleware.RequirePermission(&rr.roles, rr.logger, models.PermUserManage)
+	respondMiddleware := middleware.RequirePermission(&rr.roles, rr.logger, models.PermPetitionRespond)
+	// Петиции адресата его системы забирают по API ключу со скоупом petitions:read
+	petitionsReadKey := middleware.NewAPIKeyMiddleware(&rr.apiKeys, rr.accounts, rr.logger, models.ScopePetitionsRead)
+
+	route.GET("", rr.getRecipients)
+	route.GET("/me/petitions", petitionsReadKey, respondMiddleware, rr.getMyPetitions)
+	route.GET("/:id", rr.getRecipientByID)
+	route.POST("", authMiddleware, manageMiddleware, rr.createRecipient)
+	route.PUT("/:id", authMiddleware, manageMiddleware, rr.updateRecipient)
+	route.DELETE("/:id", authMiddleware, manageMiddleware, rr.deleteRecipient)
+	route.GET("/:id/members", authMiddleware, manageMiddleware, rr.getMembers)
+	route.POST("/:id/members", authMiddleware, manageMiddleware, rr.addMember)
+	route.DELETE("/:id/members/:userID", authMiddleware, manageMiddleware, rr.removeMember)
+}
+
+func (rr *RecipientModelRoute) getRecipients(c *gin.Context) {
+	recipients, err := rr.repo.GetAll()
+	if err != nil {
+		rr.logger.Errorf("Error getting recipients: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get recipients"})
+		return
+	}
+	c.JSON(http.StatusOK, recipients)
+}
+
+func (rr *RecipientModelRoute) getRecipientByID(c *gin.Context) {
+	recipient, ok := rr.recipientFromPath(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, recipient)
+}
+
+func (rr *RecipientModelRoute) createRecipient(c *gin.Context) {
+	var input models.RecipientInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	recipient := models.Recipient{
+		Organisation: input.Organisation,
+		ContactName:  input.ContactName,
+		ContactEmail: input.ContactEmail,
+		ContactPhone: input.ContactPhone,
+	}
+	if err := rr.repo.Create(&recipient); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create recipient"})
+		return
+	}
+	c.JSON(http.StatusCreated, recipient)
+}
+
+func (rr *RecipientModelRoute) updateRecipient(c *gin.Context) {
+	recipient, ok := rr.recipientFromPath(c)
+	if !ok {
+		return
+	}
+
+	var input models.RecipientInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	recipient.Organisation = input.Organisation
+	recipient.ContactName = input.ContactName
+	recipient.ContactEmail = input.ContactEmail
+	recipient.ContactPhone = input.ContactPhone
+	if err := rr.repo.Update(recipient); err != nil {
+		rr.logger.Errorf("Error updating recipient %d: %v", recipient.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update recipient"})
+		return
+	}
+	c.JSON(http.StatusOK, recipient)
+}
+
+// deleteRecipient Удаляет адресата, если к нему не привязан ни один аккаунт
+func (rr *RecipientModelRoute) deleteRecipient(c *gin.Context) {
+	recipient, ok := rr.recipientFromPath(c)
+	if !ok {
+		return
+	}
+
+	members, err := rr.repo.CountMembers(recipient.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete recipient"})
+		return
+	}
+	if members > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Recipient still has member accounts"})
+		return
+	}
+
+	if err := rr.repo.DeleteByID(recipient.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete recipient"})
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+func (rr *RecipientModelRoute) getMembers(c *gin.Context) {
+	recipient, ok := rr.recipientFromPath(c)
+	if !ok {
+		return
+	}
+
+	members, err := rr.repo.GetMembers(recipient.ID)
+	if err != nil {
+		rr.logger.Errorf("Error getting members of recipient %d: %v", recipient.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get members"})
+		return
+	}
+	views := make([]models.UserPrivateView, 0, len(members))
+	for i := range members {
+		views = append(views, models.NewUserPrivateView(&members[i]))
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+// addMember Привязывает аккаунт к адресату и выдает ему роль Recipient
+func (rr *RecipientModelRoute) addMember(c *gin.Context) {
+	recipient, ok := rr.recipientFromPath(c)
+	if !ok {
+		return
+	}
+
+	var input models.RecipientMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	user, err := rr.users.GetByID(input.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	oldRole := user.Role
+	user.RecipientID = &recipient.ID
+	user.Role = models.RoleRecipient
+	if err := rr.saveMember(c, user, oldRole, "member of "+recipient.Organisation); err != nil {
+		rr.logger.Errorf("Failed to add user %d to recipient %d: %v", user.ID, recipient.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewUserPrivateView(user))
+}
+
+// removeMember Отвязывает аккаунт от адресата и возвращает ему обычную роль
+func (rr *RecipientModelRoute) removeMember(c *gin.Context) {
+	recipient, ok := rr.recipientFromPath(c)
+	if !ok {
+		return
+	}
+	userID, err := strconv.ParseUint(c.Param("userID"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	user, err := rr.users.GetByID(uint(userID))
+	if err != nil || user.RecipientID == nil || *user.RecipientID != recipient.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	oldRole := user.Role
+	user.RecipientID = nil
+	if user.Role == models.RoleRecipient {
+		user.Role = models.RoleUser
+	}
+	if err := rr.saveMember(c, user, oldRole, "removed from "+recipient.Organisation); err != nil {
+		rr.logger.Errorf("Failed to remove user %d from recipient %d: %v", user.ID, recipient.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// saveMember Сохраняет привязку аккаунта вместе с записью о смене роли в аудите
+func (rr *RecipientModelRoute) saveMember(c *gin.Context, user *models.UserModel, oldRole string, reason string) error {
+	return rr.users.DB.Transaction(func(tx *gorm.DB) error {
+		if err := rr.users.UpdateTx(tx, user); err != nil {
+			return err
+		}
+		return rr.audit.CreateTx(tx, &models.UserAuditLog{
+			ActorID:      c.Value("ID").(uint),
+			TargetUserID: user.ID,
+			Field:        "role",
+			OldValue:     oldRole,
+			NewValue:     user.Role,
+			Reason:       reason,
+			IP:           c.ClientIP(),
+		})
+	})
+}
+
+// getMyPetitions Петиции, адресованные организации текущего пользователя.
+// По умолчанию только ожидающие ответа, ?status= выбирает другой статус, ?status=all - все
+func (rr *RecipientModelRoute) getMyPetitions(c *gin.Context) {
+	user, err := rr.users.GetByID(c.Value("ID").(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.RecipientID == nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is not linked to a recipient"})
+		return
+	}
+
+	status := c.DefaultQuery("status", models.PetitionStatusAwaitingResponse)
+	if status == "all" {
+		status = ""
+	}
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.Query("pageSize"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	petitions, err := rr.petitions.GetAllByRecipientID(*user.RecipientID, status, page, pageSize)
+	if err != nil {
+		rr.logger.Errorf("Error getting petitions of recipient %d: %v", *user.RecipientID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get petitions"})
+		return
+	}
+	c.JSON(http.StatusOK, petitions)
+}
+
+// recipientFromPath Возвращает адресата по ID из пути, иначе сам отвечает клиенту ошибкой
+func (rr *RecipientModelRoute) recipientFromPath(c *gin.Context) (*models.Recipient, bool) {
+	recipientID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recipient ID"})
+		return nil, false
+	}
+	recipient, err := rr.repo.GetByID(uint(recipientID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recipient not found"})
+		return nil, false
+	}
+	return recipient, true
+}
@@ -0,0 +1,69 @@
+package httpHandlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/middleware"
+)
+
+type RoleModelRoute struct {
+	repo     repository.RoleRepository
+	accounts middleware.AccountChecker
+	logger   *logrus.Logger
+}
+
+// NewRoleModelRoute создает роут для управления ролями и правами
+func NewRoleModelRoute(repo repository.RoleRepository, accounts middleware.AccountChecker, logger *logrus.Logger) *RoleModelRoute {
+	return &RoleModelRoute{repo: repo, accounts: accounts, logger: logger}
+}
+
+func (rr *RoleModelRoute) BindRoleToRoute(route *gin.RouterGroup) {
+	authMiddleware := middleware.NewAuthMiddleware(rr.logger, rr.accounts)
+	userManageMiddleware := middleware.RequirePermission(&rr.repo, rr.logger, models.PermUserManage)
+	roleManageMiddleware := middleware.RequirePermission(&rr.repo, rr.logger, models.PermRoleManage)
+
+	route.GET("", authMiddleware, userManageMiddleware, rr.getRoles)
+	route.GET("/permissions", authMiddleware, userManageMiddleware, rr.getPermissions)
+	route.PUT("/:name/permissions", authMiddleware, roleManageMiddleware, rr.setRolePermissions)
+}
+
+func (rr *RoleModelRoute) getRoles(c *gin.Context) {
+	roles, err := rr.repo.GetAll()
+	if err != nil {
+		rr.logger.Errorf("Error getting roles: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get roles"})
+		return
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+func (rr *RoleModelRoute) getPermissions(c *gin.Context) {
+	permissions, err := rr.repo.GetAllPermissions()
+	if err != nil {
+		rr.logger.Errorf("Error getting permissions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get permissions"})
+		return
+	}
+	c.JSON(http.StatusOK, permissions)
+}
+
+func (rr *RoleModelRoute) setRolePermissions(c *gin.Context) {
+	var input models.RolePermissionsUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	role, err := rr.repo.SetPermissions(c.Param("name"), input.Permissions)
+	if err != nil {
+		rr.logger.Errorf("Error updating role permissions: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update role permissions: " + err.Error()})
+		return
+	}
+
+	rr.logger.Infof("Permissions of role %s changed by user %d: %v", role.Name, c.Value("ID"), input.Permissions)
+	c.JSON(http.StatusOK, role)
+}
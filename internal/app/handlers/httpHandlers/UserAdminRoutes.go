@@ -0,0 +1,232 @@
+// Функции управления пользователями для админов: поиск, блокировки и массовые действия
+
+package httpHandlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"petition_api/internal/app/models"
+	"strconv"
+	"time"
+)
+
+// searchUsers Ищет пользователей по фильтру и возвращает их по страницам
+func (ur *UserModelRoute) searchUsers(c *gin.Context) {
+	var filter models.UserSearchFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid search parameters"})
+		return
+	}
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = 20
+	}
+
+	users, total, err := ur.repo.Search(filter, time.Now())
+	if err != nil {
+		ur.logger.Errorf("error in searching users: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search users"})
+		return
+	}
+
+	views := make([]models.UserPrivateView, 0, len(users))
+	for i := range users {
+		views = append(views, models.NewUserPrivateView(&users[i]))
+	}
+	c.JSON(http.StatusOK, models.UserPage{
+		Items:    views,
+		Total:    total,
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+	})
+}
+
+// banUser Блокирует пользователя до указанного времени или бессрочно и завершает все его сессии
+func (ur *UserModelRoute) banUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input models.UserBanCreate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	now := time.Now()
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ban expiry must be in the future"})
+		return
+	}
+
+	actorID := c.Value("ID").(uint)
+	if actorID == uint(userID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You can not ban yourself"})
+		return
+	}
+	if _, err := ur.repo.GetByID(uint(userID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	ban := models.UserBan{
+		UserID:     uint(userID),
+		BannedByID: actorID,
+		Reason:     input.Reason,
+		ExpiresAt:  input.ExpiresAt,
+	}
+	until := "permanent"
+	if input.ExpiresAt != nil {
+		until = input.ExpiresAt.Format(time.RFC3339)
+	}
+
+	err = ur.repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := ur.bans.CreateTx(tx, &ban); err != nil {
+			return err
+		}
+		if err := ur.sessionDB.DeleteAllByUserIDTx(tx, uint(userID)); err != nil {
+			return err
+		}
+		return ur.audit.CreateTx(tx, &models.UserAuditLog{
+			ActorID:      actorID,
+			TargetUserID: uint(userID),
+			Field:        "ban",
+			NewValue:     "banned until " + until,
+			Reason:       input.Reason,
+			IP:           c.ClientIP(),
+		})
+	})
+	if err != nil {
+		ur.logger.Errorf("Failed to ban user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ban user"})
+		return
+	}
+	ur.accounts.Invalidate(uint(userID))
+
+	ur.logger.Infof("User %d banned user %d until %s", actorID, userID, until)
+	c.JSON(http.StatusCreated, ban)
+}
+
+// unbanUser Снимает действующие блокировки пользователя
+func (ur *UserModelRoute) unbanUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// Причина снятия блокировки необязательна
+	var input struct {
+		Reason string `json:"reason" binding:"max=255"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	actorID := c.Value("ID").(uint)
+	var lifted int64
+	err = ur.repo.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		lifted, err = ur.bans.LiftActiveTx(tx, uint(userID), actorID, time.Now())
+		if err != nil || lifted == 0 {
+			return err
+		}
+		return ur.audit.CreateTx(tx, &models.UserAuditLog{
+			ActorID:      actorID,
+			TargetUserID: uint(userID),
+			Field:        "ban",
+			OldValue:     "banned",
+			NewValue:     "unbanned",
+			Reason:       input.Reason,
+			IP:           c.ClientIP(),
+		})
+	})
+	if err != nil {
+		ur.logger.Errorf("Failed to unban user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unban user"})
+		return
+	}
+	if lifted == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User has no active ban"})
+		return
+	}
+	ur.accounts.Invalidate(uint(userID))
+
+	ur.logger.Infof("User %d unbanned user %d", actorID, userID)
+	c.Status(http.StatusOK)
+}
+
+// getBans Возвращает историю блокировок пользователя
+func (ur *UserModelRoute) getBans(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	bans, err := ur.bans.GetAllByUserID(uint(userID))
+	if err != nil {
+		ur.logger.Errorf("error in getting bans: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get bans"})
+		return
+	}
+	c.JSON(http.StatusOK, bans)
+}
+
+// bulkSetStatus Меняет статус сразу нескольким пользователям. Каждое изменение пишется в аудит отдельно,
+// ошибка по одному пользователю не отменяет изменения остальных
+func (ur *UserModelRoute) bulkSetStatus(c *gin.Context) {
+	var input models.UserBulkStatusUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	actorID := c.Value("ID").(uint)
+	result := models.UserBulkResult{
+		Updated: make([]uint, 0, len(input.UserIDs)),
+		Failed:  make(map[uint]string),
+	}
+	seen := make(map[uint]bool, len(input.UserIDs))
+	for _, userID := range input.UserIDs {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+
+		if err := ur.changeStatus(c, actorID, userID, input.Status, input.Reason); err != nil {
+			result.Failed[userID] = err.Error()
+			continue
+		}
+		result.Updated = append(result.Updated, userID)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// changeStatus Меняет статус одного пользователя при массовом изменении
+func (ur *UserModelRoute) changeStatus(c *gin.Context, actorID uint, userID uint, status string, reason string) error {
+	if actorID == userID {
+		return errors.New("you can not change your own status")
+	}
+	user, err := ur.repo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.Status == status {
+		return nil
+	}
+
+	if err := ur.updateStatus(c, user, status, reason); err != nil {
+		return errors.New("failed to update user")
+	}
+	return nil
+}
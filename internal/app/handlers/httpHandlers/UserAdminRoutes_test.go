@@ -0,0 +1,159 @@
+package httpHandlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"petition_api/internal/app/models"
+	"petition_api/utils/auth"
+	"testing"
+)
+
+// token Выдает access токен пользователю
+func (rt *userRouteTest) token(t *testing.T, user *models.UserModel) string {
+	token, err := auth.CreateAccessToken(user.ID, user.Role)
+	require.NoError(t, err)
+	return token
+}
+
+func TestSearchUsers(t *testing.T) {
+	rt := newUserRouteTest(t)
+	admin := rt.createUser(t, "admin", models.RoleAdmin, models.StatusActive)
+	user := rt.createUser(t, "aigerim", models.RoleUser, models.StatusActive)
+	for i := 0; i < 3; i++ {
+		rt.createUser(t, fmt.Sprintf("aidos%d", i), models.RoleUser, models.StatusActive)
+	}
+	rt.createUser(t, "aibek", models.RoleUser, models.StatusPassive)
+
+	// Без права user.manage искать пользователей нельзя
+	assert.Equal(t, http.StatusForbidden, rt.do(http.MethodGet, "/user", "", rt.token(t, user)).Code)
+
+	adminToken := rt.token(t, admin)
+	w := rt.do(http.MethodGet, "/user?q=aidos&page=2&pageSize=2", "", adminToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	var page models.UserPage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(t, int64(3), page.Total)
+	assert.Equal(t, 2, page.Page)
+	assert.Equal(t, 2, page.PageSize)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "aidos2", page.Items[0].Login)
+
+	w = rt.do(http.MethodGet, "/user?status=Passive", "", adminToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(t, int64(1), page.Total)
+	assert.Equal(t, 20, page.PageSize)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "aibek", page.Items[0].Login)
+
+	// Пустой результат - пустой список, а не null
+	w = rt.do(http.MethodGet, "/user?login=nobody", "", adminToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"items":[]`)
+
+	// % и _ в запросе ищутся как обычные символы, а не как шаблон
+	rt.createUser(t, "sale_50%", models.RoleUser, models.StatusActive)
+	for _, q := range []string{"%25", "_", "e_5"} {
+		w = rt.do(http.MethodGet, "/user?q="+q, "", adminToken)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		require.Len(t, page.Items, 1, q)
+		assert.Equal(t, "sale_50%", page.Items[0].Login)
+	}
+	w = rt.do(http.MethodGet, "/user?login=a%5C", "", adminToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"items":[]`)
+
+	assert.Equal(t, http.StatusBadRequest, rt.do(http.MethodGet, "/user?pageSize=101", "", adminToken).Code)
+	assert.Equal(t, http.StatusBadRequest, rt.do(http.MethodGet, "/user?status=Deleted", "", adminToken).Code)
+}
+
+func TestBanAndUnbanUser(t *testing.T) {
+	rt := newUserRouteTest(t)
+	admin := rt.createUser(t, "admin", models.RoleAdmin, models.StatusActive)
+	rt.createUser(t, "user", models.RoleUser, models.StatusActive)
+	adminToken := rt.token(t, admin)
+
+	w := rt.do(http.MethodPost, "/user/login", `{"login":"user","password":"secret"}`, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	userToken := jsonField(t, w, "access_token")
+	assert.Equal(t, http.StatusOK, rt.do(http.MethodGet, "/user/me", "", userToken).Code)
+
+	assert.Equal(t, http.StatusBadRequest, rt.do(http.MethodPost, "/user/2/ban", `{}`, adminToken).Code)
+	assert.Equal(t, http.StatusBadRequest, rt.do(http.MethodPost, "/user/2/ban", `{"reason":"spam","expires_at":"2000-01-01T00:00:00Z"}`, adminToken).Code)
+	assert.Equal(t, http.StatusBadRequest, rt.do(http.MethodPost, "/user/1/ban", `{"reason":"spam"}`, adminToken).Code)
+	assert.Equal(t, http.StatusNotFound, rt.do(http.MethodPost, "/user/99/ban", `{"reason":"spam"}`, adminToken).Code)
+	assert.Equal(t, http.StatusForbidden, rt.do(http.MethodPost, "/user/1/ban", `{"reason":"spam"}`, userToken).Code)
+
+	w = rt.do(http.MethodPost, "/user/2/ban", `{"reason":"spam"}`, adminToken)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	// Сессии завершены, токен и вход отклоняются
+	var sessions int64
+	rt.db.Model(&models.RefreshSession{}).Where("user_id = ?", 2).Count(&sessions)
+	assert.Zero(t, sessions)
+	assert.Equal(t, http.StatusForbidden, rt.do(http.MethodGet, "/user/me", "", userToken).Code)
+	assert.Equal(t, http.StatusForbidden, rt.do(http.MethodPost, "/user/login", `{"login":"user","password":"secret"}`, "").Code)
+
+	w = rt.do(http.MethodGet, "/user?banned=true", "", adminToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	var page models.UserPage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "user", page.Items[0].Login)
+
+	assert.Equal(t, http.StatusOK, rt.do(http.MethodDelete, "/user/2/ban", `{"reason":"appeal"}`, adminToken).Code)
+	assert.Equal(t, http.StatusNotFound, rt.do(http.MethodDelete, "/user/2/ban", "", adminToken).Code)
+	assert.Equal(t, http.StatusOK, rt.do(http.MethodPost, "/user/login", `{"login":"user","password":"secret"}`, "").Code)
+
+	// История хранит снятую блокировку, а аудит - оба действия
+	w = rt.do(http.MethodGet, "/user/2/bans", "", adminToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	var bans []models.UserBan
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &bans))
+	require.Len(t, bans, 1)
+	assert.Equal(t, "spam", bans[0].Reason)
+	assert.NotNil(t, bans[0].LiftedAt)
+	if assert.NotNil(t, bans[0].LiftedByID) {
+		assert.Equal(t, admin.ID, *bans[0].LiftedByID)
+	}
+
+	var audit []models.UserAuditLog
+	require.NoError(t, rt.db.Where("target_user_id = ? AND field = ?", 2, "ban").Order("id").Find(&audit).Error)
+	require.Len(t, audit, 2)
+	assert.Equal(t, "banned until permanent", audit[0].NewValue)
+	assert.Equal(t, "unbanned", audit[1].NewValue)
+	assert.Equal(t, "appeal", audit[1].Reason)
+}
+
+func TestBulkSetStatus(t *testing.T) {
+	rt := newUserRouteTest(t)
+	admin := rt.createUser(t, "admin", models.RoleAdmin, models.StatusActive)
+	first := rt.createUser(t, "first", models.RoleUser, models.StatusActive)
+	second := rt.createUser(t, "second", models.RoleUser, models.StatusActive)
+	adminToken := rt.token(t, admin)
+
+	assert.Equal(t, http.StatusBadRequest, rt.do(http.MethodPut, "/user/bulk/status", `{"user_ids":[],"status":"Passive"}`, adminToken).Code)
+	assert.Equal(t, http.StatusBadRequest, rt.do(http.MethodPut, "/user/bulk/status", `{"user_ids":[2],"status":"Deleted"}`, adminToken).Code)
+	assert.Equal(t, http.StatusForbidden, rt.do(http.MethodPut, "/user/bulk/status", `{"user_ids":[3],"status":"Passive"}`, rt.token(t, first)).Code)
+
+	body := fmt.Sprintf(`{"user_ids":[%d,%d,%d,%d,99],"status":"Passive","reason":"spam"}`, first.ID, second.ID, second.ID, admin.ID)
+	w := rt.do(http.MethodPut, "/user/bulk/status", body, adminToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	var result models.UserBulkResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.ElementsMatch(t, []uint{first.ID, second.ID}, result.Updated)
+	assert.Len(t, result.Failed, 2)
+	assert.Contains(t, result.Failed, admin.ID)
+	assert.Contains(t, result.Failed, uint(99))
+
+	var passive int64
+	rt.db.Model(&models.UserModel{}).Where("status = ?", models.StatusPassive).Count(&passive)
+	assert.Equal(t, int64(2), passive)
+	var audit int64
+	rt.db.Model(&models.UserAuditLog{}).Where("field = ? AND reason = ?", "status", "spam").Count(&audit)
+	assert.Equal(t, int64(2), audit)
+}
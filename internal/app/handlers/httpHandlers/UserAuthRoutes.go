@@ -10,17 +10,28 @@ import (
 	"net/http"
 	"petition_api/internal/app/models"
 	"petition_api/utils/auth"
+	"strings"
 	"time"
 )
 
+// tokenPair Ответ с парой токенов для клиентов, которые не используют куки (серверы, CLI)
+type tokenPair struct {
+	TokenType        string      `json:"token_type"`
+	AccessToken      string      `json:"access_token"`
+	ExpiresIn        int64       `json:"expires_in"`
+	RefreshToken     string      `json:"refresh_token"`
+	RefreshExpiresIn int64       `json:"refresh_expires_in"`
+	User             interface{} `json:"user"`
+}
+
 // createUser Создает нового пользователя
 // если успешно создано, создает пару jwt токенов сохроняет рефреш токен в сессиях и возвращает его в куки
 func (ur *UserModelRoute) createUser(c *gin.Context) {
-	var user models.UserModel
+	var registration models.UserRegistration
 
 	// Взятие данных с джейсона
-	if err := c.ShouldBindJSON(&user); err != nil {
-		ur.logger.Error("error while parsing body: %v", err.Error())
+	if err := c.ShouldBindJSON(&registration); err != nil {
+		ur.logger.Errorf("error while parsing body: %v", err.Error())
 		var unmarshalTypeError *json.UnmarshalTypeError
 		if errors.As(err, &unmarshalTypeError) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format"})
@@ -29,11 +40,32 @@ func (ur *UserModelRoute) createUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
+	if registration.Region != "" && !models.ValidRegion(registration.Region) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown region"})
+		return
+	}
+	if models.ReservedLogin(registration.Login) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Login is reserved"})
+		return
+	}
+
+	// Роль и статус нового пользователя задает сервер, а не клиент
+	user := models.UserModel{
+		Login:     registration.Login,
+		Password:  registration.Password,
+		Role:      models.RoleUser,
+		FirstName: registration.FirstName,
+		LastName:  registration.LastName,
+		Email:     registration.Email,
+		BirthDate: registration.BirthDate,
+		Region:    registration.Region,
+		Status:    models.StatusActive,
+	}
 
 	// Хэшируем пороль для безопасности
 	hashedPassword, err := auth.HashPassword(user.Password)
 	if err != nil {
-		ur.logger.Error("error in hashing password: %v", err)
+		ur.logger.Errorf("error in hashing password: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error in hashing password"})
 		return
 	}
@@ -45,45 +77,27 @@ func (ur *UserModelRoute) createUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user. Error: " + err.Error()})
 		return
 	}
-	// Создаем access и refresh токены
-	accessToken, err := auth.CreateAccessToken(userID, user.Role)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create access token. Error: " + err.Error()})
-		return
-	}
-	refreshToken, err := auth.CreateRefreshToken(userID, user.Role)
+
+	accessToken, refreshToken, err := ur.startSession(c, userID, user.Role, deviceFingerprint(c, ""))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create refresh token. Error: " + err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	session := models.RefreshSession{
-		UserID:       userID,
-		RefreshToken: refreshToken,
-		UA:           c.Request.UserAgent(),
-		IP:           c.ClientIP(),
-		ExpiresIn:    time.Now().Add(time.Hour * 24 * 7).Unix(),
-		CreatedAt:    time.Time{},
-	}
-
-	err = ur.sessionDB.Save(&session)
+	newUser, err := ur.repo.GetByID(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save refresh session."})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
 	}
 
-	newUser, _ := ur.repo.GetByID(userID)
-
-	setTime := time.Now()
-	// Привязка токенов в куки
-	c.SetCookie("access_token", accessToken, int(setTime.Add(time.Minute*60).Unix()), "/", "localhost", false, true)
-	c.SetCookie("refresh_token", refreshToken, int(setTime.Add(time.Hour*24*7).Unix()), "/", "localhost", false, true)
-	c.JSON(http.StatusCreated, newUser)
+	respondWithTokens(c, http.StatusCreated, models.NewUserPrivateView(newUser), accessToken, refreshToken)
 }
 
 func (ur *UserModelRoute) login(c *gin.Context) {
 	var lgPs = struct {
-		Login    string `json:"login" binding:"required"`
-		Password string `json:"password" binding:"required"`
+		Login       string `json:"login" binding:"required"`
+		Password    string `json:"password" binding:"required"`
+		Fingerprint string `json:"fingerprint"`
 	}{}
 
 	err := c.ShouldBindJSON(&lgPs)
@@ -101,8 +115,8 @@ func (ur *UserModelRoute) login(c *gin.Context) {
 	}
 
 	ur.logger.WithFields(logrus.Fields{
-		"user": user,
-	}).Debug("User in database. login:password: ", lgPs)
+		"user_id": user.ID,
+	}).Debug("User in database. login: ", lgPs.Login)
 
 	validPass := auth.CheckPassword(lgPs.Password, user.Password)
 	if !validPass {
@@ -111,49 +125,33 @@ func (ur *UserModelRoute) login(c *gin.Context) {
 		return
 	}
 
-	// Создаем access и refresh токены
-	accessToken, err := auth.CreateAccessToken(user.ID, user.Role)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create access token. Error: " + err.Error()})
-		return
-	}
-	refreshToken, err := auth.CreateRefreshToken(user.ID, user.Role)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create refresh token. Error: " + err.Error()})
+	// Заблокированный пользователь не может войти, даже зная пароль
+	if err := ur.accounts.CheckAccount(user.ID); err != nil {
+		ur.logger.Warnf("Login of user %d rejected: %v", user.ID, err)
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
 
-	err = ur.sessionDB.Save(&models.RefreshSession{
-		UserID:       user.ID,
-		RefreshToken: refreshToken,
-		UA:           c.Request.UserAgent(),
-		IP:           c.ClientIP(),
-		ExpiresIn:    time.Now().Add(time.Hour * 24 * 7).Unix(),
-		CreatedAt:    time.Time{},
-	})
+	accessToken, refreshToken, err := ur.startSession(c, user.ID, user.Role, deviceFingerprint(c, lgPs.Fingerprint))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save refresh session."})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	user, _ = ur.repo.GetByID(user.ID)
-
-	setTime := time.Now()
-	// Привязка токенов в куки
-	c.SetCookie("access_token", accessToken, int(setTime.Add(time.Minute*60).Unix()), "/", "http://localhost", false, true)
-	c.SetCookie("refresh_token", refreshToken, int(setTime.Add(time.Hour*24*7).Unix()), "/", "http://localhost", false, true)
-
-	c.JSON(http.StatusOK, user)
+	respondWithTokens(c, http.StatusOK, models.NewUserPrivateView(user), accessToken, refreshToken)
 }
 
 func (ur *UserModelRoute) logout(c *gin.Context) {
-	tokenString, err := c.Cookie("refresh_token")
+	tokenString, err := refreshTokenFromRequest(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cookie."})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get refresh token."})
+		return
 	}
 
 	err = ur.sessionDB.Delete(tokenString)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete refresh token."})
+		return
 	}
 
 	// Удаление токенов из куков
@@ -164,10 +162,10 @@ func (ur *UserModelRoute) logout(c *gin.Context) {
 }
 
 func (ur *UserModelRoute) refreshToken(c *gin.Context) {
-	// Взять рефреш токен с куки
-	refreshToken, err := c.Cookie("refresh_token")
+	// Взять рефреш токен с куки или с тела запроса
+	refreshToken, err := refreshTokenFromRequest(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get refresh token to cookie."})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get refresh token."})
 		return
 	}
 
@@ -201,36 +199,121 @@ func (ur *UserModelRoute) refreshToken(c *gin.Context) {
 		return
 	}
 
+	user, err := ur.repo.GetByID(session.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+	if err := ur.accounts.CheckAccount(user.ID); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
 	// До сюда доходит только правильный, активный по времени, существующий токен
-	// На основе старого создаем новые токены
-	newAccessToken, newRefreshToken, err := auth.RefreshTokens(session.RefreshToken)
+	// На основе старого создаем новые токены с актуальной ролью пользователя
+	newAccessToken, newRefreshToken, err := auth.RefreshTokens(session.RefreshToken, user.Role)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed refreshing tokens."})
+		return
+	}
+
+	// Та же сессия получает новый токен, чтобы при обновлении не плодились новые записи
+	session.RefreshToken = newRefreshToken
+	session.UA = c.Request.UserAgent()
+	session.IP = c.ClientIP()
+	session.ExpiresIn = time.Now().Add(auth.RefreshTokenTTL).Unix()
+	if err := ur.sessionDB.Update(session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save new refresh session."})
+		return
 	}
 
-	// Удаляем старый токен и создаем новый на основе старого
-	err = ur.sessionDB.DeleteSession(session)
+	// Возвращаем новый аксес и рефреш токен и данные пользователя для сайта
+	respondWithTokens(c, http.StatusOK, models.NewUserPrivateView(user), newAccessToken, newRefreshToken)
+}
+
+// startSession Создает пару токенов и сохраняет refresh сессию с учетом лимита сессий пользователя.
+// Повторный вход с того же устройства заменяет старую сессию этого устройства
+func (ur *UserModelRoute) startSession(c *gin.Context, userID uint, role string, fingerprint string) (string, string, error) {
+	// Создаем access и refresh токены
+	accessToken, err := auth.CreateAccessToken(userID, role)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete old refresh token."})
+		return "", "", errors.New("Failed to create access token. Error: " + err.Error())
+	}
+	refreshToken, err := auth.CreateRefreshToken(userID, role)
+	if err != nil {
+		return "", "", errors.New("Failed to create refresh token. Error: " + err.Error())
 	}
 
-	err = ur.sessionDB.Save(&models.RefreshSession{
-		UserID:       session.ID,
+	err = ur.sessionDB.SaveForDevice(&models.RefreshSession{
+		UserID:       userID,
 		RefreshToken: refreshToken,
 		UA:           c.Request.UserAgent(),
 		IP:           c.ClientIP(),
-		ExpiresIn:    time.Now().Add(time.Hour * 24 * 7).Unix(),
-		CreatedAt:    time.Time{},
-	})
+		Fingerprint:  fingerprint,
+		ExpiresIn:    time.Now().Add(auth.RefreshTokenTTL).Unix(),
+	}, ur.maxSessions)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save new refresh session."})
+		ur.logger.Errorf("Failed to save refresh session: %v", err)
+		return "", "", errors.New("Failed to save refresh session.")
 	}
 
-	// Ставим новый аксес и рефреш токен
-	c.SetCookie("refresh_token", newRefreshToken, int(time.Now().Add(time.Hour*24*7).Unix()), "/", "localhost", false, true)
-	c.SetCookie("access_token", newAccessToken, int(time.Now().Add(60*time.Minute).Unix()), "/", "localhost", false, true)
+	return accessToken, refreshToken, nil
+}
+
+// deviceFingerprint Определяет устройство пользователя по отпечатку из тела запроса, затем из заголовка X-Device-Fingerprint.
+// User-Agent одинаков у многих устройств, поэтому без явного отпечатка возвращается пустая строка и сессия всегда новая
+func deviceFingerprint(c *gin.Context, fromBody string) string {
+	fingerprint := fromBody
+	if fingerprint == "" {
+		fingerprint = c.GetHeader("X-Device-Fingerprint")
+	}
+	if len(fingerprint) > 255 {
+		fingerprint = fingerprint[:255]
+	}
+	return fingerprint
+}
+
+// wantsTokenPair Клиент просит вернуть токены в теле ответа вместо куки.
+// Выбирается заголовком X-Token-Mode: json или параметром ?token_mode=json
+func wantsTokenPair(c *gin.Context) bool {
+	mode := c.GetHeader("X-Token-Mode")
+	if mode == "" {
+		mode = c.Query("token_mode")
+	}
+	return strings.EqualFold(mode, "json")
+}
+
+// respondWithTokens Отдает токены клиенту: по умолчанию в куки для браузера, либо парой токенов в JSON
+func respondWithTokens(c *gin.Context, status int, user interface{}, accessToken string, refreshToken string) {
+	if wantsTokenPair(c) {
+		c.JSON(status, tokenPair{
+			TokenType:        "Bearer",
+			AccessToken:      accessToken,
+			ExpiresIn:        int64(auth.AccessTokenTTL.Seconds()),
+			RefreshToken:     refreshToken,
+			RefreshExpiresIn: int64(auth.RefreshTokenTTL.Seconds()),
+			User:             user,
+		})
+		return
+	}
+
+	// Привязка токенов в куки
+	c.SetCookie("access_token", accessToken, int(auth.AccessTokenTTL.Seconds()), "/", "localhost", false, true)
+	c.SetCookie("refresh_token", refreshToken, int(auth.RefreshTokenTTL.Seconds()), "/", "localhost", false, true)
+	c.JSON(status, user)
+}
 
-	// Возвращаем данные пользователя для сайта
-	user, _ := ur.repo.GetByID(session.UserID)
-	c.JSON(http.StatusOK, user)
+// refreshTokenFromRequest Берет refresh токен из куки, а если его нет - из JSON тела {"refresh_token": "..."}
+func refreshTokenFromRequest(c *gin.Context) (string, error) {
+	if refreshToken, err := c.Cookie("refresh_token"); err == nil && refreshToken != "" {
+		return refreshToken, nil
+	}
+
+	var body struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		return "", err
+	}
+	return body.RefreshToken, nil
 }
@@ -0,0 +1,70 @@
+package httpHandlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"petition_api/internal/app/services"
+	"petition_api/middleware"
+	"strconv"
+	"time"
+)
+
+type UserEmailRoute struct {
+	verification *services.EmailVerificationService
+	accounts     middleware.AccountChecker
+	logger       *logrus.Logger
+}
+
+// NewUserEmailRoute создает роут для подтверждения email
+func NewUserEmailRoute(verification *services.EmailVerificationService, accounts middleware.AccountChecker, logger *logrus.Logger) *UserEmailRoute {
+	return &UserEmailRoute{verification: verification, accounts: accounts, logger: logger}
+}
+
+func (er *UserEmailRoute) BindEmailToRoute(route *gin.RouterGroup) {
+	authMiddleware := middleware.NewAuthMiddleware(er.logger, er.accounts)
+
+	route.POST("/me/email/verification", authMiddleware, er.sendVerification)
+	route.GET("/email/verify", er.verify)
+}
+
+// sendVerification Отправляет письмо со ссылкой подтверждения email текущего пользователя
+func (er *UserEmailRoute) sendVerification(c *gin.Context) {
+	err := er.verification.Send(c.Value("ID").(uint), time.Now())
+	switch {
+	case err == nil:
+		c.JSON(http.StatusAccepted, gin.H{"sent": true})
+	case errors.Is(err, services.ErrEmailAlreadyVerified), errors.Is(err, services.ErrNoEmail):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		er.logger.Errorf("Failed to send verification email: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+	}
+}
+
+// verify Подтверждает email по подписанной ссылке ?user=&expires=&token=
+func (er *UserEmailRoute) verify(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Query("user"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid verification link"})
+		return
+	}
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid verification link"})
+		return
+	}
+
+	user, err := er.verification.Verify(uint(userID), expires, c.Query("token"), time.Now())
+	if errors.Is(err, services.ErrInvalidVerificationLink) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		er.logger.Errorf("Failed to verify email of user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"email": user.Email, "email_verified_at": user.EmailVerifiedAt})
+}
@@ -1,53 +1,81 @@
 package httpHandlers
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 	"net/http"
 	"petition_api/internal/app/models"
 	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/services"
 	"petition_api/middleware"
+	"petition_api/utils/auth"
 	"strconv"
+	"time"
 )
 
 type UserModelRoute struct {
-	repo      repository.UserRepository
-	sessionDB repository.SessionRepo
-	logger    *logrus.Logger
+	repo         repository.UserRepository
+	petitionRepo repository.PetitionRepository
+	voteRepo     repository.VoteRepository
+	sessionDB    repository.SessionRepo
+	roles        repository.RoleRepository
+	audit        repository.UserAuditRepository
+	bans         repository.UserBanRepository
+	accounts     *services.AccountStatusService
+	deletion     *services.AccountDeletionService
+	maxSessions  int
+	logger       *logrus.Logger
 }
 
 // NewUserModelRoute создает новую роут
-func NewUserModelRoute(repo repository.UserRepository, sessionDB repository.SessionRepo, logger *logrus.Logger) *UserModelRoute {
-	return &UserModelRoute{repo: repo, sessionDB: sessionDB, logger: logger}
+// maxSessions ограничивает число активных сессий одного пользователя (0 - без ограничений)
+func NewUserModelRoute(repo repository.UserRepository, petitionRepo repository.PetitionRepository, voteRepo repository.VoteRepository, sessionDB repository.SessionRepo, roles repository.RoleRepository, audit repository.UserAuditRepository, bans repository.UserBanRepository, accounts *services.AccountStatusService, deletion *services.AccountDeletionService, maxSessions int, logger *logrus.Logger) *UserModelRoute {
+	return &UserModelRoute{
+		repo:         repo,
+		petitionRepo: petitionRepo,
+		voteRepo:     voteRepo,
+		sessionDB:    sessionDB,
+		roles:        roles,
+		audit:        audit,
+		bans:         bans,
+		accounts:     accounts,
+		deletion:     deletion,
+		maxSessions:  maxSessions,
+		logger:       logger,
+	}
 }
 
 func (ur *UserModelRoute) BindUserToRoute(route *gin.RouterGroup) {
 
-	authMiddleware := middleware.NewAuthMiddleware(ur.logger)
-	roleAdminMiddleware := middleware.NewRoleAdminMiddleware(ur.logger)
+	authMiddleware := middleware.NewAuthMiddleware(ur.logger, ur.accounts)
+	userManageMiddleware := middleware.RequirePermission(&ur.roles, ur.logger, models.PermUserManage)
 
 	route.POST("/registration", ur.createUser)
-	// TODO : Нужно чтобы старое удалялось когда новый раз логин сделает когда старый не истек
 	route.POST("/login", ur.login)
+	// GET оставлен для браузера с куками, клиенты с токенами в JSON передают refresh токен в теле POST
 	route.GET("/logout", authMiddleware, ur.logout)
+	route.POST("/logout", authMiddleware, ur.logout)
 	route.GET("/refresh", ur.refreshToken)
+	route.POST("/refresh", ur.refreshToken)
 
-	route.GET("", authMiddleware, roleAdminMiddleware, ur.getUsers)
+	route.GET("", authMiddleware, userManageMiddleware, ur.searchUsers)
+	route.PUT("/bulk/status", authMiddleware, userManageMiddleware, ur.bulkSetStatus)
 	route.GET("/getWithToken", authMiddleware, ur.getByToken)
+	route.GET("/me", authMiddleware, ur.getByToken)
+	route.POST("/me/deletion", authMiddleware, ur.requestDeletion)
+	route.DELETE("/me/deletion", authMiddleware, ur.cancelDeletion)
 	route.GET("/:id", authMiddleware, ur.getUserByID)
 	route.PUT("/:id", authMiddleware, ur.updateUser)
 	route.PATCH("/:id", authMiddleware, ur.patchUser)
 	route.DELETE("/:id", authMiddleware, ur.deleteUser)
-}
-
-func (ur *UserModelRoute) getUsers(c *gin.Context) {
-	users, err := ur.repo.GetAll()
-	if err != nil {
-		ur.logger.Error("error in getting all users: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{})
-		return
-	}
-	c.JSON(http.StatusOK, users)
+	route.PUT("/:id/role", authMiddleware, userManageMiddleware, ur.assignRole)
+	route.PUT("/:id/status", authMiddleware, userManageMiddleware, ur.setStatus)
+	route.GET("/:id/audit", authMiddleware, userManageMiddleware, ur.getAuditLog)
+	route.POST("/:id/ban", authMiddleware, userManageMiddleware, ur.banUser)
+	route.DELETE("/:id/ban", authMiddleware, userManageMiddleware, ur.unbanUser)
+	route.GET("/:id/bans", authMiddleware, userManageMiddleware, ur.getBans)
 }
 
 func (ur *UserModelRoute) getUserByID(c *gin.Context) {
@@ -63,7 +91,26 @@ func (ur *UserModelRoute) getUserByID(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	// Свой профиль и профили для админов - с личными данными, остальным - публичный профиль
+	if ur.canManageUser(c, user.ID) {
+		c.JSON(http.StatusOK, models.NewUserPrivateView(user))
+		return
+	}
+
+	petitionsAuthored, err := ur.petitionRepo.CountByUserID(user.ID)
+	if err != nil {
+		ur.logger.Errorf("error in counting user petitions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user profile"})
+		return
+	}
+	signaturesCount, err := ur.voteRepo.CountByUserID(user.ID)
+	if err != nil {
+		ur.logger.Errorf("error in counting user signatures: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewUserPublicProfile(user, petitionsAuthored, signaturesCount))
 }
 
 func (ur *UserModelRoute) getByToken(c *gin.Context) {
@@ -75,7 +122,7 @@ func (ur *UserModelRoute) getByToken(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	c.JSON(http.StatusOK, models.NewUserPrivateView(user))
 }
 
 func (ur *UserModelRoute) updateUser(c *gin.Context) {
@@ -86,26 +133,54 @@ func (ur *UserModelRoute) updateUser(c *gin.Context) {
 		return
 	}
 
-	// Юзер может изменить только свой профиль. А админ может изменить всех
-	tokenUserId := c.Value("ID").(uint)
-	tokenUserRole := c.Value("Role").(string)
-
-	if tokenUserRole != "Admin" && tokenUserId != uint(userID) {
+	// Юзер может изменить только свой профиль. А с правом user.manage можно изменить всех
+	if !ur.canManageUser(c, uint(userID)) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Doesn't have access"})
+		return
 	}
 
-	var user models.UserModel
-	if err := c.ShouldBindJSON(&user); err != nil {
+	// Роль и статус меняются только через /user/:id/role и /user/:id/status
+	var updateUser models.UserProfileUpdate
+	if err := c.ShouldBindJSON(&updateUser); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
+	if updateUser.Region != "" && !models.ValidRegion(updateUser.Region) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown region"})
+		return
+	}
+
+	user, err := ur.repo.GetByID(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if updateUser.Login != user.Login && models.ReservedLogin(updateUser.Login) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Login is reserved"})
+		return
+	}
 
 	ur.logger.WithFields(logrus.Fields{
-		"user": user,
+		"user": updateUser.Login,
 	}).Debug("Новый данные пользователя")
 
-	user.ID = uint(userID)
-	if err := ur.repo.Update(&user); err != nil {
+	user.Login = updateUser.Login
+	user.FirstName = updateUser.FirstName
+	user.LastName = updateUser.LastName
+	user.SetEmail(updateUser.Email)
+	if !ur.changeEligibility(c, user, updateUser.BirthDate, updateUser.Region) {
+		return
+	}
+	if updateUser.Password != "" {
+		hashedPassword, err := auth.HashPassword(updateUser.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error in hashing password"})
+			return
+		}
+		user.Password = hashedPassword
+	}
+
+	if err := ur.repo.Update(user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
 		return
 	}
@@ -120,11 +195,8 @@ func (ur *UserModelRoute) patchUser(c *gin.Context) {
 		return
 	}
 
-	// Юзер может изменить только свой профиль. А админ может изменить всех
-	tokenUserID := c.Value("ID").(uint)
-	tokenUserRole := c.Value("Role").(string)
-
-	if tokenUserRole != "Admin" && tokenUserID != uint(userID) {
+	// Юзер может изменить только свой профиль. А с правом user.manage можно изменить всех
+	if !ur.canManageUser(c, uint(userID)) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Doesn't have access"})
 		return
 	}
@@ -136,22 +208,34 @@ func (ur *UserModelRoute) patchUser(c *gin.Context) {
 		return
 	}
 
-	// Привязываем только те поля, которые нужно обновить
+	// Привязываем только те поля, которые нужно обновить.
+	// Роль и статус меняются только через /user/:id/role и /user/:id/status
 	var updateUser models.UserUpdate
 	if err := c.ShouldBindJSON(&updateUser); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
+	if updateUser.Region != nil && *updateUser.Region != "" && !models.ValidRegion(*updateUser.Region) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown region"})
+		return
+	}
+
+	if updateUser.Login != "" && updateUser.Login != user.Login && models.ReservedLogin(updateUser.Login) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Login is reserved"})
+		return
+	}
 
 	// Обновляем только указанные поля
 	if updateUser.Login != "" {
 		user.Login = updateUser.Login
 	}
 	if updateUser.Password != "" {
-		user.Password = updateUser.Password
-	}
-	if updateUser.Role != "" {
-		user.Role = updateUser.Role
+		hashedPassword, err := auth.HashPassword(updateUser.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error in hashing password"})
+			return
+		}
+		user.Password = hashedPassword
 	}
 	if updateUser.FirstName != "" {
 		user.FirstName = updateUser.FirstName
@@ -160,13 +244,17 @@ func (ur *UserModelRoute) patchUser(c *gin.Context) {
 		user.LastName = updateUser.LastName
 	}
 	if updateUser.Email != "" {
-		user.Email = updateUser.Email
+		user.SetEmail(updateUser.Email)
 	}
+	birthDate, region := user.BirthDate, user.Region
 	if !updateUser.BirthDate.IsZero() {
-		user.BirthDate = updateUser.BirthDate
+		birthDate = updateUser.BirthDate
 	}
-	if updateUser.Status != "" {
-		user.Status = updateUser.Status
+	if updateUser.Region != nil {
+		region = *updateUser.Region
+	}
+	if !ur.changeEligibility(c, user, birthDate, region) {
+		return
 	}
 	// Обновляем пользователя в базе данных
 	if err := ur.repo.Update(user); err != nil {
@@ -184,18 +272,247 @@ func (ur *UserModelRoute) deleteUser(c *gin.Context) {
 		return
 	}
 
-	// Юзер может удалить только свой профиль. А админ может удалить всех
-	tokenUserId := c.Value("ID").(uint)
-	tokenUserRole := c.Value("Role").(string)
-
-	if tokenUserRole != "Admin" && tokenUserId != uint(userID) {
+	// Юзер может удалить только свой профиль. А с правом user.manage можно удалить всех
+	if !ur.canManageUser(c, uint(userID)) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Doesn't have access"})
+		return
 	}
 
-	if err := ur.repo.DeleteByID(uint(userID)); err != nil {
+	// Пользователь с правом user.manage может удалить чужой аккаунт сразу, без периода ожидания
+	if c.Query("immediate") == "true" && c.Value("ID").(uint) != uint(userID) {
+		if err := ur.deletion.Anonymise(uint(userID)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+			return
+		}
+		c.Status(http.StatusOK)
+		return
+	}
+
+	scheduledAt, err := ur.deletion.Schedule(uint(userID))
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
 		return
 	}
 
+	c.JSON(http.StatusAccepted, gin.H{"deletion_scheduled_at": scheduledAt})
+}
+
+// requestDeletion Запрашивает удаление своего аккаунта. До назначенного времени удаление можно отменить
+func (ur *UserModelRoute) requestDeletion(c *gin.Context) {
+	scheduledAt, err := ur.deletion.Schedule(c.Value("ID").(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule account deletion"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"deletion_scheduled_at": scheduledAt})
+}
+
+// cancelDeletion Отменяет запрошенное удаление своего аккаунта
+func (ur *UserModelRoute) cancelDeletion(c *gin.Context) {
+	if err := ur.deletion.Cancel(c.Value("ID").(uint)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to cancel account deletion: " + err.Error()})
+		return
+	}
+
 	c.Status(http.StatusOK)
 }
+
+// assignRole Назначает пользователю роль и записывает изменение в аудит
+func (ur *UserModelRoute) assignRole(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input models.UserRoleUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	// Иначе админ может случайно лишить себя прав, а пользователь с user.manage - назначить себе любую роль
+	if c.Value("ID").(uint) == uint(userID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You can not change your own role"})
+		return
+	}
+
+	exists, err := ur.roles.RoleExists(input.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check role"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+		return
+	}
+
+	user, err := ur.repo.GetByID(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	oldRole := user.Role
+	user.Role = input.Role
+	if err := ur.updateWithAudit(c, user, "role", oldRole, input.Role, input.Reason); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+	// Права по новой роли действуют сразу, а не после истечения access токена
+	ur.accounts.Invalidate(user.ID)
+
+	c.JSON(http.StatusOK, models.NewUserPrivateView(user))
+}
+
+// setStatus Меняет статус пользователя и записывает изменение в аудит
+func (ur *UserModelRoute) setStatus(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input models.UserStatusUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if c.Value("ID").(uint) == uint(userID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You can not change your own status"})
+		return
+	}
+
+	user, err := ur.repo.GetByID(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := ur.updateStatus(c, user, input.Status, input.Reason); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewUserPrivateView(user))
+}
+
+// getAuditLog Возвращает историю изменений роли и статуса пользователя
+func (ur *UserModelRoute) getAuditLog(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.Query("pageSize"))
+	if err != nil || pageSize < 1 {
+		pageSize = 20
+	}
+
+	entries, err := ur.audit.GetByTargetUserID(uint(userID), page, pageSize)
+	if err != nil {
+		ur.logger.Errorf("error in getting audit log: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get audit log"})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// updateWithAudit Сохраняет пользователя и запись аудита о смене поля в одной транзакции
+func (ur *UserModelRoute) updateWithAudit(c *gin.Context, user *models.UserModel, field string, oldValue string, newValue string, reason string) error {
+	err := ur.repo.DB.Transaction(func(tx *gorm.DB) error {
+		return ur.updateWithAuditTx(tx, c, user, field, oldValue, newValue, reason)
+	})
+	if err != nil {
+		ur.logger.Errorf("Failed to change %s of user %d: %v", field, user.ID, err)
+		return err
+	}
+
+	ur.logger.Infof("User %d changed %s of user %d: %s -> %s", c.Value("ID").(uint), field, user.ID, oldValue, newValue)
+	return nil
+}
+
+// updateWithAuditTx Сохраняет пользователя и запись аудита о смене поля в рамках транзакции
+func (ur *UserModelRoute) updateWithAuditTx(tx *gorm.DB, c *gin.Context, user *models.UserModel, field string, oldValue string, newValue string, reason string) error {
+	if err := ur.repo.UpdateTx(tx, user); err != nil {
+		return err
+	}
+	return ur.audit.CreateTx(tx, &models.UserAuditLog{
+		ActorID:      c.Value("ID").(uint),
+		TargetUserID: user.ID,
+		Field:        field,
+		OldValue:     oldValue,
+		NewValue:     newValue,
+		Reason:       reason,
+		IP:           c.ClientIP(),
+	})
+}
+
+// updateStatus Меняет статус пользователя с записью в аудит.
+// При отключении аккаунта (Passive) все его refresh сессии удаляются в той же транзакции
+func (ur *UserModelRoute) updateStatus(c *gin.Context, user *models.UserModel, status string, reason string) error {
+	oldStatus := user.Status
+	user.Status = status
+	err := ur.repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := ur.updateWithAuditTx(tx, c, user, "status", oldStatus, status, reason); err != nil {
+			return err
+		}
+		if status == models.StatusPassive {
+			return ur.sessionDB.DeleteAllByUserIDTx(tx, user.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		user.Status = oldStatus
+		ur.logger.Errorf("Failed to change status of user %d: %v", user.ID, err)
+		return err
+	}
+	// Уже выданные access токены перестают работать сразу, а не после истечения кэша
+	ur.accounts.Invalidate(user.ID)
+
+	ur.logger.Infof("User %d changed status of user %d: %s -> %s", c.Value("ID").(uint), user.ID, oldStatus, status)
+	return nil
+}
+
+// canManageUser Пользователь может управлять своим профилем, а с правом user.manage - любым
+// changeEligibility Меняет регион и дату рождения, от которых зависят условия подписи петиций.
+// Сам пользователь может менять их раз в models.EligibilityChangeCooldown, иначе отвечает 409 и возвращает false.
+// Пользователи с правом user.manage исправляют чужие данные без ограничения.
+// Первое указание региона тоже считается сменой
+func (ur *UserModelRoute) changeEligibility(c *gin.Context, user *models.UserModel, birthDate time.Time, region string) bool {
+	if sameDate(user.BirthDate, birthDate) && user.Region == region {
+		return true
+	}
+	if c.Value("ID").(uint) == user.ID {
+		now := time.Now()
+		if availableAt := user.EligibilityChangeAvailableAt(); now.Before(availableAt) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":        fmt.Sprintf("Region and birth date can be changed once in %d days", int(models.EligibilityChangeCooldown.Hours()/24)),
+				"available_at": availableAt,
+			})
+			return false
+		}
+		user.EligibilityChangedAt = &now
+	}
+	user.BirthDate = birthDate
+	user.Region = region
+	return true
+}
+
+// sameDate Совпадают ли календарные даты. Дата рождения хранится без времени
+func sameDate(a time.Time, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+func (ur *UserModelRoute) canManageUser(c *gin.Context, userID uint) bool {
+	if c.Value("ID").(uint) == userID {
+		return true
+	}
+	return middleware.HasPermission(c, &ur.roles, models.PermUserManage)
+}
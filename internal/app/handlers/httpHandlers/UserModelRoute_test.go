@@ -0,0 +1,334 @@
+package httpHandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/services"
+	"petition_api/internal/app/testutil"
+	"petition_api/utils/auth"
+	"testing"
+	"time"
+)
+
+type userRouteTest struct {
+	db     *gorm.DB
+	router *gin.Engine
+}
+
+func newUserRouteTest(t *testing.T) *userRouteTest {
+	testutil.SetAuthKey(t)
+
+	db := testutil.OpenDB(t,
+		models.UserModel{},
+		models.RefreshSession{},
+		models.Petition{},
+		models.Vote{},
+		models.Permission{},
+		models.Role{},
+		models.UserAuditLog{},
+		models.UserBan{},
+	)
+
+	logger := testutil.Logger()
+	userRepo := repository.NewUserRepository(db, logger)
+	roleRepo := repository.NewRoleRepository(db, logger)
+	if err := roleRepo.Seed(models.DefaultPermissions, models.DefaultRolePermissions); err != nil {
+		t.Fatal(err)
+	}
+	banRepo := repository.NewUserBanRepository(db, logger)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewUserModelRoute(
+		userRepo,
+		repository.NewPetitionRepository(db, logger),
+		repository.NewVoteRepository(db, logger),
+		repository.NewSessionRepo(db, logger),
+		roleRepo,
+		repository.NewUserAuditRepository(db, logger),
+		banRepo,
+		services.NewAccountStatusService(userRepo, banRepo, logger),
+		nil,
+		5,
+		logger,
+	).BindUserToRoute(router.Group("/user"))
+
+	return &userRouteTest{db: db, router: router}
+}
+
+func (rt *userRouteTest) createUser(t *testing.T, login string, role string, status string) *models.UserModel {
+	hash, err := auth.HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := models.UserModel{Login: login, Password: hash, Role: role, Email: login + "@mail.kz", Status: status}
+	if err := rt.db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &user
+}
+
+func (rt *userRouteTest) do(method string, path string, body string, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Token-Mode", "json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	rt.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestLoginRejectsPassiveUser(t *testing.T) {
+	rt := newUserRouteTest(t)
+	rt.createUser(t, "active", models.RoleUser, models.StatusActive)
+	rt.createUser(t, "passive", models.RoleUser, models.StatusPassive)
+
+	w := rt.do(http.MethodPost, "/user/login", `{"login":"active","password":"secret"}`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = rt.do(http.MethodPost, "/user/login", `{"login":"passive","password":"secret"}`, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "account is disabled")
+}
+
+func TestSetPassiveRevokesAccess(t *testing.T) {
+	rt := newUserRouteTest(t)
+	admin := rt.createUser(t, "admin", models.RoleAdmin, models.StatusActive)
+	rt.createUser(t, "user", models.RoleUser, models.StatusActive)
+
+	w := rt.do(http.MethodPost, "/user/login", `{"login":"user","password":"secret"}`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	accessToken := jsonField(t, w, "access_token")
+	refreshToken := jsonField(t, w, "refresh_token")
+
+	// Токен пользователя работает, статус уже закэширован middleware
+	assert.Equal(t, http.StatusOK, rt.do(http.MethodGet, "/user/me", "", accessToken).Code)
+
+	adminToken, err := auth.CreateAccessToken(admin.ID, admin.Role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = rt.do(http.MethodPut, "/user/2/status", `{"status":"Passive","reason":"spam"}`, adminToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var sessions int64
+	rt.db.Model(&models.RefreshSession{}).Where("user_id = ?", 2).Count(&sessions)
+	assert.Equal(t, int64(0), sessions)
+
+	assert.Equal(t, http.StatusForbidden, rt.do(http.MethodGet, "/user/me", "", accessToken).Code)
+	w = rt.do(http.MethodPost, "/user/refresh", `{"refresh_token":"`+refreshToken+`"}`, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestLogoutWithTokenPair(t *testing.T) {
+	rt := newUserRouteTest(t)
+	rt.createUser(t, "user", models.RoleUser, models.StatusActive)
+
+	w := rt.do(http.MethodPost, "/user/login", `{"login":"user","password":"secret"}`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	accessToken := jsonField(t, w, "access_token")
+	refreshToken := jsonField(t, w, "refresh_token")
+
+	w = rt.do(http.MethodPost, "/user/logout", `{"refresh_token":"`+refreshToken+`"}`, accessToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Сессия удалена, refresh токен больше не обновляет пару
+	var sessions int64
+	rt.db.Model(&models.RefreshSession{}).Count(&sessions)
+	assert.Zero(t, sessions)
+	w = rt.do(http.MethodPost, "/user/refresh", `{"refresh_token":"`+refreshToken+`"}`, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func jsonField(t *testing.T, w *httptest.ResponseRecorder, field string) string {
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	value, _ := body[field].(string)
+	return value
+}
+
+func TestEligibilityFieldsChangeCooldown(t *testing.T) {
+	rt := newUserRouteTest(t)
+	admin := rt.createUser(t, "admin", models.RoleAdmin, models.StatusActive)
+	user := rt.createUser(t, "user", models.RoleUser, models.StatusActive)
+	userToken, err := auth.CreateAccessToken(user.ID, user.Role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminToken, err := auth.CreateAccessToken(admin.ID, admin.Role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/user/%d", user.ID)
+
+	// Первое указание региона проходит и запускает период ожидания
+	assert.Equal(t, http.StatusOK, rt.do(http.MethodPatch, path, `{"region":"almaty"}`, userToken).Code)
+	// Остальные поля профиля меняются без ограничений
+	assert.Equal(t, http.StatusOK, rt.do(http.MethodPatch, path, `{"first_name":"Айгерим","region":"almaty"}`, userToken).Code)
+
+	// Сменить регион или дату рождения прямо перед подписью нельзя
+	w := rt.do(http.MethodPatch, path, `{"region":"astana"}`, userToken)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.NotEmpty(t, jsonField(t, w, "available_at"))
+	w = rt.do(http.MethodPatch, path, `{"birth_date":"2000-01-01T00:00:00Z"}`, userToken)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = rt.do(http.MethodPut, path, `{"login":"user","email":"user@mail.kz","birth_date":"2000-01-01T00:00:00Z","region":"almaty"}`, userToken)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var stored models.UserModel
+	assert.NoError(t, rt.db.First(&stored, user.ID).Error)
+	assert.Equal(t, "almaty", stored.Region)
+	assert.Equal(t, "Айгерим", stored.FirstName)
+
+	// Админ исправляет данные без ограничения
+	assert.Equal(t, http.StatusOK, rt.do(http.MethodPatch, path, `{"region":"astana"}`, adminToken).Code)
+
+	// После периода ожидания пользователь снова может сменить регион
+	rt.db.Model(&models.UserModel{}).Where("id = ?", user.ID).
+		Update("eligibility_changed_at", time.Now().Add(-models.EligibilityChangeCooldown-time.Hour))
+	assert.Equal(t, http.StatusOK, rt.do(http.MethodPatch, path, `{"region":"shymkent"}`, userToken).Code)
+	var changed models.UserModel
+	assert.NoError(t, rt.db.First(&changed, user.ID).Error)
+	assert.Equal(t, "shymkent", changed.Region)
+	if assert.NotNil(t, changed.EligibilityChangedAt) {
+		assert.WithinDuration(t, time.Now(), *changed.EligibilityChangedAt, time.Minute)
+	}
+}
+
+func TestUserCanNotEscalateOwnRoleOrStatus(t *testing.T) {
+	rt := newUserRouteTest(t)
+	admin := rt.createUser(t, "admin", models.RoleAdmin, models.StatusActive)
+	user := rt.createUser(t, "user", models.RoleUser, models.StatusActive)
+	userToken, err := auth.CreateAccessToken(user.ID, user.Role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminToken, err := auth.CreateAccessToken(admin.ID, admin.Role)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Роль и статус в профиле игнорируются
+	path := fmt.Sprintf("/user/%d", user.ID)
+	assert.Equal(t, http.StatusOK, rt.do(http.MethodPatch, path, `{"first_name":"Айгерим","role":"Admin","status":"Passive"}`, userToken).Code)
+	// Без права user.manage менять роль и статус нельзя
+	assert.Equal(t, http.StatusForbidden, rt.do(http.MethodPut, path+"/role", `{"role":"Admin"}`, userToken).Code)
+	assert.Equal(t, http.StatusForbidden, rt.do(http.MethodPut, path+"/status", `{"status":"Active"}`, userToken).Code)
+	// Админ не может сменить роль и статус самому себе
+	adminPath := fmt.Sprintf("/user/%d", admin.ID)
+	w := rt.do(http.MethodPut, adminPath+"/role", `{"role":"User"}`, adminToken)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "You can not change your own role", jsonField(t, w, "error"))
+	w = rt.do(http.MethodPut, adminPath+"/status", `{"status":"Passive"}`, adminToken)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "You can not change your own status", jsonField(t, w, "error"))
+
+	var stored models.UserModel
+	assert.NoError(t, rt.db.First(&stored, user.ID).Error)
+	assert.Equal(t, models.RoleUser, stored.Role)
+	assert.Equal(t, models.StatusActive, stored.Status)
+	assert.Equal(t, "Айгерим", stored.FirstName)
+	var storedAdmin models.UserModel
+	assert.NoError(t, rt.db.First(&storedAdmin, admin.ID).Error)
+	assert.Equal(t, models.RoleAdmin, storedAdmin.Role)
+	assert.Equal(t, models.StatusActive, storedAdmin.Status)
+
+	var audit int64
+	rt.db.Model(&models.UserAuditLog{}).Count(&audit)
+	assert.Zero(t, audit)
+
+	// Роль при регистрации задает сервер
+	w = rt.do(http.MethodPost, "/user/registration", `{"login":"newbie","password":"secret","email":"newbie@mail.kz","role":"Admin","status":"Passive"}`, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var registered models.UserModel
+	assert.NoError(t, rt.db.Where("login = ?", "newbie").First(&registered).Error)
+	assert.Equal(t, models.RoleUser, registered.Role)
+	assert.Equal(t, models.StatusActive, registered.Status)
+}
+
+func TestRoleAndStatusChangesAreAudited(t *testing.T) {
+	rt := newUserRouteTest(t)
+	admin := rt.createUser(t, "admin", models.RoleAdmin, models.StatusActive)
+	user := rt.createUser(t, "user", models.RoleUser, models.StatusActive)
+	adminToken, err := auth.CreateAccessToken(admin.ID, admin.Role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/user/%d", user.ID)
+
+	assert.Equal(t, http.StatusBadRequest, rt.do(http.MethodPut, path+"/role", `{"role":"Emperor"}`, adminToken).Code)
+	assert.Equal(t, http.StatusOK, rt.do(http.MethodPut, path+"/role", `{"role":"Moderator","reason":"helps with comments"}`, adminToken).Code)
+	assert.Equal(t, http.StatusOK, rt.do(http.MethodPut, path+"/status", `{"status":"Passive","reason":"spam"}`, adminToken).Code)
+
+	// Журнал виден только с правом user.manage
+	moderator := rt.createUser(t, "moderator", models.RoleModerator, models.StatusActive)
+	moderatorToken, err := auth.CreateAccessToken(moderator.ID, moderator.Role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusForbidden, rt.do(http.MethodGet, path+"/audit", "", moderatorToken).Code)
+
+	w := rt.do(http.MethodGet, path+"/audit", "", adminToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var entries []models.UserAuditLog
+	if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+	// Новые записи первыми
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "status", entries[0].Field)
+		assert.Equal(t, models.StatusActive, entries[0].OldValue)
+		assert.Equal(t, models.StatusPassive, entries[0].NewValue)
+		assert.Equal(t, "spam", entries[0].Reason)
+		assert.Equal(t, "role", entries[1].Field)
+		assert.Equal(t, models.RoleUser, entries[1].OldValue)
+		assert.Equal(t, models.RoleModerator, entries[1].NewValue)
+		assert.Equal(t, "helps with comments", entries[1].Reason)
+		for _, entry := range entries {
+			assert.Equal(t, admin.ID, entry.ActorID)
+			assert.Equal(t, user.ID, entry.TargetUserID)
+			assert.NotEmpty(t, entry.IP)
+		}
+	}
+
+	w = rt.do(http.MethodGet, path+"/audit?page=2&pageSize=1", "", adminToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "role", entries[0].Field)
+	}
+}
+
+func TestDemotedAdminLosesAccessImmediately(t *testing.T) {
+	rt := newUserRouteTest(t)
+	admin := rt.createUser(t, "admin", models.RoleAdmin, models.StatusActive)
+	other := rt.createUser(t, "other", models.RoleAdmin, models.StatusActive)
+	adminToken, err := auth.CreateAccessToken(admin.ID, admin.Role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherToken, err := auth.CreateAccessToken(other.ID, other.Role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusOK, rt.do(http.MethodGet, "/user", "", otherToken).Code)
+
+	w := rt.do(http.MethodPut, fmt.Sprintf("/user/%d/role", other.ID), `{"role":"User"}`, adminToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	// В токене все еще Admin, но права берутся по роли из базы
+	assert.Equal(t, http.StatusForbidden, rt.do(http.MethodGet, "/user", "", otherToken).Code)
+}
@@ -0,0 +1,50 @@
+package httpHandlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/services"
+	"strconv"
+)
+
+type VoteAuditRoute struct {
+	audit     *services.VoteAuditService
+	petitions repository.PetitionRepository
+	logger    *logrus.Logger
+}
+
+// NewVoteAuditRoute создает роут для публичной проверки журнала голосов петиций
+func NewVoteAuditRoute(audit *services.VoteAuditService, petitions repository.PetitionRepository, logger *logrus.Logger) *VoteAuditRoute {
+	return &VoteAuditRoute{audit: audit, petitions: petitions, logger: logger}
+}
+
+func (ar *VoteAuditRoute) BindVoteAuditToRoute(route *gin.RouterGroup) {
+	route.GET("/:id/votes/audit", ar.verify)
+}
+
+// verify Хэш последней записи журнала петиции, число записей каждого действия и результат сверки с подписями.
+// Хэш можно сохранить и позже убедиться, что история подписей не переписана
+func (ar *VoteAuditRoute) verify(c *gin.Context) {
+	petitionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid petition ID"})
+		return
+	}
+	if _, err := ar.petitions.GetByID(uint(petitionID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Petition not found"})
+		return
+	}
+
+	report, err := ar.audit.VerifyPetition(uint(petitionID))
+	if err != nil {
+		ar.logger.Errorf("Error verifying vote audit log of petition %d: %v", petitionID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify vote audit log"})
+		return
+	}
+	if !report.Valid {
+		ar.logger.Warnf("Vote audit log of petition %d is invalid: %s", petitionID, report.Problem)
+	}
+	c.JSON(http.StatusOK, report)
+}
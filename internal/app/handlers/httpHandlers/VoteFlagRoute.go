@@ -0,0 +1,93 @@
+package httpHandlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/services"
+	"petition_api/middleware"
+	"strconv"
+	"time"
+)
+
+type VoteFlagRoute struct {
+	fraud    *services.VoteFraudService
+	roles    repository.RoleRepository
+	accounts middleware.AccountChecker
+	logger   *logrus.Logger
+}
+
+// NewVoteFlagRoute создает роут для проверки подозрительных голосов админами
+func NewVoteFlagRoute(fraud *services.VoteFraudService, roles repository.RoleRepository, accounts middleware.AccountChecker, logger *logrus.Logger) *VoteFlagRoute {
+	return &VoteFlagRoute{fraud: fraud, roles: roles, accounts: accounts, logger: logger}
+}
+
+func (fr *VoteFlagRoute) BindVoteFlagToRoute(route *gin.RouterGroup) {
+	authMiddleware := middleware.NewAuthMiddleware(fr.logger, fr.accounts)
+	userManageMiddleware := middleware.RequirePermission(&fr.roles, fr.logger, models.PermUserManage)
+
+	route.GET("", authMiddleware, userManageMiddleware, fr.getFlags)
+	route.POST("/:id/dismiss", authMiddleware, userManageMiddleware, fr.dismiss)
+	route.POST("/:id/invalidate", authMiddleware, userManageMiddleware, fr.invalidate)
+}
+
+// getFlags Подозрительные голоса, новые первыми. ?status=pending&petition_id=1
+func (fr *VoteFlagRoute) getFlags(c *gin.Context) {
+	var filter models.VoteFlagFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	page, err := fr.fraud.List(filter)
+	if err != nil {
+		fr.logger.Errorf("Error getting vote flags: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get vote flags"})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// dismiss Признает голос честным
+func (fr *VoteFlagRoute) dismiss(c *gin.Context) {
+	flagID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flag ID"})
+		return
+	}
+	if err := fr.fraud.Dismiss(uint(flagID), c.Value("ID").(uint), time.Now()); err != nil {
+		fr.flagError(c, err, "Failed to dismiss vote flag")
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// invalidate Признает голос недействительным и удаляет его
+func (fr *VoteFlagRoute) invalidate(c *gin.Context) {
+	flagID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flag ID"})
+		return
+	}
+	if err := fr.fraud.Invalidate(uint(flagID), c.Value("ID").(uint), time.Now()); err != nil {
+		fr.flagError(c, err, "Failed to invalidate vote")
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// flagError Переводит ошибки проверки голоса в ответ. message - ответ на непредвиденную ошибку
+func (fr *VoteFlagRoute) flagError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrVoteFlagNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Vote flag not found"})
+	case errors.Is(err, services.ErrVoteFlagReviewed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		fr.logger.Errorf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,193 @@
+package httpHandlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"petition_api/internal/app/models"
+	"petition_api/internal/app/services"
+	"petition_api/middleware"
+	"strconv"
+)
+
+type WebhookModelRoute struct {
+	webhooks *services.WebhookService
+	accounts middleware.AccountChecker
+	logger   *logrus.Logger
+}
+
+// NewWebhookModelRoute создает роут для вебхуков интеграций
+func NewWebhookModelRoute(webhooks *services.WebhookService, accounts middleware.AccountChecker, logger *logrus.Logger) *WebhookModelRoute {
+	return &WebhookModelRoute{webhooks: webhooks, accounts: accounts, logger: logger}
+}
+
+func (wr *WebhookModelRoute) BindWebhookToRoute(route *gin.RouterGroup) {
+	authMiddleware := middleware.NewAuthMiddleware(wr.logger, wr.accounts)
+
+	route.POST("", authMiddleware, wr.createWebhook)
+	route.GET("", authMiddleware, wr.getWebhooks)
+	route.GET("/events", wr.getEventTypes)
+	route.GET("/:id", authMiddleware, wr.getWebhook)
+	route.PUT("/:id", authMiddleware, wr.updateWebhook)
+	route.DELETE("/:id", authMiddleware, wr.deleteWebhook)
+	route.GET("/:id/deliveries", authMiddleware, wr.getDeliveries)
+	route.POST("/:id/deliveries/:deliveryID/redeliver", authMiddleware, wr.redeliver)
+}
+
+func (wr *WebhookModelRoute) createWebhook(c *gin.Context) {
+	var input models.WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		wr.logger.Errorf("Invalid request body: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	webhook, err := wr.webhooks.Create(c.Value("ID").(uint), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidWebhookURL):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrWebhookLimit):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			wr.logger.Errorf("Error creating webhook: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		}
+		return
+	}
+
+	// Секрет подписи возвращается только один раз
+	view := models.NewWebhookView(webhook)
+	view.Secret = webhook.Secret
+	c.JSON(http.StatusCreated, view)
+}
+
+func (wr *WebhookModelRoute) getWebhooks(c *gin.Context) {
+	webhooks, err := wr.webhooks.List(c.Value("ID").(uint))
+	if err != nil {
+		wr.logger.Errorf("Error getting webhooks: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get webhooks"})
+		return
+	}
+
+	views := make([]models.WebhookView, 0, len(webhooks))
+	for i := range webhooks {
+		views = append(views, models.NewWebhookView(&webhooks[i]))
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+// getEventTypes Список событий, на которые можно подписаться
+func (wr *WebhookModelRoute) getEventTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, models.WebhookEventTypes)
+}
+
+func (wr *WebhookModelRoute) getWebhook(c *gin.Context) {
+	webhook, ok := wr.loadWebhook(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, models.NewWebhookView(webhook))
+}
+
+func (wr *WebhookModelRoute) updateWebhook(c *gin.Context) {
+	webhook, ok := wr.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	var input models.WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		wr.logger.Errorf("Invalid request body: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := wr.webhooks.Update(webhook, input); err != nil {
+		if errors.Is(err, services.ErrInvalidWebhookURL) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		wr.logger.Errorf("Error updating webhook %d: %v", webhook.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update webhook"})
+		return
+	}
+	c.JSON(http.StatusOK, models.NewWebhookView(webhook))
+}
+
+func (wr *WebhookModelRoute) deleteWebhook(c *gin.Context) {
+	webhook, ok := wr.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	if err := wr.webhooks.Delete(webhook); err != nil {
+		wr.logger.Errorf("Error deleting webhook %d: %v", webhook.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// getDeliveries Журнал доставок вебхука, ?status=dead - только мертвые
+func (wr *WebhookModelRoute) getDeliveries(c *gin.Context) {
+	webhook, ok := wr.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	var filter models.WebhookDeliveryFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	page, err := wr.webhooks.Deliveries(webhook, filter)
+	if err != nil {
+		wr.logger.Errorf("Error getting deliveries of webhook %d: %v", webhook.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get webhook deliveries"})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// redeliver Ставит завершенную доставку в очередь заново, например после исправления получателя
+func (wr *WebhookModelRoute) redeliver(c *gin.Context) {
+	webhook, ok := wr.loadWebhook(c)
+	if !ok {
+		return
+	}
+	deliveryID, err := strconv.ParseUint(c.Param("deliveryID"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+
+	delivery, err := wr.webhooks.Redeliver(webhook, uint(deliveryID))
+	if err != nil {
+		if errors.Is(err, services.ErrWebhookDeliveryPending) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
+
+// loadWebhook Находит вебхук текущего пользователя по :id или отвечает ошибкой
+func (wr *WebhookModelRoute) loadWebhook(c *gin.Context) (*models.Webhook, bool) {
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return nil, false
+	}
+
+	webhook, err := wr.webhooks.Get(uint(webhookID), c.Value("ID").(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return nil, false
+	}
+	return webhook, true
+}
@@ -0,0 +1,111 @@
+package websocket
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/middleware"
+	"sync"
+)
+
+// NotificationWebsocket Личный канал пользователя, по которому приходят его уведомления
+type NotificationWebsocket struct {
+	repo     repository.NotificationRepository
+	accounts middleware.AccountChecker
+	logger   *logrus.Logger
+
+	mutex   sync.Mutex
+	clients map[uint]map[*websocket.Conn]bool
+}
+
+func NewNotificationWebsocket(repo repository.NotificationRepository, accounts middleware.AccountChecker, logger *logrus.Logger) *NotificationWebsocket {
+	return &NotificationWebsocket{
+		repo:     repo,
+		accounts: accounts,
+		logger:   logger,
+		clients:  make(map[uint]map[*websocket.Conn]bool),
+	}
+}
+
+func (nw *NotificationWebsocket) AddToRoute(route *gin.RouterGroup) {
+	authMiddleware := middleware.NewAuthMiddleware(nw.logger, nw.accounts)
+
+	route.GET("/ws", authMiddleware, nw.handleWebSocket)
+}
+
+// handleWebSocket Подключает пользователя к его каналу и сразу присылает число непрочитанных
+func (nw *NotificationWebsocket) handleWebSocket(c *gin.Context) {
+	userID := c.Value("ID").(uint)
+
+	conn, err := upgrade.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		nw.logger.Errorf("Failed to set websocket upgrade: %v", err)
+		return
+	}
+
+	nw.mutex.Lock()
+	if nw.clients[userID] == nil {
+		nw.clients[userID] = make(map[*websocket.Conn]bool)
+	}
+	nw.clients[userID][conn] = true
+	nw.mutex.Unlock()
+
+	defer nw.disconnect(userID, conn)
+
+	unread, err := nw.repo.CountUnread(userID)
+	if err != nil {
+		nw.logger.Errorf("Failed to count unread notifications: %v", err)
+		return
+	}
+	nw.mutex.Lock()
+	err = conn.WriteJSON(Message{
+		MessageType: "unread_count",
+		Payload:     map[string]int64{"unread": unread},
+	})
+	nw.mutex.Unlock()
+	if err != nil {
+		return
+	}
+
+	// Канал только для отправки: входящие сообщения читаются, чтобы заметить закрытие соединения
+	for {
+		var msg Message
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		if msg.MessageType == "closeConn" {
+			return
+		}
+	}
+}
+
+func (nw *NotificationWebsocket) disconnect(userID uint, conn *websocket.Conn) {
+	nw.mutex.Lock()
+	delete(nw.clients[userID], conn)
+	if len(nw.clients[userID]) == 0 {
+		delete(nw.clients, userID)
+	}
+	nw.mutex.Unlock()
+	if err := conn.Close(); err != nil {
+		nw.logger.Debugf("Failed to close websocket connection: %v", err)
+	}
+}
+
+// PushToUser Отправляет сообщение во все открытые вкладки пользователя
+func (nw *NotificationWebsocket) PushToUser(userID uint, messageType string, payload interface{}) {
+	nw.mutex.Lock()
+	defer nw.mutex.Unlock()
+	for client := range nw.clients[userID] {
+		if err := client.WriteJSON(Message{
+			MessageType: messageType,
+			Payload:     payload,
+		}); err != nil {
+			nw.logger.Errorf("Write error: %v", err)
+			if err := client.Close(); err != nil {
+				nw.logger.Errorf("Failed to close client connection: %v", err)
+			}
+			delete(nw.clients[userID], client)
+		}
+	}
+}
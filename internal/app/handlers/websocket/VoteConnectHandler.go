@@ -9,12 +9,18 @@ import (
 	"net/http"
 	"petition_api/internal/app/models"
 	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/services"
+	"petition_api/middleware"
 	"strconv"
 	"sync"
+	"time"
 )
 
 type VoteWebsocket struct {
 	voteRepo repository.VoteRepository
+	users    repository.UserRepository
+	votes    *services.VoteService
+	accounts middleware.AccountChecker
 	logger   *logrus.Logger
 }
 
@@ -23,17 +29,35 @@ type Message struct {
 	Payload     interface{} `json:"payload"`
 }
 
-func NewVoteWebsocket(voteRepo repository.VoteRepository, logger *logrus.Logger) *VoteWebsocket {
+// VoteMessage Данные сообщений vote, unvote и checkVote. Кто подписывает, берется из токена соединения
+type VoteMessage struct {
+	PetitionID uint   `json:"petition_id"`
+	Visibility string `json:"visibility"`
+	Reason     string `json:"reason"`
+}
+
+func NewVoteWebsocket(voteRepo repository.VoteRepository, users repository.UserRepository, votes *services.VoteService, accounts middleware.AccountChecker, logger *logrus.Logger) *VoteWebsocket {
 	return &VoteWebsocket{
 		voteRepo: voteRepo,
+		users:    users,
+		votes:    votes,
+		accounts: accounts,
 		logger:   logger,
 	}
 }
 
 func (vw *VoteWebsocket) AddToRoute(route *gin.RouterGroup) {
-	route.GET("/ws/:petitionID", vw.handleWebSocket)
+	authMiddleware := middleware.NewAuthMiddleware(vw.logger, vw.accounts)
+
+	route.GET("/ws/:petitionID", authMiddleware, vw.handleWebSocket)
 }
 
+// recentSignersLimit Сколько последних подписей отправляется при подключении к сокету петиции
+const recentSignersLimit = 10
+
+// writeWait Сколько ждать записи в соединение, прежде чем считать клиента отключенным
+const writeWait = 10 * time.Second
+
 var (
 	upgrade = websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool {
@@ -41,9 +65,26 @@ var (
 		},
 	}
 	mutex   = &sync.Mutex{}
-	clients = make(map[uint]map[*websocket.Conn]bool)
+	clients = make(map[uint]map[*voteClient]bool)
 )
 
+// voteClient Соединение с сокетом петиции. gorilla/websocket допускает только одного пишущего,
+// а в соединение пишут и его собственный обработчик, и рассылки из сервисов, поэтому запись под своим мьютексом
+type voteClient struct {
+	conn  *websocket.Conn
+	mutex sync.Mutex
+}
+
+// write Отправляет сообщение клиенту
+func (vc *voteClient) write(messageType string, payload interface{}) error {
+	vc.mutex.Lock()
+	defer vc.mutex.Unlock()
+	if err := vc.conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
+		return err
+	}
+	return vc.conn.WriteJSON(Message{MessageType: messageType, Payload: payload})
+}
+
 func (vw *VoteWebsocket) handleWebSocket(c *gin.Context) {
 	petitionIDStr := c.Param("petitionID")
 	petitionID, err := strconv.ParseUint(petitionIDStr, 10, 32)
@@ -51,6 +92,7 @@ func (vw *VoteWebsocket) handleWebSocket(c *gin.Context) {
 		vw.logger.Errorf("Invalid petition ID: %v", err)
 		return
 	}
+	userID := c.Value("ID").(uint)
 
 	conn, err := upgrade.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -58,16 +100,17 @@ func (vw *VoteWebsocket) handleWebSocket(c *gin.Context) {
 		return
 	}
 
+	client := &voteClient{conn: conn}
 	mutex.Lock()
 	if clients[uint(petitionID)] == nil {
-		clients[uint(petitionID)] = make(map[*websocket.Conn]bool)
+		clients[uint(petitionID)] = make(map[*voteClient]bool)
 	}
-	clients[uint(petitionID)][conn] = true
+	clients[uint(petitionID)][client] = true
 	mutex.Unlock()
 
 	defer func() {
 		mutex.Lock()
-		delete(clients[uint(petitionID)], conn)
+		delete(clients[uint(petitionID)], client)
 		mutex.Unlock()
 		if err := conn.Close(); err != nil {
 			vw.logger.Errorf("Failed to close websocket connection: %v", err)
@@ -80,137 +123,136 @@ func (vw *VoteWebsocket) handleWebSocket(c *gin.Context) {
 		return
 	}
 
-	_ = conn.WriteJSON(Message{
-		MessageType: "vote_count",
-		Payload:     map[string]int64{"vote_count": count},
-	})
+	_ = client.write("vote_count", map[string]int64{"vote_count": count})
+
+	recent, _, err := vw.voteRepo.GetListedSignatures(uint(petitionID), 1, recentSignersLimit)
+	if err != nil {
+		vw.logger.Errorf("Failed to get recent signers: %v", err)
+		return
+	}
+	signers := make([]models.PublicSignature, 0, len(recent))
+	for i := range recent {
+		signers = append(signers, recent[i].Public())
+	}
+	_ = client.write("recent_signers", signers)
 
 	for {
 		var msg Message
 		if err := conn.ReadJSON(&msg); err != nil {
+			// Соединение закрыто, в том числе рассылкой после ошибки записи: читать из него больше нельзя
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &syntaxErr) && !errors.As(err, &typeErr) {
+				vw.logger.Debugf("Websocket connection closed: %v", err)
+				return
+			}
 			vw.logger.Errorf("Failed to read message: %v", err)
-			_ = conn.WriteJSON(Message{
-				MessageType: "error",
-				Payload:     map[string]string{"errorMsg": "Failed to read message"},
-			})
+			_ = client.write("error", map[string]string{"errorMsg": "Failed to read message"})
 			continue
 		}
 
 		vw.logger.Debugf("Received message: %v", msg.Payload)
-		var vote models.Vote
+		var input VoteMessage
 		data, _ := json.Marshal(msg.Payload)
-		if err := json.Unmarshal(data, &vote); err != nil {
+		if err := json.Unmarshal(data, &input); err != nil {
 			vw.logger.Errorf("Failed to unmarshal vote: %v", err)
-			_ = conn.WriteJSON(Message{
-				MessageType: "error",
-				Payload:     map[string]string{"errorMsg": "Invalid payload format"},
-			})
+			_ = client.write("error", map[string]string{"errorMsg": "Invalid payload format"})
 			continue
 		}
 
 		switch msg.MessageType {
 		case "vote":
-			if err := vw.voteHandle(&vote); err != nil {
-				_ = conn.WriteJSON(Message{
-					MessageType: "error",
-					Payload:     map[string]string{"errorMsg": err.Error()},
-				})
+			// Откуда подписали, берется из соединения, а не из сообщения клиента
+			if err := vw.voteHandle(userID, uint(petitionID), &input, c.ClientIP(), c.Request.UserAgent()); err != nil {
+				_ = client.write("error", voteErrorPayload(err))
 			}
 		case "unvote":
-			if err := vw.unvoteHandle(&vote); err != nil {
-				_ = conn.WriteJSON(Message{
-					MessageType: "error",
-					Payload:     map[string]string{"errorMsg": err.Error()},
-				})
+			if err := vw.unvoteHandle(userID, uint(petitionID), &input); err != nil {
+				_ = client.write("error", map[string]string{"errorMsg": err.Error()})
 			}
 		case "checkVote":
-			if err := vw.checkVoteHandle(conn, &vote); err != nil {
-				_ = conn.WriteJSON(Message{
-					MessageType: "error",
-					Payload:     map[string]string{"errorMsg": err.Error()},
-				})
+			if err := vw.checkVoteHandle(client, userID, uint(petitionID), &input); err != nil {
+				_ = client.write("error", map[string]string{"errorMsg": err.Error()})
 			}
 		case "closeConn":
-			mutex.Lock()
-			delete(clients[uint(petitionID)], conn)
-			mutex.Unlock()
-			if err := conn.Close(); err != nil {
-				vw.logger.Errorf("Failed to close websocket connection: %v", err)
-			}
 			vw.logger.Debug("Closing connection")
 			return
 		default:
-			_ = conn.WriteJSON(Message{
-				MessageType: "error",
-				Payload:     map[string]string{"errorMsg": "Unknown message type"},
-			})
+			_ = client.write("error", map[string]string{"errorMsg": "Unknown message type"})
 		}
 	}
 }
 
-func (vw *VoteWebsocket) voteValidate(vote *models.Vote) error {
-	if vote.PetitionID == 0 {
+// voteValidate Проверяет сообщение. Соединение открыто для одной петиции, и сообщения о других петициях отклоняются
+func (vw *VoteWebsocket) voteValidate(petitionID uint, input *VoteMessage) error {
+	if input.PetitionID == 0 {
 		return errors.New("invalid petition ID")
 	}
-	if vote.UserID == 0 {
-		return errors.New("invalid user ID")
+	if input.PetitionID != petitionID {
+		return errors.New("petition ID does not match the connection")
 	}
 	return nil
 }
 
-func (vw *VoteWebsocket) voteHandle(vote *models.Vote) error {
-	if err := vw.voteValidate(vote); err != nil {
+// voteHandle Подписывает петицию от имени пользователя соединения
+func (vw *VoteWebsocket) voteHandle(userID uint, petitionID uint, input *VoteMessage, ip string, ua string) error {
+	if err := vw.voteValidate(petitionID, input); err != nil {
 		return err
 	}
-
-	_, err := vw.voteRepo.Create(vote)
+	user, err := vw.users.GetByID(userID)
 	if err != nil {
-		vw.logger.Errorf("Failed to create vote: %v", err)
-		return err
+		return errors.New("user not found")
 	}
 
-	if err := vw.broadcastVoteCount(vote.PetitionID); err != nil {
-		vw.logger.Errorf("Failed to broadcast vote count: %v", err)
+	vote := models.Vote{
+		Login:      user.Login,
+		UserID:     user.ID,
+		PetitionID: input.PetitionID,
+		Visibility: input.Visibility,
+		Reason:     input.Reason,
+		IP:         ip,
+		UA:         ua,
+	}
+	if err := vw.votes.Vote(&vote); err != nil {
+		vw.logger.Errorf("Failed to create vote: %v", err)
 		return err
 	}
-
 	return nil
 }
 
-func (vw *VoteWebsocket) unvoteHandle(vote *models.Vote) error {
-	if err := vw.voteValidate(vote); err != nil {
-		return err
+// voteErrorPayload Ошибка подписи для клиента. Если не выполнены условия подписи, добавляется их список
+func voteErrorPayload(err error) interface{} {
+	var ineligible *services.IneligibleError
+	if errors.As(err, &ineligible) {
+		return map[string]interface{}{"errorMsg": err.Error(), "reasons": ineligible.Violations}
 	}
+	return map[string]string{"errorMsg": err.Error()}
+}
 
-	err := vw.voteRepo.DeleteByUserIDAndPetitionID(vote.UserID, vote.PetitionID)
-	if err != nil {
-		vw.logger.Errorf("Failed to delete vote: %v", err)
+func (vw *VoteWebsocket) unvoteHandle(userID uint, petitionID uint, input *VoteMessage) error {
+	if err := vw.voteValidate(petitionID, input); err != nil {
 		return err
 	}
 
-	if err := vw.broadcastVoteCount(vote.PetitionID); err != nil {
-		vw.logger.Errorf("Failed to broadcast vote count: %v", err)
+	if err := vw.votes.Unvote(userID, input.PetitionID); err != nil {
+		vw.logger.Errorf("Failed to delete vote: %v", err)
 		return err
 	}
-
 	return nil
 }
 
-func (vw *VoteWebsocket) checkVoteHandle(conn *websocket.Conn, vote *models.Vote) error {
-	if err := vw.voteValidate(vote); err != nil {
+func (vw *VoteWebsocket) checkVoteHandle(client *voteClient, userID uint, petitionID uint, input *VoteMessage) error {
+	if err := vw.voteValidate(petitionID, input); err != nil {
 		return err
 	}
 
-	exist, err := vw.voteRepo.VoteExist(vote.PetitionID, vote.UserID)
+	exist, err := vw.voteRepo.VoteExist(input.PetitionID, userID)
 	if err != nil {
 		vw.logger.Errorf("Failed to check vote: %v", err)
 		return err
 	}
 
-	if err := conn.WriteJSON(Message{
-		MessageType: "checkVote",
-		Payload:     map[string]bool{"exists": exist},
-	}); err != nil {
+	if err := client.write("checkVote", map[string]bool{"exists": exist}); err != nil {
 		vw.logger.Errorf("Failed to write success message: %v", err)
 		return err
 	}
@@ -226,20 +268,105 @@ func (vw *VoteWebsocket) broadcastVoteCount(petitionID uint) error {
 		return err
 	}
 
+	vw.BroadcastToPetition(petitionID, "vote_count", map[string]int64{"vote_count": count})
+	return nil
+}
+
+// NotifyVoted Рассылает клиентам петиции новый счетчик и новую подпись
+func (vw *VoteWebsocket) NotifyVoted(vote *models.Vote) error {
+	if err := vw.broadcastVoteCount(vote.PetitionID); err != nil {
+		return err
+	}
+	vw.broadcastRecentSigner(vote)
+	return nil
+}
+
+// NotifyUnvoted Рассылает клиентам петиции новый счетчик после отзыва подписи
+func (vw *VoteWebsocket) NotifyUnvoted(petitionID uint) error {
+	return vw.broadcastVoteCount(petitionID)
+}
+
+// NotifyInvalidated Рассылает клиентам петиции новый счетчик и убирает подпись из ленты последних подписей
+func (vw *VoteWebsocket) NotifyInvalidated(vote *models.Vote) error {
+	if err := vw.broadcastVoteCount(vote.PetitionID); err != nil {
+		return err
+	}
+	vw.BroadcastToPetition(vote.PetitionID, "vote_invalidated", map[string]uint{"vote_id": vote.ID})
+	return nil
+}
+
+// broadcastRecentSigner Добавляет новую подпись в ленту последних подписей у клиентов петиции. Скрытые подписи не рассылаются
+func (vw *VoteWebsocket) broadcastRecentSigner(vote *models.Vote) {
+	if vote.Visibility == models.VoteVisibilityHidden {
+		return
+	}
+	signature, err := vw.voteRepo.GetSignatureByVoteID(vote.PetitionID, vote.ID)
+	if err != nil {
+		vw.logger.Errorf("Failed to get signature of vote %d: %v", vote.ID, err)
+		return
+	}
+	vw.BroadcastToPetition(vote.PetitionID, "recent_signer", signature.Public())
+}
+
+// NotifyTargetReached Сообщает клиентам петиции, что она набрала нужное число подписей
+func (vw *VoteWebsocket) NotifyTargetReached(petition *models.Petition, recipient *models.Recipient, members []models.UserModel) error {
+	vw.BroadcastToPetition(petition.ID, "target_reached", map[string]interface{}{
+		"status":            petition.Status,
+		"target_reached_at": petition.TargetReachedAt,
+	})
+	return nil
+}
+
+// NotifyResponded Рассылает клиентам петиции официальный ответ адресата
+func (vw *VoteWebsocket) NotifyResponded(petition *models.Petition, response *models.PetitionResponse) error {
+	vw.BroadcastToPetition(petition.ID, "petition_response", map[string]interface{}{
+		"status":   petition.Status,
+		"response": response,
+	})
+	return nil
+}
+
+// NotifyMilestone Сообщает клиентам петиции о пройденной вехе сбора подписей
+func (vw *VoteWebsocket) NotifyMilestone(petition *models.Petition, milestone *models.PetitionMilestone) error {
+	vw.BroadcastToPetition(petition.ID, "milestone", map[string]interface{}{
+		"percent":    milestone.Percent,
+		"vote_count": milestone.VoteCount,
+		"target":     milestone.Target,
+		"reached_at": milestone.ReachedAt,
+	})
+	return nil
+}
+
+// BroadcastToPetition Отправляет сообщение всем клиентам, подключенным к сокету петиции.
+// Общий мьютекс держится только на время копирования списка клиентов, чтобы медленный клиент не задерживал
+// подключения и рассылки других петиций. Клиенты, которым не удалось отправить сообщение, отключаются
+func (vw *VoteWebsocket) BroadcastToPetition(petitionID uint, messageType string, payload interface{}) {
 	mutex.Lock()
-	defer mutex.Unlock()
+	targets := make([]*voteClient, 0, len(clients[petitionID]))
 	for client := range clients[petitionID] {
-		if err := client.WriteJSON(Message{
-			MessageType: "vote_count",
-			Payload:     map[string]int64{"vote_count": count},
-		}); err != nil {
+		targets = append(targets, client)
+	}
+	mutex.Unlock()
+
+	var failed []*voteClient
+	for _, client := range targets {
+		if err := client.write(messageType, payload); err != nil {
 			vw.logger.Errorf("Write error: %v", err)
-			if err := client.Close(); err != nil {
-				vw.logger.Errorf("Failed to close client connection: %v", err)
-			}
-			delete(clients[petitionID], client)
+			failed = append(failed, client)
 		}
 	}
+	if len(failed) == 0 {
+		return
+	}
 
-	return nil
+	mutex.Lock()
+	for _, client := range failed {
+		delete(clients[petitionID], client)
+	}
+	mutex.Unlock()
+	for _, client := range failed {
+		if err := client.conn.Close(); err != nil {
+			vw.logger.Errorf("Failed to close client connection: %v", err)
+		}
+	}
 }
@@ -0,0 +1,142 @@
+package websocket
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/testutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// connectToPetition Подключает пользователя к сокету новой петиции
+func connectToPetition(t *testing.T) (*VoteWebsocket, *models.Petition, *websocket.Conn) {
+	db := testutil.OpenDB(t, models.UserModel{}, models.Petition{}, models.Vote{})
+	petition := models.Petition{Title: "Парк"}
+	require.NoError(t, db.Create(&petition).Error)
+
+	logger := testutil.Logger()
+	vw := NewVoteWebsocket(repository.NewVoteRepository(db, logger), repository.NewUserRepository(db, logger), nil, nil, logger)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/ws/:petitionID", func(c *gin.Context) { c.Set("ID", uint(1)) }, vw.handleWebSocket)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + fmt.Sprintf("/ws/%d", petition.ID)
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return vw, &petition, conn
+}
+
+// TestBroadcastWhileClientWrites Рассылки из других горутин не пишут в соединение одновременно с его обработчиком
+func TestBroadcastWhileClientWrites(t *testing.T) {
+	vw, petition, conn := connectToPetition(t)
+
+	// vote_count и recent_signers при подключении
+	for i := 0; i < 2; i++ {
+		var msg Message
+		require.NoError(t, conn.ReadJSON(&msg))
+	}
+
+	const rounds = 200
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			vw.BroadcastToPetition(petition.ID, "petition_news", map[string]int{"n": i})
+		}
+	}()
+	for i := 0; i < rounds; i++ {
+		require.NoError(t, conn.WriteJSON(Message{MessageType: "checkVote", Payload: map[string]uint{"petition_id": petition.ID}}))
+	}
+
+	received := map[string]int{}
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(10*time.Second)))
+	for received["petition_news"]+received["checkVote"] < 2*rounds {
+		var msg Message
+		require.NoError(t, conn.ReadJSON(&msg))
+		received[msg.MessageType]++
+	}
+	wg.Wait()
+	assert.Equal(t, map[string]int{"petition_news": rounds, "checkVote": rounds}, received)
+}
+
+// TestVoteMessageForOtherPetition Сообщения о петиции, отличной от петиции соединения, отклоняются
+func TestVoteMessageForOtherPetition(t *testing.T) {
+	_, petition, conn := connectToPetition(t)
+	for i := 0; i < 2; i++ {
+		var msg Message
+		require.NoError(t, conn.ReadJSON(&msg))
+	}
+
+	for _, messageType := range []string{"vote", "unvote", "checkVote"} {
+		require.NoError(t, conn.WriteJSON(Message{MessageType: messageType, Payload: map[string]uint{"petition_id": petition.ID + 1}}))
+		var msg Message
+		require.NoError(t, conn.ReadJSON(&msg))
+		assert.Equal(t, "error", msg.MessageType)
+		assert.Equal(t, map[string]interface{}{"errorMsg": "petition ID does not match the connection"}, msg.Payload)
+	}
+}
+
+// TestBroadcastDoesNotBlockOtherPetitions Медленный клиент одной петиции не задерживает рассылки другим петициям
+func TestBroadcastDoesNotBlockOtherPetitions(t *testing.T) {
+	accepted := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrade.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		accepted <- conn
+	}))
+	defer server.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	const slowPetition, otherPetition = 1001, 1002
+	slow := &voteClient{conn: <-accepted}
+	mutex.Lock()
+	clients[slowPetition] = map[*voteClient]bool{slow: true}
+	mutex.Unlock()
+	defer func() {
+		mutex.Lock()
+		delete(clients, slowPetition)
+		mutex.Unlock()
+	}()
+
+	// Пока клиент занят записью, рассылка его петиции ждет, а остальные проходят
+	slow.mutex.Lock()
+	vw := NewVoteWebsocket(repository.VoteRepository{}, repository.UserRepository{}, nil, nil, testutil.Logger())
+	blocked := make(chan struct{})
+	go func() {
+		vw.BroadcastToPetition(slowPetition, "petition_news", nil)
+		close(blocked)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		vw.BroadcastToPetition(otherPetition, "petition_news", nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("broadcast to another petition waited for a slow client")
+	}
+
+	slow.mutex.Unlock()
+	<-blocked
+	var msg Message
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "petition_news", msg.MessageType)
+}
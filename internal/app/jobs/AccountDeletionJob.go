@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"petition_api/internal/app/services"
+	"time"
+)
+
+// AccountDeletionJob Периодически удаляет аккаунты, у которых истек период ожидания
+type AccountDeletionJob struct {
+	deletion *services.AccountDeletionService
+	interval time.Duration
+	stop     chan struct{}
+}
+
+func NewAccountDeletionJob(deletion *services.AccountDeletionService, interval time.Duration) *AccountDeletionJob {
+	return &AccountDeletionJob{
+		deletion: deletion,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start запускает задачу в отдельной горутине
+func (j *AccountDeletionJob) Start() {
+	go func() {
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		j.deletion.ProcessDue(time.Now())
+		for {
+			select {
+			case <-ticker.C:
+				j.deletion.ProcessDue(time.Now())
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop останавливает задачу
+func (j *AccountDeletionJob) Stop() {
+	close(j.stop)
+}
@@ -0,0 +1,167 @@
+package jobs
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/services"
+	"petition_api/utils/auth"
+	"time"
+)
+
+// DataExportJob Готовит в фоне выгрузки персональных данных и удаляет файлы с истекшей ссылкой
+type DataExportJob struct {
+	exports  repository.DataExportRepository
+	exporter *services.UserDataExportService
+	dir      string
+	linkTTL  time.Duration
+	interval time.Duration
+	// staleAfter Через сколько выгрузка в статусе processing считается брошенной и собирается заново
+	staleAfter time.Duration
+	logger     *logrus.Logger
+	stop       chan struct{}
+}
+
+func NewDataExportJob(
+	exports repository.DataExportRepository,
+	exporter *services.UserDataExportService,
+	dir string,
+	linkTTL time.Duration,
+	interval time.Duration,
+	staleAfter time.Duration,
+	logger *logrus.Logger,
+) *DataExportJob {
+	return &DataExportJob{
+		exports:    exports,
+		exporter:   exporter,
+		dir:        dir,
+		linkTTL:    linkTTL,
+		interval:   interval,
+		staleAfter: staleAfter,
+		logger:     logger,
+		stop:       make(chan struct{}),
+	}
+}
+
+// Start запускает задачу в отдельной горутине
+func (j *DataExportJob) Start() {
+	go func() {
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				j.resetStale()
+				j.processPending()
+				j.removeExpired()
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop останавливает задачу
+func (j *DataExportJob) Stop() {
+	close(j.stop)
+}
+
+// resetStale Возвращает в очередь выгрузки, сборка которых оборвалась. Пока такая выгрузка
+// в статусе processing, пользователь не может запросить новую
+func (j *DataExportJob) resetStale() {
+	count, err := j.exports.ResetStale(time.Now().Add(-j.staleAfter))
+	if err != nil {
+		j.logger.Errorf("Failed to reset stale data exports: %v", err)
+		return
+	}
+	if count > 0 {
+		j.logger.Warnf("Returned %d stale data exports to the queue", count)
+	}
+}
+
+// processPending Готовит все ожидающие выгрузки
+func (j *DataExportJob) processPending() {
+	for {
+		export, err := j.exports.ClaimPending()
+		if err != nil {
+			j.logger.Errorf("Failed to claim data export: %v", err)
+			return
+		}
+		if export == nil {
+			return
+		}
+		j.process(export)
+	}
+}
+
+func (j *DataExportJob) process(export *models.DataExport) {
+	if err := os.MkdirAll(j.dir, 0o700); err != nil {
+		j.fail(export, err)
+		return
+	}
+
+	path := filepath.Join(j.dir, fmt.Sprintf("export_%d_%d.zip", export.UserID, export.ID))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		j.fail(export, err)
+		return
+	}
+	err = j.exporter.WriteArchive(file, export.UserID)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		j.fail(export, err)
+		return
+	}
+
+	token, err := auth.RandomToken(32)
+	if err != nil {
+		_ = os.Remove(path)
+		j.fail(export, err)
+		return
+	}
+
+	expiresAt := time.Now().Add(j.linkTTL)
+	export.Status = models.ExportStatusReady
+	export.FilePath = path
+	export.Token = token
+	export.ExpiresAt = &expiresAt
+	if err := j.exports.Update(export); err != nil {
+		j.logger.Errorf("Failed to save data export %d: %v", export.ID, err)
+		return
+	}
+	j.logger.Infof("Data export %d for user %d is ready", export.ID, export.UserID)
+}
+
+func (j *DataExportJob) fail(export *models.DataExport, err error) {
+	j.logger.Errorf("Failed to build data export %d: %v", export.ID, err)
+	export.Status = models.ExportStatusFailed
+	export.Error = "failed to build archive"
+	if err := j.exports.Update(export); err != nil {
+		j.logger.Errorf("Failed to save data export %d: %v", export.ID, err)
+	}
+}
+
+// removeExpired Удаляет файлы выгрузок, ссылка на которые истекла
+func (j *DataExportJob) removeExpired() {
+	expired, err := j.exports.GetExpired(time.Now())
+	if err != nil {
+		j.logger.Errorf("Failed to get expired data exports: %v", err)
+		return
+	}
+	for _, export := range expired {
+		if err := os.Remove(export.FilePath); err != nil && !os.IsNotExist(err) {
+			j.logger.Errorf("Failed to remove data export file %s: %v", export.FilePath, err)
+			continue
+		}
+		if err := j.exports.DeleteByID(export.ID); err != nil {
+			j.logger.Errorf("Failed to delete data export %d: %v", export.ID, err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"petition_api/internal/app/services"
+	"time"
+)
+
+// EmailDigestJob Периодически отправляет письма с уведомлениями и сводки по расписанию
+type EmailDigestJob struct {
+	digests  *services.EmailDigestService
+	interval time.Duration
+	stop     chan struct{}
+}
+
+func NewEmailDigestJob(digests *services.EmailDigestService, interval time.Duration) *EmailDigestJob {
+	return &EmailDigestJob{
+		digests:  digests,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start запускает задачу в отдельной горутине
+func (j *EmailDigestJob) Start() {
+	go func() {
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				j.digests.ProcessDue(time.Now())
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop останавливает задачу
+func (j *EmailDigestJob) Stop() {
+	close(j.stop)
+}
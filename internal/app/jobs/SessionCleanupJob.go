@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"github.com/sirupsen/logrus"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/services"
+	"time"
+)
+
+// SessionCleanupJob периодически удаляет истекшие refresh сессии из базы и устаревшие записи кэша статусов аккаунтов
+type SessionCleanupJob struct {
+	sessionDB repository.SessionRepo
+	accounts  *services.AccountStatusService
+	interval  time.Duration
+	logger    *logrus.Logger
+	stop      chan struct{}
+}
+
+func NewSessionCleanupJob(sessionDB repository.SessionRepo, accounts *services.AccountStatusService, interval time.Duration, logger *logrus.Logger) *SessionCleanupJob {
+	return &SessionCleanupJob{
+		sessionDB: sessionDB,
+		accounts:  accounts,
+		interval:  interval,
+		logger:    logger,
+		stop:      make(chan struct{}),
+	}
+}
+
+// Start запускает задачу в отдельной горутине
+func (j *SessionCleanupJob) Start() {
+	go func() {
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		j.run()
+		for {
+			select {
+			case <-ticker.C:
+				j.run()
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop останавливает задачу
+func (j *SessionCleanupJob) Stop() {
+	close(j.stop)
+}
+
+func (j *SessionCleanupJob) run() {
+	now := time.Now()
+	if purged := j.accounts.PurgeExpired(now); purged > 0 {
+		j.logger.Debugf("Expired account status cache entries purged: %d", purged)
+	}
+
+	deleted, err := j.sessionDB.DeleteExpired(now)
+	if err != nil {
+		j.logger.Errorf("Failed to delete expired sessions: %v", err)
+		return
+	}
+	if deleted > 0 {
+		j.logger.Infof("Expired sessions deleted: %d", deleted)
+	}
+}
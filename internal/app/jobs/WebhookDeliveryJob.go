@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"petition_api/internal/app/services"
+	"time"
+)
+
+// WebhookDeliveryJob Периодически раскладывает события по вебхукам и отправляет доставки
+type WebhookDeliveryJob struct {
+	webhooks *services.WebhookService
+	interval time.Duration
+	stop     chan struct{}
+}
+
+func NewWebhookDeliveryJob(webhooks *services.WebhookService, interval time.Duration) *WebhookDeliveryJob {
+	return &WebhookDeliveryJob{
+		webhooks: webhooks,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start запускает задачу в отдельной горутине
+func (j *WebhookDeliveryJob) Start() {
+	go func() {
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				j.webhooks.ProcessDue(time.Now())
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop останавливает задачу
+func (j *WebhookDeliveryJob) Stop() {
+	close(j.stop)
+}
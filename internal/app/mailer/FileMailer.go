@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// FileMailer Сохраняет письма в папку в виде .eml файлов. Нужен для разработки и тестов
+type FileMailer struct {
+	dir     string
+	from    string
+	counter uint64
+}
+
+func NewFileMailer(dir string, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	now := time.Now()
+	data, err := BuildMIME(m.from, msg, now)
+	if err != nil {
+		return err
+	}
+	n := atomic.AddUint64(&m.counter, 1)
+	name := fmt.Sprintf("%s-%d.eml", now.Format("20060102-150405.000000000"), n)
+	return os.WriteFile(filepath.Join(m.dir, name), data, 0o644)
+}
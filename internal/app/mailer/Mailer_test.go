@@ -0,0 +1,106 @@
+package mailer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testDigest(lang string) Digest {
+	return Digest{
+		Language: lang,
+		Name:     "Айгерим",
+		Period:   PeriodDaily,
+		Items: []DigestItem{
+			{Type: "petition_comment", PetitionTitle: "Парк <на Абая>", Text: "Поддерживаю", Link: "http://localhost/petition/1"},
+			{Type: "petition_status", PetitionTitle: "Парк <на Абая>", Text: "accepted", Link: "http://localhost/petition/1"},
+			{Type: "petition_milestone", PetitionTitle: "Парк <на Абая>", Text: "50", Link: "http://localhost/petition/1"},
+		},
+		UnsubscribeURL: "http://localhost/notifications/unsubscribe?user=1&type=all&token=x",
+		PreferencesURL: "http://localhost/user/me/notifications/preferences",
+	}
+}
+
+func TestRenderDigestLanguages(t *testing.T) {
+	templates, err := NewTemplates()
+	assert.NoError(t, err)
+
+	subject, html, text, err := templates.RenderDigest(testDigest(LangRussian))
+	assert.NoError(t, err)
+	assert.Equal(t, "Сводка уведомлений за день", subject)
+	assert.Contains(t, text, "Новый комментарий к вашей петиции «Парк <на Абая>»")
+	assert.Contains(t, text, "сменила статус: принята")
+	// В HTML данные пользователей экранируются
+	assert.Contains(t, html, "Парк &lt;на Абая&gt;")
+	assert.NotContains(t, html, "<на Абая>")
+
+	subject, _, text, err = templates.RenderDigest(testDigest(LangKazakh))
+	assert.NoError(t, err)
+	assert.Equal(t, "Күнделікті хабарландырулар шолуы", subject)
+	assert.Contains(t, text, "мәртебесі өзгерді: қабылданды")
+
+	subject, _, text, err = templates.RenderDigest(testDigest(LangEnglish))
+	assert.NoError(t, err)
+	assert.Equal(t, "Your daily notification digest", subject)
+	assert.Contains(t, text, "Hello, Айгерим!")
+	assert.Contains(t, text, "Petition “Парк <на Абая>” reached 50% of its signature goal")
+
+	// Неизвестный язык заменяется русским
+	subject, _, _, err = templates.RenderDigest(testDigest("de"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Сводка уведомлений за день", subject)
+}
+
+func TestRenderVerification(t *testing.T) {
+	templates, err := NewTemplates()
+	assert.NoError(t, err)
+
+	verification := Verification{
+		Language:   LangKazakh,
+		Name:       "Айгерим",
+		Email:      "aigerim@mail.kz",
+		Link:       "http://localhost/user/email/verify?user=1&expires=2&token=a&b",
+		ValidHours: 48,
+	}
+	subject, html, text, err := templates.RenderVerification(verification)
+	assert.NoError(t, err)
+	assert.Equal(t, "Email-ды растаңыз", subject)
+	assert.Contains(t, text, "aigerim@mail.kz мекенжайын растау")
+	assert.Contains(t, text, "Сілтеме 48 сағат жарамды.")
+	assert.Contains(t, html, "token=a&amp;b")
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewFileMailer(dir, "noreply@petition.local")
+	assert.NoError(t, err)
+
+	err = m.Send(Message{
+		To:      "user@mail.kz",
+		Subject: "Новое уведомление",
+		HTML:    "<p>Привет</p>",
+		Text:    "Привет",
+		Headers: map[string]string{"List-Unsubscribe": "<http://localhost/unsubscribe>"},
+	})
+	assert.NoError(t, err)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.Len(t, files, 1)
+	data, _ := os.ReadFile(files[0])
+	eml := string(data)
+	assert.Contains(t, eml, "To: user@mail.kz")
+	assert.Contains(t, eml, "Subject: =?utf-8?q?")
+	assert.Contains(t, eml, "List-Unsubscribe: <http://localhost/unsubscribe>")
+	assert.Contains(t, eml, "multipart/alternative")
+	assert.True(t, strings.Index(eml, "text/plain") < strings.Index(eml, "text/html"))
+}
+
+func TestBuildMIMEDate(t *testing.T) {
+	date := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	data, err := BuildMIME("noreply@petition.local", Message{To: "a@b.kz", Subject: "Hi", Text: "x", HTML: "y"}, date)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "Date: Sun, 01 Mar 2026 09:00:00 +0000")
+}
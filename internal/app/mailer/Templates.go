@@ -0,0 +1,155 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// Языки писем
+const (
+	LangRussian = "ru"
+	LangKazakh  = "kk"
+	LangEnglish = "en"
+	// DefaultLanguage Язык писем, если пользователь его не выбрал
+	DefaultLanguage = LangRussian
+)
+
+// Периоды рассылки, от которых зависят тема и вступление письма
+const (
+	PeriodInstant = "instant"
+	PeriodDaily   = "daily"
+	PeriodWeekly  = "weekly"
+)
+
+// DigestItem Одно событие в письме
+type DigestItem struct {
+	// Type Тип уведомления, по нему выбирается текст
+	Type          string
+	PetitionTitle string
+	// Text Подробности события: отрывок комментария или новый статус петиции
+	Text string
+	Link string
+}
+
+// Digest Данные письма с одним или несколькими событиями
+type Digest struct {
+	Language       string
+	Name           string
+	Period         string
+	Items          []DigestItem
+	UnsubscribeURL string
+	PreferencesURL string
+}
+
+// Verification Данные письма со ссылкой подтверждения email
+type Verification struct {
+	Language string
+	Name     string
+	Email    string
+	Link     string
+	// ValidHours Сколько часов действует ссылка
+	ValidHours int
+}
+
+// Templates Шаблоны писем с уведомлениями и подтверждением email
+type Templates struct {
+	html       *htmltemplate.Template
+	text       *texttemplate.Template
+	verifyHTML *htmltemplate.Template
+	verifyText *texttemplate.Template
+}
+
+// NewTemplates загружает встроенные шаблоны писем
+func NewTemplates() (*Templates, error) {
+	funcs := map[string]interface{}{
+		"t":    translate,
+		"item": itemTitle,
+	}
+	html, err := htmltemplate.New("digest.html.tmpl").Funcs(funcs).ParseFS(templateFS, "templates/digest.html.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	text, err := texttemplate.New("digest.txt.tmpl").Funcs(funcs).ParseFS(templateFS, "templates/digest.txt.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	verifyHTML, err := htmltemplate.New("verify_email.html.tmpl").Funcs(funcs).ParseFS(templateFS, "templates/verify_email.html.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	verifyText, err := texttemplate.New("verify_email.txt.tmpl").Funcs(funcs).ParseFS(templateFS, "templates/verify_email.txt.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	return &Templates{html: html, text: text, verifyHTML: verifyHTML, verifyText: verifyText}, nil
+}
+
+// RenderDigest возвращает тему, HTML и текстовую версию письма на языке digest.Language
+func (t *Templates) RenderDigest(digest Digest) (subject string, html string, text string, err error) {
+	if _, ok := translations[digest.Language]; !ok {
+		digest.Language = DefaultLanguage
+	}
+
+	var htmlBuf, textBuf bytes.Buffer
+	if err := t.html.Execute(&htmlBuf, digest); err != nil {
+		return "", "", "", err
+	}
+	if err := t.text.Execute(&textBuf, digest); err != nil {
+		return "", "", "", err
+	}
+	return translate(digest.Language, "subject."+digest.Period), htmlBuf.String(), textBuf.String(), nil
+}
+
+// RenderVerification возвращает тему, HTML и текстовую версию письма подтверждения email
+func (t *Templates) RenderVerification(verification Verification) (subject string, html string, text string, err error) {
+	if _, ok := translations[verification.Language]; !ok {
+		verification.Language = DefaultLanguage
+	}
+
+	var htmlBuf, textBuf bytes.Buffer
+	if err := t.verifyHTML.Execute(&htmlBuf, verification); err != nil {
+		return "", "", "", err
+	}
+	if err := t.verifyText.Execute(&textBuf, verification); err != nil {
+		return "", "", "", err
+	}
+	return translate(verification.Language, "subject.verify_email"), htmlBuf.String(), textBuf.String(), nil
+}
+
+// SupportedLanguage поддерживается ли язык писем
+func SupportedLanguage(lang string) bool {
+	_, ok := translations[lang]
+	return ok
+}
+
+// translate Возвращает строку на нужном языке, подставляя аргументы. Неизвестный ключ возвращается как есть
+func translate(lang string, key string, args ...interface{}) string {
+	format, ok := translations[lang][key]
+	if !ok {
+		format, ok = translations[DefaultLanguage][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// itemTitle Заголовок события. У смены статуса в заголовок попадает переведенный статус, у вехи - процент
+func itemTitle(lang string, item DigestItem) string {
+	switch item.Type {
+	case "petition_status":
+		return translate(lang, "item."+item.Type, item.PetitionTitle, translate(lang, "status."+item.Text))
+	case "petition_milestone":
+		return translate(lang, "item."+item.Type, item.PetitionTitle, item.Text)
+	}
+	return translate(lang, "item."+item.Type, item.PetitionTitle)
+}
@@ -0,0 +1,95 @@
+package mailer
+
+// translations Строки писем по языкам
+var translations = map[string]map[string]string{
+	LangRussian: {
+		"subject.instant": "Новое уведомление",
+		"subject.daily":   "Сводка уведомлений за день",
+		"subject.weekly":  "Сводка уведомлений за неделю",
+		"greeting":        "Здравствуйте, %s!",
+		"intro.instant":   "У вас новые уведомления:",
+		"intro.daily":     "Что произошло за день:",
+		"intro.weekly":    "Что произошло за неделю:",
+		"open":            "Открыть петицию",
+		"footer":          "Вы получили это письмо, потому что следите за петициями.",
+		"unsubscribe":     "Отписаться от этих писем",
+		"preferences":     "Настроить уведомления",
+
+		"item.petition_comment":   "Новый комментарий к вашей петиции «%s»",
+		"item.comment_reply":      "Ответ на ваш комментарий к петиции «%s»",
+		"item.petition_status":    "Петиция «%s» сменила статус: %s",
+		"item.petition_news":      "Новость петиции «%s»",
+		"item.petition_milestone": "Петиция «%s» собрала %s%% подписей от цели",
+
+		"status.open":              "идет сбор подписей",
+		"status.awaiting_response": "ожидает ответа адресата",
+		"status.accepted":          "принята",
+		"status.rejected":          "отклонена",
+
+		"subject.verify_email": "Подтвердите email",
+		"verify.intro":         "Чтобы подтвердить адрес %s, перейдите по ссылке.",
+		"verify.confirm":       "Подтвердить email",
+		"verify.expires":       "Ссылка действует %d ч.",
+		"verify.ignore":        "Если вы не запрашивали подтверждение, просто проигнорируйте это письмо.",
+	},
+	LangKazakh: {
+		"subject.instant": "Жаңа хабарландыру",
+		"subject.daily":   "Күнделікті хабарландырулар шолуы",
+		"subject.weekly":  "Апталық хабарландырулар шолуы",
+		"greeting":        "Сәлеметсіз бе, %s!",
+		"intro.instant":   "Сізге жаңа хабарландырулар келді:",
+		"intro.daily":     "Бір күнде не болды:",
+		"intro.weekly":    "Бір аптада не болды:",
+		"open":            "Петицияны ашу",
+		"footer":          "Сіз бұл хатты петицияларды бақылайтындықтан алдыңыз.",
+		"unsubscribe":     "Бұл хаттардан бас тарту",
+		"preferences":     "Хабарландыру баптаулары",
+
+		"item.petition_comment":   "«%s» петицияңызға жаңа пікір",
+		"item.comment_reply":      "«%s» петициясындағы пікіріңізге жауап",
+		"item.petition_status":    "«%s» петициясының мәртебесі өзгерді: %s",
+		"item.petition_news":      "«%s» петициясының жаңалығы",
+		"item.petition_milestone": "«%s» петициясы мақсаттағы қолдардың %s%%-ын жинады",
+
+		"status.open":              "қол жинау жүріп жатыр",
+		"status.awaiting_response": "адресаттың жауабын күтуде",
+		"status.accepted":          "қабылданды",
+		"status.rejected":          "қабылданбады",
+
+		"subject.verify_email": "Email-ды растаңыз",
+		"verify.intro":         "%s мекенжайын растау үшін сілтемеге өтіңіз.",
+		"verify.confirm":       "Email-ды растау",
+		"verify.expires":       "Сілтеме %d сағат жарамды.",
+		"verify.ignore":        "Егер сіз растауды сұрамаған болсаңыз, бұл хатты елемеңіз.",
+	},
+	LangEnglish: {
+		"subject.instant": "New notification",
+		"subject.daily":   "Your daily notification digest",
+		"subject.weekly":  "Your weekly notification digest",
+		"greeting":        "Hello, %s!",
+		"intro.instant":   "You have new notifications:",
+		"intro.daily":     "Here is what happened today:",
+		"intro.weekly":    "Here is what happened this week:",
+		"open":            "Open petition",
+		"footer":          "You received this email because you follow petitions.",
+		"unsubscribe":     "Unsubscribe from these emails",
+		"preferences":     "Notification settings",
+
+		"item.petition_comment":   "New comment on your petition “%s”",
+		"item.comment_reply":      "Reply to your comment on petition “%s”",
+		"item.petition_status":    "Petition “%s” changed status: %s",
+		"item.petition_news":      "News from petition “%s”",
+		"item.petition_milestone": "Petition “%s” reached %s%% of its signature goal",
+
+		"status.open":              "collecting signatures",
+		"status.awaiting_response": "awaiting the recipient's response",
+		"status.accepted":          "accepted",
+		"status.rejected":          "rejected",
+
+		"subject.verify_email": "Confirm your email",
+		"verify.intro":         "To confirm %s, follow the link below.",
+		"verify.confirm":       "Confirm email",
+		"verify.expires":       "The link is valid for %d hours.",
+		"verify.ignore":        "If you didn't request this, just ignore this email.",
+	},
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// Скоупы персональных API ключей
+const (
+	ScopePetitionsRead = "petitions:read"
+	ScopeVotesRead     = "votes:read"
+)
+
+// APIKeyScopes Все допустимые скоупы
+var APIKeyScopes = []string{ScopePetitionsRead, ScopeVotesRead}
+
+// APIKey Персональный API ключ пользователя для интеграций.
+// Хранится только префикс ключа и хэш секрета, сам ключ показывается один раз при создании
+type APIKey struct {
+	gorm.Model
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	Name       string     `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix     string     `gorm:"type:varchar(20);not null;uniqueIndex" json:"prefix"`
+	SecretHash string     `gorm:"type:char(64);not null" json:"-"`
+	Scopes     string     `gorm:"type:varchar(255);not null" json:"-"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+// ScopeList возвращает скоупы ключа списком
+func (k *APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// HasScope проверяет, есть ли у ключа скоуп
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsExpired проверяет, истек ли срок действия ключа
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && now.After(*k.ExpiresAt)
+}
+
+// APIKeyCreate Данные для создания ключа
+type APIKeyCreate struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,oneof=petitions:read votes:read"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=3650"`
+}
+
+// APIKeyView Ключ в ответе API
+type APIKeyView struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	// Key Полный ключ, заполняется только при создании
+	Key string `json:"key,omitempty"`
+}
+
+// NewAPIKeyView Собирает ответ по ключу
+func NewAPIKeyView(k *APIKey) APIKeyView {
+	return APIKeyView{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.ScopeList(),
+		LastUsedAt: k.LastUsedAt,
+		ExpiresAt:  k.ExpiresAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// AvatarSizes Размеры квадратных миниатюр аватара в пикселях
+var AvatarSizes = []int{64, 128, 256}
+
+// AvatarURLs Ссылки на миниатюры аватара, ключ - размер в пикселях ("64", "128", "256")
+type AvatarURLs map[string]string
+
+// avatarURLResolver Строит ссылку на файл по ключу в хранилище. Задается при запуске сервера
+var avatarURLResolver = func(key string) string {
+	return "/files/" + key
+}
+
+// SetAvatarURLResolver Задает функцию, которая превращает ключ файла в хранилище в ссылку
+func SetAvatarURLResolver(resolver func(key string) string) {
+	avatarURLResolver = resolver
+}
+
+// AvatarFileKey Ключ файла миниатюры аватара нужного размера
+func AvatarFileKey(avatarKey string, size int) string {
+	return fmt.Sprintf("%s_%d.jpg", avatarKey, size)
+}
+
+// NewAvatarURLs Собирает ссылки на все миниатюры аватара. Если аватара нет, возвращает nil
+func NewAvatarURLs(avatarKey string) AvatarURLs {
+	if avatarKey == "" {
+		return nil
+	}
+	urls := make(AvatarURLs, len(AvatarSizes))
+	for _, size := range AvatarSizes {
+		urls[strconv.Itoa(size)] = avatarURLResolver(AvatarFileKey(avatarKey, size))
+	}
+	return urls
+}
@@ -8,4 +8,8 @@ type Comment struct {
 	UserID     uint   `gorm:"not null" json:"user_id"`
 	Login      string `gorm:"type:varchar(20);not null" json:"login"`
 	PetitionID uint   `gorm:"not null" json:"petition_id"`
+	// ParentID Комментарий, на который это ответ
+	ParentID *uint `gorm:"index" json:"parent_id,omitempty"`
+	// AuthorAvatar Ссылки на аватар автора, заполняются при выдаче комментария
+	AuthorAvatar AvatarURLs `gorm:"-" json:"author_avatar,omitempty"`
 }
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// Статусы выгрузки персональных данных
+const (
+	ExportStatusPending    = "pending"
+	ExportStatusProcessing = "processing"
+	ExportStatusReady      = "ready"
+	ExportStatusFailed     = "failed"
+)
+
+// DataExport Задача на выгрузку всех данных пользователя в ZIP архив
+type DataExport struct {
+	gorm.Model
+	UserID   uint   `gorm:"not null;index" json:"user_id"`
+	Status   string `gorm:"type:varchar(20);not null;index" json:"status"`
+	FilePath string `gorm:"type:varchar(255)" json:"-"`
+	// Token Секрет для ссылки на скачивание
+	Token     string     `gorm:"type:varchar(64);index" json:"-"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Error     string     `gorm:"type:varchar(255)" json:"error,omitempty"`
+}
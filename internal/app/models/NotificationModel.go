@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// Типы уведомлений
+const (
+	// NotificationPetitionComment Новый комментарий к петиции пользователя
+	NotificationPetitionComment = "petition_comment"
+	// NotificationCommentReply Ответ на комментарий пользователя
+	NotificationCommentReply = "comment_reply"
+	// NotificationPetitionStatus Петиция сменила статус: набрала подписи или получила ответ
+	NotificationPetitionStatus = "petition_status"
+	// NotificationPetitionNews Автор опубликовал новость петиции
+	NotificationPetitionNews = "petition_news"
+	// NotificationPetitionMilestone Петиция набрала 10, 25, 50, 75 или 100% цели. Text - процент
+	NotificationPetitionMilestone = "petition_milestone"
+)
+
+// Notification Уведомление в приложении. Текст собирает клиент по типу, Text - подробности события
+type Notification struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	UserID     uint   `gorm:"not null;index:idx_notifications_user_read" json:"-"`
+	Type       string `gorm:"type:varchar(30);not null" json:"type"`
+	PetitionID uint   `gorm:"not null;default:0" json:"petition_id,omitempty"`
+	CommentID  uint   `gorm:"not null;default:0" json:"comment_id,omitempty"`
+	// ActorID Кто вызвал событие. 0 - событие системы
+	ActorID uint `gorm:"not null;default:0" json:"actor_id,omitempty"`
+	// Text Подробности: отрывок комментария или новости, новый статус
+	Text   string     `gorm:"type:varchar(300);not null;default:''" json:"text"`
+	ReadAt *time.Time `gorm:"index:idx_notifications_user_read" json:"read_at"`
+	// EmailedAt Когда уведомление попало в письмо или было пропущено, потому что письма выключены
+	EmailedAt *time.Time `gorm:"index" json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NotificationFilter Параметры списка уведомлений
+type NotificationFilter struct {
+	UnreadOnly bool `form:"unread"`
+	Page       int  `form:"page" binding:"omitempty,min=1"`
+	PageSize   int  `form:"pageSize" binding:"omitempty,min=1,max=100"`
+}
+
+// NotificationPage Страница уведомлений вместе с числом непрочитанных
+type NotificationPage struct {
+	Items    []Notification `json:"items"`
+	Total    int64          `json:"total"`
+	Unread   int64          `json:"unread"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"page_size"`
+}
+
+// NotificationReadInput Уведомления, которые нужно отметить прочитанными
+type NotificationReadInput struct {
+	IDs []uint `json:"ids" binding:"required,min=1,max=500"`
+}
+
+// PetitionSubscription Подписка пользователя на события петиции без подписи под ней
+type PetitionSubscription struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_subscription_user_petition" json:"user_id"`
+	PetitionID uint      `gorm:"not null;uniqueIndex:idx_subscription_user_petition;index" json:"petition_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
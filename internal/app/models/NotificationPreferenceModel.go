@@ -0,0 +1,54 @@
+package models
+
+// Как пользователь получает письма о событиях одного типа
+const (
+	DeliveryInstant = "instant"
+	DeliveryDaily   = "daily"
+	DeliveryWeekly  = "weekly"
+	DeliveryOff     = "off"
+)
+
+// DefaultDeliveryMode Режим для типов событий, которые пользователь не настраивал
+const DefaultDeliveryMode = DeliveryDaily
+
+// NotificationTypes Типы уведомлений, для которых настраиваются письма
+var NotificationTypes = []string{
+	NotificationPetitionComment,
+	NotificationCommentReply,
+	NotificationPetitionStatus,
+	NotificationPetitionNews,
+	NotificationPetitionMilestone,
+}
+
+// NotificationPreference Режим писем пользователя для одного типа событий
+type NotificationPreference struct {
+	ID        uint   `gorm:"primaryKey" json:"-"`
+	UserID    uint   `gorm:"not null;uniqueIndex:idx_preference_user_type" json:"-"`
+	EventType string `gorm:"type:varchar(30);not null;uniqueIndex:idx_preference_user_type" json:"event_type"`
+	Mode      string `gorm:"type:varchar(10);not null" json:"mode"`
+}
+
+// NotificationSettings Язык писем и режимы по типам событий
+type NotificationSettings struct {
+	Language string            `json:"language" binding:"omitempty,oneof=ru kk en"`
+	Modes    map[string]string `json:"modes"`
+}
+
+// ValidDeliveryMode Известен ли режим писем
+func ValidDeliveryMode(mode string) bool {
+	switch mode {
+	case DeliveryInstant, DeliveryDaily, DeliveryWeekly, DeliveryOff:
+		return true
+	}
+	return false
+}
+
+// ValidNotificationType Настраиваются ли письма для этого типа событий
+func ValidNotificationType(eventType string) bool {
+	for _, t := range NotificationTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+)
+
+// Виды вложений петиции
+const (
+	// AttachmentKindCover Обложка петиции, у петиции не больше одной
+	AttachmentKindCover = "cover"
+	// AttachmentKindDocument Подтверждающий документ или изображение
+	AttachmentKindDocument = "document"
+)
+
+// PetitionAttachment Файл, прикрепленный к петиции. Сам файл лежит в хранилище по StorageKey
+type PetitionAttachment struct {
+	gorm.Model
+	PetitionID  uint   `gorm:"not null;index" json:"petition_id"`
+	UserID      uint   `gorm:"not null" json:"user_id"`
+	Kind        string `gorm:"type:varchar(20);not null" json:"kind"`
+	FileName    string `gorm:"type:varchar(255);not null" json:"file_name"`
+	ContentType string `gorm:"type:varchar(100);not null" json:"content_type"`
+	Size        int64  `gorm:"not null" json:"size"`
+	StorageKey  string `gorm:"type:varchar(255);not null" json:"-"`
+	// DownloadURL Ссылка на скачивание, заполняется при выдаче
+	DownloadURL string `gorm:"-" json:"download_url"`
+}
+
+// AttachmentDownloadPath Путь для скачивания вложения через API
+func AttachmentDownloadPath(petitionID uint, attachmentID uint) string {
+	return fmt.Sprintf("/petition/%d/attachments/%d", petitionID, attachmentID)
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// PetitionEligibility Условия, которым должен соответствовать подписант петиции.
+// Нулевые значения означают, что условие не проверяется
+type PetitionEligibility struct {
+	ID         uint `gorm:"primaryKey" json:"-"`
+	PetitionID uint `gorm:"not null;uniqueIndex" json:"petition_id"`
+	// MinAge Минимальный возраст подписанта в полных годах по UserModel.BirthDate
+	MinAge int `gorm:"not null;default:0" json:"min_age"`
+	// Regions Регионы проживания из models.Regions. Пустой список - подписать могут жители любого региона
+	Regions []string `gorm:"type:varchar(500);serializer:json" json:"regions"`
+	// RequireVerifiedEmail Подписывать могут только пользователи с подтвержденным email
+	RequireVerifiedEmail bool `gorm:"not null;default:false" json:"require_verified_email"`
+	// MinAccountAgeDays Сколько дней должно пройти с регистрации подписанта
+	MinAccountAgeDays int       `gorm:"not null;default:0" json:"min_account_age_days"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// PetitionEligibilityInput Условия подписи, которые задает автор петиции
+type PetitionEligibilityInput struct {
+	MinAge               int      `json:"min_age" binding:"min=0,max=120"`
+	Regions              []string `json:"regions" binding:"max=20"`
+	RequireVerifiedEmail bool     `json:"require_verified_email"`
+	MinAccountAgeDays    int      `json:"min_account_age_days" binding:"min=0,max=3650"`
+}
+
+// EligibilityCheck Может ли пользователь подписать петицию и почему нет
+type EligibilityCheck struct {
+	Eligible bool                   `json:"eligible"`
+	Reasons  []EligibilityViolation `json:"reasons"`
+}
+
+// EligibilityViolation Невыполненное условие подписи
+type EligibilityViolation struct {
+	// Code Машиночитаемый код условия: min_age, region, email_not_verified, account_age
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Коды невыполненных условий подписи
+const (
+	EligibilityMinAge           = "min_age"
+	EligibilityRegion           = "region"
+	EligibilityEmailNotVerified = "email_not_verified"
+	EligibilityAccountAge       = "account_age"
+)
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// MilestonePercents Доли TargetByVote в процентах, при наборе которых петиция отмечает веху
+var MilestonePercents = []int{10, 25, 50, 75, 100}
+
+// PetitionMilestone Веха сбора подписей. Каждая веха петиции сохраняется один раз
+type PetitionMilestone struct {
+	ID         uint  `gorm:"primaryKey" json:"id"`
+	PetitionID uint  `gorm:"not null;uniqueIndex:idx_milestone_petition_percent" json:"petition_id"`
+	Percent    int   `gorm:"not null;uniqueIndex:idx_milestone_petition_percent" json:"percent"`
+	Target     uint  `gorm:"not null" json:"target"`
+	VoteCount  int64 `gorm:"not null" json:"vote_count"`
+	// ReachedAt Когда веха была пройдена впервые. Отзыв подписей ее не отменяет
+	ReachedAt time.Time `gorm:"not null" json:"reached_at"`
+}
+
+// MilestonesReached Вехи, которые покрывает count подписей при цели target, по возрастанию
+func MilestonesReached(count int64, target uint) []int {
+	if target == 0 {
+		return nil
+	}
+	var reached []int
+	for _, percent := range MilestonePercents {
+		// Без деления, чтобы 1 подпись из 10 давала ровно 10%
+		if count*100 >= int64(target)*int64(percent) {
+			reached = append(reached, percent)
+		}
+	}
+	return reached
+}
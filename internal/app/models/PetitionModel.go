@@ -1,6 +1,19 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// Статусы петиции
+const (
+	// PetitionStatusOpen Идет сбор подписей
+	PetitionStatusOpen = "open"
+	// PetitionStatusAwaitingResponse Петиция набрала нужное число подписей и ждет ответа адресата
+	PetitionStatusAwaitingResponse = "awaiting_response"
+	PetitionStatusAccepted         = "accepted"
+	PetitionStatusRejected         = "rejected"
+)
 
 type Petition struct {
 	gorm.Model
@@ -10,12 +23,34 @@ type Petition struct {
 	TargetByVote uint   `gorm:"type:int;not null" json:"target_by_vote"`
 	CurrentVotes uint   `gorm:"type:int;not null" json:"current_votes"`
 	Recipient    string `gorm:"type:varchar(100);" json:"recipient"`
+	// RecipientID Адресат из справочника. Если указан, Recipient заполняется названием его организации
+	RecipientID *uint  `gorm:"index" json:"recipient_id"`
+	Status      string `gorm:"type:varchar(20);not null;default:open;index" json:"status"`
+	// TargetReachedAt Когда петиция впервые набрала TargetByVote подписей
+	TargetReachedAt *time.Time `json:"target_reached_at"`
+	// AnonymousVotes Голоса удаленных аккаунтов, которые остались только числом
+	AnonymousVotes uint `gorm:"type:int;not null;default:0" json:"-"`
+	// CoverURL Ссылка на обложку, заполняется при выдаче петиции
+	CoverURL string `gorm:"-" json:"cover_url,omitempty"`
+	// Response Официальный ответ адресата, заполняется при выдаче одной петиции
+	Response *PetitionResponse `gorm:"-" json:"response,omitempty"`
+	// Milestones Пройденные вехи сбора подписей, заполняются при выдаче одной петиции
+	Milestones []PetitionMilestone `gorm:"-" json:"milestones,omitempty"`
+	// RecentReasons Последние причины подписи из публичного списка, заполняются при выдаче одной петиции
+	RecentReasons []PublicSignature `gorm:"-" json:"recent_reasons,omitempty"`
+}
+
+// CollectsSignatures Петицию еще можно подписать: сбор открыт или цель набрана, но ответа пока нет
+func (p *Petition) CollectsSignatures() bool {
+	return p.Status == PetitionStatusOpen || p.Status == PetitionStatusAwaitingResponse
 }
 
 type PetitionUpdate struct {
 	Title        string `json:"title" binding:"omitempty"`
 	Description  string `json:"description" binding:"omitempty"`
 	TargetByVote uint   `json:"target_by_vote" binding:"omitempty"`
-	CurrentVotes uint   `json:"current_votes" binding:"omitempty"`
 	Recipient    string `json:"recipient" binding:"omitempty"`
+	RecipientID  uint   `json:"recipient_id" binding:"omitempty"`
+	// Reason Причина правки, сохраняется в истории версий
+	Reason string `json:"reason" binding:"max=255"`
 }
@@ -0,0 +1,18 @@
+package models
+
+import "gorm.io/gorm"
+
+// PetitionNews Новость от автора петиции для подписавших: встречи, ответы, итоги
+type PetitionNews struct {
+	gorm.Model
+	PetitionID uint   `gorm:"not null;index" json:"petition_id"`
+	AuthorID   uint   `gorm:"not null" json:"author_id"`
+	Title      string `gorm:"type:varchar(150);not null" json:"title"`
+	Content    string `gorm:"type:text;not null" json:"content"`
+}
+
+// PetitionNewsInput Создание и изменение новости петиции
+type PetitionNewsInput struct {
+	Title   string `json:"title" binding:"required,max=150"`
+	Content string `json:"content" binding:"required,max=20000"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "gorm.io/gorm"
+
+// Решения адресата по петиции
+const (
+	ResponseDecisionAccepted = "accepted"
+	ResponseDecisionRejected = "rejected"
+)
+
+// PetitionResponse Официальный ответ адресата на петицию. У петиции может быть только один ответ
+type PetitionResponse struct {
+	gorm.Model
+	PetitionID  uint   `gorm:"not null;uniqueIndex" json:"petition_id"`
+	RecipientID uint   `gorm:"not null;index" json:"recipient_id"`
+	AuthorID    uint   `gorm:"not null" json:"author_id"`
+	Decision    string `gorm:"type:varchar(20);not null" json:"decision"`
+	Content     string `gorm:"type:text;not null" json:"content"`
+}
+
+// PetitionResponseInput Тело запроса на публикацию ответа
+type PetitionResponseInput struct {
+	Decision string `json:"decision" binding:"required,oneof=accepted rejected"`
+	Content  string `json:"content" binding:"required,max=20000"`
+}
+
+// PetitionStatusForDecision Статус, который петиция получает после ответа с этим решением
+func PetitionStatusForDecision(decision string) string {
+	if decision == ResponseDecisionAccepted {
+		return PetitionStatusAccepted
+	}
+	return PetitionStatusRejected
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"petition_api/utils/textdiff"
+	"time"
+)
+
+// PetitionRevision Сохраненная версия текста петиции. Первая версия создается вместе с петицией,
+// новая - при каждом изменении заголовка, описания, адресата или цели
+type PetitionRevision struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	PetitionID   uint   `gorm:"not null;uniqueIndex:idx_petition_revision" json:"petition_id"`
+	Number       uint   `gorm:"not null;uniqueIndex:idx_petition_revision" json:"number"`
+	Title        string `gorm:"type:varchar(100);not null" json:"title"`
+	Description  string `gorm:"type:text;not null" json:"description"`
+	TargetByVote uint   `gorm:"type:int;not null" json:"target_by_vote"`
+	Recipient    string `gorm:"type:varchar(100);" json:"recipient"`
+	EditorID     uint   `gorm:"not null" json:"editor_id"`
+	// Reason Причина правки. Обязательна, если модератор меняет текст уже подписанной петиции
+	Reason    string    `gorm:"type:varchar(255)" json:"reason"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+}
+
+// NewPetitionRevision Снимок текущего состояния петиции
+func NewPetitionRevision(petition *Petition, number uint, editorID uint, reason string) PetitionRevision {
+	return PetitionRevision{
+		PetitionID:   petition.ID,
+		Number:       number,
+		Title:        petition.Title,
+		Description:  petition.Description,
+		TargetByVote: petition.TargetByVote,
+		Recipient:    petition.Recipient,
+		EditorID:     editorID,
+		Reason:       reason,
+	}
+}
+
+// PetitionRevisionDiff Построчная разница между двумя версиями петиции
+type PetitionRevisionDiff struct {
+	PetitionID  uint            `json:"petition_id"`
+	From        uint            `json:"from"`
+	To          uint            `json:"to"`
+	Title       []textdiff.Line `json:"title"`
+	Description []textdiff.Line `json:"description"`
+	Recipient   []textdiff.Line `json:"recipient"`
+	// TargetByVote Цель до и после, если она менялась
+	TargetByVote []uint `json:"target_by_vote,omitempty"`
+}
+
+// NewPetitionRevisionDiff Сравнивает две версии петиции
+func NewPetitionRevisionDiff(from *PetitionRevision, to *PetitionRevision) PetitionRevisionDiff {
+	diff := PetitionRevisionDiff{
+		PetitionID:  from.PetitionID,
+		From:        from.Number,
+		To:          to.Number,
+		Title:       textdiff.Lines(from.Title, to.Title),
+		Description: textdiff.Lines(from.Description, to.Description),
+		Recipient:   textdiff.Lines(from.Recipient, to.Recipient),
+	}
+	if from.TargetByVote != to.TargetByVote {
+		diff.TargetByVote = []uint{from.TargetByVote, to.TargetByVote}
+	}
+	return diff
+}
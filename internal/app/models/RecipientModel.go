@@ -0,0 +1,26 @@
+package models
+
+import "gorm.io/gorm"
+
+// Recipient Адресат петиций: организация и ее контакты.
+// Аккаунты организации привязываются через UserModel.RecipientID и получают роль Recipient
+type Recipient struct {
+	gorm.Model
+	Organisation string `gorm:"type:varchar(150);not null;uniqueIndex" json:"organisation"`
+	ContactName  string `gorm:"type:varchar(100);not null;default:''" json:"contact_name"`
+	ContactEmail string `gorm:"type:varchar(100);not null;default:''" json:"contact_email"`
+	ContactPhone string `gorm:"type:varchar(30);not null;default:''" json:"contact_phone"`
+}
+
+// RecipientInput Данные для создания и изменения адресата
+type RecipientInput struct {
+	Organisation string `json:"organisation" binding:"required,max=150"`
+	ContactName  string `json:"contact_name" binding:"max=100"`
+	ContactEmail string `json:"contact_email" binding:"omitempty,email,max=100"`
+	ContactPhone string `json:"contact_phone" binding:"max=30"`
+}
+
+// RecipientMemberInput Аккаунт, который нужно привязать к адресату
+type RecipientMemberInput struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
@@ -4,10 +4,12 @@ import "time"
 
 type RefreshSession struct {
 	ID           uint      `gorm:"primaryKey"`
-	UserID       uint      `gorm:"type:char(36);not null"`
+	UserID       uint      `gorm:"type:char(36);not null;index"`
 	RefreshToken string    `gorm:"type:text;not null"`
 	UA           string    `gorm:"type:varchar(200);not null"`
-	IP           string    `gorm:"type:varchar(15);not null"`
-	ExpiresIn    int64     `gorm:"not null"`
+	IP           string    `gorm:"type:varchar(45);not null"`
+	Fingerprint  string    `gorm:"type:varchar(255);not null;index"`
+	ExpiresIn    int64     `gorm:"not null;index"`
 	CreatedAt    time.Time `gorm:"not null;"`
+	UpdatedAt    time.Time
 }
@@ -0,0 +1,56 @@
+package models
+
+// Встроенные роли
+const (
+	RoleUser      = "User"
+	RoleModerator = "Moderator"
+	RoleAdmin     = "Admin"
+	// RoleRecipient Представитель адресата петиций
+	RoleRecipient = "Recipient"
+)
+
+// Права, которые проверяются в роутах
+const (
+	PermPetitionModerate = "petition.moderate"
+	PermCommentDeleteAny = "comment.delete.any"
+	PermUserManage       = "user.manage"
+	PermRoleManage       = "role.manage"
+	PermPetitionRespond  = "petition.respond"
+)
+
+// Permission Право на действие в системе
+type Permission struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	Code        string `gorm:"type:varchar(50);not null;uniqueIndex" json:"code"`
+	Description string `gorm:"type:varchar(200)" json:"description"`
+}
+
+// Role Роль пользователя с набором прав
+type Role struct {
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	Name        string       `gorm:"type:varchar(20);not null;uniqueIndex" json:"name"`
+	Permissions []Permission `gorm:"many2many:role_permissions;" json:"permissions"`
+}
+
+// DefaultPermissions Права, которые создаются при запуске сервера
+var DefaultPermissions = []Permission{
+	{Code: PermPetitionModerate, Description: "Edit and delete any petition"},
+	{Code: PermCommentDeleteAny, Description: "Delete any comment"},
+	{Code: PermUserManage, Description: "View, edit and delete any user, assign roles"},
+	{Code: PermRoleManage, Description: "Change permissions of roles"},
+	{Code: PermPetitionRespond, Description: "Publish official responses to petitions addressed to own organisation"},
+}
+
+// DefaultRolePermissions Роли, которые создаются при запуске сервера, с их правами по умолчанию.
+// Права уже существующих ролей не перезаписываются, чтобы изменения админов сохранялись
+var DefaultRolePermissions = map[string][]string{
+	RoleUser:      {},
+	RoleModerator: {PermPetitionModerate, PermCommentDeleteAny},
+	RoleAdmin:     {PermPetitionModerate, PermCommentDeleteAny, PermUserManage, PermRoleManage},
+	RoleRecipient: {PermPetitionRespond},
+}
+
+// RolePermissionsUpdate Новый набор прав роли
+type RolePermissionsUpdate struct {
+	Permissions []string `json:"permissions" binding:"required"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// UserBan Блокировка пользователя. Без ExpiresAt блокировка бессрочная, снятая блокировка хранится для истории
+type UserBan struct {
+	gorm.Model
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	BannedByID uint       `gorm:"not null" json:"banned_by_id"`
+	Reason     string     `gorm:"type:varchar(255);not null" json:"reason"`
+	ExpiresAt  *time.Time `gorm:"index" json:"expires_at"`
+	LiftedAt   *time.Time `json:"lifted_at,omitempty"`
+	LiftedByID *uint      `json:"lifted_by_id,omitempty"`
+}
+
+// ActiveAt Действует ли блокировка в момент now
+func (b *UserBan) ActiveAt(now time.Time) bool {
+	if b.LiftedAt != nil {
+		return false
+	}
+	return b.ExpiresAt == nil || b.ExpiresAt.After(now)
+}
+
+// UserBanCreate Блокировка пользователя админом
+type UserBanCreate struct {
+	Reason    string     `json:"reason" binding:"required,max=255"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// UserSearchFilter Параметры поиска пользователей для админов
+type UserSearchFilter struct {
+	// Query Ищет подстроку в логине, email, имени и фамилии
+	Query          string     `form:"q"`
+	Login          string     `form:"login"`
+	Email          string     `form:"email"`
+	Name           string     `form:"name"`
+	Role           string     `form:"role"`
+	Status         string     `form:"status" binding:"omitempty,oneof=Active Passive"`
+	Banned         *bool      `form:"banned"`
+	RegisteredFrom *time.Time `form:"registered_from" time_format:"2006-01-02"`
+	RegisteredTo   *time.Time `form:"registered_to" time_format:"2006-01-02"`
+	Page           int        `form:"page" binding:"omitempty,min=1"`
+	PageSize       int        `form:"pageSize" binding:"omitempty,min=1,max=100"`
+}
+
+// UserPage Страница результатов поиска пользователей
+type UserPage struct {
+	Items    []UserPrivateView `json:"items"`
+	Total    int64             `json:"total"`
+	Page     int               `json:"page"`
+	PageSize int               `json:"page_size"`
+}
+
+// UserBulkStatusUpdate Смена статуса сразу нескольких пользователей
+type UserBulkStatusUpdate struct {
+	UserIDs []uint `json:"user_ids" binding:"required,min=1,max=500"`
+	Status  string `json:"status" binding:"required,oneof=Active Passive"`
+	Reason  string `json:"reason" binding:"max=255"`
+}
+
+// UserBulkResult Результат массового действия: какие пользователи изменены, а какие нет и почему
+type UserBulkResult struct {
+	Updated []uint          `json:"updated"`
+	Failed  map[uint]string `json:"failed"`
+}
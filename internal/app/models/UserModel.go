@@ -2,28 +2,227 @@ package models
 
 import (
 	"gorm.io/gorm"
+	"strings"
 	"time"
 )
 
+// Статусы аккаунта пользователя
+const (
+	StatusActive  = "Active"
+	StatusPassive = "Passive"
+)
+
+// DeletedUserLogin Логин служебного пользователя, на которого переходят комментарии и петиции удаленных аккаунтов
+const DeletedUserLogin = "deleted_user"
+
+// ReservedLoginPrefix Начало логинов служебного пользователя и обезличенных аккаунтов (deleted_<id>).
+// Такие логины нельзя занять при регистрации или смене логина
+const ReservedLoginPrefix = "deleted_"
+
+// ReservedLogin Зарезервирован ли логин для служебных нужд
+func ReservedLogin(login string) bool {
+	return strings.HasPrefix(strings.ToLower(login), ReservedLoginPrefix)
+}
+
 type UserModel struct {
 	gorm.Model
-	Login     string    `gorm:"type:varchar(20);unique;not null" json:"login" binding:"required"`
-	Password  string    `gorm:"type:varchar(255);not null" json:"password" binding:"required"`
-	Role      string    `gorm:"type:varchar(20);not null" json:"role" binding:"required,oneof=User Admin"`
+	Login string `gorm:"type:varchar(20);unique;not null" json:"login" binding:"required"`
+	// Password Хэш пароля, никогда не попадает в JSON
+	Password  string    `gorm:"type:varchar(255);not null" json:"-"`
+	Role      string    `gorm:"type:varchar(20);not null" json:"role" binding:"required"`
 	FirstName string    `gorm:"type:varchar(20);not null" json:"first_name"`
 	LastName  string    `gorm:"type:varchar(20);not null" json:"last_name"`
 	Email     string    `gorm:"type:varchar(50);not null" json:"email" binding:"email"`
 	BirthDate time.Time `gorm:"type:date;not null" json:"birth_date"`
 	Status    string    `gorm:"type:varchar(20);not null" json:"status" binding:"oneof=Active Passive"`
+	// DeletionScheduledAt Когда аккаунт будет удален и обезличен. Пусто, если удаление не запрошено
+	DeletionScheduledAt *time.Time `gorm:"index" json:"deletion_scheduled_at"`
+	// AvatarKey Общая часть ключей миниатюр аватара в хранилище. Пусто, если аватара нет
+	AvatarKey string `gorm:"type:varchar(255);not null;default:''" json:"-"`
+	// RecipientID Адресат, от имени которого аккаунт отвечает на петиции
+	RecipientID *uint `gorm:"index" json:"recipient_id,omitempty"`
+	// Language Язык писем: ru, kk или en
+	Language string `gorm:"type:varchar(2);not null;default:ru" json:"language"`
+	// Region Регион проживания из Regions. Пусто, если пользователь его не указал
+	Region string `gorm:"type:varchar(30);not null;default:''" json:"region"`
+	// EmailVerifiedAt Когда пользователь подтвердил текущий email. Сбрасывается при смене email
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// EligibilityChangedAt Когда пользователь сам последний раз менял регион или дату рождения
+	EligibilityChangedAt *time.Time `json:"eligibility_changed_at"`
+	// Placeholder Служебный пользователь для контента удаленных аккаунтов. Ищется по этому флагу, а не по логину
+	Placeholder bool `gorm:"not null;default:false;index" json:"-"`
+}
+
+// EligibilityChangeCooldown Как часто пользователь может сам менять регион и дату рождения.
+// От них зависят условия подписи петиций, поэтому сменить их прямо перед подписью нельзя
+const EligibilityChangeCooldown = 30 * 24 * time.Hour
+
+// EligibilityChangeAvailableAt Когда пользователь сможет снова сменить регион или дату рождения
+func (u *UserModel) EligibilityChangeAvailableAt() time.Time {
+	if u.EligibilityChangedAt == nil {
+		return time.Time{}
+	}
+	return u.EligibilityChangedAt.Add(EligibilityChangeCooldown)
+}
+
+// Regions Коды регионов Казахстана: города республиканского значения и области
+var Regions = []string{
+	"astana", "almaty", "shymkent",
+	"abai", "akmola", "aktobe", "almaty_region", "atyrau", "east_kazakhstan", "zhambyl", "zhetysu",
+	"west_kazakhstan", "karaganda", "kostanay", "kyzylorda", "mangystau", "pavlodar", "north_kazakhstan",
+	"turkistan", "ulytau",
+}
+
+// ValidRegion Есть ли регион в списке Regions
+func ValidRegion(region string) bool {
+	for _, r := range Regions {
+		if r == region {
+			return true
+		}
+	}
+	return false
+}
+
+// SetEmail Меняет email. Подтверждение старого адреса к новому не относится
+func (u *UserModel) SetEmail(email string) {
+	if u.Email != email {
+		u.EmailVerifiedAt = nil
+	}
+	u.Email = email
+}
+
+// UserPublicProfile Публичный профиль пользователя без личных данных
+type UserPublicProfile struct {
+	ID                uint       `json:"id"`
+	Login             string     `json:"login"`
+	FirstName         string     `json:"first_name"`
+	LastName          string     `json:"last_name"`
+	PetitionsAuthored int64      `json:"petitions_authored"`
+	SignaturesCount   int64      `json:"signatures_count"`
+	Avatar            AvatarURLs `json:"avatar,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// UserPrivateView Данные пользователя для него самого и для админов
+type UserPrivateView struct {
+	ID        uint      `json:"id"`
+	Login     string    `json:"login"`
+	Role      string    `json:"role"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Email     string    `json:"email"`
+	BirthDate time.Time `json:"birth_date"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Avatar Ссылки на миниатюры аватара по размерам
+	Avatar AvatarURLs `json:"avatar,omitempty"`
+	// DeletionScheduledAt Когда аккаунт будет удален, если удаление запрошено
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	// RecipientID Адресат, от имени которого пользователь отвечает на петиции
+	RecipientID *uint  `json:"recipient_id,omitempty"`
+	Region      string `json:"region"`
+	// EmailVerifiedAt Когда подтвержден email. Пусто, если не подтвержден
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// EligibilityChangedAt Когда пользователь последний раз менял регион или дату рождения
+	EligibilityChangedAt *time.Time `json:"eligibility_changed_at,omitempty"`
+}
+
+// NewUserPublicProfile Собирает публичный профиль пользователя
+func NewUserPublicProfile(u *UserModel, petitionsAuthored int64, signaturesCount int64) UserPublicProfile {
+	return UserPublicProfile{
+		ID:                u.ID,
+		Login:             u.Login,
+		FirstName:         u.FirstName,
+		LastName:          u.LastName,
+		PetitionsAuthored: petitionsAuthored,
+		SignaturesCount:   signaturesCount,
+		Avatar:            NewAvatarURLs(u.AvatarKey),
+		CreatedAt:         u.CreatedAt,
+	}
+}
+
+// NewUserPrivateView Собирает данные пользователя для него самого
+func NewUserPrivateView(u *UserModel) UserPrivateView {
+	return UserPrivateView{
+		ID:        u.ID,
+		Login:     u.Login,
+		Role:      u.Role,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Email:     u.Email,
+		BirthDate: u.BirthDate,
+		Status:    u.Status,
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+		Avatar:    NewAvatarURLs(u.AvatarKey),
+
+		DeletionScheduledAt: u.DeletionScheduledAt,
+		RecipientID:         u.RecipientID,
+		Region:              u.Region,
+		EmailVerifiedAt:     u.EmailVerifiedAt,
+
+		EligibilityChangedAt: u.EligibilityChangedAt,
+	}
+}
+
+// UserRegistration Данные для регистрации. Роль и статус задает сервер
+type UserRegistration struct {
+	Login     string    `json:"login" binding:"required,max=20"`
+	Password  string    `json:"password" binding:"required"`
+	FirstName string    `json:"first_name" binding:"max=20"`
+	LastName  string    `json:"last_name" binding:"max=20"`
+	Email     string    `json:"email" binding:"required,email,max=50"`
+	BirthDate time.Time `json:"birth_date"`
+	// Region Необязательный регион проживания из models.Regions
+	Region string `json:"region"`
 }
 
+// UserProfileUpdate Полное обновление профиля пользователем. Роль и статус здесь менять нельзя
+type UserProfileUpdate struct {
+	Login     string    `json:"login" binding:"required,max=20"`
+	Password  string    `json:"password" binding:"omitempty"`
+	FirstName string    `json:"first_name" binding:"max=20"`
+	LastName  string    `json:"last_name" binding:"max=20"`
+	Email     string    `json:"email" binding:"required,email,max=50"`
+	BirthDate time.Time `json:"birth_date"`
+	// Region Регион из models.Regions, пустая строка убирает регион
+	Region string `json:"region"`
+}
+
+// UserUpdate Частичное обновление профиля пользователем. Роль и статус здесь менять нельзя
 type UserUpdate struct {
-	Login     string    `json:"login" binding:"omitempty"`
+	Login     string    `json:"login" binding:"omitempty,max=20"`
 	Password  string    `json:"password" binding:"omitempty"`
-	Role      string    `json:"role" binding:"omitempty,oneof=User Admin"`
-	FirstName string    `json:"first_name" binding:"omitempty"`
-	LastName  string    `json:"last_name" binding:"omitempty"`
-	Email     string    `json:"email" binding:"email" binding:"omitempty"`
+	FirstName string    `json:"first_name" binding:"omitempty,max=20"`
+	LastName  string    `json:"last_name" binding:"omitempty,max=20"`
+	Email     string    `json:"email" binding:"omitempty,email,max=50"`
 	BirthDate time.Time `json:"birth_date" binding:"omitempty"`
-	Status    string    `json:"status" binding:"omitempty,oneof=Active Passive"`
+	// Region Регион из models.Regions. nil - не менять, пустая строка убирает регион
+	Region *string `json:"region"`
+}
+
+// UserRoleUpdate Смена роли пользователя админом
+type UserRoleUpdate struct {
+	Role   string `json:"role" binding:"required"`
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+// UserStatusUpdate Смена статуса пользователя админом
+type UserStatusUpdate struct {
+	Status string `json:"status" binding:"required,oneof=Active Passive"`
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+// UserAuditLog Запись о том, кто и что изменил в аккаунте пользователя
+type UserAuditLog struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	ActorID      uint      `gorm:"not null;index" json:"actor_id"`
+	TargetUserID uint      `gorm:"not null;index" json:"target_user_id"`
+	Field        string    `gorm:"type:varchar(30);not null" json:"field"`
+	OldValue     string    `gorm:"type:varchar(255)" json:"old_value"`
+	NewValue     string    `gorm:"type:varchar(255)" json:"new_value"`
+	Reason       string    `gorm:"type:varchar(255)" json:"reason"`
+	IP           string    `gorm:"type:varchar(45)" json:"ip"`
+	CreatedAt    time.Time `gorm:"not null;index" json:"created_at"`
 }
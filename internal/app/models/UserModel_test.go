@@ -0,0 +1,38 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestUserPasswordNeverSerialised(t *testing.T) {
+	user := UserModel{Login: "zhandar", Password: "$2a$10$hash", Email: "z@example.com"}
+
+	for name, value := range map[string]interface{}{
+		"model":   user,
+		"private": NewUserPrivateView(&user),
+		"public":  NewUserPublicProfile(&user, 1, 2),
+	} {
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.NotContains(t, string(data), "password", name)
+		assert.NotContains(t, string(data), "$2a$10$hash", name)
+	}
+}
+
+func TestUserPublicProfileHasNoPrivateData(t *testing.T) {
+	user := UserModel{Login: "zhandar", Email: "z@example.com", Role: RoleAdmin}
+
+	data, err := json.Marshal(NewUserPublicProfile(&user, 3, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotContains(t, string(data), "z@example.com")
+	assert.NotContains(t, string(data), "birth_date")
+	assert.NotContains(t, string(data), "role")
+	assert.Contains(t, string(data), `"petitions_authored":3`)
+	assert.Contains(t, string(data), `"signatures_count":5`)
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Действия в журнале голосов
+const (
+	// VoteAuditVoted Петицию подписали
+	VoteAuditVoted = "vote"
+	// VoteAuditUnvoted Подписавший отозвал подпись
+	VoteAuditUnvoted = "unvote"
+	// VoteAuditInvalidated Админ признал подпись недействительной
+	VoteAuditInvalidated = "invalidate"
+	// VoteAuditAnonymised Аккаунт удален, подпись стала анонимным голосом петиции
+	VoteAuditAnonymised = "anonymise"
+	// VoteAuditImported Подпись, поданная до появления журнала, добавлена командой votechain -backfill
+	VoteAuditImported = "import"
+)
+
+// VoteAuditGenesisHash PrevHash первой записи журнала петиции
+var VoteAuditGenesisHash = strings.Repeat("0", 64)
+
+// VoteAuditEntry Запись журнала голосов. У каждой петиции своя цепочка: запись хранит хэш предыдущей,
+// поэтому изменение или удаление любой записи ломает все последующие. Записи только добавляются.
+// UserID входит в хэш, чтобы передача подписи другому пользователю была видна, и наружу не отдается
+type VoteAuditEntry struct {
+	ID         uint `gorm:"primaryKey" json:"-"`
+	PetitionID uint `gorm:"not null;uniqueIndex:idx_vote_audit_petition_seq" json:"petition_id"`
+	// Seq Номер записи в цепочке петиции, начиная с 1
+	Seq    uint   `gorm:"not null;uniqueIndex:idx_vote_audit_petition_seq" json:"seq"`
+	VoteID uint   `gorm:"not null;index" json:"vote_id"`
+	UserID uint   `gorm:"not null" json:"-"`
+	Action string `gorm:"type:varchar(20);not null" json:"action"`
+	// CreatedAt Время с точностью до секунды, входит в хэш
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+	PrevHash  string    `gorm:"type:char(64);not null" json:"prev_hash"`
+	Hash      string    `gorm:"type:char(64);not null" json:"hash"`
+}
+
+// ComputeHash SHA-256 от полей записи и хэша предыдущей записи
+func (e *VoteAuditEntry) ComputeHash() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d|%d|%d|%d|%s|%d|%s",
+		e.PetitionID, e.Seq, e.VoteID, e.UserID, e.Action, e.CreatedAt.Unix(), e.PrevHash)))
+	return hex.EncodeToString(sum[:])
+}
+
+// VoteChainReport Результат проверки цепочки журнала петиции
+type VoteChainReport struct {
+	PetitionID uint `json:"petition_id"`
+	// Head Хэш последней записи. Для пустого журнала - VoteAuditGenesisHash
+	Head    string `json:"head"`
+	Entries int64  `json:"entries"`
+	// Voted, Unvoted, Invalidated, Anonymised, Imported Число записей каждого действия
+	Voted       int64 `json:"voted"`
+	Unvoted     int64 `json:"unvoted"`
+	Invalidated int64 `json:"invalidated"`
+	Anonymised  int64 `json:"anonymised"`
+	Imported    int64 `json:"imported"`
+	// ChainVotes Сколько подписей должно быть в таблице votes по журналу
+	ChainVotes int64 `json:"chain_votes"`
+	// TableVotes Сколько подписей петиции в таблице votes
+	TableVotes int64 `json:"table_votes"`
+	// Valid Цепочка не нарушена и совпадает с таблицей votes
+	Valid bool `json:"valid"`
+	// Problem Первое найденное расхождение
+	Problem string `json:"problem,omitempty"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Причины, по которым голос отмечен как подозрительный
+const (
+	// VoteFlagSameIP С одного IP за окно проверки подписали слишком много аккаунтов
+	VoteFlagSameIP = "same_ip"
+	// VoteFlagSameSubnet С одной подсети (/24 для IPv4, /64 для IPv6) подписали слишком много аккаунтов
+	VoteFlagSameSubnet = "same_subnet"
+	// VoteFlagNewAccounts Петицию массово подписывают только что зарегистрированные аккаунты
+	VoteFlagNewAccounts = "new_accounts"
+	// VoteFlagRevote Пользователь снова подписал петицию после того, как его голос признали недействительным
+	VoteFlagRevote = "revote"
+)
+
+// Статусы проверки подозрительного голоса
+const (
+	VoteFlagPending = "pending"
+	// VoteFlagDismissed Админ признал голос честным
+	VoteFlagDismissed = "dismissed"
+	// VoteFlagInvalidated Голос признан недействительным и удален
+	VoteFlagInvalidated = "invalidated"
+)
+
+// VoteFlag Отметка о подозрительном голосе. Голос при этом учитывается, пока админ его не отклонит.
+// Логин, IP и UA копируются из голоса, чтобы отметка осталась после его удаления
+type VoteFlag struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	VoteID     uint   `gorm:"not null;uniqueIndex:idx_vote_flag_reason" json:"vote_id"`
+	Reason     string `gorm:"type:varchar(30);not null;uniqueIndex:idx_vote_flag_reason" json:"reason"`
+	PetitionID uint   `gorm:"not null;index" json:"petition_id"`
+	UserID     uint   `gorm:"not null;index" json:"user_id"`
+	Login      string `gorm:"type:varchar(20);not null" json:"login"`
+	// Details Что именно обнаружено, например сколько голосов и за какое время
+	Details string `gorm:"type:varchar(255);not null" json:"details"`
+	IP      string `gorm:"type:varchar(45);not null" json:"ip"`
+	UA      string `gorm:"type:varchar(200);not null" json:"ua"`
+	Status  string `gorm:"type:varchar(20);not null;default:pending;index" json:"status"`
+	// ReviewedBy Админ, который проверил голос
+	ReviewedBy *uint      `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt  time.Time  `gorm:"not null;index" json:"created_at"`
+}
+
+// VoteFlagFilter Параметры списка подозрительных голосов
+type VoteFlagFilter struct {
+	Status     string `form:"status" binding:"omitempty,oneof=pending dismissed invalidated"`
+	PetitionID uint   `form:"petition_id"`
+	Page       int    `form:"page" binding:"omitempty,min=1"`
+	PageSize   int    `form:"pageSize" binding:"omitempty,min=1,max=100"`
+}
+
+// VoteFlagPage Страница подозрительных голосов
+type VoteFlagPage struct {
+	Items    []VoteFlag `json:"items"`
+	Total    int64      `json:"total"`
+	Page     int        `json:"page"`
+	PageSize int        `json:"page_size"`
+}
+
+// FreshAccountVote Голос вместе с датой регистрации подписавшего
+type FreshAccountVote struct {
+	VoteID           uint
+	UserID           uint
+	SignedAt         time.Time
+	AccountCreatedAt time.Time
+}
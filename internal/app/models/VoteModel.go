@@ -1,10 +1,108 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// Видимость подписи в публичном списке петиции, ее выбирает подписавший
+const (
+	// VoteVisibilityPublic Логин и имя подписавшего видны всем
+	VoteVisibilityPublic = "public"
+	// VoteVisibilityAnonymous Подпись есть в списке, но без имени. Выбирается, если подписавший ничего не указал
+	VoteVisibilityAnonymous = "anonymous"
+	// VoteVisibilityHidden Подпись учитывается только в счетчике
+	VoteVisibilityHidden = "hidden"
+)
+
+// MaxVoteReasonLength Наибольшая длина причины подписи в символах
+const MaxVoteReasonLength = 280
+
+// ValidVoteVisibility Проверяет значение видимости подписи
+func ValidVoteVisibility(visibility string) bool {
+	switch visibility {
+	case VoteVisibilityPublic, VoteVisibilityAnonymous, VoteVisibilityHidden:
+		return true
+	}
+	return false
+}
 
 type Vote struct {
 	gorm.Model
-	Login      string `gorm:"type:varchar(20);not null;index" json:"login"`
-	UserID     uint   `gorm:"not null;index" json:"user_id"`
-	PetitionID uint   `gorm:"not null;index" json:"petition_id"`
+	Login string `gorm:"type:varchar(20);not null;index" json:"login"`
+	// Уникальный индекс чтобы не было дважды голосовать в одну петицию
+	UserID     uint `gorm:"not null;index;uniqueIndex:idx_user_petition" json:"user_id"`
+	PetitionID uint `gorm:"not null;index;uniqueIndex:idx_user_petition" json:"petition_id"`
+	// Visibility Как подпись показывается в публичном списке петиции
+	Visibility string `gorm:"type:varchar(20);not null;default:anonymous" json:"visibility"`
+	// Reason Почему подписал, необязательно. Проходит тот же фильтр, что и комментарии
+	Reason string `gorm:"type:varchar(280);not null;default:''" json:"reason"`
+	// IP, Subnet и UA Откуда подписали, для поиска накруток. В ответы API не попадают
+	IP     string `gorm:"type:varchar(45);not null;default:'';index" json:"-"`
+	Subnet string `gorm:"type:varchar(50);not null;default:'';index" json:"-"`
+	UA     string `gorm:"type:varchar(200);not null;default:''" json:"-"`
+}
+
+// MaxVoteUALength Сколько символов user-agent сохраняется с голосом
+const MaxVoteUALength = 200
+
+// VoteInput Подпись петиции через REST
+type VoteInput struct {
+	Visibility string `json:"visibility" binding:"omitempty,oneof=public anonymous hidden"`
+	Reason     string `json:"reason" binding:"max=280"`
+}
+
+// VoteReasonInput Новая причина подписи, пустая строка удаляет причину
+type VoteReasonInput struct {
+	Reason string `json:"reason" binding:"max=280"`
+}
+
+// Signature Подпись в списке подписантов для адресата: голос вместе с именем пользователя
+type Signature struct {
+	VoteID     uint      `json:"vote_id"`
+	Login      string    `json:"login"`
+	FirstName  string    `json:"first_name"`
+	LastName   string    `json:"last_name"`
+	Visibility string    `json:"visibility"`
+	Reason     string    `json:"reason"`
+	SignedAt   time.Time `json:"signed_at"`
+}
+
+// PublicSignature Подпись в публичном списке петиции. У анонимных подписей имени нет
+type PublicSignature struct {
+	Login     string    `json:"login,omitempty"`
+	FirstName string    `json:"first_name,omitempty"`
+	LastName  string    `json:"last_name,omitempty"`
+	Anonymous bool      `json:"anonymous"`
+	Reason    string    `json:"reason,omitempty"`
+	SignedAt  time.Time `json:"signed_at"`
+}
+
+// Public Собирает подпись для публичного списка с учетом выбора подписавшего
+func (s *Signature) Public() PublicSignature {
+	if s.Visibility != VoteVisibilityPublic {
+		return PublicSignature{Anonymous: true, Reason: s.Reason, SignedAt: s.SignedAt}
+	}
+	return PublicSignature{
+		Login:     s.Login,
+		FirstName: s.FirstName,
+		LastName:  s.LastName,
+		Reason:    s.Reason,
+		SignedAt:  s.SignedAt,
+	}
+}
+
+// PublicSignatureFilter Параметры публичного списка подписей
+type PublicSignatureFilter struct {
+	Page     int `form:"page" binding:"omitempty,min=1"`
+	PageSize int `form:"pageSize" binding:"omitempty,min=1,max=100"`
+}
+
+// PublicSignaturePage Страница публичного списка подписей, новые подписи первыми.
+// Total - число подписей в списке, скрытые подписи в него не входят
+type PublicSignaturePage struct {
+	Items    []PublicSignature `json:"items"`
+	Total    int64             `json:"total"`
+	Page     int               `json:"page"`
+	PageSize int               `json:"page_size"`
 }
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// События, на которые подписываются вебхуки
+const (
+	// WebhookPetitionCreated Создана петиция
+	WebhookPetitionCreated = "petition.created"
+	// WebhookVoteCreated Петицию подписали. Кто подписал, не передается
+	WebhookVoteCreated = "vote.created"
+	// WebhookVoteDeleted Подпись отозвана
+	WebhookVoteDeleted = "vote.deleted"
+	// WebhookPetitionMilestone Петиция прошла веху сбора подписей
+	WebhookPetitionMilestone = "petition.milestone"
+	// WebhookPetitionSucceeded Петиция набрала нужное число подписей
+	WebhookPetitionSucceeded = "petition.succeeded"
+	// WebhookPetitionResponded Адресат опубликовал официальный ответ
+	WebhookPetitionResponded = "petition.responded"
+)
+
+// WebhookEventTypes Все события вебхуков
+var WebhookEventTypes = []string{
+	WebhookPetitionCreated,
+	WebhookVoteCreated,
+	WebhookVoteDeleted,
+	WebhookPetitionMilestone,
+	WebhookPetitionSucceeded,
+	WebhookPetitionResponded,
+}
+
+// Состояния доставки события на вебхук
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	// WebhookDeliveryDead Попытки закончились, доставку можно повторить вручную
+	WebhookDeliveryDead = "dead"
+)
+
+// Webhook Подписка интеграции на события. Secret хранится открыто, им подписывается каждый запрос
+type Webhook struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"-"`
+	URL       string    `gorm:"type:varchar(500);not null" json:"url"`
+	Secret    string    `gorm:"type:varchar(100);not null" json:"-"`
+	Events    string    `gorm:"type:varchar(255);not null" json:"-"`
+	Active    bool      `gorm:"not null;default:true" json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// EventList возвращает события вебхука списком
+func (w *Webhook) EventList() []string {
+	if w.Events == "" {
+		return []string{}
+	}
+	return strings.Split(w.Events, ",")
+}
+
+// Subscribed подписан ли вебхук на событие
+func (w *Webhook) Subscribed(eventType string) bool {
+	for _, e := range w.EventList() {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookInput Данные для создания и изменения вебхука
+type WebhookInput struct {
+	URL    string   `json:"url" binding:"required,url,max=500"`
+	Events []string `json:"events" binding:"required,min=1,dive,oneof=petition.created vote.created vote.deleted petition.milestone petition.succeeded petition.responded"`
+	Active *bool    `json:"active"`
+}
+
+// WebhookView Вебхук в ответе API
+type WebhookView struct {
+	ID        uint      `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Secret Секрет подписи, заполняется только при создании
+	Secret string `json:"secret,omitempty"`
+}
+
+// NewWebhookView Собирает ответ по вебхуку
+func NewWebhookView(w *Webhook) WebhookView {
+	return WebhookView{
+		ID:        w.ID,
+		URL:       w.URL,
+		Events:    w.EventList(),
+		Active:    w.Active,
+		CreatedAt: w.CreatedAt,
+		UpdatedAt: w.UpdatedAt,
+	}
+}
+
+// WebhookEvent Событие в исходящей очереди (outbox). Пишется в одной транзакции с изменением,
+// которое его вызвало, и раскладывается по подписанным вебхукам фоновой задачей
+type WebhookEvent struct {
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Type string `gorm:"type:varchar(40);not null" json:"type"`
+	// Payload Данные события в JSON
+	Payload      string     `gorm:"type:text;not null" json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DispatchedAt *time.Time `gorm:"index" json:"-"`
+}
+
+// WebhookDelivery Доставка одного события на один вебхук и результат последней попытки
+type WebhookDelivery struct {
+	ID             uint         `gorm:"primaryKey" json:"id"`
+	WebhookID      uint         `gorm:"not null;index" json:"webhook_id"`
+	Webhook        Webhook      `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	EventID        uint         `gorm:"not null;index" json:"event_id"`
+	Event          WebhookEvent `json:"-"`
+	EventType      string       `gorm:"type:varchar(40);not null" json:"event_type"`
+	Status         string       `gorm:"type:varchar(20);not null;index:idx_webhook_deliveries_due" json:"status"`
+	Attempts       int          `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time    `gorm:"index:idx_webhook_deliveries_due" json:"next_attempt_at"`
+	LastStatusCode int          `gorm:"not null;default:0" json:"last_status_code,omitempty"`
+	LastError      string       `gorm:"type:varchar(300);not null;default:''" json:"last_error,omitempty"`
+	DeliveredAt    *time.Time   `json:"delivered_at"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// WebhookDeliveryFilter Параметры журнала доставок
+type WebhookDeliveryFilter struct {
+	Status   string `form:"status" binding:"omitempty,oneof=pending succeeded dead"`
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"pageSize" binding:"omitempty,min=1,max=100"`
+}
+
+// WebhookDeliveryPage Страница журнала доставок
+type WebhookDeliveryPage struct {
+	Items    []WebhookDelivery `json:"items"`
+	Total    int64             `json:"total"`
+	Page     int               `json:"page"`
+	PageSize int               `json:"page_size"`
+}
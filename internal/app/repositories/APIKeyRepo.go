@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+	"time"
+)
+
+type APIKeyRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewAPIKeyRepository(db *gorm.DB, logger *logrus.Logger) APIKeyRepository {
+	return APIKeyRepository{
+		DB:     db,
+		logger: logger,
+	}
+}
+
+// Create создает новый API ключ
+func (r *APIKeyRepository) Create(key *models.APIKey) error {
+	if err := r.DB.Create(key).Error; err != nil {
+		r.logger.Error("Error creating api key:", err)
+		return err
+	}
+	r.logger.Info("API key created. ID: ", key.ID)
+	return nil
+}
+
+// GetByPrefix возвращает не отозванный ключ по его префиксу
+func (r *APIKeyRepository) GetByPrefix(prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+	result := r.DB.Where("prefix = ?", prefix).First(&key)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("api key not found")
+		}
+		return nil, result.Error
+	}
+	return &key, nil
+}
+
+// GetAllByUserID возвращает все не отозванные ключи пользователя
+func (r *APIKeyRepository) GetAllByUserID(userID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if err := r.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Revoke отзывает ключ пользователя. Возвращает ошибку, если у пользователя нет такого ключа
+func (r *APIKeyRepository) Revoke(id uint, userID uint) error {
+	result := r.DB.Where("user_id = ?", userID).Delete(&models.APIKey{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("api key not found")
+	}
+	r.logger.Info("API key revoked. ID: ", id)
+	return nil
+}
+
+// RevokeAllByUserIDTx отзывает все ключи пользователя в рамках транзакции
+func (r *APIKeyRepository) RevokeAllByUserIDTx(tx *gorm.DB, userID uint) error {
+	return tx.Where("user_id = ?", userID).Delete(&models.APIKey{}).Error
+}
+
+// TouchLastUsed обновляет время последнего использования ключа
+func (r *APIKeyRepository) TouchLastUsed(id uint, at time.Time) error {
+	return r.DB.Model(&models.APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", at).Error
+}
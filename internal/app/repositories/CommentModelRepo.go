@@ -56,6 +56,24 @@ func (r *CommentRepository) GetByPetitionID(petitionID uint) ([]models.Comment,
 	return comments, nil
 }
 
+// GetAllByUserID возвращает все комментарии пользователя
+func (r *CommentRepository) GetAllByUserID(userID uint) ([]models.Comment, error) {
+	var comments []models.Comment
+	if err := r.DB.Where("user_id = ?", userID).Order("id").Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// CountByUserID возвращает число комментариев пользователя
+func (r *CommentRepository) CountByUserID(userID uint) (int64, error) {
+	var count int64
+	if err := r.DB.Model(&models.Comment{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 // GetByID возвращает комментарий по его идентификатору
 func (r *CommentRepository) GetByID(id uint) (*models.Comment, error) {
 	var comment models.Comment
@@ -78,6 +96,12 @@ func (r *CommentRepository) Update(comment *models.Comment) error {
 	return nil
 }
 
+// ReassignUserTx передает все комментарии пользователя другому пользователю в рамках транзакции
+func (r *CommentRepository) ReassignUserTx(tx *gorm.DB, fromUserID uint, toUserID uint, toLogin string) error {
+	return tx.Model(&models.Comment{}).Where("user_id = ?", fromUserID).
+		Updates(map[string]interface{}{"user_id": toUserID, "login": toLogin}).Error
+}
+
 // DeleteByID удаляет комментарий из базы данных по его идентификатору
 func (r *CommentRepository) DeleteByID(id uint) error {
 	result := r.DB.Delete(&models.Comment{}, id)
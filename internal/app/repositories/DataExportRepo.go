@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+	"time"
+)
+
+type DataExportRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewDataExportRepository(db *gorm.DB, logger *logrus.Logger) DataExportRepository {
+	return DataExportRepository{
+		DB:     db,
+		logger: logger,
+	}
+}
+
+// Create создает новую задачу выгрузки
+func (r *DataExportRepository) Create(export *models.DataExport) error {
+	if err := r.DB.Create(export).Error; err != nil {
+		r.logger.Error("Error creating data export:", err)
+		return err
+	}
+	r.logger.Info("Data export created. ID: ", export.ID)
+	return nil
+}
+
+// GetByID возвращает выгрузку пользователя по ее ID
+func (r *DataExportRepository) GetByID(id uint, userID uint) (*models.DataExport, error) {
+	var export models.DataExport
+	result := r.DB.Where("user_id = ?", userID).First(&export, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("data export not found")
+		}
+		return nil, result.Error
+	}
+	return &export, nil
+}
+
+// GetByToken возвращает готовую выгрузку по токену ссылки на скачивание
+func (r *DataExportRepository) GetByToken(token string) (*models.DataExport, error) {
+	var export models.DataExport
+	result := r.DB.Where("token = ? AND status = ?", token, models.ExportStatusReady).First(&export)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("data export not found")
+		}
+		return nil, result.Error
+	}
+	return &export, nil
+}
+
+// GetActiveByUserID возвращает выгрузку пользователя, которая еще не готова, если она есть
+func (r *DataExportRepository) GetActiveByUserID(userID uint) (*models.DataExport, error) {
+	var export models.DataExport
+	result := r.DB.Where("user_id = ? AND status IN ?", userID,
+		[]string{models.ExportStatusPending, models.ExportStatusProcessing}).First(&export)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &export, nil
+}
+
+// ClaimPending берет в работу самую старую ожидающую выгрузку. Возвращает nil, если таких нет
+func (r *DataExportRepository) ClaimPending() (*models.DataExport, error) {
+	var export models.DataExport
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("status = ?", models.ExportStatusPending).Order("id").First(&export).Error; err != nil {
+			return err
+		}
+		// Условие на статус не дает двум обработчикам взять одну задачу
+		result := tx.Model(&models.DataExport{}).
+			Where("id = ? AND status = ?", export.ID, models.ExportStatusPending).
+			Update("status", models.ExportStatusProcessing)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		export.Status = models.ExportStatusProcessing
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &export, nil
+}
+
+// ResetStale возвращает в очередь выгрузки, которые взяты в работу раньше before и так и не готовы,
+// например после падения сервера во время сборки архива. Возвращает число таких выгрузок
+func (r *DataExportRepository) ResetStale(before time.Time) (int64, error) {
+	result := r.DB.Model(&models.DataExport{}).
+		Where("status = ? AND updated_at < ?", models.ExportStatusProcessing, before).
+		Update("status", models.ExportStatusPending)
+	return result.RowsAffected, result.Error
+}
+
+// Update обновляет выгрузку
+func (r *DataExportRepository) Update(export *models.DataExport) error {
+	return r.DB.Save(export).Error
+}
+
+// GetExpired возвращает готовые выгрузки, ссылка на которые истекла до момента now
+func (r *DataExportRepository) GetExpired(now time.Time) ([]models.DataExport, error) {
+	var exports []models.DataExport
+	if err := r.DB.Where("status = ? AND expires_at < ?", models.ExportStatusReady, now).Find(&exports).Error; err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+// DeleteByID удаляет выгрузку
+func (r *DataExportRepository) DeleteByID(id uint) error {
+	return r.DB.Delete(&models.DataExport{}, id).Error
+}
+
+// DeleteAllByUserIDTx удаляет все выгрузки пользователя в рамках транзакции и возвращает пути их файлов
+func (r *DataExportRepository) DeleteAllByUserIDTx(tx *gorm.DB, userID uint) ([]string, error) {
+	var paths []string
+	if err := tx.Model(&models.DataExport{}).Where("user_id = ? AND file_path <> ''", userID).
+		Pluck("file_path", &paths).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.DataExport{}).Error; err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
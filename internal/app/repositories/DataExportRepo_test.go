@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"github.com/stretchr/testify/assert"
+	"petition_api/internal/app/models"
+	"petition_api/internal/app/testutil"
+	"testing"
+	"time"
+)
+
+func TestResetStaleDataExports(t *testing.T) {
+	db := testutil.OpenDB(t, models.DataExport{})
+	repo := NewDataExportRepository(db, testutil.Logger())
+
+	// Сборку первой выгрузки оборвал перезапуск сервера
+	if err := repo.Create(&models.DataExport{UserID: 1, Status: models.ExportStatusPending}); err != nil {
+		t.Fatal(err)
+	}
+	stale, err := repo.ClaimPending()
+	if err != nil || stale == nil {
+		t.Fatal("export was not claimed", err)
+	}
+	db.Model(&models.DataExport{}).Where("id = ?", stale.ID).UpdateColumn("updated_at", time.Now().Add(-time.Hour))
+
+	if err := repo.Create(&models.DataExport{UserID: 2, Status: models.ExportStatusPending}); err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := repo.ClaimPending()
+	if err != nil || fresh == nil {
+		t.Fatal("export was not claimed", err)
+	}
+
+	count, err := repo.ResetStale(time.Now().Add(-30 * time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// Брошенная выгрузка снова в очереди, а та, что собирается сейчас, не тронута
+	claimed, err := repo.ClaimPending()
+	assert.NoError(t, err)
+	if assert.NotNil(t, claimed) {
+		assert.Equal(t, stale.ID, claimed.ID)
+	}
+	active, err := repo.GetActiveByUserID(2)
+	assert.NoError(t, err)
+	if assert.NotNil(t, active) {
+		assert.Equal(t, models.ExportStatusProcessing, active.Status)
+	}
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"petition_api/internal/app/models"
+)
+
+type NotificationPreferenceRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewNotificationPreferenceRepository(db *gorm.DB, logger *logrus.Logger) NotificationPreferenceRepository {
+	return NotificationPreferenceRepository{
+		DB:     db,
+		logger: logger,
+	}
+}
+
+// GetModes возвращает режимы писем пользователя по всем типам событий, ненастроенные - по умолчанию
+func (r *NotificationPreferenceRepository) GetModes(userID uint) (map[string]string, error) {
+	var preferences []models.NotificationPreference
+	if err := r.DB.Where("user_id = ?", userID).Find(&preferences).Error; err != nil {
+		return nil, err
+	}
+	modes := make(map[string]string, len(models.NotificationTypes))
+	for _, eventType := range models.NotificationTypes {
+		modes[eventType] = models.DefaultDeliveryMode
+	}
+	for _, preference := range preferences {
+		modes[preference.EventType] = preference.Mode
+	}
+	return modes, nil
+}
+
+// SetModes сохраняет режимы писем пользователя. Типы, которых нет в modes, не меняются
+func (r *NotificationPreferenceRepository) SetModes(userID uint, modes map[string]string) error {
+	if len(modes) == 0 {
+		return nil
+	}
+	preferences := make([]models.NotificationPreference, 0, len(modes))
+	for eventType, mode := range modes {
+		preferences = append(preferences, models.NotificationPreference{UserID: userID, EventType: eventType, Mode: mode})
+	}
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "event_type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"mode"}),
+	}).Create(&preferences).Error
+}
+
+// DeleteAllByUserIDTx удаляет настройки пользователя в рамках транзакции
+func (r *NotificationPreferenceRepository) DeleteAllByUserIDTx(tx *gorm.DB, userID uint) error {
+	return tx.Where("user_id = ?", userID).Delete(&models.NotificationPreference{}).Error
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+	"time"
+)
+
+type NotificationRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewNotificationRepository(db *gorm.DB, logger *logrus.Logger) NotificationRepository {
+	return NotificationRepository{
+		DB:     db,
+		logger: logger,
+	}
+}
+
+// CreateBatch сохраняет уведомления пачками
+func (r *NotificationRepository) CreateBatch(notifications []models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	if err := r.DB.CreateInBatches(notifications, 500).Error; err != nil {
+		r.logger.Error("Error creating notifications:", err)
+		return err
+	}
+	return nil
+}
+
+// GetByUserID возвращает уведомления пользователя по страницам, новые первыми, и их общее число
+func (r *NotificationRepository) GetByUserID(userID uint, unreadOnly bool, page int, pageSize int) ([]models.Notification, int64, error) {
+	query := r.DB.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	notifications := make([]models.Notification, 0)
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&notifications).Error; err != nil {
+		return nil, 0, err
+	}
+	return notifications, total, nil
+}
+
+// GetAllByUserID возвращает все уведомления пользователя
+func (r *NotificationRepository) GetAllByUserID(userID uint) ([]models.Notification, error) {
+	var notifications []models.Notification
+	if err := r.DB.Where("user_id = ?", userID).Order("id").Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// CountByUserID возвращает число уведомлений пользователя
+func (r *NotificationRepository) CountByUserID(userID uint) (int64, error) {
+	var count int64
+	if err := r.DB.Model(&models.Notification{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountUnread возвращает число непрочитанных уведомлений пользователя
+func (r *NotificationRepository) CountUnread(userID uint) (int64, error) {
+	var count int64
+	if err := r.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// MarkRead отмечает прочитанными уведомления пользователя. Чужие ID пропускаются
+func (r *NotificationRepository) MarkRead(userID uint, ids []uint, at time.Time) (int64, error) {
+	result := r.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND id IN ? AND read_at IS NULL", userID, ids).
+		Update("read_at", at)
+	return result.RowsAffected, result.Error
+}
+
+// MarkAllRead отмечает прочитанными все уведомления пользователя
+func (r *NotificationRepository) MarkAllRead(userID uint, at time.Time) (int64, error) {
+	result := r.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", at)
+	return result.RowsAffected, result.Error
+}
+
+// GetPendingEmail возвращает уведомления, которые еще не попали в письма и у которых режим писем равен mode.
+// Уведомления упорядочены по пользователям, чтобы их было удобно собирать в письма
+func (r *NotificationRepository) GetPendingEmail(mode string, limit int) ([]models.Notification, error) {
+	notifications := make([]models.Notification, 0)
+	err := r.DB.Table("notifications").
+		Select("notifications.*").
+		Joins("LEFT JOIN notification_preferences ON notification_preferences.user_id = notifications.user_id AND notification_preferences.event_type = notifications.type").
+		Where("notifications.emailed_at IS NULL AND COALESCE(notification_preferences.mode, ?) = ?", models.DefaultDeliveryMode, mode).
+		Order("notifications.user_id, notifications.id").
+		Limit(limit).
+		Find(&notifications).Error
+	if err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// MarkEmailed отмечает уведомления обработанными для писем
+func (r *NotificationRepository) MarkEmailed(ids []uint, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.DB.Model(&models.Notification{}).Where("id IN ?", ids).Update("emailed_at", at).Error
+}
+
+// DeleteAllByUserIDTx удаляет уведомления пользователя в рамках транзакции
+func (r *NotificationRepository) DeleteAllByUserIDTx(tx *gorm.DB, userID uint) error {
+	return tx.Where("user_id = ?", userID).Delete(&models.Notification{}).Error
+}
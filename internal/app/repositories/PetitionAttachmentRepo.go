@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"petition_api/internal/app/models"
+)
+
+type PetitionAttachmentRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewPetitionAttachmentRepository(db *gorm.DB, logger *logrus.Logger) PetitionAttachmentRepository {
+	return PetitionAttachmentRepository{
+		DB:     db,
+		logger: logger,
+	}
+}
+
+// Create сохраняет данные о вложении
+func (r *PetitionAttachmentRepository) Create(attachment *models.PetitionAttachment) error {
+	return r.CreateTx(r.DB, attachment)
+}
+
+// CreateTx сохраняет данные о вложении в рамках транзакции
+func (r *PetitionAttachmentRepository) CreateTx(tx *gorm.DB, attachment *models.PetitionAttachment) error {
+	if err := tx.Create(attachment).Error; err != nil {
+		r.logger.Error("Error creating petition attachment:", err)
+		return err
+	}
+	return nil
+}
+
+// LockPetitionTx блокирует строку петиции до конца транзакции, чтобы параллельные загрузки
+// проверяли квоту и заменяли обложку по очереди
+func (r *PetitionAttachmentRepository) LockPetitionTx(tx *gorm.DB, petitionID uint) error {
+	var locked []uint
+	return tx.Unscoped().Model(&models.Petition{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", petitionID).Pluck("id", &locked).Error
+}
+
+// GetByID возвращает вложение петиции по его ID
+func (r *PetitionAttachmentRepository) GetByID(petitionID uint, id uint) (*models.PetitionAttachment, error) {
+	var attachment models.PetitionAttachment
+	result := r.DB.Where("petition_id = ?", petitionID).First(&attachment, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("attachment not found")
+		}
+		return nil, result.Error
+	}
+	return &attachment, nil
+}
+
+// GetAllByPetitionID возвращает все вложения петиции, обложка первой
+func (r *PetitionAttachmentRepository) GetAllByPetitionID(petitionID uint) ([]models.PetitionAttachment, error) {
+	attachments := make([]models.PetitionAttachment, 0)
+	if err := r.DB.Where("petition_id = ?", petitionID).
+		Order("kind = '" + models.AttachmentKindCover + "' DESC, id ASC").
+		Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// GetCovers возвращает обложки петиций с указанными ID, ключ - ID петиции
+func (r *PetitionAttachmentRepository) GetCovers(petitionIDs []uint) (map[uint]models.PetitionAttachment, error) {
+	covers := make(map[uint]models.PetitionAttachment)
+	if len(petitionIDs) == 0 {
+		return covers, nil
+	}
+	var attachments []models.PetitionAttachment
+	if err := r.DB.Where("petition_id IN ? AND kind = ?", petitionIDs, models.AttachmentKindCover).
+		Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	for _, attachment := range attachments {
+		covers[attachment.PetitionID] = attachment
+	}
+	return covers, nil
+}
+
+// GetCoversTx возвращает все обложки петиции в рамках транзакции
+func (r *PetitionAttachmentRepository) GetCoversTx(tx *gorm.DB, petitionID uint) ([]models.PetitionAttachment, error) {
+	var covers []models.PetitionAttachment
+	if err := tx.Where("petition_id = ? AND kind = ?", petitionID, models.AttachmentKindCover).
+		Find(&covers).Error; err != nil {
+		return nil, err
+	}
+	return covers, nil
+}
+
+// GetUsage возвращает число и общий размер документов петиции. Обложка в квоту не входит
+func (r *PetitionAttachmentRepository) GetUsage(petitionID uint) (count int64, totalSize int64, err error) {
+	return r.GetUsageTx(r.DB, petitionID)
+}
+
+// GetUsageTx возвращает число и общий размер документов петиции в рамках транзакции
+func (r *PetitionAttachmentRepository) GetUsageTx(tx *gorm.DB, petitionID uint) (count int64, totalSize int64, err error) {
+	var usage struct {
+		Count     int64
+		TotalSize int64
+	}
+	err = tx.Model(&models.PetitionAttachment{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(size), 0) AS total_size").
+		Where("petition_id = ? AND kind = ?", petitionID, models.AttachmentKindDocument).
+		Scan(&usage).Error
+	return usage.Count, usage.TotalSize, err
+}
+
+// DeleteByID удаляет данные о вложении
+func (r *PetitionAttachmentRepository) DeleteByID(id uint) error {
+	return r.DeleteByIDTx(r.DB, id)
+}
+
+// DeleteByIDTx удаляет данные о вложении в рамках транзакции
+func (r *PetitionAttachmentRepository) DeleteByIDTx(tx *gorm.DB, id uint) error {
+	return tx.Unscoped().Delete(&models.PetitionAttachment{}, id).Error
+}
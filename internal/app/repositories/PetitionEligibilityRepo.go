@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"petition_api/internal/app/models"
+)
+
+type PetitionEligibilityRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewPetitionEligibilityRepository(db *gorm.DB, logger *logrus.Logger) PetitionEligibilityRepository {
+	return PetitionEligibilityRepository{
+		DB:     db,
+		logger: logger,
+	}
+}
+
+// GetByPetitionID возвращает условия подписи петиции. Если условий нет, возвращает nil без ошибки
+func (r *PetitionEligibilityRepository) GetByPetitionID(petitionID uint) (*models.PetitionEligibility, error) {
+	var eligibility models.PetitionEligibility
+	err := r.DB.Where("petition_id = ?", petitionID).First(&eligibility).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &eligibility, nil
+}
+
+// Save создает или заменяет условия подписи петиции
+func (r *PetitionEligibilityRepository) Save(eligibility *models.PetitionEligibility) error {
+	result := r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "petition_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"min_age", "regions", "require_verified_email", "min_account_age_days", "updated_at"}),
+	}).Create(eligibility)
+	if result.Error != nil {
+		r.logger.Error("Error saving petition eligibility:", result.Error)
+		return result.Error
+	}
+	return nil
+}
+
+// DeleteByPetitionID удаляет условия подписи петиции
+func (r *PetitionEligibilityRepository) DeleteByPetitionID(petitionID uint) error {
+	return r.DB.Where("petition_id = ?", petitionID).Delete(&models.PetitionEligibility{}).Error
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"petition_api/internal/app/models"
+)
+
+type PetitionMilestoneRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewPetitionMilestoneRepository(db *gorm.DB, logger *logrus.Logger) PetitionMilestoneRepository {
+	return PetitionMilestoneRepository{
+		DB:     db,
+		logger: logger,
+	}
+}
+
+// Create сохраняет веху, если ее еще нет. Возвращает true, если веху записал этот вызов:
+// уникальный индекс по петиции и проценту не даст отметить веху дважды при одновременных голосах
+func (r *PetitionMilestoneRepository) Create(milestone *models.PetitionMilestone) (bool, error) {
+	return r.CreateTx(r.DB, milestone)
+}
+
+// CreateTx сохраняет веху в рамках транзакции, если ее еще нет
+func (r *PetitionMilestoneRepository) CreateTx(tx *gorm.DB, milestone *models.PetitionMilestone) (bool, error) {
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(milestone)
+	if result.Error != nil {
+		r.logger.Error("Error creating milestone:", result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// GetByPetitionID возвращает пройденные вехи петиции по возрастанию
+func (r *PetitionMilestoneRepository) GetByPetitionID(petitionID uint) ([]models.PetitionMilestone, error) {
+	var milestones []models.PetitionMilestone
+	if err := r.DB.Where("petition_id = ?", petitionID).Order("percent").Find(&milestones).Error; err != nil {
+		return nil, err
+	}
+	return milestones, nil
+}
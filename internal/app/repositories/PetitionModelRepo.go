@@ -5,7 +5,9 @@ import (
 	"github.com/go-sql-driver/mysql"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"petition_api/internal/app/models"
+	"time"
 )
 
 type PetitionRepository struct {
@@ -36,6 +38,11 @@ func (r *PetitionRepository) Create(petition *models.Petition) (*models.Petition
 	return petition, nil
 }
 
+// CreateTx создает новую петицию в рамках транзакции
+func (r *PetitionRepository) CreateTx(tx *gorm.DB, petition *models.Petition) error {
+	return tx.Create(petition).Error
+}
+
 // GetAll возвращает список всех петиций из базы данных по страницам
 func (r *PetitionRepository) GetAll(page int, pageSize int) ([]models.Petition, error) {
 	var petitions []models.Petition
@@ -62,6 +69,86 @@ func (r *PetitionRepository) GetByID(id uint) (*models.Petition, error) {
 	return &petition, nil
 }
 
+// GetForUpdateTx возвращает петицию и блокирует ее строку до конца транзакции.
+// Для неизвестного ID возвращает gorm.ErrRecordNotFound
+func (r *PetitionRepository) GetForUpdateTx(tx *gorm.DB, id uint) (*models.Petition, error) {
+	var petition models.Petition
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&petition, id).Error; err != nil {
+		return nil, err
+	}
+	return &petition, nil
+}
+
+// GetTitlesByIDs возвращает заголовки петиций по их ID
+func (r *PetitionRepository) GetTitlesByIDs(ids []uint) (map[uint]string, error) {
+	titles := make(map[uint]string, len(ids))
+	if len(ids) == 0 {
+		return titles, nil
+	}
+	var petitions []models.Petition
+	if err := r.DB.Select("id", "title").Where("id IN ?", ids).Find(&petitions).Error; err != nil {
+		return nil, err
+	}
+	for _, petition := range petitions {
+		titles[petition.ID] = petition.Title
+	}
+	return titles, nil
+}
+
+// GetAllByUserID возвращает все петиции пользователя
+func (r *PetitionRepository) GetAllByUserID(userID uint) ([]models.Petition, error) {
+	var petitions []models.Petition
+	if err := r.DB.Where("user_id = ?", userID).Order("id").Find(&petitions).Error; err != nil {
+		return nil, err
+	}
+	return petitions, nil
+}
+
+// GetAllByRecipientID возвращает петиции адресата по страницам, новые первыми. Пустой status - петиции в любом статусе
+func (r *PetitionRepository) GetAllByRecipientID(recipientID uint, status string, page int, pageSize int) ([]models.Petition, error) {
+	petitions := make([]models.Petition, 0)
+	query := r.DB.Where("recipient_id = ?", recipientID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&petitions).Error; err != nil {
+		return nil, err
+	}
+	return petitions, nil
+}
+
+// MarkTargetReached переводит открытую петицию в ожидание ответа.
+// Возвращает true только для первого вызова, поэтому адресата уведомляют один раз даже при одновременных голосах
+func (r *PetitionRepository) MarkTargetReached(id uint, at time.Time) (bool, error) {
+	return r.MarkTargetReachedTx(r.DB, id, at)
+}
+
+// MarkTargetReachedTx переводит открытую петицию в ожидание ответа в рамках транзакции
+func (r *PetitionRepository) MarkTargetReachedTx(tx *gorm.DB, id uint, at time.Time) (bool, error) {
+	result := tx.Model(&models.Petition{}).
+		Where("id = ? AND status = ? AND target_reached_at IS NULL", id, models.PetitionStatusOpen).
+		Updates(map[string]interface{}{"status": models.PetitionStatusAwaitingResponse, "target_reached_at": at})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// SetStatusTx меняет статус петиции в рамках транзакции
+func (r *PetitionRepository) SetStatusTx(tx *gorm.DB, id uint, status string) error {
+	return tx.Model(&models.Petition{}).Where("id = ?", id).Update("status", status).Error
+}
+
+// CountByUserID возвращает число петиций, автором которых является пользователь
+func (r *PetitionRepository) CountByUserID(userID uint) (int64, error) {
+	var count int64
+	if err := r.DB.Model(&models.Petition{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 // Update обновляет информацию о петиции в базе данных
 func (r *PetitionRepository) Update(petition *models.Petition) error {
 	result := r.DB.Save(petition)
@@ -71,15 +158,24 @@ func (r *PetitionRepository) Update(petition *models.Petition) error {
 	return nil
 }
 
-// UpdateTx обновляет информацию о петиции в базе данных в рамках транзакции и возвращает обновленную петицию
+// petitionEditableColumns Колонки, которые меняются при редактировании петиции. Статус, счетчики голосов
+// и время достижения цели меняются другими запросами, поэтому прочитанная ранее петиция их не перезаписывает
+var petitionEditableColumns = []string{"title", "description", "target_by_vote", "recipient", "recipient_id", "updated_at"}
+
+// UpdateTx обновляет редактируемые поля петиции в рамках транзакции и возвращает обновленную петицию
 func (r *PetitionRepository) UpdateTx(tx *gorm.DB, petition *models.Petition) (*models.Petition, error) {
-	result := tx.Save(petition)
+	result := tx.Model(petition).Select(petitionEditableColumns).Updates(petition)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return petition, nil
 }
 
+// ReassignUserTx передает все петиции пользователя другому пользователю в рамках транзакции
+func (r *PetitionRepository) ReassignUserTx(tx *gorm.DB, fromUserID uint, toUserID uint) error {
+	return tx.Model(&models.Petition{}).Where("user_id = ?", fromUserID).Update("user_id", toUserID).Error
+}
+
 // DeleteByID удаляет петицию из базы данных по ее ID
 func (r *PetitionRepository) DeleteByID(id uint) error {
 	result := r.DB.Delete(&models.Petition{}, id)
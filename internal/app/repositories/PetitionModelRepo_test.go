@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"github.com/stretchr/testify/assert"
+	"petition_api/internal/app/models"
+	"petition_api/internal/app/testutil"
+	"testing"
+	"time"
+)
+
+func TestUpdateTxKeepsConcurrentChanges(t *testing.T) {
+	db := testutil.OpenDB(t, models.Petition{})
+
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", TargetByVote: 100}
+	if err := db.Create(&petition).Error; err != nil {
+		t.Fatal(err)
+	}
+	repo := NewPetitionRepository(db, testutil.Logger())
+	stale, err := repo.GetByID(petition.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Пока автор редактирует текст, петиция набирает подписи и получает голос удаленного аккаунта
+	reachedAt := time.Now()
+	db.Model(&models.Petition{}).Where("id = ?", petition.ID).Updates(map[string]interface{}{
+		"status":            models.PetitionStatusAwaitingResponse,
+		"target_reached_at": reachedAt,
+		"anonymous_votes":   1,
+	})
+
+	stale.Title = "Сквер"
+	_, err = repo.UpdateTx(db, stale)
+	assert.NoError(t, err)
+
+	var stored models.Petition
+	assert.NoError(t, db.First(&stored, petition.ID).Error)
+	assert.Equal(t, "Сквер", stored.Title)
+	assert.Equal(t, models.PetitionStatusAwaitingResponse, stored.Status)
+	assert.NotNil(t, stored.TargetReachedAt)
+	assert.Equal(t, uint(1), stored.AnonymousVotes)
+}
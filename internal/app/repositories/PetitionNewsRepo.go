@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+)
+
+type PetitionNewsRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewPetitionNewsRepository(db *gorm.DB, logger *logrus.Logger) PetitionNewsRepository {
+	return PetitionNewsRepository{
+		DB:     db,
+		logger: logger,
+	}
+}
+
+// Create создает новость петиции
+func (r *PetitionNewsRepository) Create(news *models.PetitionNews) error {
+	if err := r.DB.Create(news).Error; err != nil {
+		r.logger.Error("Error creating petition news:", err)
+		return err
+	}
+	return nil
+}
+
+// GetAllByPetitionID возвращает новости петиции по страницам, новые первыми
+func (r *PetitionNewsRepository) GetAllByPetitionID(petitionID uint, page int, pageSize int) ([]models.PetitionNews, error) {
+	news := make([]models.PetitionNews, 0)
+	offset := (page - 1) * pageSize
+	if err := r.DB.Where("petition_id = ?", petitionID).
+		Order("created_at DESC, id DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&news).Error; err != nil {
+		return nil, err
+	}
+	return news, nil
+}
+
+// GetByID возвращает новость петиции по ее ID
+func (r *PetitionNewsRepository) GetByID(petitionID uint, id uint) (*models.PetitionNews, error) {
+	var news models.PetitionNews
+	result := r.DB.Where("petition_id = ?", petitionID).First(&news, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("news not found")
+		}
+		return nil, result.Error
+	}
+	return &news, nil
+}
+
+// Update сохраняет изменения новости
+func (r *PetitionNewsRepository) Update(news *models.PetitionNews) error {
+	return r.DB.Save(news).Error
+}
+
+// DeleteByID удаляет новость
+func (r *PetitionNewsRepository) DeleteByID(id uint) error {
+	return r.DB.Delete(&models.PetitionNews{}, id).Error
+}
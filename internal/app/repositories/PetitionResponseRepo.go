@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+)
+
+type PetitionResponseRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewPetitionResponseRepository(db *gorm.DB, logger *logrus.Logger) PetitionResponseRepository {
+	return PetitionResponseRepository{
+		DB:     db,
+		logger: logger,
+	}
+}
+
+// CreateTx сохраняет ответ адресата в рамках транзакции
+func (r *PetitionResponseRepository) CreateTx(tx *gorm.DB, response *models.PetitionResponse) error {
+	return tx.Create(response).Error
+}
+
+// GetByPetitionID возвращает ответ на петицию. Если ответа нет, возвращает nil без ошибки
+func (r *PetitionResponseRepository) GetByPetitionID(petitionID uint) (*models.PetitionResponse, error) {
+	var responses []models.PetitionResponse
+	if err := r.DB.Where("petition_id = ?", petitionID).Limit(1).Find(&responses).Error; err != nil {
+		return nil, err
+	}
+	if len(responses) == 0 {
+		return nil, nil
+	}
+	return &responses[0], nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+)
+
+type PetitionRevisionRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewPetitionRevisionRepository(db *gorm.DB, logger *logrus.Logger) PetitionRevisionRepository {
+	return PetitionRevisionRepository{
+		DB:     db,
+		logger: logger,
+	}
+}
+
+// CreateTx сохраняет версию петиции в рамках транзакции
+func (r *PetitionRevisionRepository) CreateTx(tx *gorm.DB, revision *models.PetitionRevision) error {
+	return tx.Create(revision).Error
+}
+
+// LastNumberTx возвращает номер последней версии петиции, 0 если версий нет
+func (r *PetitionRevisionRepository) LastNumberTx(tx *gorm.DB, petitionID uint) (uint, error) {
+	var numbers []uint
+	if err := tx.Model(&models.PetitionRevision{}).
+		Where("petition_id = ?", petitionID).
+		Order("number DESC").
+		Limit(1).
+		Pluck("number", &numbers).Error; err != nil {
+		return 0, err
+	}
+	if len(numbers) == 0 {
+		return 0, nil
+	}
+	return numbers[0], nil
+}
+
+// GetAllByPetitionID возвращает все версии петиции, первая версия первой
+func (r *PetitionRevisionRepository) GetAllByPetitionID(petitionID uint) ([]models.PetitionRevision, error) {
+	revisions := make([]models.PetitionRevision, 0)
+	if err := r.DB.Where("petition_id = ?", petitionID).Order("number ASC").Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// GetByNumber возвращает версию петиции по номеру
+func (r *PetitionRevisionRepository) GetByNumber(petitionID uint, number uint) (*models.PetitionRevision, error) {
+	var revision models.PetitionRevision
+	result := r.DB.Where("petition_id = ? AND number = ?", petitionID, number).First(&revision)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("revision not found")
+		}
+		return nil, result.Error
+	}
+	return &revision, nil
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+)
+
+type RecipientRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewRecipientRepository(db *gorm.DB, logger *logrus.Logger) RecipientRepository {
+	return RecipientRepository{
+		DB:     db,
+		logger: logger,
+	}
+}
+
+// Create создает адресата
+func (r *RecipientRepository) Create(recipient *models.Recipient) error {
+	if err := r.DB.Create(recipient).Error; err != nil {
+		r.logger.Error("Error creating recipient:", err)
+		return err
+	}
+	return nil
+}
+
+// GetAll возвращает всех адресатов по названию организации
+func (r *RecipientRepository) GetAll() ([]models.Recipient, error) {
+	recipients := make([]models.Recipient, 0)
+	if err := r.DB.Order("organisation").Find(&recipients).Error; err != nil {
+		return nil, err
+	}
+	return recipients, nil
+}
+
+// GetByID возвращает адресата по его ID
+func (r *RecipientRepository) GetByID(id uint) (*models.Recipient, error) {
+	var recipient models.Recipient
+	result := r.DB.First(&recipient, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("recipient not found")
+		}
+		return nil, result.Error
+	}
+	return &recipient, nil
+}
+
+// Update сохраняет изменения адресата
+func (r *RecipientRepository) Update(recipient *models.Recipient) error {
+	return r.DB.Save(recipient).Error
+}
+
+// DeleteByID удаляет адресата по его ID
+func (r *RecipientRepository) DeleteByID(id uint) error {
+	return r.DB.Delete(&models.Recipient{}, id).Error
+}
+
+// GetMembers возвращает аккаунты, привязанные к адресату
+func (r *RecipientRepository) GetMembers(recipientID uint) ([]models.UserModel, error) {
+	members := make([]models.UserModel, 0)
+	if err := r.DB.Where("recipient_id = ?", recipientID).Order("id").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// CountMembers возвращает число аккаунтов, привязанных к адресату
+func (r *RecipientRepository) CountMembers(recipientID uint) (int64, error) {
+	var count int64
+	if err := r.DB.Model(&models.UserModel{}).Where("recipient_id = ?", recipientID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+	"sync"
+	"time"
+)
+
+// permissionCacheTTL Сколько живет закэшированный набор прав ролей
+const permissionCacheTTL = time.Minute
+
+type RoleRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+	cache  *permissionCache
+}
+
+// permissionCache Права ролей в памяти, чтобы не ходить в базу на каждый запрос
+type permissionCache struct {
+	mu       sync.RWMutex
+	roles    map[string]map[string]bool
+	loadedAt time.Time
+}
+
+func NewRoleRepository(db *gorm.DB, logger *logrus.Logger) RoleRepository {
+	return RoleRepository{
+		DB:     db,
+		logger: logger,
+		cache:  &permissionCache{},
+	}
+}
+
+// Seed создает недостающие права и роли. У новых ролей ставятся права по умолчанию
+func (r *RoleRepository) Seed(permissions []models.Permission, rolePermissions map[string][]string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		for _, permission := range permissions {
+			p := permission
+			if err := tx.Where(models.Permission{Code: p.Code}).
+				Attrs(models.Permission{Description: p.Description}).
+				FirstOrCreate(&p).Error; err != nil {
+				return err
+			}
+		}
+
+		for name, codes := range rolePermissions {
+			var role models.Role
+			err := tx.Where("name = ?", name).First(&role).Error
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			var perms []models.Permission
+			if len(codes) > 0 {
+				if err := tx.Where("code IN ?", codes).Find(&perms).Error; err != nil {
+					return err
+				}
+			}
+			role = models.Role{Name: name, Permissions: perms}
+			if err := tx.Create(&role).Error; err != nil {
+				return err
+			}
+			r.logger.Info("Role created: ", name)
+		}
+		return nil
+	})
+}
+
+// GetAll возвращает все роли с их правами
+func (r *RoleRepository) GetAll() ([]models.Role, error) {
+	var roles []models.Role
+	if err := r.DB.Preload("Permissions").Order("id").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// GetAllPermissions возвращает все права
+func (r *RoleRepository) GetAllPermissions() ([]models.Permission, error) {
+	var permissions []models.Permission
+	if err := r.DB.Order("code").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// GetByName возвращает роль с правами по названию
+func (r *RoleRepository) GetByName(name string) (*models.Role, error) {
+	var role models.Role
+	result := r.DB.Preload("Permissions").Where("name = ?", name).First(&role)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("role not found")
+		}
+		return nil, result.Error
+	}
+	return &role, nil
+}
+
+// RoleExists проверяет, есть ли роль с таким названием
+func (r *RoleRepository) RoleExists(name string) (bool, error) {
+	roles, err := r.rolePermissions()
+	if err != nil {
+		return false, err
+	}
+	_, ok := roles[name]
+	return ok, nil
+}
+
+// SetPermissions заменяет права роли на переданный набор
+func (r *RoleRepository) SetPermissions(name string, codes []string) (*models.Role, error) {
+	role, err := r.GetByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var perms []models.Permission
+	if len(codes) > 0 {
+		if err := r.DB.Where("code IN ?", codes).Find(&perms).Error; err != nil {
+			return nil, err
+		}
+		if len(perms) != len(uniqueCodes(codes)) {
+			return nil, errors.New("unknown permission")
+		}
+	}
+
+	if err := r.DB.Model(role).Association("Permissions").Replace(perms); err != nil {
+		return nil, err
+	}
+	r.InvalidateCache()
+	role.Permissions = perms
+	return role, nil
+}
+
+// HasPermission проверяет, есть ли у роли право
+func (r *RoleRepository) HasPermission(role string, permission string) (bool, error) {
+	roles, err := r.rolePermissions()
+	if err != nil {
+		return false, err
+	}
+	return roles[role][permission], nil
+}
+
+// InvalidateCache сбрасывает кэш прав ролей
+func (r *RoleRepository) InvalidateCache() {
+	r.cache.mu.Lock()
+	r.cache.roles = nil
+	r.cache.mu.Unlock()
+}
+
+// rolePermissions возвращает права всех ролей из кэша, при необходимости загружая их из базы
+func (r *RoleRepository) rolePermissions() (map[string]map[string]bool, error) {
+	r.cache.mu.RLock()
+	if r.cache.roles != nil && time.Since(r.cache.loadedAt) < permissionCacheTTL {
+		roles := r.cache.roles
+		r.cache.mu.RUnlock()
+		return roles, nil
+	}
+	r.cache.mu.RUnlock()
+
+	all, err := r.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	roles := make(map[string]map[string]bool, len(all))
+	for _, role := range all {
+		perms := make(map[string]bool, len(role.Permissions))
+		for _, p := range role.Permissions {
+			perms[p.Code] = true
+		}
+		roles[role.Name] = perms
+	}
+
+	r.cache.mu.Lock()
+	r.cache.roles = roles
+	r.cache.loadedAt = time.Now()
+	r.cache.mu.Unlock()
+	return roles, nil
+}
+
+func uniqueCodes(codes []string) map[string]bool {
+	set := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		set[code] = true
+	}
+	return set
+}
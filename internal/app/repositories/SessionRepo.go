@@ -5,6 +5,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 	"petition_api/internal/app/models"
+	"time"
 )
 
 type SessionRepo struct {
@@ -61,7 +62,74 @@ func (repo *SessionRepo) DeleteAllByUserID(userID string) error {
 	return repo.DB.Where("user_id = ?", userID).Delete(&models.RefreshSession{}).Error
 }
 
+// DeleteAllByUserIDTx Удаляет все сессий пользователя в рамках транзакции
+func (repo *SessionRepo) DeleteAllByUserIDTx(tx *gorm.DB, userID uint) error {
+	return tx.Where("user_id = ?", userID).Delete(&models.RefreshSession{}).Error
+}
+
 // DeleteSession Удаляет конкретную сессию
 func (repo *SessionRepo) DeleteSession(session *models.RefreshSession) error {
 	return repo.DB.Model(&models.RefreshSession{}).Delete(session).Error
 }
+
+// SaveForDevice сохраняет сессию с учетом политики сессий пользователя.
+// Если у пользователя уже есть сессия с этого устройства, она переиспользуется и получает новый токен.
+// Иначе создается новая сессия, а самые старые сессии сверх maxPerUser удаляются.
+// Сессия без отпечатка устройства никогда не переиспользуется
+func (repo *SessionRepo) SaveForDevice(session *models.RefreshSession, maxPerUser int) error {
+	return repo.DB.Transaction(func(tx *gorm.DB) error {
+		err := gorm.ErrRecordNotFound
+		var existing models.RefreshSession
+		if session.Fingerprint != "" {
+			err = tx.Where("user_id = ? AND fingerprint = ?", session.UserID, session.Fingerprint).
+				Order("created_at DESC").
+				First(&existing).Error
+		}
+		if err == nil {
+			existing.RefreshToken = session.RefreshToken
+			existing.UA = session.UA
+			existing.IP = session.IP
+			existing.ExpiresIn = session.ExpiresIn
+			if err := tx.Save(&existing).Error; err != nil {
+				return err
+			}
+			*session = existing
+			repo.logger.Debug("Refresh session reused. ID: ", existing.ID)
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if maxPerUser > 0 {
+			var sessions []models.RefreshSession
+			if err := tx.Where("user_id = ?", session.UserID).
+				Order("created_at ASC").
+				Find(&sessions).Error; err != nil {
+				return err
+			}
+			// Оставляем место для новой сессии
+			if extra := len(sessions) - maxPerUser + 1; extra > 0 {
+				ids := make([]uint, 0, extra)
+				for _, old := range sessions[:extra] {
+					ids = append(ids, old.ID)
+				}
+				if err := tx.Delete(&models.RefreshSession{}, ids).Error; err != nil {
+					return err
+				}
+				repo.logger.Debug("Oldest refresh sessions evicted: ", ids)
+			}
+		}
+
+		return tx.Create(session).Error
+	})
+}
+
+// DeleteExpired удаляет все сессии, срок которых истек до момента now, и возвращает их количество
+func (repo *SessionRepo) DeleteExpired(now time.Time) (int64, error) {
+	result := repo.DB.Where("expires_in < ?", now.Unix()).Delete(&models.RefreshSession{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
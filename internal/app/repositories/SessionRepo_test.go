@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"github.com/stretchr/testify/assert"
+	"petition_api/internal/app/models"
+	"petition_api/internal/app/testutil"
+	"testing"
+	"time"
+)
+
+func newDeviceSession(userID uint, fingerprint string, token string, createdAt time.Time) *models.RefreshSession {
+	return &models.RefreshSession{
+		UserID:       userID,
+		RefreshToken: token,
+		UA:           "Firefox",
+		IP:           "10.0.0.1",
+		Fingerprint:  fingerprint,
+		ExpiresIn:    createdAt.Add(time.Hour).Unix(),
+		CreatedAt:    createdAt,
+	}
+}
+
+func TestSaveForDeviceReusesSession(t *testing.T) {
+	db := testutil.OpenDB(t, models.RefreshSession{})
+	repo := NewSessionRepo(db, testutil.Logger())
+	now := time.Now()
+
+	first := newDeviceSession(1, "laptop", "token-1", now)
+	assert.NoError(t, repo.SaveForDevice(first, 5))
+
+	// Повторный вход с того же устройства обновляет сессию, а не создает новую
+	again := newDeviceSession(1, "laptop", "token-2", now.Add(time.Minute))
+	again.IP = "10.0.0.2"
+	assert.NoError(t, repo.SaveForDevice(again, 5))
+	assert.Equal(t, first.ID, again.ID)
+
+	var sessions []models.RefreshSession
+	assert.NoError(t, db.Find(&sessions).Error)
+	if assert.Len(t, sessions, 1) {
+		assert.Equal(t, "token-2", sessions[0].RefreshToken)
+		assert.Equal(t, "10.0.0.2", sessions[0].IP)
+	}
+	_, found, err := repo.Contains("token-1")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	// То же устройство у другого пользователя - отдельная сессия
+	assert.NoError(t, repo.SaveForDevice(newDeviceSession(2, "laptop", "token-3", now), 5))
+	var count int64
+	db.Model(&models.RefreshSession{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestSaveForDeviceWithoutFingerprint(t *testing.T) {
+	db := testutil.OpenDB(t, models.RefreshSession{})
+	repo := NewSessionRepo(db, testutil.Logger())
+	now := time.Now()
+
+	// Без отпечатка устройства каждый вход - отдельная сессия
+	first := newDeviceSession(1, "", "token-1", now)
+	assert.NoError(t, repo.SaveForDevice(first, 5))
+	second := newDeviceSession(1, "", "token-2", now.Add(time.Minute))
+	assert.NoError(t, repo.SaveForDevice(second, 5))
+	assert.NotEqual(t, first.ID, second.ID)
+
+	var count int64
+	db.Model(&models.RefreshSession{}).Where("user_id = ?", 1).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestSaveForDeviceEvictsOldestSessions(t *testing.T) {
+	db := testutil.OpenDB(t, models.RefreshSession{})
+	repo := NewSessionRepo(db, testutil.Logger())
+	now := time.Now()
+
+	for i, fingerprint := range []string{"phone", "laptop", "tablet"} {
+		assert.NoError(t, repo.SaveForDevice(newDeviceSession(1, fingerprint, "token-"+fingerprint, now.Add(time.Duration(i)*time.Minute)), 3))
+	}
+	assert.NoError(t, repo.SaveForDevice(newDeviceSession(2, "desktop", "token-other", now), 3))
+
+	// Четвертое устройство вытесняет самую старую сессию пользователя
+	assert.NoError(t, repo.SaveForDevice(newDeviceSession(1, "desktop", "token-desktop", now.Add(time.Hour)), 3))
+	sessions, err := repo.FindAllByUserID("1")
+	assert.NoError(t, err)
+	fingerprints := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		fingerprints = append(fingerprints, session.Fingerprint)
+	}
+	assert.ElementsMatch(t, []string{"laptop", "tablet", "desktop"}, fingerprints)
+
+	// Сессии других пользователей не затрагиваются
+	_, found, err := repo.Contains("token-other")
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	// Без ограничения сессии не вытесняются
+	assert.NoError(t, repo.SaveForDevice(newDeviceSession(1, "tv", "token-tv", now.Add(2*time.Hour)), 0))
+	var count int64
+	db.Model(&models.RefreshSession{}).Where("user_id = ?", 1).Count(&count)
+	assert.Equal(t, int64(4), count)
+}
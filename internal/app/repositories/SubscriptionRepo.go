@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"petition_api/internal/app/models"
+)
+
+type SubscriptionRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewSubscriptionRepository(db *gorm.DB, logger *logrus.Logger) SubscriptionRepository {
+	return SubscriptionRepository{
+		DB:     db,
+		logger: logger,
+	}
+}
+
+// Create подписывает пользователя на петицию. Повторная подписка ничего не меняет
+func (r *SubscriptionRepository) Create(userID uint, petitionID uint) error {
+	subscription := models.PetitionSubscription{UserID: userID, PetitionID: petitionID}
+	if err := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&subscription).Error; err != nil {
+		r.logger.Error("Error creating subscription:", err)
+		return err
+	}
+	return nil
+}
+
+// Delete отписывает пользователя от петиции
+func (r *SubscriptionRepository) Delete(userID uint, petitionID uint) error {
+	return r.DB.Where("user_id = ? AND petition_id = ?", userID, petitionID).
+		Delete(&models.PetitionSubscription{}).Error
+}
+
+// Exists подписан ли пользователь на петицию
+func (r *SubscriptionRepository) Exists(userID uint, petitionID uint) (bool, error) {
+	var count int64
+	if err := r.DB.Model(&models.PetitionSubscription{}).
+		Where("user_id = ? AND petition_id = ?", userID, petitionID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetByUserID возвращает подписки пользователя, новые первыми
+func (r *SubscriptionRepository) GetByUserID(userID uint) ([]models.PetitionSubscription, error) {
+	subscriptions := make([]models.PetitionSubscription, 0)
+	if err := r.DB.Where("user_id = ?", userID).Order("id DESC").Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// GetFollowerIDs возвращает всех, кто следит за петицией: автора, подписавших и подписчиков, без повторов
+func (r *SubscriptionRepository) GetFollowerIDs(petitionID uint) ([]uint, error) {
+	var ids []uint
+	err := r.DB.Raw(`SELECT user_id FROM petition_subscriptions WHERE petition_id = ?
+		UNION SELECT user_id FROM votes WHERE petition_id = ? AND deleted_at IS NULL
+		UNION SELECT user_id FROM petitions WHERE id = ? AND deleted_at IS NULL`,
+		petitionID, petitionID, petitionID).Scan(&ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// DeleteAllByUserIDTx удаляет подписки пользователя в рамках транзакции
+func (r *SubscriptionRepository) DeleteAllByUserIDTx(tx *gorm.DB, userID uint) error {
+	return tx.Where("user_id = ?", userID).Delete(&models.PetitionSubscription{}).Error
+}
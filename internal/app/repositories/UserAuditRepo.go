@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+)
+
+type UserAuditRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewUserAuditRepository(db *gorm.DB, logger *logrus.Logger) UserAuditRepository {
+	return UserAuditRepository{
+		DB:     db,
+		logger: logger,
+	}
+}
+
+// Create сохраняет запись аудита
+func (r *UserAuditRepository) Create(entry *models.UserAuditLog) error {
+	if err := r.DB.Create(entry).Error; err != nil {
+		r.logger.Error("Error creating audit log entry:", err)
+		return err
+	}
+	return nil
+}
+
+// CreateTx сохраняет запись аудита в рамках транзакции
+func (r *UserAuditRepository) CreateTx(tx *gorm.DB, entry *models.UserAuditLog) error {
+	return tx.Create(entry).Error
+}
+
+// GetByTargetUserID возвращает историю изменений аккаунта пользователя по страницам, новые записи первыми
+func (r *UserAuditRepository) GetByTargetUserID(userID uint, page int, pageSize int) ([]models.UserAuditLog, error) {
+	var entries []models.UserAuditLog
+	offset := (page - 1) * pageSize
+	if err := r.DB.Where("target_user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
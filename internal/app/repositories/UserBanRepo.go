@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+	"time"
+)
+
+type UserBanRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewUserBanRepository(db *gorm.DB, logger *logrus.Logger) UserBanRepository {
+	return UserBanRepository{
+		DB:     db,
+		logger: logger,
+	}
+}
+
+// CreateTx сохраняет блокировку в рамках транзакции
+func (r *UserBanRepository) CreateTx(tx *gorm.DB, ban *models.UserBan) error {
+	return tx.Create(ban).Error
+}
+
+// GetActiveByUserID возвращает действующую блокировку пользователя или nil, если ее нет
+func (r *UserBanRepository) GetActiveByUserID(userID uint, now time.Time) (*models.UserBan, error) {
+	// Find вместо First, чтобы отсутствие блокировки не считалось ошибкой и не попадало в лог на каждый запрос
+	var bans []models.UserBan
+	err := r.DB.Where("user_id = ? AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+		Order("id DESC").
+		Limit(1).
+		Find(&bans).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(bans) == 0 {
+		return nil, nil
+	}
+	return &bans[0], nil
+}
+
+// GetAllByUserID возвращает все блокировки пользователя, новые первыми
+func (r *UserBanRepository) GetAllByUserID(userID uint) ([]models.UserBan, error) {
+	var bans []models.UserBan
+	if err := r.DB.Where("user_id = ?", userID).Order("id DESC").Find(&bans).Error; err != nil {
+		return nil, err
+	}
+	return bans, nil
+}
+
+// LiftActiveTx снимает все действующие блокировки пользователя в рамках транзакции и возвращает их число
+func (r *UserBanRepository) LiftActiveTx(tx *gorm.DB, userID uint, liftedByID uint, now time.Time) (int64, error) {
+	result := tx.Model(&models.UserBan{}).
+		Where("user_id = ? AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+		Updates(map[string]interface{}{
+			"lifted_at":    now,
+			"lifted_by_id": liftedByID,
+		})
+	return result.RowsAffected, result.Error
+}
@@ -2,10 +2,13 @@ package repository
 
 import (
 	"errors"
+	"fmt"
 	"github.com/go-sql-driver/mysql"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 	"petition_api/internal/app/models"
+	"strings"
+	"time"
 )
 
 type UserRepository struct {
@@ -36,15 +39,70 @@ func (r *UserRepository) Create(user *models.UserModel) (uint, error) {
 	return user.ID, nil
 }
 
-func (r *UserRepository) GetAll() ([]models.UserModel, error) {
-	var users []models.UserModel
-	if err := r.DB.Find(&users).Error; err != nil {
-		return nil, err
+// likeEscape Символ экранирования в шаблонах LIKE. Передается параметром, потому что MySQL и SQLite
+// по-разному разбирают обратную косую черту в строковых литералах
+const likeEscape = `\`
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likeContains Шаблон LIKE для поиска подстроки. %, _ и \ во вводе ищутся как обычные символы
+func likeContains(value string) string {
+	return "%" + likeEscaper.Replace(value) + "%"
+}
+
+// Search возвращает страницу пользователей по фильтру и общее число найденных.
+// Если никого не нашлось, возвращается пустой список без ошибки
+func (r *UserRepository) Search(filter models.UserSearchFilter, now time.Time) ([]models.UserModel, int64, error) {
+	query := r.DB.Model(&models.UserModel{})
+	if filter.Query != "" {
+		like := likeContains(filter.Query)
+		query = query.Where("login LIKE ? ESCAPE ? OR email LIKE ? ESCAPE ? OR first_name LIKE ? ESCAPE ? OR last_name LIKE ? ESCAPE ?",
+			like, likeEscape, like, likeEscape, like, likeEscape, like, likeEscape)
+	}
+	if filter.Login != "" {
+		query = query.Where("login LIKE ? ESCAPE ?", likeContains(filter.Login), likeEscape)
+	}
+	if filter.Email != "" {
+		query = query.Where("email LIKE ? ESCAPE ?", likeContains(filter.Email), likeEscape)
+	}
+	if filter.Name != "" {
+		like := likeContains(filter.Name)
+		query = query.Where("first_name LIKE ? ESCAPE ? OR last_name LIKE ? ESCAPE ?", like, likeEscape, like, likeEscape)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.RegisteredFrom != nil {
+		query = query.Where("created_at >= ?", *filter.RegisteredFrom)
+	}
+	if filter.RegisteredTo != nil {
+		// Дата "по" включительно
+		query = query.Where("created_at < ?", filter.RegisteredTo.AddDate(0, 0, 1))
+	}
+	if filter.Banned != nil {
+		activeBans := r.DB.Model(&models.UserBan{}).Select("user_id").
+			Where("lifted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", now)
+		if *filter.Banned {
+			query = query.Where("id IN (?)", activeBans)
+		} else {
+			query = query.Where("id NOT IN (?)", activeBans)
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
 	}
-	if len(users) == 0 {
-		return nil, errors.New("no users found")
+
+	users := make([]models.UserModel, 0)
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.Order("id ASC").Offset(offset).Limit(filter.PageSize).Find(&users).Error; err != nil {
+		return nil, 0, err
 	}
-	return users, nil
+	return users, total, nil
 }
 
 // GetByID возвращает пользователя из базы данных по его ID
@@ -60,6 +118,21 @@ func (r *UserRepository) GetByID(id uint) (*models.UserModel, error) {
 	return &user, nil
 }
 
+// GetAccountByID возвращает статус и роль аккаунта пользователя. found = false, если пользователя нет или он удален
+func (r *UserRepository) GetAccountByID(id uint) (status string, role string, found bool, err error) {
+	var accounts []struct {
+		Status string
+		Role   string
+	}
+	if err := r.DB.Model(&models.UserModel{}).Select("status", "role").Where("id = ?", id).Limit(1).Find(&accounts).Error; err != nil {
+		return "", "", false, err
+	}
+	if len(accounts) == 0 {
+		return "", "", false, nil
+	}
+	return accounts[0].Status, accounts[0].Role, true, nil
+}
+
 func (r *UserRepository) GetPasswordByLogin(login string) (*models.UserModel, error) {
 	var user *models.UserModel
 	result := r.DB.Where("login = ?", login).First(&user)
@@ -81,6 +154,11 @@ func (r *UserRepository) Update(user *models.UserModel) error {
 	return nil
 }
 
+// UpdateTx обновляет информацию о пользователе в рамках транзакции
+func (r *UserRepository) UpdateTx(tx *gorm.DB, user *models.UserModel) error {
+	return tx.Save(user).Error
+}
+
 // DeleteByID удаляет пользователя из базы данных по его ID
 func (r *UserRepository) DeleteByID(id uint) error {
 	result := r.DB.Delete(&models.UserModel{}, id)
@@ -89,3 +167,118 @@ func (r *UserRepository) DeleteByID(id uint) error {
 	}
 	return nil
 }
+
+// UpdateAvatarKey меняет ключ аватара пользователя
+func (r *UserRepository) UpdateAvatarKey(id uint, avatarKey string) error {
+	return r.DB.Model(&models.UserModel{}).Where("id = ?", id).Update("avatar_key", avatarKey).Error
+}
+
+// UpdateLanguage меняет язык писем пользователя
+func (r *UserRepository) UpdateLanguage(id uint, language string) error {
+	return r.DB.Model(&models.UserModel{}).Where("id = ?", id).Update("language", language).Error
+}
+
+// MarkEmailVerified отмечает email подтвержденным, если за это время пользователь его не сменил
+func (r *UserRepository) MarkEmailVerified(id uint, email string, at time.Time) error {
+	return r.DB.Model(&models.UserModel{}).Where("id = ? AND email = ?", id, email).Update("email_verified_at", at).Error
+}
+
+// GetAvatarKeys возвращает ключи аватаров пользователей с указанными ID. Пользователей без аватара в ответе нет
+func (r *UserRepository) GetAvatarKeys(ids []uint) (map[uint]string, error) {
+	keys := make(map[uint]string)
+	if len(ids) == 0 {
+		return keys, nil
+	}
+	var rows []struct {
+		ID        uint
+		AvatarKey string
+	}
+	if err := r.DB.Model(&models.UserModel{}).Select("id, avatar_key").
+		Where("id IN ? AND avatar_key <> ''", ids).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		keys[row.ID] = row.AvatarKey
+	}
+	return keys, nil
+}
+
+// EnsureDeletedUser возвращает служебного пользователя для контента удаленных аккаунтов, создавая его при необходимости.
+// Пользователь ищется по флагу placeholder: обычный аккаунт с логином deleted_user им не станет
+func (r *UserRepository) EnsureDeletedUser() (*models.UserModel, error) {
+	var user models.UserModel
+	err := r.DB.Where("placeholder = ?", true).First(&user).Error
+	if err == nil {
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Служебный пользователь, созданный до появления флага, узнается по пустому паролю: у аккаунтов всегда bcrypt хэш
+	err = r.DB.Where("login = ? AND password = ''", models.DeletedUserLogin).First(&user).Error
+	if err == nil {
+		if err := r.DB.Model(&user).Update("placeholder", true).Error; err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Пустой пароль не совпадет ни с одним bcrypt хэшем, поэтому войти под этим пользователем нельзя
+	user = models.UserModel{
+		Login:       models.DeletedUserLogin,
+		Role:        models.RoleUser,
+		BirthDate:   time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC),
+		Status:      models.StatusPassive,
+		Placeholder: true,
+	}
+	if err := r.DB.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ScheduleDeletion назначает удаление аккаунта на время at
+func (r *UserRepository) ScheduleDeletion(id uint, at time.Time) error {
+	return r.DB.Model(&models.UserModel{}).Where("id = ?", id).Update("deletion_scheduled_at", at).Error
+}
+
+// CancelDeletion отменяет запрошенное удаление аккаунта
+func (r *UserRepository) CancelDeletion(id uint) error {
+	return r.DB.Model(&models.UserModel{}).Where("id = ?", id).Update("deletion_scheduled_at", nil).Error
+}
+
+// GetDueForDeletion возвращает ID пользователей, у которых истек срок до удаления
+func (r *UserRepository) GetDueForDeletion(now time.Time) ([]uint, error) {
+	var ids []uint
+	if err := r.DB.Model(&models.UserModel{}).
+		Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", now).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// AnonymiseTx стирает личные данные пользователя и мягко удаляет его в рамках транзакции
+func (r *UserRepository) AnonymiseTx(tx *gorm.DB, id uint) error {
+	err := tx.Model(&models.UserModel{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"login":                 fmt.Sprintf("deleted_%d", id),
+		"password":              "",
+		"first_name":            "",
+		"last_name":             "",
+		"email":                 "",
+		"birth_date":            time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC),
+		"status":                models.StatusPassive,
+		"avatar_key":            "",
+		"region":                "",
+		"email_verified_at":     nil,
+		"deletion_scheduled_at": nil,
+	}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Delete(&models.UserModel{}, id).Error
+}
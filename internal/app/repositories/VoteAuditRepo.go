@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"petition_api/internal/app/models"
+	"time"
+)
+
+type VoteAuditRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewVoteAuditRepository(db *gorm.DB, logger *logrus.Logger) VoteAuditRepository {
+	return VoteAuditRepository{
+		DB:     db,
+		logger: logger,
+	}
+}
+
+// AppendTx добавляет запись о голосе vote в конец цепочки его петиции в рамках транзакции.
+// Строка петиции блокируется до конца транзакции, чтобы параллельные записи не ссылались на одну и ту же предыдущую
+func (r *VoteAuditRepository) AppendTx(tx *gorm.DB, vote *models.Vote, action string, at time.Time) (*models.VoteAuditEntry, error) {
+	petitionID := vote.PetitionID
+	var locked []uint
+	if err := tx.Unscoped().Model(&models.Petition{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", petitionID).Pluck("id", &locked).Error; err != nil {
+		return nil, err
+	}
+
+	entry := models.VoteAuditEntry{
+		PetitionID: petitionID,
+		Seq:        1,
+		VoteID:     vote.ID,
+		UserID:     vote.UserID,
+		Action:     action,
+		CreatedAt:  at.Truncate(time.Second),
+		PrevHash:   models.VoteAuditGenesisHash,
+	}
+	var last models.VoteAuditEntry
+	err := tx.Where("petition_id = ?", petitionID).Order("seq DESC").Take(&last).Error
+	switch {
+	case err == nil:
+		entry.Seq = last.Seq + 1
+		entry.PrevHash = last.Hash
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+	entry.Hash = entry.ComputeHash()
+
+	if err := tx.Create(&entry).Error; err != nil {
+		r.logger.Error("Error appending vote audit entry:", err)
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetFirst возвращает первую запись цепочки петиции или nil, если записей нет
+func (r *VoteAuditRepository) GetFirst(petitionID uint) (*models.VoteAuditEntry, error) {
+	var entry models.VoteAuditEntry
+	err := r.DB.Where("petition_id = ?", petitionID).Order("seq").Take(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetHead возвращает последнюю запись цепочки петиции или nil, если записей нет
+func (r *VoteAuditRepository) GetHead(petitionID uint) (*models.VoteAuditEntry, error) {
+	var entry models.VoteAuditEntry
+	err := r.DB.Where("petition_id = ?", petitionID).Order("seq DESC").Take(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// EachEntry перебирает записи цепочки петиции по порядку. Строки читаются курсором. Ошибка из fn прерывает перебор
+func (r *VoteAuditRepository) EachEntry(petitionID uint, fn func(models.VoteAuditEntry) error) error {
+	rows, err := r.DB.Model(&models.VoteAuditEntry{}).Where("petition_id = ?", petitionID).Order("seq").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var entry models.VoteAuditEntry
+		if err := r.DB.ScanRows(rows, &entry); err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GetPetitionIDs возвращает петиции, у которых есть записи в журнале
+func (r *VoteAuditRepository) GetPetitionIDs() ([]uint, error) {
+	var ids []uint
+	if err := r.DB.Model(&models.VoteAuditEntry{}).Distinct("petition_id").Order("petition_id").Pluck("petition_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
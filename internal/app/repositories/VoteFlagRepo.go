@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"petition_api/internal/app/models"
+	"time"
+)
+
+type VoteFlagRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewVoteFlagRepository(db *gorm.DB, logger *logrus.Logger) VoteFlagRepository {
+	return VoteFlagRepository{
+		DB:     db,
+		logger: logger,
+	}
+}
+
+// CreateMany сохраняет отметки, пропуская голоса, уже отмеченные по той же причине. Возвращает число новых отметок
+func (r *VoteFlagRepository) CreateMany(flags []models.VoteFlag) (int64, error) {
+	if len(flags) == 0 {
+		return 0, nil
+	}
+	result := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&flags)
+	if result.Error != nil {
+		r.logger.Error("Error creating vote flags:", result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// GetByID возвращает отметку по идентификатору
+func (r *VoteFlagRepository) GetByID(id uint) (*models.VoteFlag, error) {
+	var flag models.VoteFlag
+	if err := r.DB.First(&flag, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("vote flag not found")
+		}
+		return nil, err
+	}
+	return &flag, nil
+}
+
+// Search возвращает отметки по фильтру, новые первыми
+func (r *VoteFlagRepository) Search(filter models.VoteFlagFilter) ([]models.VoteFlag, int64, error) {
+	query := r.DB.Model(&models.VoteFlag{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.PetitionID != 0 {
+		query = query.Where("petition_id = ?", filter.PetitionID)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var flags []models.VoteFlag
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(filter.PageSize).Find(&flags).Error; err != nil {
+		return nil, 0, err
+	}
+	return flags, total, nil
+}
+
+// GetInvalidatedByUserID возвращает отметки, по которым админ признал голоса пользователя недействительными
+func (r *VoteFlagRepository) GetInvalidatedByUserID(userID uint) ([]models.VoteFlag, error) {
+	var flags []models.VoteFlag
+	if err := r.DB.Where("user_id = ? AND status = ?", userID, models.VoteFlagInvalidated).Order("id").Find(&flags).Error; err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
+// HasInvalidated был ли голос пользователя за петицию признан недействительным
+func (r *VoteFlagRepository) HasInvalidated(userID uint, petitionID uint) (bool, error) {
+	var count int64
+	if err := r.DB.Model(&models.VoteFlag{}).
+		Where("user_id = ? AND petition_id = ? AND status = ?", userID, petitionID, models.VoteFlagInvalidated).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ResolveByVoteID закрывает все непроверенные отметки голоса со статусом status
+func (r *VoteFlagRepository) ResolveByVoteID(voteID uint, status string, reviewerID uint, at time.Time) error {
+	return r.DB.Model(&models.VoteFlag{}).
+		Where("vote_id = ? AND status = ?", voteID, models.VoteFlagPending).
+		Updates(map[string]interface{}{"status": status, "reviewed_by": reviewerID, "reviewed_at": at}).Error
+}
@@ -6,11 +6,14 @@ import (
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 	"petition_api/internal/app/models"
+	"time"
 )
 
 type VoteRepository struct {
-	DB     *gorm.DB
-	logger *logrus.Logger
+	DB *gorm.DB
+	// ExcludePassiveVoters Не учитывать в счетчиках голоса отключенных (Passive) пользователей
+	ExcludePassiveVoters bool
+	logger               *logrus.Logger
 }
 
 func NewVoteRepository(db *gorm.DB, logger *logrus.Logger) VoteRepository {
@@ -22,18 +25,26 @@ func NewVoteRepository(db *gorm.DB, logger *logrus.Logger) VoteRepository {
 
 // Create создает новый голос в базе данных
 func (r *VoteRepository) Create(vote *models.Vote) (uint, error) {
-	if err := r.DB.Create(vote).Error; err != nil {
+	if err := r.CreateTx(r.DB, vote); err != nil {
+		return 0, err
+	}
+	return vote.ID, nil
+}
+
+// CreateTx создает новый голос в рамках транзакции
+func (r *VoteRepository) CreateTx(tx *gorm.DB, vote *models.Vote) error {
+	if err := tx.Create(vote).Error; err != nil {
 		var mysqlError *mysql.MySQLError
 		if errors.As(err, &mysqlError) {
 			if mysqlError.Number == 1062 {
-				return 0, errors.New("duplicate vote")
+				return errors.New("duplicate vote")
 			}
 		}
 		r.logger.Error("Error creating vote:", err)
-		return 0, err
+		return err
 	}
 	r.logger.Info("Vote created. ID: ", vote.ID)
-	return vote.ID, nil
+	return nil
 }
 
 // GetAll возвращает список всех голосов из базы данных по страницам
@@ -47,18 +58,96 @@ func (r *VoteRepository) GetAll(page int, pageSize int) ([]models.Vote, error) {
 }
 
 // GetCountVoteByPetitionID возвращает число голосов по идентификатору петиции
+// вместе с обезличенными голосами удаленных аккаунтов
 func (r *VoteRepository) GetCountVoteByPetitionID(petitionID uint) (int64, error) {
+	return r.GetCountVoteByPetitionIDTx(r.DB, petitionID)
+}
+
+// GetCountVoteByPetitionIDTx возвращает число голосов петиции в рамках транзакции
+func (r *VoteRepository) GetCountVoteByPetitionIDTx(tx *gorm.DB, petitionID uint) (int64, error) {
+	var count int64
+	query := tx.Model(&models.Vote{}).Where("votes.petition_id = ?", petitionID)
+	if r.ExcludePassiveVoters {
+		query = query.Joins("JOIN user_models ON user_models.id = votes.user_id AND user_models.status <> ?", models.StatusPassive)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return 0, err
+	}
+	var anonymous []int64
+	if err := tx.Model(&models.Petition{}).Where("id = ?", petitionID).Pluck("anonymous_votes", &anonymous).Error; err != nil {
+		return 0, err
+	}
+	if len(anonymous) > 0 {
+		count += anonymous[0]
+	}
+	return count, nil
+}
+
+// HasVotes есть ли у петиции хотя бы одна подпись, включая обезличенные и голоса отключенных пользователей
+func (r *VoteRepository) HasVotes(petitionID uint) (bool, error) {
+	return r.HasVotesTx(r.DB, petitionID)
+}
+
+// HasVotesTx есть ли у петиции хотя бы одна подпись, в рамках транзакции
+func (r *VoteRepository) HasVotesTx(tx *gorm.DB, petitionID uint) (bool, error) {
+	var count int64
+	if err := tx.Model(&models.Vote{}).Where("petition_id = ?", petitionID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+	var anonymous []int64
+	if err := tx.Model(&models.Petition{}).Where("id = ?", petitionID).Pluck("anonymous_votes", &anonymous).Error; err != nil {
+		return false, err
+	}
+	return len(anonymous) > 0 && anonymous[0] > 0, nil
+}
+
+// AnonymiseByUserIDTx превращает голоса пользователя в анонимные счетчики петиций и удаляет сами голоса
+func (r *VoteRepository) AnonymiseByUserIDTx(tx *gorm.DB, userID uint) error {
+	var petitionIDs []uint
+	if err := tx.Model(&models.Vote{}).Where("user_id = ?", userID).Pluck("petition_id", &petitionIDs).Error; err != nil {
+		return err
+	}
+	if len(petitionIDs) == 0 {
+		return nil
+	}
+	// На каждую петицию у пользователя не больше одного голоса (уникальный индекс)
+	if err := tx.Model(&models.Petition{}).Where("id IN ?", petitionIDs).
+		UpdateColumn("anonymous_votes", gorm.Expr("anonymous_votes + 1")).Error; err != nil {
+		return err
+	}
+	// Отметки о подозрительных голосах хранят IP и UA, вместе с голосами они больше не нужны
+	if err := tx.Where("user_id = ?", userID).Delete(&models.VoteFlag{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("user_id = ?", userID).Delete(&models.Vote{}).Error
+}
+
+// GetAllByUserID возвращает все голоса пользователя
+func (r *VoteRepository) GetAllByUserID(userID uint) ([]models.Vote, error) {
+	var votes []models.Vote
+	if err := r.DB.Where("user_id = ?", userID).Order("id").Find(&votes).Error; err != nil {
+		return nil, err
+	}
+	return votes, nil
+}
+
+// CountByUserID возвращает число подписей пользователя
+func (r *VoteRepository) CountByUserID(userID uint) (int64, error) {
 	var count int64
-	if err := r.DB.Model(&models.Vote{}).Where("petition_id = ?", petitionID).Count(&count).Error; err != nil {
+	if err := r.DB.Model(&models.Vote{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
-// VoteExist возвращает число голосов по идентификатору петиции
+// VoteExist проверяет, подписал ли пользователь петицию. Строки с deleted_at тоже учитываются:
+// они занимают уникальный индекс idx_user_petition
 func (r *VoteRepository) VoteExist(petitionID uint, userID uint) (bool, error) {
 	var count int64
-	if err := r.DB.Model(&models.Vote{}).Where("user_id = ? AND petition_id = ?", userID, petitionID).Count(&count).Error; err != nil {
+	if err := r.DB.Unscoped().Model(&models.Vote{}).Where("user_id = ? AND petition_id = ?", userID, petitionID).Count(&count).Error; err != nil {
 		return false, err
 	}
 	if count == 0 {
@@ -82,6 +171,23 @@ func (r *VoteRepository) GetByPetitionID(petitionID uint) ([]models.Vote, error)
 	return votes, nil
 }
 
+// GetByUserIDAndPetitionID возвращает голос пользователя за петицию
+func (r *VoteRepository) GetByUserIDAndPetitionID(userID uint, petitionID uint) (*models.Vote, error) {
+	var vote models.Vote
+	if err := r.DB.Where("user_id = ? AND petition_id = ?", userID, petitionID).First(&vote).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("vote not found")
+		}
+		return nil, err
+	}
+	return &vote, nil
+}
+
+// UpdateReason меняет причину подписи
+func (r *VoteRepository) UpdateReason(id uint, reason string) error {
+	return r.DB.Model(&models.Vote{}).Where("id = ?", id).Update("reason", reason).Error
+}
+
 // GetByID возвращает голос по его идентификатору
 func (r *VoteRepository) GetByID(id uint) (*models.Vote, error) {
 	var vote models.Vote
@@ -104,11 +210,203 @@ func (r *VoteRepository) DeleteByID(id uint) error {
 	return nil
 }
 
+// GetByIDTx возвращает голос по идентификатору в рамках транзакции
+func (r *VoteRepository) GetByIDTx(tx *gorm.DB, id uint) (*models.Vote, error) {
+	var vote models.Vote
+	if err := tx.First(&vote, id).Error; err != nil {
+		return nil, err
+	}
+	return &vote, nil
+}
+
+// DeleteByIDTx удаляет голос в рамках транзакции
+func (r *VoteRepository) DeleteByIDTx(tx *gorm.DB, id uint) error {
+	return tx.Unscoped().Delete(&models.Vote{}, id).Error
+}
+
+// GetAllByUserIDTx возвращает все голоса пользователя в рамках транзакции
+func (r *VoteRepository) GetAllByUserIDTx(tx *gorm.DB, userID uint) ([]models.Vote, error) {
+	var votes []models.Vote
+	if err := tx.Where("user_id = ?", userID).Order("id").Find(&votes).Error; err != nil {
+		return nil, err
+	}
+	return votes, nil
+}
+
+// GetByUserIDAndPetitionIDTx возвращает голос пользователя за петицию в рамках транзакции, включая строку с deleted_at,
+// чтобы такой голос можно было отозвать
+func (r *VoteRepository) GetByUserIDAndPetitionIDTx(tx *gorm.DB, userID uint, petitionID uint) (*models.Vote, error) {
+	var vote models.Vote
+	if err := tx.Unscoped().Where("user_id = ? AND petition_id = ?", userID, petitionID).First(&vote).Error; err != nil {
+		return nil, err
+	}
+	return &vote, nil
+}
+
+// GetVotersByPetitionID возвращает все голоса петиции как голос -> пользователь, включая голоса отключенных
+// пользователей и строки с deleted_at: голоса удаляются только насовсем, любая такая строка должна быть видна сверке
+func (r *VoteRepository) GetVotersByPetitionID(petitionID uint) (map[uint]uint, error) {
+	var votes []models.Vote
+	if err := r.DB.Unscoped().Select("id", "user_id").Where("petition_id = ?", petitionID).Find(&votes).Error; err != nil {
+		return nil, err
+	}
+	voters := make(map[uint]uint, len(votes))
+	for _, vote := range votes {
+		voters[vote.ID] = vote.UserID
+	}
+	return voters, nil
+}
+
+// GetPetitionIDs возвращает петиции, у которых есть голоса
+func (r *VoteRepository) GetPetitionIDs() ([]uint, error) {
+	var ids []uint
+	if err := r.DB.Unscoped().Model(&models.Vote{}).Distinct("petition_id").Order("petition_id").Pluck("petition_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// GetByIDs возвращает голоса с указанными идентификаторами
+func (r *VoteRepository) GetByIDs(ids []uint) ([]models.Vote, error) {
+	var votes []models.Vote
+	if len(ids) == 0 {
+		return votes, nil
+	}
+	if err := r.DB.Where("id IN ?", ids).Order("id").Find(&votes).Error; err != nil {
+		return nil, err
+	}
+	return votes, nil
+}
+
+// GetIDsCreatedSince возвращает из ids голоса, поданные не раньше since, включая строки с deleted_at
+func (r *VoteRepository) GetIDsCreatedSince(ids []uint, since time.Time) ([]uint, error) {
+	result := make([]uint, 0)
+	if len(ids) == 0 {
+		return result, nil
+	}
+	if err := r.DB.Unscoped().Model(&models.Vote{}).Where("id IN ? AND created_at >= ?", ids, since).Order("id").Pluck("id", &result).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetRecentByIP возвращает голоса за петицию с адреса ip, поданные не раньше since
+func (r *VoteRepository) GetRecentByIP(petitionID uint, ip string, since time.Time) ([]models.Vote, error) {
+	var votes []models.Vote
+	if err := r.DB.Where("petition_id = ? AND ip = ? AND created_at >= ?", petitionID, ip, since).
+		Order("id").Find(&votes).Error; err != nil {
+		return nil, err
+	}
+	return votes, nil
+}
+
+// GetRecentBySubnet возвращает голоса за петицию из подсети subnet, поданные не раньше since
+func (r *VoteRepository) GetRecentBySubnet(petitionID uint, subnet string, since time.Time) ([]models.Vote, error) {
+	var votes []models.Vote
+	if err := r.DB.Where("petition_id = ? AND subnet = ? AND created_at >= ?", petitionID, subnet, since).
+		Order("id").Find(&votes).Error; err != nil {
+		return nil, err
+	}
+	return votes, nil
+}
+
+// GetRecentFromAccountsSince возвращает голоса за петицию, поданные не раньше since
+// пользователями, зарегистрированными не раньше accountsSince
+func (r *VoteRepository) GetRecentFromAccountsSince(petitionID uint, since time.Time, accountsSince time.Time) ([]models.FreshAccountVote, error) {
+	var votes []models.FreshAccountVote
+	err := r.DB.Model(&models.Vote{}).
+		Select("votes.id AS vote_id, votes.user_id, votes.created_at AS signed_at, user_models.created_at AS account_created_at").
+		Joins("JOIN user_models ON user_models.id = votes.user_id").
+		Where("votes.petition_id = ? AND votes.created_at >= ? AND user_models.created_at >= ?", petitionID, since, accountsSince).
+		Order("votes.id").Scan(&votes).Error
+	if err != nil {
+		return nil, err
+	}
+	return votes, nil
+}
+
 // DeleteByUserIDAndPetitionID удаляет голос из базы данных по идентификатору пользователя и петиции
 func (r *VoteRepository) DeleteByUserIDAndPetitionID(userID uint, petitionID uint) error {
-	result := r.DB.Unscoped().Where("user_id = ? AND petition_id = ?", userID, petitionID).Delete(&models.Vote{})
+	_, err := r.DeleteByUserIDAndPetitionIDTx(r.DB, userID, petitionID)
+	return err
+}
+
+// DeleteByUserIDAndPetitionIDTx удаляет голос в рамках транзакции. Возвращает false, если голоса не было
+func (r *VoteRepository) DeleteByUserIDAndPetitionIDTx(tx *gorm.DB, userID uint, petitionID uint) (bool, error) {
+	result := tx.Unscoped().Where("user_id = ? AND petition_id = ?", userID, petitionID).Delete(&models.Vote{})
 	if result.Error != nil {
-		return result.Error
+		return false, result.Error
 	}
-	return nil
+	return result.RowsAffected > 0, nil
+}
+
+// signatureColumns Колонки models.Signature. Логин берется из аккаунта: в votes.login он мог устареть после смены логина
+const signatureColumns = "votes.id AS vote_id, user_models.login, user_models.first_name, user_models.last_name, votes.visibility, votes.reason, votes.created_at AS signed_at"
+
+// signatures запрос подписей петиции вместе с подписантами
+func (r *VoteRepository) signatures(petitionID uint) *gorm.DB {
+	query := r.DB.Model(&models.Vote{}).
+		Joins("JOIN user_models ON user_models.id = votes.user_id").
+		Where("votes.petition_id = ?", petitionID)
+	if r.ExcludePassiveVoters {
+		query = query.Where("user_models.status <> ?", models.StatusPassive)
+	}
+	return query
+}
+
+// EachSignature перебирает подписи петиции по порядку вместе с именами подписантов.
+// Строки читаются курсором, весь список в память не загружается. Ошибка из fn прерывает перебор
+func (r *VoteRepository) EachSignature(petitionID uint, fn func(models.Signature) error) error {
+	rows, err := r.signatures(petitionID).Select(signatureColumns).Order("votes.id").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var signature models.Signature
+		if err := r.DB.ScanRows(rows, &signature); err != nil {
+			return err
+		}
+		if err := fn(signature); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GetListedSignatures возвращает подписи петиции для публичного списка по страницам, новые первыми.
+// Скрытые подписи не возвращаются и не входят в total
+func (r *VoteRepository) GetListedSignatures(petitionID uint, page int, pageSize int) ([]models.Signature, int64, error) {
+	return r.listedSignatures(r.signatures(petitionID), page, pageSize)
+}
+
+// GetReasons возвращает подписи с причинами из публичного списка по страницам, новые первыми
+func (r *VoteRepository) GetReasons(petitionID uint, page int, pageSize int) ([]models.Signature, int64, error) {
+	return r.listedSignatures(r.signatures(petitionID).Where("votes.reason <> ''"), page, pageSize)
+}
+
+func (r *VoteRepository) listedSignatures(query *gorm.DB, page int, pageSize int) ([]models.Signature, int64, error) {
+	query = query.Where("votes.visibility <> ?", models.VoteVisibilityHidden)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var signatures []models.Signature
+	offset := (page - 1) * pageSize
+	if err := query.Select(signatureColumns).Order("votes.id DESC").Offset(offset).Limit(pageSize).Scan(&signatures).Error; err != nil {
+		return nil, 0, err
+	}
+	return signatures, total, nil
+}
+
+// GetSignatureByVoteID возвращает подпись по идентификатору голоса
+func (r *VoteRepository) GetSignatureByVoteID(petitionID uint, voteID uint) (*models.Signature, error) {
+	var signatures []models.Signature
+	if err := r.signatures(petitionID).Select(signatureColumns).Where("votes.id = ?", voteID).Scan(&signatures).Error; err != nil {
+		return nil, err
+	}
+	if len(signatures) == 0 {
+		return nil, errors.New("signature not found")
+	}
+	return &signatures[0], nil
 }
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"github.com/stretchr/testify/assert"
+	"petition_api/internal/app/models"
+	"petition_api/internal/app/testutil"
+	"testing"
+)
+
+func TestGetCountVoteExcludesPassiveVoters(t *testing.T) {
+	db := testutil.OpenDB(t, models.UserModel{}, models.Petition{}, models.Vote{})
+
+	petition := models.Petition{Title: "Парк", AnonymousVotes: 1}
+	if err := db.Create(&petition).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i, status := range []string{models.StatusActive, models.StatusActive, models.StatusPassive} {
+		user := models.UserModel{Login: string(rune('a' + i)), Role: models.RoleUser, Status: status}
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Create(&models.Vote{Login: user.Login, UserID: user.ID, PetitionID: petition.ID}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo := NewVoteRepository(db, testutil.Logger())
+	count, err := repo.GetCountVoteByPetitionID(petition.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+
+	repo.ExcludePassiveVoters = true
+	count, err = repo.GetCountVoteByPetitionID(petition.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestGetListedSignaturesHonoursVisibility(t *testing.T) {
+	db := testutil.OpenDB(t, models.UserModel{}, models.Petition{}, models.Vote{})
+
+	petition := models.Petition{Title: "Парк"}
+	if err := db.Create(&petition).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i, visibility := range []string{models.VoteVisibilityPublic, models.VoteVisibilityAnonymous, models.VoteVisibilityHidden, ""} {
+		user := models.UserModel{Login: string(rune('a' + i)), FirstName: "Айгерим", LastName: "Қасымова", Role: models.RoleUser, Status: models.StatusActive}
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatal(err)
+		}
+		// Логин в голосе устарел, в списке должен быть логин аккаунта
+		if err := db.Create(&models.Vote{Login: "old_" + user.Login, UserID: user.ID, PetitionID: petition.ID, Visibility: visibility}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo := NewVoteRepository(db, testutil.Logger())
+	signatures, total, err := repo.GetListedSignatures(petition.ID, 1, 10)
+	assert.NoError(t, err)
+	// Скрытая подпись не попадает в список, без выбора подпись анонимная
+	assert.Equal(t, int64(3), total)
+	if assert.Len(t, signatures, 3) {
+		assert.Equal(t, models.PublicSignature{Anonymous: true, SignedAt: signatures[0].SignedAt}, signatures[0].Public())
+		assert.True(t, signatures[1].Public().Anonymous)
+		public := signatures[2].Public()
+		assert.False(t, public.Anonymous)
+		assert.Equal(t, "a", public.Login)
+		assert.Equal(t, "Қасымова", public.LastName)
+	}
+
+	page, total, err := repo.GetListedSignatures(petition.ID, 2, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Len(t, page, 1)
+
+	// В выгрузке для адресата есть все подписи
+	var exported int
+	assert.NoError(t, repo.EachSignature(petition.ID, func(signature models.Signature) error {
+		exported++
+		assert.NotContains(t, signature.Login, "old_")
+		return nil
+	}))
+	assert.Equal(t, 4, exported)
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+	"time"
+)
+
+type WebhookDeliveryRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewWebhookDeliveryRepository(db *gorm.DB, logger *logrus.Logger) WebhookDeliveryRepository {
+	return WebhookDeliveryRepository{
+		DB:     db,
+		logger: logger,
+	}
+}
+
+// CreateBatchTx сохраняет доставки события в рамках транзакции
+func (r *WebhookDeliveryRepository) CreateBatchTx(tx *gorm.DB, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return tx.Create(&deliveries).Error
+}
+
+// GetDue возвращает ожидающие доставки, время попытки которых наступило, вместе с событием и вебхуком
+func (r *WebhookDeliveryRepository) GetDue(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	if err := r.DB.Preload("Event").Preload("Webhook").
+		Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+		Order("next_attempt_at").Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// Claim откладывает следующую попытку до until, чтобы доставку не отправили одновременно два экземпляра сервера.
+// Возвращает false, если доставку уже забрали
+func (r *WebhookDeliveryRepository) Claim(id uint, now time.Time, until time.Time) (bool, error) {
+	result := r.DB.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", id, models.WebhookDeliveryPending, now).
+		Update("next_attempt_at", until)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Update сохраняет результат попытки
+func (r *WebhookDeliveryRepository) Update(delivery *models.WebhookDelivery) error {
+	return r.DB.Omit("Webhook", "Event").Save(delivery).Error
+}
+
+// GetByIDAndWebhookID возвращает доставку вебхука. Доставки других вебхуков не находятся
+func (r *WebhookDeliveryRepository) GetByIDAndWebhookID(id uint, webhookID uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := r.DB.Where("id = ? AND webhook_id = ?", id, webhookID).First(&delivery).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// GetByWebhookID возвращает журнал доставок вебхука по страницам, новые первыми, и их общее число
+func (r *WebhookDeliveryRepository) GetByWebhookID(webhookID uint, status string, page int, pageSize int) ([]models.WebhookDelivery, int64, error) {
+	query := r.DB.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var deliveries []models.WebhookDelivery
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&deliveries).Error; err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+// DeleteFinishedBefore удаляет завершенные доставки, обновленные до cutoff
+func (r *WebhookDeliveryRepository) DeleteFinishedBefore(cutoff time.Time) (int64, error) {
+	result := r.DB.Where("status <> ? AND updated_at < ?", models.WebhookDeliveryPending, cutoff).
+		Delete(&models.WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
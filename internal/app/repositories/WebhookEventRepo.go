@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+	"time"
+)
+
+type WebhookEventRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewWebhookEventRepository(db *gorm.DB, logger *logrus.Logger) WebhookEventRepository {
+	return WebhookEventRepository{
+		DB:     db,
+		logger: logger,
+	}
+}
+
+// CreateTx кладет событие в исходящую очередь в той же транзакции, что и изменение, которое его вызвало.
+// Если транзакция откатится, событие не уйдет
+func (r *WebhookEventRepository) CreateTx(tx *gorm.DB, eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&models.WebhookEvent{Type: eventType, Payload: string(payload)}).Error
+}
+
+// GetUndispatched возвращает еще не разложенные по вебхукам события в порядке появления
+func (r *WebhookEventRepository) GetUndispatched(limit int) ([]models.WebhookEvent, error) {
+	var events []models.WebhookEvent
+	if err := r.DB.Where("dispatched_at IS NULL").Order("id").Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// MarkDispatchedTx отмечает событие разложенным. Возвращает false, если его уже забрал другой экземпляр сервера
+func (r *WebhookEventRepository) MarkDispatchedTx(tx *gorm.DB, id uint, at time.Time) (bool, error) {
+	result := tx.Model(&models.WebhookEvent{}).Where("id = ? AND dispatched_at IS NULL", id).Update("dispatched_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// DeleteDispatchedBefore удаляет разложенные до cutoff события, на которые не осталось доставок
+func (r *WebhookEventRepository) DeleteDispatchedBefore(cutoff time.Time) (int64, error) {
+	result := r.DB.Where("dispatched_at < ?", cutoff).
+		Where("NOT EXISTS (?)", r.DB.Model(&models.WebhookDelivery{}).Select("1").Where("webhook_deliveries.event_id = webhook_events.id")).
+		Delete(&models.WebhookEvent{})
+	return result.RowsAffected, result.Error
+}
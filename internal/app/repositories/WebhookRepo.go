@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+)
+
+type WebhookRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewWebhookRepository(db *gorm.DB, logger *logrus.Logger) WebhookRepository {
+	return WebhookRepository{
+		DB:     db,
+		logger: logger,
+	}
+}
+
+// Create сохраняет новый вебхук
+func (r *WebhookRepository) Create(webhook *models.Webhook) error {
+	if err := r.DB.Create(webhook).Error; err != nil {
+		r.logger.Error("Error creating webhook:", err)
+		return err
+	}
+	return nil
+}
+
+// GetByIDAndUserID возвращает вебхук пользователя. Чужие вебхуки не находятся
+func (r *WebhookRepository) GetByIDAndUserID(id uint, userID uint) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := r.DB.Where("id = ? AND user_id = ?", id, userID).First(&webhook).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// GetByUserID возвращает вебхуки пользователя
+func (r *WebhookRepository) GetByUserID(userID uint) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	if err := r.DB.Where("user_id = ?", userID).Order("id").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// CountByUserID возвращает число вебхуков пользователя
+func (r *WebhookRepository) CountByUserID(userID uint) (int64, error) {
+	var count int64
+	if err := r.DB.Model(&models.Webhook{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetActiveByEventTx возвращает включенные вебхуки, подписанные на событие, в рамках транзакции
+func (r *WebhookRepository) GetActiveByEventTx(tx *gorm.DB, eventType string) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	if err := tx.Where("active = ?", true).Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	subscribed := webhooks[:0]
+	for _, webhook := range webhooks {
+		if webhook.Subscribed(eventType) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+	return subscribed, nil
+}
+
+// Update сохраняет изменения вебхука
+func (r *WebhookRepository) Update(webhook *models.Webhook) error {
+	return r.DB.Save(webhook).Error
+}
+
+// DeleteByID удаляет вебхук вместе с журналом его доставок
+func (r *WebhookRepository) DeleteByID(id uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Webhook{}, id).Error
+	})
+}
+
+// DeleteAllByUserIDTx удаляет все вебхуки пользователя и их доставки в рамках транзакции
+func (r *WebhookRepository) DeleteAllByUserIDTx(tx *gorm.DB, userID uint) error {
+	if err := tx.Where("webhook_id IN (?)", tx.Model(&models.Webhook{}).Select("id").Where("user_id = ?", userID)).
+		Delete(&models.WebhookDelivery{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ?", userID).Delete(&models.Webhook{}).Error
+}
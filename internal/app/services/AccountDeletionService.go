@@ -0,0 +1,203 @@
+package services
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"os"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"time"
+)
+
+// AccountDeletionService Удаление аккаунтов с периодом ожидания и обезличиванием контента
+type AccountDeletionService struct {
+	users     repository.UserRepository
+	sessions  repository.SessionRepo
+	petitions repository.PetitionRepository
+	comments  repository.CommentRepository
+	votes     repository.VoteRepository
+	audit     repository.VoteAuditRepository
+	apiKeys   repository.APIKeyRepository
+	webhooks  repository.WebhookRepository
+	exports   repository.DataExportRepository
+	// notifications, subscriptions и preferences Личные уведомления, подписки и настройки писем удаляются вместе с аккаунтом
+	notifications repository.NotificationRepository
+	subscriptions repository.SubscriptionRepository
+	preferences   repository.NotificationPreferenceRepository
+	avatars       *AvatarService
+	// gracePeriod Сколько времени у пользователя есть, чтобы отменить удаление
+	gracePeriod time.Duration
+	logger      *logrus.Logger
+}
+
+func NewAccountDeletionService(
+	users repository.UserRepository,
+	sessions repository.SessionRepo,
+	petitions repository.PetitionRepository,
+	comments repository.CommentRepository,
+	votes repository.VoteRepository,
+	audit repository.VoteAuditRepository,
+	apiKeys repository.APIKeyRepository,
+	webhooks repository.WebhookRepository,
+	exports repository.DataExportRepository,
+	notifications repository.NotificationRepository,
+	subscriptions repository.SubscriptionRepository,
+	preferences repository.NotificationPreferenceRepository,
+	avatars *AvatarService,
+	gracePeriod time.Duration,
+	logger *logrus.Logger,
+) *AccountDeletionService {
+	return &AccountDeletionService{
+		users:         users,
+		sessions:      sessions,
+		petitions:     petitions,
+		comments:      comments,
+		votes:         votes,
+		audit:         audit,
+		apiKeys:       apiKeys,
+		webhooks:      webhooks,
+		exports:       exports,
+		notifications: notifications,
+		subscriptions: subscriptions,
+		preferences:   preferences,
+		avatars:       avatars,
+		gracePeriod:   gracePeriod,
+		logger:        logger,
+	}
+}
+
+// Schedule назначает удаление аккаунта по истечении периода ожидания и возвращает время удаления
+func (s *AccountDeletionService) Schedule(userID uint) (time.Time, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if user.DeletionScheduledAt != nil {
+		return *user.DeletionScheduledAt, nil
+	}
+
+	at := time.Now().Add(s.gracePeriod)
+	if err := s.users.ScheduleDeletion(userID, at); err != nil {
+		return time.Time{}, err
+	}
+	s.logger.Infof("Deletion of user %d scheduled at %s", userID, at.Format(time.RFC3339))
+	return at, nil
+}
+
+// Cancel отменяет запрошенное удаление аккаунта
+func (s *AccountDeletionService) Cancel(userID uint) error {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.DeletionScheduledAt == nil {
+		return errors.New("account deletion is not scheduled")
+	}
+	if err := s.users.CancelDeletion(userID); err != nil {
+		return err
+	}
+	s.logger.Infof("Deletion of user %d cancelled", userID)
+	return nil
+}
+
+// Anonymise удаляет аккаунт сразу: стирает личные данные, передает комментарии и петиции
+// служебному пользователю, оставляет от голосов только анонимные счетчики, отзывает сессии и ключи
+// и удаляет уведомления, настройки писем и подтверждение email
+func (s *AccountDeletionService) Anonymise(userID uint) error {
+	placeholder, err := s.users.EnsureDeletedUser()
+	if err != nil {
+		return err
+	}
+	if placeholder.ID == userID {
+		return errors.New("placeholder user can not be deleted")
+	}
+
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	var exportFiles []string
+	err = s.users.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.comments.ReassignUserTx(tx, userID, placeholder.ID, placeholder.Login); err != nil {
+			return err
+		}
+		if err := s.petitions.ReassignUserTx(tx, userID, placeholder.ID); err != nil {
+			return err
+		}
+		if err := s.recordAnonymisedVotesTx(tx, userID); err != nil {
+			return err
+		}
+		if err := s.votes.AnonymiseByUserIDTx(tx, userID); err != nil {
+			return err
+		}
+		if err := s.sessions.DeleteAllByUserIDTx(tx, userID); err != nil {
+			return err
+		}
+		if err := s.apiKeys.RevokeAllByUserIDTx(tx, userID); err != nil {
+			return err
+		}
+		if err := s.webhooks.DeleteAllByUserIDTx(tx, userID); err != nil {
+			return err
+		}
+		if err := s.notifications.DeleteAllByUserIDTx(tx, userID); err != nil {
+			return err
+		}
+		if err := s.subscriptions.DeleteAllByUserIDTx(tx, userID); err != nil {
+			return err
+		}
+		if err := s.preferences.DeleteAllByUserIDTx(tx, userID); err != nil {
+			return err
+		}
+		files, err := s.exports.DeleteAllByUserIDTx(tx, userID)
+		if err != nil {
+			return err
+		}
+		exportFiles = files
+		return s.users.AnonymiseTx(tx, userID)
+	})
+	if err != nil {
+		s.logger.Errorf("Failed to anonymise user %d: %v", userID, err)
+		return err
+	}
+
+	// Архивы и аватар с персональными данными удаляем после успешной транзакции
+	s.avatars.RemoveFiles(user.AvatarKey)
+	for _, path := range exportFiles {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			s.logger.Errorf("Failed to remove data export file %s: %v", path, err)
+		}
+	}
+
+	s.logger.Infof("User %d deleted and anonymised", userID)
+	return nil
+}
+
+// ProcessDue удаляет все аккаунты, у которых истек период ожидания
+func (s *AccountDeletionService) ProcessDue(now time.Time) {
+	ids, err := s.users.GetDueForDeletion(now)
+	if err != nil {
+		s.logger.Errorf("Failed to get accounts due for deletion: %v", err)
+		return
+	}
+	for _, id := range ids {
+		// Ошибка уже залогирована, аккаунт попробуем удалить в следующий раз
+		_ = s.Anonymise(id)
+	}
+}
+
+// recordAnonymisedVotesTx Записывает в журнал голосов, что голоса пользователя стали анонимными счетчиками петиций
+func (s *AccountDeletionService) recordAnonymisedVotesTx(tx *gorm.DB, userID uint) error {
+	votes, err := s.votes.GetAllByUserIDTx(tx, userID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for i := range votes {
+		if _, err := s.audit.AppendTx(tx, &votes[i], models.VoteAuditAnonymised, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
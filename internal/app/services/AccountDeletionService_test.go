@@ -0,0 +1,169 @@
+package services
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/storage"
+	"petition_api/internal/app/testutil"
+	"testing"
+	"time"
+)
+
+func newAccountDeletionTest(t *testing.T) (*gorm.DB, *VoteService, *VoteAuditService, *AccountDeletionService) {
+	db, votes, _ := newVoteServiceTest(t)
+	if err := db.AutoMigrate(models.Comment{}, models.RefreshSession{}, models.VoteFlag{}, models.APIKey{}, models.Webhook{},
+		models.WebhookDelivery{}, models.DataExport{}, models.Notification{}, models.PetitionSubscription{}, models.NotificationPreference{}); err != nil {
+		t.Fatal(err)
+	}
+	logger := testutil.Logger()
+	users := repository.NewUserRepository(db, logger)
+	audit := repository.NewVoteAuditRepository(db, logger)
+	deletion := NewAccountDeletionService(
+		users,
+		repository.NewSessionRepo(db, logger),
+		repository.NewPetitionRepository(db, logger),
+		repository.NewCommentRepository(db, logger),
+		repository.NewVoteRepository(db, logger),
+		audit,
+		repository.NewAPIKeyRepository(db, logger),
+		repository.NewWebhookRepository(db, logger),
+		repository.NewDataExportRepository(db, logger),
+		repository.NewNotificationRepository(db, logger),
+		repository.NewSubscriptionRepository(db, logger),
+		repository.NewNotificationPreferenceRepository(db, logger),
+		NewAvatarService(users, storage.NewLocalStorage(t.TempDir(), "/files"), 64<<10, logger),
+		time.Hour,
+		logger,
+	)
+	return db, votes, NewVoteAuditService(audit, votes.votes, logger), deletion
+}
+
+func TestAccountDeletionScheduleAndCancel(t *testing.T) {
+	db, _, _, deletion := newAccountDeletionTest(t)
+	user := createTestUser(t, db, "aigerim", models.StatusActive)
+
+	at, err := deletion.Schedule(user.ID)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), at, time.Minute)
+
+	// Повторный запрос не сдвигает срок
+	again, err := deletion.Schedule(user.ID)
+	require.NoError(t, err)
+	assert.True(t, at.Equal(again))
+
+	// До истечения срока аккаунт не удаляется
+	deletion.ProcessDue(time.Now())
+	var stored models.UserModel
+	require.NoError(t, db.First(&stored, user.ID).Error)
+	assert.Equal(t, "aigerim", stored.Login)
+
+	require.NoError(t, deletion.Cancel(user.ID))
+	assert.Error(t, deletion.Cancel(user.ID))
+	deletion.ProcessDue(time.Now().Add(2 * time.Hour))
+	var cancelled models.UserModel
+	require.NoError(t, db.First(&cancelled, user.ID).Error)
+	assert.Nil(t, cancelled.DeletionScheduledAt)
+}
+
+func TestAccountDeletionAnonymisesContent(t *testing.T) {
+	db, votes, audit, deletion := newAccountDeletionTest(t)
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", TargetByVote: 100}
+	require.NoError(t, db.Create(&petition).Error)
+	vote := voteFrom(t, db, votes, petition.ID, "aigerim", "10.0.0.1")
+	voteFrom(t, db, votes, petition.ID, "other", "10.0.0.2")
+	userID := vote.UserID
+	own := models.Petition{Title: "Сквер", Description: "Разбить сквер", UserID: userID}
+	require.NoError(t, db.Create(&own).Error)
+	require.NoError(t, db.Create(&models.Comment{Content: "Поддерживаю", UserID: userID, Login: "aigerim", PetitionID: petition.ID}).Error)
+	require.NoError(t, db.Create(&models.NotificationPreference{UserID: userID, EventType: models.NotificationPetitionComment, Mode: models.DeliveryOff}).Error)
+	require.NoError(t, db.Model(&models.UserModel{}).Where("id = ?", userID).Update("email_verified_at", time.Now()).Error)
+
+	_, err := deletion.Schedule(userID)
+	require.NoError(t, err)
+	deletion.ProcessDue(time.Now().Add(2 * time.Hour))
+
+	var placeholder models.UserModel
+	require.NoError(t, db.Where("placeholder = ?", true).First(&placeholder).Error)
+	assert.Equal(t, models.DeletedUserLogin, placeholder.Login)
+
+	var comment models.Comment
+	require.NoError(t, db.First(&comment).Error)
+	assert.Equal(t, placeholder.ID, comment.UserID)
+	assert.Equal(t, placeholder.Login, comment.Login)
+	require.NoError(t, db.First(&own, own.ID).Error)
+	assert.Equal(t, placeholder.ID, own.UserID)
+
+	// Голос стал анонимным счетчиком петиции, а в журнале осталась запись anonymise
+	var left int64
+	db.Unscoped().Model(&models.Vote{}).Where("user_id = ?", userID).Count(&left)
+	assert.Zero(t, left)
+	require.NoError(t, db.First(&petition, petition.ID).Error)
+	assert.Equal(t, uint(1), petition.AnonymousVotes)
+	report, err := audit.VerifyPetition(petition.ID)
+	require.NoError(t, err)
+	assert.True(t, report.Valid, report.Problem)
+	assert.Equal(t, int64(1), report.Anonymised)
+	assert.Equal(t, int64(1), report.TableVotes)
+
+	var user models.UserModel
+	require.NoError(t, db.Unscoped().First(&user, userID).Error)
+	assert.Equal(t, fmt.Sprintf("deleted_%d", userID), user.Login)
+	assert.Empty(t, user.Email)
+	assert.Nil(t, user.EmailVerifiedAt)
+	assert.True(t, user.DeletedAt.Valid)
+
+	// Настройки писем удалены вместе с аккаунтом
+	var preferences int64
+	db.Model(&models.NotificationPreference{}).Where("user_id = ?", userID).Count(&preferences)
+	assert.Zero(t, preferences)
+}
+
+func TestAccountDeletionIgnoresSquattedPlaceholderLogin(t *testing.T) {
+	db, _, _, deletion := newAccountDeletionTest(t)
+	// Аккаунт с логином deleted_user, зарегистрированный до запрета, служебным не считается
+	squatter := createTestUser(t, db, models.DeletedUserLogin+"x", models.StatusActive)
+	require.NoError(t, db.Model(squatter).Update("login", models.DeletedUserLogin).Error)
+	user := createTestUser(t, db, "aigerim", models.StatusActive)
+	require.NoError(t, db.Create(&models.Comment{Content: "Поддерживаю", UserID: user.ID, Login: user.Login, PetitionID: 1}).Error)
+
+	// Служебный пользователь заводится заранее, как на существующей базе
+	placeholder := models.UserModel{Login: "deleted_placeholder", Role: models.RoleUser, Status: models.StatusPassive, Placeholder: true}
+	require.NoError(t, db.Create(&placeholder).Error)
+
+	require.NoError(t, deletion.Anonymise(user.ID))
+	var comment models.Comment
+	require.NoError(t, db.First(&comment).Error)
+	assert.Equal(t, placeholder.ID, comment.UserID)
+	assert.NotEqual(t, squatter.ID, comment.UserID)
+
+	assert.Error(t, deletion.Anonymise(placeholder.ID))
+}
+
+func TestEnsureDeletedUserAdoptsLegacyPlaceholder(t *testing.T) {
+	db := testutil.OpenDB(t, models.UserModel{})
+	users := repository.NewUserRepository(db, testutil.Logger())
+	legacy := models.UserModel{Login: models.DeletedUserLogin, Role: models.RoleUser, Status: models.StatusPassive}
+	require.NoError(t, db.Create(&legacy).Error)
+
+	placeholder, err := users.EnsureDeletedUser()
+	require.NoError(t, err)
+	assert.Equal(t, legacy.ID, placeholder.ID)
+
+	again, err := users.EnsureDeletedUser()
+	require.NoError(t, err)
+	assert.Equal(t, legacy.ID, again.ID)
+	var count int64
+	db.Model(&models.UserModel{}).Where("placeholder = ?", true).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestReservedLogin(t *testing.T) {
+	assert.True(t, models.ReservedLogin("deleted_user"))
+	assert.True(t, models.ReservedLogin("Deleted_42"))
+	assert.False(t, models.ReservedLogin("deleted"))
+	assert.False(t, models.ReservedLogin("aigerim_deleted_"))
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"sync"
+	"time"
+)
+
+// accountStatusCacheTTL Сколько живет закэшированный результат проверки аккаунта
+const accountStatusCacheTTL = 30 * time.Second
+
+var (
+	// ErrAccountBanned Аккаунт заблокирован
+	ErrAccountBanned = errors.New("account is banned")
+	// ErrAccountPassive Аккаунт отключен (статус Passive)
+	ErrAccountPassive = errors.New("account is disabled")
+	// ErrAccountNotFound Аккаунт удален
+	ErrAccountNotFound = errors.New("account not found")
+)
+
+// BanError Ошибка с причиной и сроком блокировки, сравнивается с ErrAccountBanned через errors.Is
+type BanError struct {
+	Reason    string
+	ExpiresAt *time.Time
+}
+
+func (e *BanError) Error() string {
+	if e.ExpiresAt == nil {
+		return fmt.Sprintf("account is banned: %s", e.Reason)
+	}
+	return fmt.Sprintf("account is banned until %s: %s", e.ExpiresAt.Format(time.RFC3339), e.Reason)
+}
+
+func (e *BanError) Is(target error) bool {
+	return target == ErrAccountBanned
+}
+
+// AccountStatusService Проверяет, может ли пользователь пользоваться API, и знает его текущую роль.
+// Результат кэшируется ненадолго, чтобы middleware не ходило в базу на каждый запрос.
+// Устаревшие записи кэша удаляет PurgeExpired
+type AccountStatusService struct {
+	users  repository.UserRepository
+	bans   repository.UserBanRepository
+	logger *logrus.Logger
+
+	mu    sync.RWMutex
+	cache map[uint]accountStatusEntry
+}
+
+type accountStatusEntry struct {
+	err       error
+	role      string
+	expiresAt time.Time
+}
+
+func NewAccountStatusService(users repository.UserRepository, bans repository.UserBanRepository, logger *logrus.Logger) *AccountStatusService {
+	return &AccountStatusService{
+		users:  users,
+		bans:   bans,
+		logger: logger,
+		cache:  make(map[uint]accountStatusEntry),
+	}
+}
+
+// CheckAccount Возвращает ошибку, если пользователю запрещено пользоваться API:
+// аккаунт удален, отключен (Passive) или заблокирован
+func (s *AccountStatusService) CheckAccount(userID uint) error {
+	entry, err := s.entry(userID)
+	if err != nil {
+		return err
+	}
+	return entry.err
+}
+
+// CurrentRole Возвращает роль пользователя из базы. Роль в access токене могла устареть после смены админом
+func (s *AccountStatusService) CurrentRole(userID uint) (string, error) {
+	entry, err := s.entry(userID)
+	if err != nil {
+		return "", err
+	}
+	return entry.role, nil
+}
+
+// entry Возвращает результат проверки аккаунта из кэша или из базы
+func (s *AccountStatusService) entry(userID uint) (accountStatusEntry, error) {
+	now := time.Now()
+
+	s.mu.RLock()
+	entry, ok := s.cache[userID]
+	s.mu.RUnlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry, nil
+	}
+
+	entry, err := s.lookup(userID, now)
+	if err != nil {
+		// Ошибка базы не кэшируется, чтобы не блокировать пользователя надолго
+		s.logger.Errorf("Failed to check account %d: %v", userID, err)
+		return accountStatusEntry{}, err
+	}
+
+	s.mu.Lock()
+	s.cache[userID] = entry
+	s.mu.Unlock()
+	return entry, nil
+}
+
+// Invalidate сбрасывает закэшированный статус пользователя, чтобы изменения применились сразу
+func (s *AccountStatusService) Invalidate(userID uint) {
+	s.mu.Lock()
+	delete(s.cache, userID)
+	s.mu.Unlock()
+}
+
+// PurgeExpired удаляет из кэша записи, срок которых истек к now, и возвращает их число.
+// Без этого кэш хранил бы запись о каждом пользователе, который хоть раз заходил
+func (s *AccountStatusService) PurgeExpired(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	purged := 0
+	for userID, entry := range s.cache {
+		if !now.Before(entry.expiresAt) {
+			delete(s.cache, userID)
+			purged++
+		}
+	}
+	return purged
+}
+
+// lookup Проверяет аккаунт в базе. В entry.err причина отказа, вторая ошибка - ошибка базы
+func (s *AccountStatusService) lookup(userID uint, now time.Time) (accountStatusEntry, error) {
+	entry := accountStatusEntry{expiresAt: now.Add(accountStatusCacheTTL)}
+	status, role, found, err := s.users.GetAccountByID(userID)
+	if err != nil {
+		return entry, err
+	}
+	entry.role = role
+	if !found {
+		entry.err = ErrAccountNotFound
+		return entry, nil
+	}
+	if status == models.StatusPassive {
+		entry.err = ErrAccountPassive
+		return entry, nil
+	}
+
+	ban, err := s.bans.GetActiveByUserID(userID, now)
+	if err != nil {
+		return entry, err
+	}
+	if ban != nil {
+		entry.err = banError(ban)
+	}
+	return entry, nil
+}
+
+func banError(ban *models.UserBan) error {
+	return &BanError{Reason: ban.Reason, ExpiresAt: ban.ExpiresAt}
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/testutil"
+	"testing"
+	"time"
+)
+
+func createTestUser(t *testing.T, db *gorm.DB, login string, status string) *models.UserModel {
+	user := models.UserModel{Login: login, Password: "x", Role: models.RoleUser, Email: login + "@mail.kz", Status: status}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &user
+}
+
+func TestCheckAccountStatus(t *testing.T) {
+	db := testutil.OpenDB(t, models.UserModel{}, models.UserBan{})
+	service := NewAccountStatusService(repository.NewUserRepository(db, testutil.Logger()), repository.NewUserBanRepository(db, testutil.Logger()), testutil.Logger())
+	active := createTestUser(t, db, "active", models.StatusActive)
+	passive := createTestUser(t, db, "passive", models.StatusPassive)
+
+	assert.NoError(t, service.CheckAccount(active.ID))
+	assert.ErrorIs(t, service.CheckAccount(passive.ID), ErrAccountPassive)
+	assert.ErrorIs(t, service.CheckAccount(999), ErrAccountNotFound)
+}
+
+func TestCheckAccountBan(t *testing.T) {
+	db := testutil.OpenDB(t, models.UserModel{}, models.UserBan{})
+	service := NewAccountStatusService(repository.NewUserRepository(db, testutil.Logger()), repository.NewUserBanRepository(db, testutil.Logger()), testutil.Logger())
+	banned := createTestUser(t, db, "banned", models.StatusActive)
+	expired := createTestUser(t, db, "expired", models.StatusActive)
+
+	past := time.Now().Add(-time.Hour)
+	db.Create(&models.UserBan{UserID: banned.ID, BannedByID: 1, Reason: "spam"})
+	db.Create(&models.UserBan{UserID: expired.ID, BannedByID: 1, Reason: "spam", ExpiresAt: &past})
+
+	err := service.CheckAccount(banned.ID)
+	assert.True(t, errors.Is(err, ErrAccountBanned))
+	assert.Contains(t, err.Error(), "spam")
+	assert.NoError(t, service.CheckAccount(expired.ID))
+}
+
+func TestCheckAccountCache(t *testing.T) {
+	db := testutil.OpenDB(t, models.UserModel{}, models.UserBan{})
+	service := NewAccountStatusService(repository.NewUserRepository(db, testutil.Logger()), repository.NewUserBanRepository(db, testutil.Logger()), testutil.Logger())
+	user := createTestUser(t, db, "user", models.StatusActive)
+	assert.NoError(t, service.CheckAccount(user.ID))
+
+	db.Model(user).Update("status", models.StatusPassive)
+	// Пока кэш жив, база не читается
+	assert.NoError(t, service.CheckAccount(user.ID))
+
+	service.Invalidate(user.ID)
+	assert.ErrorIs(t, service.CheckAccount(user.ID), ErrAccountPassive)
+}
+
+func TestAccountCachePurgeExpired(t *testing.T) {
+	db := testutil.OpenDB(t, models.UserModel{}, models.UserBan{})
+	service := NewAccountStatusService(repository.NewUserRepository(db, testutil.Logger()), repository.NewUserBanRepository(db, testutil.Logger()), testutil.Logger())
+	first := createTestUser(t, db, "first", models.StatusActive)
+	second := createTestUser(t, db, "second", models.StatusActive)
+	assert.NoError(t, service.CheckAccount(first.ID))
+	assert.NoError(t, service.CheckAccount(second.ID))
+
+	// Живые записи остаются, истекшие удаляются
+	assert.Zero(t, service.PurgeExpired(time.Now()))
+	assert.Equal(t, 2, service.PurgeExpired(time.Now().Add(accountStatusCacheTTL)))
+	assert.Empty(t, service.cache)
+}
+
+func TestCurrentRole(t *testing.T) {
+	db := testutil.OpenDB(t, models.UserModel{}, models.UserBan{})
+	service := NewAccountStatusService(repository.NewUserRepository(db, testutil.Logger()), repository.NewUserBanRepository(db, testutil.Logger()), testutil.Logger())
+	user := createTestUser(t, db, "user", models.StatusActive)
+	role, err := service.CurrentRole(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleUser, role)
+
+	db.Model(user).Update("role", models.RoleAdmin)
+	service.Invalidate(user.ID)
+	role, err = service.CurrentRole(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, role)
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/storage"
+	"petition_api/utils/auth"
+	"petition_api/utils/imaging"
+)
+
+// maxAvatarDimension Максимальная ширина и высота исходного изображения, чтобы не раскодировать огромные картинки
+const maxAvatarDimension = 6000
+
+// avatarJPEGQuality Качество JPEG для миниатюр
+const avatarJPEGQuality = 85
+
+// AvatarKeyPrefix Папка хранилища с аватарами. Только она раздается статикой, остальные файлы отдают обработчики
+const AvatarKeyPrefix = "avatars"
+
+var (
+	// ErrAvatarTooLarge Файл аватара больше допустимого размера
+	ErrAvatarTooLarge = errors.New("avatar file is too large")
+	// ErrAvatarUnsupported Файл не является изображением JPEG, PNG или GIF
+	ErrAvatarUnsupported = errors.New("avatar must be a JPEG, PNG or GIF image")
+	// ErrAvatarDimensions Изображение пустое или слишком большое по ширине или высоте
+	ErrAvatarDimensions = errors.New("avatar image dimensions are invalid")
+)
+
+// avatarContentTypes Типы изображений, которые принимаются как аватар. Тип определяется по содержимому файла
+var avatarContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// AvatarService Загрузка аватаров: проверка файла, нарезка миниатюр и сохранение в хранилище
+type AvatarService struct {
+	users   repository.UserRepository
+	storage storage.Storage
+	// maxSize Максимальный размер загружаемого файла в байтах
+	maxSize int64
+	logger  *logrus.Logger
+}
+
+func NewAvatarService(users repository.UserRepository, storage storage.Storage, maxSize int64, logger *logrus.Logger) *AvatarService {
+	return &AvatarService{
+		users:   users,
+		storage: storage,
+		maxSize: maxSize,
+		logger:  logger,
+	}
+}
+
+// MaxSize Максимальный размер файла аватара в байтах
+func (s *AvatarService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Upload Проверяет изображение, сохраняет миниатюры всех размеров и заменяет ими старый аватар пользователя
+func (s *AvatarService) Upload(userID uint, file io.Reader) (models.AvatarURLs, error) {
+	data, err := io.ReadAll(io.LimitReader(file, s.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.maxSize {
+		return nil, ErrAvatarTooLarge
+	}
+	if !avatarContentTypes[http.DetectContentType(data)] {
+		return nil, ErrAvatarUnsupported
+	}
+
+	// Размеры читаются из заголовка файла до полного раскодирования
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrAvatarUnsupported
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width > maxAvatarDimension || config.Height > maxAvatarDimension {
+		return nil, ErrAvatarDimensions
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrAvatarUnsupported
+	}
+
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	random, err := auth.RandomToken(8)
+	if err != nil {
+		return nil, err
+	}
+	avatarKey := fmt.Sprintf("%s/%d/%s", AvatarKeyPrefix, userID, random)
+	for _, size := range models.AvatarSizes {
+		if err := s.putThumbnail(img, avatarKey, size); err != nil {
+			s.RemoveFiles(avatarKey)
+			return nil, err
+		}
+	}
+
+	if err := s.users.UpdateAvatarKey(userID, avatarKey); err != nil {
+		s.RemoveFiles(avatarKey)
+		return nil, err
+	}
+	s.RemoveFiles(user.AvatarKey)
+
+	s.logger.Infof("User %d uploaded new avatar %s", userID, avatarKey)
+	return models.NewAvatarURLs(avatarKey), nil
+}
+
+// Delete Удаляет аватар пользователя
+func (s *AvatarService) Delete(userID uint) error {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.AvatarKey == "" {
+		return nil
+	}
+	if err := s.users.UpdateAvatarKey(userID, ""); err != nil {
+		return err
+	}
+	s.RemoveFiles(user.AvatarKey)
+	return nil
+}
+
+// RemoveFiles Удаляет из хранилища все миниатюры аватара. Ошибки только логируются
+func (s *AvatarService) RemoveFiles(avatarKey string) {
+	if avatarKey == "" {
+		return
+	}
+	for _, size := range models.AvatarSizes {
+		key := models.AvatarFileKey(avatarKey, size)
+		if err := s.storage.Delete(key); err != nil {
+			s.logger.Errorf("Failed to remove avatar file %s: %v", key, err)
+		}
+	}
+}
+
+// putThumbnail Сохраняет миниатюру одного размера в JPEG. Прозрачные части заливаются белым
+func (s *AvatarService) putThumbnail(img image.Image, avatarKey string, size int) error {
+	thumb := imaging.Thumbnail(img, size)
+	flat := image.NewRGBA(thumb.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), thumb, image.Point{}, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, flat, &jpeg.Options{Quality: avatarJPEGQuality}); err != nil {
+		return err
+	}
+	return s.storage.Put(models.AvatarFileKey(avatarKey, size), &buf, "image/jpeg")
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/storage"
+	"petition_api/internal/app/testutil"
+	"strings"
+	"testing"
+)
+
+func newAvatarTest(t *testing.T) (*gorm.DB, storage.Storage, *AvatarService) {
+	db := testutil.OpenDB(t, models.UserModel{})
+	files := storage.NewLocalStorage(t.TempDir(), "/files")
+	service := NewAvatarService(repository.NewUserRepository(db, testutil.Logger()), files, 64<<10, testutil.Logger())
+	return db, files, service
+}
+
+func testPNG(t *testing.T, width int, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestAvatarUpload(t *testing.T) {
+	db, files, service := newAvatarTest(t)
+	user := createTestUser(t, db, "user", models.StatusActive)
+
+	urls, err := service.Upload(user.ID, bytes.NewReader(testPNG(t, 300, 200)))
+	assert.NoError(t, err)
+	assert.Len(t, urls, len(models.AvatarSizes))
+
+	var saved models.UserModel
+	db.First(&saved, user.ID)
+	for _, size := range models.AvatarSizes {
+		file, err := files.Open(models.AvatarFileKey(saved.AvatarKey, size))
+		if !assert.NoError(t, err) {
+			continue
+		}
+		thumb, err := jpeg.Decode(file)
+		file.Close()
+		assert.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, size, size), thumb.Bounds())
+	}
+
+	// Новый аватар заменяет старые файлы
+	oldKey := saved.AvatarKey
+	_, err = service.Upload(user.ID, bytes.NewReader(testPNG(t, 50, 50)))
+	assert.NoError(t, err)
+	_, err = files.Open(models.AvatarFileKey(oldKey, models.AvatarSizes[0]))
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestAvatarUploadRejects(t *testing.T) {
+	db, _, service := newAvatarTest(t)
+	user := createTestUser(t, db, "user", models.StatusActive)
+
+	_, err := service.Upload(user.ID, strings.NewReader("<html>not an image</html>"))
+	assert.ErrorIs(t, err, ErrAvatarUnsupported)
+
+	_, err = service.Upload(user.ID, bytes.NewReader(make([]byte, 65<<10)))
+	assert.ErrorIs(t, err, ErrAvatarTooLarge)
+
+	// Заголовок GIF с нулевыми размерами
+	_, err = service.Upload(user.ID, bytes.NewReader(append([]byte("GIF89a"), make([]byte, 20)...)))
+	assert.ErrorIs(t, err, ErrAvatarDimensions)
+}
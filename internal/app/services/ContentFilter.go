@@ -0,0 +1,60 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// ErrContentRejected Текст не прошел фильтр модерации
+var ErrContentRejected = errors.New("text contains prohibited words")
+
+// ContentFilter Фильтр пользовательских текстов: комментариев и причин подписи.
+// Слово из списка ищется целиком без учета регистра, слово со звездочкой в конце ("спам*") - как начало слова
+type ContentFilter struct {
+	words    map[string]bool
+	prefixes []string
+}
+
+func NewContentFilter(bannedWords []string) *ContentFilter {
+	f := &ContentFilter{words: make(map[string]bool)}
+	for _, word := range bannedWords {
+		word = normalizeWord(strings.TrimSpace(word))
+		if prefix, ok := strings.CutSuffix(word, "*"); ok {
+			if prefix != "" {
+				f.prefixes = append(f.prefixes, prefix)
+			}
+			continue
+		}
+		if word != "" {
+			f.words[word] = true
+		}
+	}
+	return f
+}
+
+// Check возвращает ErrContentRejected, если в тексте есть запрещенное слово
+func (f *ContentFilter) Check(text string) error {
+	if len(f.words) == 0 && len(f.prefixes) == 0 {
+		return nil
+	}
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		word = normalizeWord(word)
+		if f.words[word] {
+			return ErrContentRejected
+		}
+		for _, prefix := range f.prefixes {
+			if strings.HasPrefix(word, prefix) {
+				return ErrContentRejected
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeWord Приводит слово к нижнему регистру и заменяет ё на е, чтобы их не различать
+func normalizeWord(word string) string {
+	return strings.ReplaceAll(strings.ToLower(word), "ё", "е")
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"fmt"
+	"petition_api/internal/app/models"
+	"strings"
+	"time"
+)
+
+// EligibilityRule Одно условие подписи. Возвращает nil, если пользователь ему соответствует
+type EligibilityRule interface {
+	Check(user *models.UserModel, now time.Time) *models.EligibilityViolation
+}
+
+// EligibilityPolicy Набор условий, которые проверяются вместе
+type EligibilityPolicy struct {
+	rules []EligibilityRule
+}
+
+func NewEligibilityPolicy(rules ...EligibilityRule) *EligibilityPolicy {
+	return &EligibilityPolicy{rules: rules}
+}
+
+// PolicyFromEligibility Собирает политику из условий подписи петиции. Нулевые условия не добавляются
+func PolicyFromEligibility(e *models.PetitionEligibility) *EligibilityPolicy {
+	policy := NewEligibilityPolicy()
+	if e == nil {
+		return policy
+	}
+	if e.MinAge > 0 {
+		policy.rules = append(policy.rules, MinAgeRule{MinAge: e.MinAge})
+	}
+	if len(e.Regions) > 0 {
+		policy.rules = append(policy.rules, RegionRule{Regions: e.Regions})
+	}
+	if e.RequireVerifiedEmail {
+		policy.rules = append(policy.rules, VerifiedEmailRule{})
+	}
+	if e.MinAccountAgeDays > 0 {
+		policy.rules = append(policy.rules, AccountAgeRule{MinDays: e.MinAccountAgeDays})
+	}
+	return policy
+}
+
+// Empty Нет ни одного условия
+func (p *EligibilityPolicy) Empty() bool {
+	return len(p.rules) == 0
+}
+
+// Evaluate Проверяет все условия и возвращает невыполненные. Пустой список - пользователь может подписать
+func (p *EligibilityPolicy) Evaluate(user *models.UserModel, now time.Time) []models.EligibilityViolation {
+	violations := make([]models.EligibilityViolation, 0)
+	for _, rule := range p.rules {
+		if violation := rule.Check(user, now); violation != nil {
+			violations = append(violations, *violation)
+		}
+	}
+	return violations
+}
+
+// IneligibleError Пользователь не соответствует условиям подписи петиции
+type IneligibleError struct {
+	Violations []models.EligibilityViolation
+}
+
+func (e *IneligibleError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+	return "not eligible to sign this petition: " + strings.Join(messages, "; ")
+}
+
+// MinAgeRule Подписант не моложе MinAge полных лет
+type MinAgeRule struct {
+	MinAge int
+}
+
+func (r MinAgeRule) Check(user *models.UserModel, now time.Time) *models.EligibilityViolation {
+	if user.BirthDate.IsZero() {
+		return &models.EligibilityViolation{
+			Code:    models.EligibilityMinAge,
+			Message: fmt.Sprintf("birth date is required to sign, the petition is open to people aged %d and over", r.MinAge),
+		}
+	}
+	if Age(user.BirthDate, now) < r.MinAge {
+		return &models.EligibilityViolation{
+			Code:    models.EligibilityMinAge,
+			Message: fmt.Sprintf("the petition is open to people aged %d and over", r.MinAge),
+		}
+	}
+	return nil
+}
+
+// Age Полных лет на момент now
+func Age(birthDate time.Time, now time.Time) int {
+	age := now.Year() - birthDate.Year()
+	if now.Month() < birthDate.Month() || (now.Month() == birthDate.Month() && now.Day() < birthDate.Day()) {
+		age--
+	}
+	return age
+}
+
+// RegionRule Подписант живет в одном из регионов Regions
+type RegionRule struct {
+	Regions []string
+}
+
+func (r RegionRule) Check(user *models.UserModel, now time.Time) *models.EligibilityViolation {
+	for _, region := range r.Regions {
+		if user.Region == region {
+			return nil
+		}
+	}
+	message := "the petition is open to residents of: " + strings.Join(r.Regions, ", ")
+	if user.Region == "" {
+		message = "region is not set in profile, " + message
+	}
+	return &models.EligibilityViolation{Code: models.EligibilityRegion, Message: message}
+}
+
+// VerifiedEmailRule У подписанта подтвержден email
+type VerifiedEmailRule struct{}
+
+func (VerifiedEmailRule) Check(user *models.UserModel, now time.Time) *models.EligibilityViolation {
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	return &models.EligibilityViolation{
+		Code:    models.EligibilityEmailNotVerified,
+		Message: "verified email is required to sign",
+	}
+}
+
+// AccountAgeRule Аккаунт подписанта зарегистрирован не меньше MinDays дней назад
+type AccountAgeRule struct {
+	MinDays int
+}
+
+func (r AccountAgeRule) Check(user *models.UserModel, now time.Time) *models.EligibilityViolation {
+	if !now.Before(user.CreatedAt.AddDate(0, 0, r.MinDays)) {
+		return nil
+	}
+	return &models.EligibilityViolation{
+		Code:    models.EligibilityAccountAge,
+		Message: fmt.Sprintf("account must be at least %d days old to sign", r.MinDays),
+	}
+}
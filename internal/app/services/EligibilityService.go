@@ -0,0 +1,118 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"time"
+)
+
+var (
+	// ErrUnknownRegion Региона нет в models.Regions
+	ErrUnknownRegion = errors.New("unknown region")
+	// ErrEligibilityLocked Условия нельзя менять, когда петицию уже подписывали
+	ErrEligibilityLocked = errors.New("eligibility rules can't be changed after the petition has been signed")
+)
+
+// EligibilityService Условия подписи петиций: хранит их и проверяет подписантов перед сохранением голоса
+type EligibilityService struct {
+	rules  repository.PetitionEligibilityRepository
+	users  repository.UserRepository
+	votes  repository.VoteRepository
+	logger *logrus.Logger
+}
+
+func NewEligibilityService(
+	rules repository.PetitionEligibilityRepository,
+	users repository.UserRepository,
+	votes repository.VoteRepository,
+	logger *logrus.Logger,
+) *EligibilityService {
+	return &EligibilityService{rules: rules, users: users, votes: votes, logger: logger}
+}
+
+// Get Условия подписи петиции. nil, если подписать может любой пользователь
+func (s *EligibilityService) Get(petitionID uint) (*models.PetitionEligibility, error) {
+	return s.rules.GetByPetitionID(petitionID)
+}
+
+// Set Задает условия подписи. Пока у петиции нет подписей, иначе часть подписантов перестала бы им соответствовать
+func (s *EligibilityService) Set(petitionID uint, input models.PetitionEligibilityInput) (*models.PetitionEligibility, error) {
+	regions := make([]string, 0, len(input.Regions))
+	seen := make(map[string]bool, len(input.Regions))
+	for _, region := range input.Regions {
+		if !models.ValidRegion(region) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownRegion, region)
+		}
+		if !seen[region] {
+			seen[region] = true
+			regions = append(regions, region)
+		}
+	}
+	if err := s.checkUnlocked(petitionID); err != nil {
+		return nil, err
+	}
+
+	eligibility := models.PetitionEligibility{
+		PetitionID:           petitionID,
+		MinAge:               input.MinAge,
+		Regions:              regions,
+		RequireVerifiedEmail: input.RequireVerifiedEmail,
+		MinAccountAgeDays:    input.MinAccountAgeDays,
+	}
+	if err := s.rules.Save(&eligibility); err != nil {
+		return nil, err
+	}
+	return &eligibility, nil
+}
+
+// Delete Снимает условия подписи
+func (s *EligibilityService) Delete(petitionID uint) error {
+	if err := s.checkUnlocked(petitionID); err != nil {
+		return err
+	}
+	return s.rules.DeleteByPetitionID(petitionID)
+}
+
+// Check Невыполненные условия подписи петиции для пользователя. Пустой список - подписать можно
+func (s *EligibilityService) Check(userID uint, petitionID uint, now time.Time) ([]models.EligibilityViolation, error) {
+	eligibility, err := s.rules.GetByPetitionID(petitionID)
+	if err != nil {
+		return nil, err
+	}
+	policy := PolicyFromEligibility(eligibility)
+	if policy.Empty() {
+		return []models.EligibilityViolation{}, nil
+	}
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return policy.Evaluate(user, now), nil
+}
+
+// Require Возвращает *IneligibleError, если пользователь не может подписать петицию
+func (s *EligibilityService) Require(userID uint, petitionID uint, now time.Time) error {
+	violations, err := s.Check(userID, petitionID, now)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return &IneligibleError{Violations: violations}
+	}
+	return nil
+}
+
+// checkUnlocked Возвращает ErrEligibilityLocked, если у петиции уже есть подписи
+func (s *EligibilityService) checkUnlocked(petitionID uint) error {
+	signed, err := s.votes.HasVotes(petitionID)
+	if err != nil {
+		return err
+	}
+	if signed {
+		return ErrEligibilityLocked
+	}
+	return nil
+}
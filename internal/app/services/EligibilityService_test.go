@@ -0,0 +1,88 @@
+package services
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"petition_api/internal/app/models"
+	"testing"
+	"time"
+)
+
+func violationCodes(violations []models.EligibilityViolation) []string {
+	codes := make([]string, 0, len(violations))
+	for _, v := range violations {
+		codes = append(codes, v.Code)
+	}
+	return codes
+}
+
+func TestEligibilityPolicy(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	verified := now.AddDate(0, -1, 0)
+	policy := PolicyFromEligibility(&models.PetitionEligibility{
+		MinAge:               18,
+		Regions:              []string{"almaty", "almaty_region"},
+		RequireVerifiedEmail: true,
+		MinAccountAgeDays:    30,
+	})
+
+	adult := &models.UserModel{
+		BirthDate:       time.Date(2008, 3, 10, 0, 0, 0, 0, time.UTC),
+		Region:          "almaty",
+		EmailVerifiedAt: &verified,
+	}
+	adult.CreatedAt = now.AddDate(0, 0, -30)
+	assert.Empty(t, policy.Evaluate(adult, now))
+
+	// Восемнадцать исполнится только завтра, аккаунту 29 дней
+	minor := &models.UserModel{
+		BirthDate: time.Date(2008, 3, 11, 0, 0, 0, 0, time.UTC),
+		Region:    "astana",
+	}
+	minor.CreatedAt = now.AddDate(0, 0, -29)
+	assert.Equal(t,
+		[]string{models.EligibilityMinAge, models.EligibilityRegion, models.EligibilityEmailNotVerified, models.EligibilityAccountAge},
+		violationCodes(policy.Evaluate(minor, now)))
+
+	// Без даты рождения и региона условия не выполнены
+	unknown := &models.UserModel{EmailVerifiedAt: &verified}
+	unknown.CreatedAt = now.AddDate(-1, 0, 0)
+	violations := policy.Evaluate(unknown, now)
+	assert.Equal(t, []string{models.EligibilityMinAge, models.EligibilityRegion}, violationCodes(violations))
+	assert.Contains(t, violations[1].Message, "region is not set")
+
+	assert.True(t, PolicyFromEligibility(nil).Empty())
+	assert.True(t, PolicyFromEligibility(&models.PetitionEligibility{Regions: []string{}}).Empty())
+}
+
+func TestVoteRequiresEligibility(t *testing.T) {
+	db, service, notifier := newVoteServiceTest(t)
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", TargetByVote: 10}
+	db.Create(&petition)
+	user := createTestUser(t, db, "signer", models.StatusActive)
+
+	_, err := service.eligibility.Set(petition.ID, models.PetitionEligibilityInput{Regions: []string{"atlantis"}})
+	assert.ErrorIs(t, err, ErrUnknownRegion)
+	_, err = service.eligibility.Set(petition.ID, models.PetitionEligibilityInput{MinAge: 18, Regions: []string{"almaty", "almaty"}})
+	require.NoError(t, err)
+	rules, err := service.eligibility.Get(petition.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"almaty"}, rules.Regions)
+
+	vote := models.Vote{Login: user.Login, UserID: user.ID, PetitionID: petition.ID}
+	err = service.Vote(&vote)
+	var ineligible *IneligibleError
+	require.True(t, errors.As(err, &ineligible))
+	assert.Equal(t, []string{models.EligibilityMinAge, models.EligibilityRegion}, violationCodes(ineligible.Violations))
+	assert.Empty(t, notifier.voted)
+
+	db.Model(user).Updates(map[string]interface{}{"birth_date": time.Now().AddDate(-20, 0, 0), "region": "almaty"})
+	require.NoError(t, service.Vote(&vote))
+	assert.Equal(t, []uint{vote.ID}, notifier.voted)
+
+	// После первой подписи условия не меняются
+	_, err = service.eligibility.Set(petition.ID, models.PetitionEligibilityInput{})
+	assert.ErrorIs(t, err, ErrEligibilityLocked)
+	assert.ErrorIs(t, service.eligibility.Delete(petition.ID), ErrEligibilityLocked)
+}
@@ -0,0 +1,329 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net/url"
+	"petition_api/internal/app/mailer"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"strconv"
+	"time"
+)
+
+// UnsubscribeAll Тип в ссылке отписки, который выключает письма обо всех событиях
+const UnsubscribeAll = "all"
+
+// digestBatchSize Сколько уведомлений обрабатывается за один запрос к базе
+const digestBatchSize = 500
+
+var (
+	// ErrInvalidUnsubscribeToken Ссылка отписки подделана или устарела
+	ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe link")
+	// ErrInvalidNotificationSettings В настройках неизвестный тип событий или режим
+	ErrInvalidNotificationSettings = errors.New("unknown notification type or delivery mode")
+)
+
+// DigestSchedule Когда рассылаются сводки
+type DigestSchedule struct {
+	// Hour Час отправки ежедневных и еженедельных сводок
+	Hour int
+	// WeeklyDay День недели еженедельной сводки
+	WeeklyDay time.Weekday
+}
+
+// UnsubscribeSigner Подписывает ссылки отписки, чтобы по ним нельзя было отписать чужой аккаунт
+type UnsubscribeSigner struct {
+	secret []byte
+}
+
+func NewUnsubscribeSigner(secret string) *UnsubscribeSigner {
+	return &UnsubscribeSigner{secret: []byte(secret)}
+}
+
+// ForPurpose возвращает подписчик с отдельным ключом HMAC(secret, purpose).
+// Ссылки разного назначения подписываются разными ключами, и подпись одной ссылки не подходит к другой
+func (s *UnsubscribeSigner) ForPurpose(purpose string) *UnsubscribeSigner {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(purpose))
+	return &UnsubscribeSigner{secret: mac.Sum(nil)}
+}
+
+// Token возвращает HMAC-SHA256 подпись пары пользователь и тип событий
+func (s *UnsubscribeSigner) Token(userID uint, eventType string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(strconv.FormatUint(uint64(userID), 10) + ":" + eventType))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись за постоянное время
+func (s *UnsubscribeSigner) Verify(userID uint, eventType string, token string) bool {
+	return hmac.Equal([]byte(s.Token(userID, eventType)), []byte(token))
+}
+
+// EmailDigestService Собирает уведомления в письма по настройкам пользователей и отправляет их
+type EmailDigestService struct {
+	notifications repository.NotificationRepository
+	preferences   repository.NotificationPreferenceRepository
+	users         repository.UserRepository
+	petitions     repository.PetitionRepository
+	mailer        mailer.Mailer
+	templates     *mailer.Templates
+	signer        *UnsubscribeSigner
+	// baseURL Адрес сервиса для ссылок в письмах
+	baseURL  string
+	schedule DigestSchedule
+	// lastDaily и lastWeekly Когда последний раз рассылались сводки
+	lastDaily  time.Time
+	lastWeekly time.Time
+	logger     *logrus.Logger
+}
+
+func NewEmailDigestService(
+	notifications repository.NotificationRepository,
+	preferences repository.NotificationPreferenceRepository,
+	users repository.UserRepository,
+	petitions repository.PetitionRepository,
+	m mailer.Mailer,
+	templates *mailer.Templates,
+	signer *UnsubscribeSigner,
+	baseURL string,
+	schedule DigestSchedule,
+	logger *logrus.Logger,
+) *EmailDigestService {
+	now := time.Now()
+	return &EmailDigestService{
+		notifications: notifications,
+		preferences:   preferences,
+		users:         users,
+		petitions:     petitions,
+		mailer:        m,
+		templates:     templates,
+		signer:        signer,
+		baseURL:       baseURL,
+		schedule:      schedule,
+		// Сводки, не отправленные до перезапуска, уйдут в ближайшее время по расписанию
+		lastDaily:  now,
+		lastWeekly: now,
+		logger:     logger,
+	}
+}
+
+// ProcessDue отправляет мгновенные письма и сводки, время которых наступило
+func (s *EmailDigestService) ProcessDue(now time.Time) {
+	s.process(models.DeliveryOff, "", now)
+	s.process(models.DeliveryInstant, mailer.PeriodInstant, now)
+
+	if slot := s.dailySlot(now); s.lastDaily.Before(slot) {
+		s.process(models.DeliveryDaily, mailer.PeriodDaily, now)
+		s.lastDaily = now
+	}
+	if slot := s.weeklySlot(now); s.lastWeekly.Before(slot) {
+		s.process(models.DeliveryWeekly, mailer.PeriodWeekly, now)
+		s.lastWeekly = now
+	}
+}
+
+// dailySlot Последнее наступившее время ежедневной сводки
+func (s *EmailDigestService) dailySlot(now time.Time) time.Time {
+	slot := time.Date(now.Year(), now.Month(), now.Day(), s.schedule.Hour, 0, 0, 0, now.Location())
+	if now.Before(slot) {
+		slot = slot.AddDate(0, 0, -1)
+	}
+	return slot
+}
+
+// weeklySlot Последнее наступившее время еженедельной сводки
+func (s *EmailDigestService) weeklySlot(now time.Time) time.Time {
+	slot := s.dailySlot(now)
+	for slot.Weekday() != s.schedule.WeeklyDay {
+		slot = slot.AddDate(0, 0, -1)
+	}
+	return slot
+}
+
+// process Отправляет все ожидающие уведомления с режимом mode, по письму на пользователя.
+// Уведомления с выключенными письмами только отмечаются. Неотправленные письма повторяются при следующем запуске
+func (s *EmailDigestService) process(mode string, period string, now time.Time) {
+	for {
+		batch, err := s.notifications.GetPendingEmail(mode, digestBatchSize)
+		if err != nil {
+			s.logger.Errorf("Failed to get pending %s notifications: %v", mode, err)
+			return
+		}
+		if len(batch) == 0 {
+			return
+		}
+
+		processed := 0
+		for _, group := range groupByUser(batch) {
+			if mode != models.DeliveryOff {
+				if err := s.send(group, period); err != nil {
+					s.logger.Errorf("Failed to email notifications to user %d: %v", group[0].UserID, err)
+					continue
+				}
+			}
+			ids := make([]uint, 0, len(group))
+			for _, notification := range group {
+				ids = append(ids, notification.ID)
+			}
+			if err := s.notifications.MarkEmailed(ids, now); err != nil {
+				s.logger.Errorf("Failed to mark notifications as emailed: %v", err)
+				return
+			}
+			processed++
+		}
+		if processed == 0 || len(batch) < digestBatchSize {
+			return
+		}
+	}
+}
+
+// send Собирает и отправляет одно письмо пользователю. Отключенным аккаунтам и аккаунтам без почты письма не отправляются
+func (s *EmailDigestService) send(group []models.Notification, period string) error {
+	user, err := s.users.GetByID(group[0].UserID)
+	if err != nil || user.Status == models.StatusPassive || user.Email == "" {
+		return nil
+	}
+
+	petitionIDs := make([]uint, 0, len(group))
+	types := make(map[string]bool)
+	for _, notification := range group {
+		petitionIDs = append(petitionIDs, notification.PetitionID)
+		types[notification.Type] = true
+	}
+	titles, err := s.petitions.GetTitlesByIDs(petitionIDs)
+	if err != nil {
+		return err
+	}
+
+	items := make([]mailer.DigestItem, 0, len(group))
+	for _, notification := range group {
+		title, ok := titles[notification.PetitionID]
+		if !ok {
+			title = fmt.Sprintf("#%d", notification.PetitionID)
+		}
+		items = append(items, mailer.DigestItem{
+			Type:          notification.Type,
+			PetitionTitle: title,
+			Text:          notification.Text,
+			Link:          fmt.Sprintf("%s/petition/%d", s.baseURL, notification.PetitionID),
+		})
+	}
+
+	// Письмо про события одного типа отписывает только от них, сводка - от всех писем
+	unsubscribeType := UnsubscribeAll
+	if len(types) == 1 {
+		unsubscribeType = group[0].Type
+	}
+	unsubscribeURL := s.UnsubscribeURL(user.ID, unsubscribeType)
+
+	name := user.FirstName
+	if name == "" {
+		name = user.Login
+	}
+	subject, html, text, err := s.templates.RenderDigest(mailer.Digest{
+		Language:       user.Language,
+		Name:           name,
+		Period:         period,
+		Items:          items,
+		UnsubscribeURL: unsubscribeURL,
+		PreferencesURL: s.baseURL + "/user/me/notifications/preferences",
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: subject,
+		HTML:    html,
+		Text:    text,
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	})
+}
+
+// UnsubscribeURL возвращает подписанную ссылку отписки от писем одного типа или от всех
+func (s *EmailDigestService) UnsubscribeURL(userID uint, eventType string) string {
+	query := url.Values{}
+	query.Set("user", strconv.FormatUint(uint64(userID), 10))
+	query.Set("type", eventType)
+	query.Set("token", s.signer.Token(userID, eventType))
+	return s.baseURL + "/notifications/unsubscribe?" + query.Encode()
+}
+
+// Unsubscribe выключает письма по ссылке из письма
+func (s *EmailDigestService) Unsubscribe(userID uint, eventType string, token string) error {
+	if !s.signer.Verify(userID, eventType, token) {
+		return ErrInvalidUnsubscribeToken
+	}
+	modes := make(map[string]string)
+	if eventType == UnsubscribeAll {
+		for _, t := range models.NotificationTypes {
+			modes[t] = models.DeliveryOff
+		}
+	} else if models.ValidNotificationType(eventType) {
+		modes[eventType] = models.DeliveryOff
+	} else {
+		return ErrInvalidUnsubscribeToken
+	}
+	if err := s.preferences.SetModes(userID, modes); err != nil {
+		return err
+	}
+	s.logger.Infof("User %d unsubscribed from %s emails", userID, eventType)
+	return nil
+}
+
+// GetSettings возвращает язык писем и режимы по всем типам событий
+func (s *EmailDigestService) GetSettings(userID uint) (*models.NotificationSettings, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	modes, err := s.preferences.GetModes(userID)
+	if err != nil {
+		return nil, err
+	}
+	language := user.Language
+	if !mailer.SupportedLanguage(language) {
+		language = mailer.DefaultLanguage
+	}
+	return &models.NotificationSettings{Language: language, Modes: modes}, nil
+}
+
+// UpdateSettings меняет язык и режимы писем. Неуказанные типы событий не меняются
+func (s *EmailDigestService) UpdateSettings(userID uint, settings models.NotificationSettings) (*models.NotificationSettings, error) {
+	for eventType, mode := range settings.Modes {
+		if !models.ValidNotificationType(eventType) || !models.ValidDeliveryMode(mode) {
+			return nil, ErrInvalidNotificationSettings
+		}
+	}
+	if settings.Language != "" {
+		if err := s.users.UpdateLanguage(userID, settings.Language); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.preferences.SetModes(userID, settings.Modes); err != nil {
+		return nil, err
+	}
+	return s.GetSettings(userID)
+}
+
+// groupByUser Делит уведомления, упорядоченные по пользователям, на группы одного пользователя
+func groupByUser(notifications []models.Notification) [][]models.Notification {
+	var groups [][]models.Notification
+	for i, notification := range notifications {
+		if i == 0 || notification.UserID != notifications[i-1].UserID {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], notification)
+	}
+	return groups
+}
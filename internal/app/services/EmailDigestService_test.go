@@ -0,0 +1,126 @@
+package services
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"petition_api/internal/app/mailer"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/testutil"
+	"testing"
+	"time"
+)
+
+type recordingMailer struct {
+	sent []mailer.Message
+}
+
+func (m *recordingMailer) Send(msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func newEmailDigestTest(t *testing.T) (*gorm.DB, *EmailDigestService, *recordingMailer) {
+	db := testutil.OpenDB(t, models.UserModel{}, models.Petition{}, models.Notification{}, models.NotificationPreference{})
+	templates, err := mailer.NewTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := testutil.Logger()
+	m := &recordingMailer{}
+	service := NewEmailDigestService(
+		repository.NewNotificationRepository(db, logger),
+		repository.NewNotificationPreferenceRepository(db, logger),
+		repository.NewUserRepository(db, logger),
+		repository.NewPetitionRepository(db, logger),
+		m,
+		templates,
+		NewUnsubscribeSigner("secret"),
+		"http://localhost:8080",
+		DigestSchedule{Hour: 9, WeeklyDay: time.Monday},
+		logger,
+	)
+	return db, service, m
+}
+
+func TestEmailDigestModes(t *testing.T) {
+	db, service, m := newEmailDigestTest(t)
+	// Среда, 8:00 - до ежедневной сводки
+	morning := time.Date(2026, 3, 4, 8, 0, 0, 0, time.Local)
+	service.lastDaily = morning.Add(-time.Hour)
+	service.lastWeekly = morning.Add(-time.Hour)
+
+	instant := createTestUser(t, db, "instant", models.StatusActive)
+	daily := createTestUser(t, db, "daily", models.StatusActive)
+	off := createTestUser(t, db, "off", models.StatusActive)
+	preferences := repository.NewNotificationPreferenceRepository(db, testutil.Logger())
+	assert.NoError(t, preferences.SetModes(instant.ID, map[string]string{models.NotificationPetitionComment: models.DeliveryInstant}))
+	assert.NoError(t, preferences.SetModes(off.ID, map[string]string{models.NotificationPetitionComment: models.DeliveryOff}))
+
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", UserID: instant.ID}
+	db.Create(&petition)
+	for _, user := range []*models.UserModel{instant, daily, daily, off} {
+		db.Create(&models.Notification{UserID: user.ID, Type: models.NotificationPetitionComment, PetitionID: petition.ID, Text: "Поддерживаю"})
+	}
+
+	service.ProcessDue(morning)
+	assert.Len(t, m.sent, 1)
+	assert.Equal(t, instant.Email, m.sent[0].To)
+	assert.Equal(t, "Новое уведомление", m.sent[0].Subject)
+	assert.Contains(t, m.sent[0].Headers["List-Unsubscribe"], "type=petition_comment")
+
+	var pending int64
+	db.Model(&models.Notification{}).Where("emailed_at IS NULL").Count(&pending)
+	assert.Equal(t, int64(2), pending, "daily notifications wait for the digest, off ones are skipped")
+
+	// В 9:00 оба уведомления второго пользователя уходят одной сводкой
+	service.ProcessDue(morning.Add(time.Hour))
+	assert.Len(t, m.sent, 2)
+	assert.Equal(t, daily.Email, m.sent[1].To)
+	assert.Equal(t, "Сводка уведомлений за день", m.sent[1].Subject)
+	assert.Contains(t, m.sent[1].Headers["List-Unsubscribe"], "type=petition_comment")
+
+	// Повторно в тот же день сводка не отправляется
+	service.ProcessDue(morning.Add(2 * time.Hour))
+	assert.Len(t, m.sent, 2)
+}
+
+func TestUnsubscribeLink(t *testing.T) {
+	db, service, _ := newEmailDigestTest(t)
+	user := createTestUser(t, db, "reader", models.StatusActive)
+	signer := NewUnsubscribeSigner("secret")
+
+	assert.ErrorIs(t, service.Unsubscribe(user.ID, UnsubscribeAll, "forged"), ErrInvalidUnsubscribeToken)
+	// Подпись одного пользователя не подходит другому
+	assert.ErrorIs(t, service.Unsubscribe(user.ID+1, UnsubscribeAll, signer.Token(user.ID, UnsubscribeAll)), ErrInvalidUnsubscribeToken)
+
+	assert.NoError(t, service.Unsubscribe(user.ID, models.NotificationPetitionNews, signer.Token(user.ID, models.NotificationPetitionNews)))
+	settings, err := service.GetSettings(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DeliveryOff, settings.Modes[models.NotificationPetitionNews])
+	assert.Equal(t, models.DefaultDeliveryMode, settings.Modes[models.NotificationPetitionComment])
+
+	assert.NoError(t, service.Unsubscribe(user.ID, UnsubscribeAll, signer.Token(user.ID, UnsubscribeAll)))
+	settings, _ = service.GetSettings(user.ID)
+	for _, mode := range settings.Modes {
+		assert.Equal(t, models.DeliveryOff, mode)
+	}
+}
+
+func TestUpdateNotificationSettings(t *testing.T) {
+	db, service, _ := newEmailDigestTest(t)
+	user := createTestUser(t, db, "reader", models.StatusActive)
+
+	_, err := service.UpdateSettings(user.ID, models.NotificationSettings{Modes: map[string]string{"unknown": models.DeliveryOff}})
+	assert.ErrorIs(t, err, ErrInvalidNotificationSettings)
+	_, err = service.UpdateSettings(user.ID, models.NotificationSettings{Modes: map[string]string{models.NotificationPetitionNews: "hourly"}})
+	assert.ErrorIs(t, err, ErrInvalidNotificationSettings)
+
+	settings, err := service.UpdateSettings(user.ID, models.NotificationSettings{
+		Language: mailer.LangKazakh,
+		Modes:    map[string]string{models.NotificationPetitionNews: models.DeliveryWeekly},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, mailer.LangKazakh, settings.Language)
+	assert.Equal(t, models.DeliveryWeekly, settings.Modes[models.NotificationPetitionNews])
+}
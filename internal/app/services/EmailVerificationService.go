@@ -0,0 +1,124 @@
+package services
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"net/url"
+	"petition_api/internal/app/mailer"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrInvalidVerificationLink Ссылка подтверждения подделана, устарела или выдана для другого email
+	ErrInvalidVerificationLink = errors.New("invalid or expired verification link")
+	// ErrEmailAlreadyVerified Текущий email пользователя уже подтвержден
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+	// ErrNoEmail У пользователя не указан email
+	ErrNoEmail = errors.New("user has no email")
+)
+
+// emailVerificationPurpose Назначение ключа подписи ссылок подтверждения, отделяет его от ключа ссылок отписки
+const emailVerificationPurpose = "email-verify"
+
+// EmailVerificationService Подтверждение email по ссылке из письма.
+// Ссылка подписана вместе с адресом, поэтому после смены email старые ссылки не действуют
+type EmailVerificationService struct {
+	users     repository.UserRepository
+	mailer    mailer.Mailer
+	templates *mailer.Templates
+	signer    *UnsubscribeSigner
+	// baseURL Адрес сервиса для ссылок в письмах
+	baseURL string
+	// ttl Сколько действует ссылка
+	ttl    time.Duration
+	logger *logrus.Logger
+}
+
+func NewEmailVerificationService(
+	users repository.UserRepository,
+	m mailer.Mailer,
+	templates *mailer.Templates,
+	signer *UnsubscribeSigner,
+	baseURL string,
+	ttl time.Duration,
+	logger *logrus.Logger,
+) *EmailVerificationService {
+	return &EmailVerificationService{
+		users:     users,
+		mailer:    m,
+		templates: templates,
+		signer:    signer.ForPurpose(emailVerificationPurpose),
+		baseURL:   baseURL,
+		ttl:       ttl,
+		logger:    logger,
+	}
+}
+
+// Send отправляет пользователю письмо со ссылкой подтверждения текущего email
+func (s *EmailVerificationService) Send(userID uint, now time.Time) error {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return ErrNoEmail
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	name := user.FirstName
+	if name == "" {
+		name = user.Login
+	}
+	subject, html, text, err := s.templates.RenderVerification(mailer.Verification{
+		Language:   user.Language,
+		Name:       name,
+		Email:      user.Email,
+		Link:       s.VerificationURL(user, now.Add(s.ttl)),
+		ValidHours: int(s.ttl / time.Hour),
+	})
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(mailer.Message{To: user.Email, Subject: subject, HTML: html, Text: text})
+}
+
+// VerificationURL возвращает подписанную ссылку подтверждения текущего email пользователя
+func (s *EmailVerificationService) VerificationURL(user *models.UserModel, expires time.Time) string {
+	query := url.Values{}
+	query.Set("user", strconv.FormatUint(uint64(user.ID), 10))
+	query.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("token", s.signer.Token(user.ID, verificationPayload(user.Email, expires.Unix())))
+	return s.baseURL + "/user/email/verify?" + query.Encode()
+}
+
+// Verify подтверждает email по ссылке из письма. Повторный переход по ссылке не ошибка
+func (s *EmailVerificationService) Verify(userID uint, expires int64, token string, now time.Time) (*models.UserModel, error) {
+	if now.Unix() > expires {
+		return nil, ErrInvalidVerificationLink
+	}
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, ErrInvalidVerificationLink
+	}
+	if !s.signer.Verify(user.ID, verificationPayload(user.Email, expires), token) {
+		return nil, ErrInvalidVerificationLink
+	}
+	if user.EmailVerifiedAt != nil {
+		return user, nil
+	}
+	if err := s.users.MarkEmailVerified(user.ID, user.Email, now); err != nil {
+		return nil, err
+	}
+	user.EmailVerifiedAt = &now
+	return user, nil
+}
+
+// verificationPayload Что подписывается в ссылке подтверждения. Префикс отделяет ее от ссылок отписки
+func verificationPayload(email string, expires int64) string {
+	return "verify_email:" + email + ":" + strconv.FormatInt(expires, 10)
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"petition_api/internal/app/mailer"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/testutil"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestEmailVerification(t *testing.T) {
+	db, _, m := newEmailDigestTest(t)
+	templates, err := mailer.NewTemplates()
+	require.NoError(t, err)
+	logger := testutil.Logger()
+	users := repository.NewUserRepository(db, logger)
+	service := NewEmailVerificationService(users, m, templates, NewUnsubscribeSigner("secret"), "http://localhost:8080", 48*time.Hour, logger)
+
+	user := createTestUser(t, db, "signer", models.StatusActive)
+	now := time.Now()
+	require.NoError(t, service.Send(user.ID, now))
+	require.Len(t, m.sent, 1)
+	assert.Equal(t, user.Email, m.sent[0].To)
+
+	link, err := url.Parse(service.VerificationURL(user, now.Add(48*time.Hour)))
+	require.NoError(t, err)
+	assert.Contains(t, m.sent[0].Text, link.String())
+	expires, err := strconv.ParseInt(link.Query().Get("expires"), 10, 64)
+	require.NoError(t, err)
+	token := link.Query().Get("token")
+	// Ссылка подписана своим ключом, а не ключом ссылок отписки
+	assert.False(t, NewUnsubscribeSigner("secret").Verify(user.ID, verificationPayload(user.Email, expires), token))
+
+	// Подделанная и просроченная ссылки не подтверждают email
+	_, err = service.Verify(user.ID, expires+1, token, now)
+	assert.ErrorIs(t, err, ErrInvalidVerificationLink)
+	_, err = service.Verify(user.ID, expires, token, now.Add(49*time.Hour))
+	assert.ErrorIs(t, err, ErrInvalidVerificationLink)
+
+	verified, err := service.Verify(user.ID, expires, token, now)
+	require.NoError(t, err)
+	assert.NotNil(t, verified.EmailVerifiedAt)
+	assert.ErrorIs(t, service.Send(user.ID, now), ErrEmailAlreadyVerified)
+
+	// После смены email подтверждение сбрасывается, а старая ссылка больше не действует
+	stored, err := users.GetByID(user.ID)
+	require.NoError(t, err)
+	stored.SetEmail("new@mail.kz")
+	assert.Nil(t, stored.EmailVerifiedAt)
+	require.NoError(t, users.Update(stored))
+	_, err = service.Verify(user.ID, expires, token, now)
+	assert.ErrorIs(t, err, ErrInvalidVerificationLink)
+}
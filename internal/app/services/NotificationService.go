@@ -0,0 +1,217 @@
+package services
+
+import (
+	"github.com/sirupsen/logrus"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// notificationTextLimit Сколько символов комментария или новости попадает в текст уведомления
+const notificationTextLimit = 200
+
+// NotificationPusher Доставляет сообщения подключенным клиентам пользователя в реальном времени
+type NotificationPusher interface {
+	PushToUser(userID uint, messageType string, payload interface{})
+}
+
+// NotificationService Создает уведомления о событиях петиций и отправляет их пользователям
+type NotificationService struct {
+	repo          repository.NotificationRepository
+	subscriptions repository.SubscriptionRepository
+	petitions     repository.PetitionRepository
+	comments      repository.CommentRepository
+	pusher        NotificationPusher
+	logger        *logrus.Logger
+}
+
+func NewNotificationService(
+	repo repository.NotificationRepository,
+	subscriptions repository.SubscriptionRepository,
+	petitions repository.PetitionRepository,
+	comments repository.CommentRepository,
+	pusher NotificationPusher,
+	logger *logrus.Logger,
+) *NotificationService {
+	return &NotificationService{
+		repo:          repo,
+		subscriptions: subscriptions,
+		petitions:     petitions,
+		comments:      comments,
+		pusher:        pusher,
+		logger:        logger,
+	}
+}
+
+// Notify сохраняет событие для каждого из пользователей и сразу отправляет его по вебсокету.
+// Автор события и повторы пропускаются
+func (s *NotificationService) Notify(userIDs []uint, event models.Notification) error {
+	seen := make(map[uint]bool, len(userIDs))
+	notifications := make([]models.Notification, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if userID == 0 || userID == event.ActorID || seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		notification := event
+		notification.ID = 0
+		notification.UserID = userID
+		notifications = append(notifications, notification)
+	}
+
+	if err := s.repo.CreateBatch(notifications); err != nil {
+		return err
+	}
+	for _, notification := range notifications {
+		s.pusher.PushToUser(notification.UserID, "notification", notification)
+	}
+	return nil
+}
+
+// NotifyFollowers отправляет событие всем, кто следит за петицией
+func (s *NotificationService) NotifyFollowers(petitionID uint, event models.Notification) error {
+	followers, err := s.subscriptions.GetFollowerIDs(petitionID)
+	if err != nil {
+		return err
+	}
+	event.PetitionID = petitionID
+	return s.Notify(followers, event)
+}
+
+// CommentCreated уведомляет автора петиции о новом комментарии, а автора родительского комментария - об ответе
+func (s *NotificationService) CommentCreated(comment *models.Comment) error {
+	petition, err := s.petitions.GetByID(comment.PetitionID)
+	if err != nil {
+		return err
+	}
+	event := models.Notification{
+		PetitionID: comment.PetitionID,
+		CommentID:  comment.ID,
+		ActorID:    comment.UserID,
+		Text:       excerpt(comment.Content),
+	}
+
+	var repliedTo uint
+	if comment.ParentID != nil {
+		parent, err := s.comments.GetByID(*comment.ParentID)
+		if err != nil {
+			return err
+		}
+		repliedTo = parent.UserID
+		reply := event
+		reply.Type = models.NotificationCommentReply
+		if err := s.Notify([]uint{repliedTo}, reply); err != nil {
+			return err
+		}
+	}
+
+	// Автор петиции, которому ответили на комментарий, получает только уведомление об ответе
+	if petition.UserID == repliedTo {
+		return nil
+	}
+	event.Type = models.NotificationPetitionComment
+	return s.Notify([]uint{petition.UserID}, event)
+}
+
+// NewsPublished уведомляет следящих за петицией о новости автора
+func (s *NotificationService) NewsPublished(news *models.PetitionNews) error {
+	return s.NotifyFollowers(news.PetitionID, models.Notification{
+		Type:    models.NotificationPetitionNews,
+		ActorID: news.AuthorID,
+		Text:    excerpt(news.Title),
+	})
+}
+
+// NotifyTargetReached уведомляет следящих за петицией и представителей адресата о наборе подписей
+func (s *NotificationService) NotifyTargetReached(petition *models.Petition, recipient *models.Recipient, members []models.UserModel) error {
+	event := models.Notification{Type: models.NotificationPetitionStatus, PetitionID: petition.ID, Text: petition.Status}
+	if err := s.NotifyFollowers(petition.ID, event); err != nil {
+		return err
+	}
+	memberIDs := make([]uint, 0, len(members))
+	for _, member := range members {
+		memberIDs = append(memberIDs, member.ID)
+	}
+	return s.Notify(memberIDs, event)
+}
+
+// NotifyResponded уведомляет следящих за петицией об официальном ответе
+func (s *NotificationService) NotifyResponded(petition *models.Petition, response *models.PetitionResponse) error {
+	return s.NotifyFollowers(petition.ID, models.Notification{
+		Type:    models.NotificationPetitionStatus,
+		ActorID: response.AuthorID,
+		Text:    petition.Status,
+	})
+}
+
+// NotifyMilestone уведомляет следящих за петицией о пройденной вехе сбора подписей
+func (s *NotificationService) NotifyMilestone(petition *models.Petition, milestone *models.PetitionMilestone) error {
+	return s.NotifyFollowers(petition.ID, models.Notification{
+		Type: models.NotificationPetitionMilestone,
+		Text: strconv.Itoa(milestone.Percent),
+	})
+}
+
+// List возвращает страницу уведомлений пользователя вместе с числом непрочитанных
+func (s *NotificationService) List(userID uint, filter models.NotificationFilter) (*models.NotificationPage, error) {
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = 20
+	}
+	items, total, err := s.repo.GetByUserID(userID, filter.UnreadOnly, filter.Page, filter.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.repo.CountUnread(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.NotificationPage{
+		Items:    items,
+		Total:    total,
+		Unread:   unread,
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+	}, nil
+}
+
+// UnreadCount возвращает число непрочитанных уведомлений
+func (s *NotificationService) UnreadCount(userID uint) (int64, error) {
+	return s.repo.CountUnread(userID)
+}
+
+// MarkRead отмечает уведомления прочитанными. Пустой список - все уведомления пользователя.
+// Другие вкладки пользователя получают новое число непрочитанных
+func (s *NotificationService) MarkRead(userID uint, ids []uint) (int64, error) {
+	var marked int64
+	var err error
+	if len(ids) == 0 {
+		marked, err = s.repo.MarkAllRead(userID, time.Now())
+	} else {
+		marked, err = s.repo.MarkRead(userID, ids, time.Now())
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	unread, err := s.repo.CountUnread(userID)
+	if err != nil {
+		s.logger.Errorf("Failed to count unread notifications of user %d: %v", userID, err)
+		return marked, nil
+	}
+	s.pusher.PushToUser(userID, "unread_count", map[string]int64{"unread": unread})
+	return marked, nil
+}
+
+// excerpt Обрезает текст для уведомления по границе символа
+func excerpt(text string) string {
+	if utf8.RuneCountInString(text) <= notificationTextLimit {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:notificationTextLimit-1]) + "…"
+}
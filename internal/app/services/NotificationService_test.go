@@ -0,0 +1,98 @@
+package services
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/testutil"
+	"testing"
+)
+
+type recordingPusher struct {
+	messages map[uint][]string
+}
+
+func (p *recordingPusher) PushToUser(userID uint, messageType string, payload interface{}) {
+	p.messages[userID] = append(p.messages[userID], messageType)
+}
+
+func newNotificationTest(t *testing.T) (*gorm.DB, *NotificationService, *recordingPusher) {
+	db := testutil.OpenDB(t, models.Petition{}, models.Vote{}, models.Comment{}, models.Notification{}, models.PetitionSubscription{})
+	logger := testutil.Logger()
+	pusher := &recordingPusher{messages: make(map[uint][]string)}
+	service := NewNotificationService(
+		repository.NewNotificationRepository(db, logger),
+		repository.NewSubscriptionRepository(db, logger),
+		repository.NewPetitionRepository(db, logger),
+		repository.NewCommentRepository(db, logger),
+		pusher,
+		logger,
+	)
+	return db, service, pusher
+}
+
+func TestCommentNotifications(t *testing.T) {
+	db, service, pusher := newNotificationTest(t)
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", UserID: 1}
+	db.Create(&petition)
+
+	comment := models.Comment{Content: "Поддерживаю", UserID: 2, Login: "second", PetitionID: petition.ID}
+	db.Create(&comment)
+	assert.NoError(t, service.CommentCreated(&comment))
+
+	reply := models.Comment{Content: "Спасибо", UserID: 3, Login: "third", PetitionID: petition.ID, ParentID: &comment.ID}
+	db.Create(&reply)
+	assert.NoError(t, service.CommentCreated(&reply))
+
+	// Автор петиции отвечает сам себе в обсуждении - уведомлений нет
+	own := models.Comment{Content: "Уточнение", UserID: 1, Login: "author", PetitionID: petition.ID}
+	db.Create(&own)
+	assert.NoError(t, service.CommentCreated(&own))
+
+	authorPage, err := service.List(1, models.NotificationFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), authorPage.Unread)
+	assert.Equal(t, models.NotificationPetitionComment, authorPage.Items[0].Type)
+	assert.Equal(t, reply.ID, authorPage.Items[0].CommentID)
+
+	commenterPage, err := service.List(2, models.NotificationFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, commenterPage.Items, 1)
+	assert.Equal(t, models.NotificationCommentReply, commenterPage.Items[0].Type)
+	assert.Equal(t, []string{"notification"}, pusher.messages[2])
+}
+
+func TestMarkNotificationsRead(t *testing.T) {
+	db, service, pusher := newNotificationTest(t)
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", UserID: 1}
+	db.Create(&petition)
+	db.Create(&models.PetitionSubscription{UserID: 2, PetitionID: petition.ID})
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, service.NotifyFollowers(petition.ID, models.Notification{Type: models.NotificationPetitionStatus, Text: "accepted"}))
+	}
+	page, err := service.List(2, models.NotificationFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), page.Unread)
+
+	// Чужие уведомления не отмечаются
+	marked, err := service.MarkRead(1, []uint{page.Items[0].ID})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), marked)
+
+	marked, err = service.MarkRead(2, []uint{page.Items[0].ID})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), marked)
+	unread, _ := service.UnreadCount(2)
+	assert.Equal(t, int64(2), unread)
+
+	page, err = service.List(2, models.NotificationFilter{UnreadOnly: true})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 2)
+
+	marked, err = service.MarkRead(2, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), marked)
+	assert.Contains(t, pusher.messages[2], "unread_count")
+}
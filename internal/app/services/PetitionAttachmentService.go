@@ -0,0 +1,259 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"path/filepath"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/storage"
+	"petition_api/utils/auth"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// ErrAttachmentTooLarge Файл больше допустимого размера
+	ErrAttachmentTooLarge = errors.New("attachment file is too large")
+	// ErrAttachmentType Тип файла не разрешен для вложений
+	ErrAttachmentType = errors.New("attachment must be a PDF, JPEG, PNG or GIF file")
+	// ErrCoverNotImage Обложкой может быть только изображение
+	ErrCoverNotImage = errors.New("cover must be a JPEG, PNG or GIF image")
+	// ErrAttachmentQuota У петиции закончилась квота на вложения
+	ErrAttachmentQuota = errors.New("petition attachment quota exceeded")
+	// ErrAttachmentKind Неизвестный вид вложения
+	ErrAttachmentKind = errors.New("attachment kind must be cover or document")
+)
+
+// attachmentExtensions Разрешенные типы вложений (определяются по содержимому) и расширения файлов в хранилище
+var attachmentExtensions = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+}
+
+// AttachmentQuota Ограничения на вложения одной петиции
+type AttachmentQuota struct {
+	// MaxFileSize Максимальный размер одного файла в байтах
+	MaxFileSize int64
+	// MaxFiles Максимальное число документов у петиции
+	MaxFiles int64
+	// MaxTotalSize Максимальный общий размер документов петиции в байтах
+	MaxTotalSize int64
+}
+
+// PetitionAttachmentService Загрузка, выдача и удаление файлов петиций
+type PetitionAttachmentService struct {
+	repo    repository.PetitionAttachmentRepository
+	storage storage.Storage
+	quota   AttachmentQuota
+	logger  *logrus.Logger
+}
+
+func NewPetitionAttachmentService(repo repository.PetitionAttachmentRepository, storage storage.Storage, quota AttachmentQuota, logger *logrus.Logger) *PetitionAttachmentService {
+	return &PetitionAttachmentService{
+		repo:    repo,
+		storage: storage,
+		quota:   quota,
+		logger:  logger,
+	}
+}
+
+// MaxFileSize Максимальный размер одного файла в байтах
+func (s *PetitionAttachmentService) MaxFileSize() int64 {
+	return s.quota.MaxFileSize
+}
+
+// Add Проверяет файл и квоту петиции и сохраняет вложение. Новая обложка заменяет старую
+func (s *PetitionAttachmentService) Add(petitionID uint, userID uint, kind string, fileName string, file io.Reader) (*models.PetitionAttachment, error) {
+	if kind != models.AttachmentKindCover && kind != models.AttachmentKindDocument {
+		return nil, ErrAttachmentKind
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, s.quota.MaxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.quota.MaxFileSize {
+		return nil, ErrAttachmentTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	extension, ok := attachmentExtensions[contentType]
+	if !ok {
+		return nil, ErrAttachmentType
+	}
+	if kind == models.AttachmentKindCover && !strings.HasPrefix(contentType, "image/") {
+		return nil, ErrCoverNotImage
+	}
+
+	// Предварительная проверка, чтобы не сохранять файл, который заведомо не поместится.
+	// Окончательно квота проверяется в транзакции ниже
+	if kind == models.AttachmentKindDocument {
+		if err := s.checkQuota(s.repo.DB, petitionID, int64(len(data))); err != nil {
+			return nil, err
+		}
+	}
+
+	random, err := auth.RandomToken(12)
+	if err != nil {
+		return nil, err
+	}
+	attachment := models.PetitionAttachment{
+		PetitionID:  petitionID,
+		UserID:      userID,
+		Kind:        kind,
+		FileName:    cleanFileName(fileName, extension),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		StorageKey:  fmt.Sprintf("petitions/%d/%s%s", petitionID, random, extension),
+	}
+	if err := s.storage.Put(attachment.StorageKey, bytes.NewReader(data), contentType); err != nil {
+		return nil, err
+	}
+	// Строка петиции заблокирована до конца транзакции: параллельная загрузка дождется ее
+	// и увидит этот файл в квоте или эту обложку как старую
+	var oldCovers []models.PetitionAttachment
+	err = s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.repo.LockPetitionTx(tx, petitionID); err != nil {
+			return err
+		}
+		if kind == models.AttachmentKindDocument {
+			if err := s.checkQuota(tx, petitionID, attachment.Size); err != nil {
+				return err
+			}
+		} else {
+			covers, err := s.repo.GetCoversTx(tx, petitionID)
+			if err != nil {
+				return err
+			}
+			for _, cover := range covers {
+				if err := s.repo.DeleteByIDTx(tx, cover.ID); err != nil {
+					return err
+				}
+			}
+			oldCovers = covers
+		}
+		return s.repo.CreateTx(tx, &attachment)
+	})
+	if err != nil {
+		s.removeFile(attachment.StorageKey)
+		return nil, err
+	}
+	for _, cover := range oldCovers {
+		s.removeFile(cover.StorageKey)
+	}
+
+	s.logger.Infof("User %d attached %s %d to petition %d", userID, kind, attachment.ID, petitionID)
+	attachment.DownloadURL = models.AttachmentDownloadPath(petitionID, attachment.ID)
+	return &attachment, nil
+}
+
+// GetAll Возвращает вложения петиции со ссылками на скачивание
+func (s *PetitionAttachmentService) GetAll(petitionID uint) ([]models.PetitionAttachment, error) {
+	attachments, err := s.repo.GetAllByPetitionID(petitionID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range attachments {
+		attachments[i].DownloadURL = models.AttachmentDownloadPath(petitionID, attachments[i].ID)
+	}
+	return attachments, nil
+}
+
+// Get Возвращает вложение петиции
+func (s *PetitionAttachmentService) Get(petitionID uint, id uint) (*models.PetitionAttachment, error) {
+	attachment, err := s.repo.GetByID(petitionID, id)
+	if err != nil {
+		return nil, err
+	}
+	attachment.DownloadURL = models.AttachmentDownloadPath(petitionID, attachment.ID)
+	return attachment, nil
+}
+
+// Open Открывает файл вложения на чтение
+func (s *PetitionAttachmentService) Open(attachment *models.PetitionAttachment) (io.ReadCloser, error) {
+	return s.storage.Open(attachment.StorageKey)
+}
+
+// CoverURLs Возвращает ссылки на обложки петиций, ключ - ID петиции
+func (s *PetitionAttachmentService) CoverURLs(petitionIDs []uint) (map[uint]string, error) {
+	covers, err := s.repo.GetCovers(petitionIDs)
+	if err != nil {
+		return nil, err
+	}
+	urls := make(map[uint]string, len(covers))
+	for petitionID, cover := range covers {
+		urls[petitionID] = models.AttachmentDownloadPath(petitionID, cover.ID)
+	}
+	return urls, nil
+}
+
+// Delete Удаляет вложение и его файл
+func (s *PetitionAttachmentService) Delete(attachment *models.PetitionAttachment) error {
+	if err := s.repo.DeleteByID(attachment.ID); err != nil {
+		return err
+	}
+	s.removeFile(attachment.StorageKey)
+	return nil
+}
+
+// DeleteAllForPetition Удаляет все вложения петиции вместе с файлами
+func (s *PetitionAttachmentService) DeleteAllForPetition(petitionID uint) error {
+	attachments, err := s.repo.GetAllByPetitionID(petitionID)
+	if err != nil {
+		return err
+	}
+	for i := range attachments {
+		if err := s.Delete(&attachments[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkQuota Проверяет, что документ размером size помещается в квоту петиции
+func (s *PetitionAttachmentService) checkQuota(tx *gorm.DB, petitionID uint, size int64) error {
+	count, totalSize, err := s.repo.GetUsageTx(tx, petitionID)
+	if err != nil {
+		return err
+	}
+	if count+1 > s.quota.MaxFiles || totalSize+size > s.quota.MaxTotalSize {
+		return ErrAttachmentQuota
+	}
+	return nil
+}
+
+func (s *PetitionAttachmentService) removeFile(key string) {
+	if err := s.storage.Delete(key); err != nil {
+		s.logger.Errorf("Failed to remove attachment file %s: %v", key, err)
+	}
+}
+
+// cleanFileName Оставляет от имени файла только безопасное имя без пути и управляющих символов.
+// Если имени нет, подставляется имя по расширению
+func cleanFileName(name string, extension string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "file" + extension
+	}
+	// Имя обрезается по символам, чтобы не разрезать кириллицу посередине
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
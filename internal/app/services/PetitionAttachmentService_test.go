@@ -0,0 +1,174 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"path/filepath"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/storage"
+	"petition_api/internal/app/testutil"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var testPDF = []byte("%PDF-1.4\n1 0 obj << /Type /Catalog >> endobj\n%%EOF")
+
+func newAttachmentTest(t *testing.T) (storage.Storage, *PetitionAttachmentService) {
+	db := testutil.OpenDB(t, models.Petition{}, models.PetitionAttachment{})
+	files := storage.NewLocalStorage(t.TempDir(), "/files")
+	service := NewPetitionAttachmentService(
+		repository.NewPetitionAttachmentRepository(db, testutil.Logger()),
+		files,
+		AttachmentQuota{MaxFileSize: 1024, MaxFiles: 2, MaxTotalSize: 150},
+		testutil.Logger(),
+	)
+	return files, service
+}
+
+func TestAttachmentValidation(t *testing.T) {
+	_, service := newAttachmentTest(t)
+
+	attachment, err := service.Add(1, 5, models.AttachmentKindDocument, `C:\docs\..\письмо "акимату".pdf`, bytes.NewReader(testPDF))
+	assert.NoError(t, err)
+	assert.Equal(t, "application/pdf", attachment.ContentType)
+	assert.Equal(t, "письмо акимату.pdf", attachment.FileName)
+	assert.Equal(t, "/petition/1/attachments/1", attachment.DownloadURL)
+
+	_, err = service.Add(1, 5, models.AttachmentKindDocument, "run.pdf", strings.NewReader("#!/bin/sh\nrm -rf /"))
+	assert.ErrorIs(t, err, ErrAttachmentType)
+
+	_, err = service.Add(1, 5, models.AttachmentKindCover, "cover.pdf", bytes.NewReader(testPDF))
+	assert.ErrorIs(t, err, ErrCoverNotImage)
+
+	_, err = service.Add(1, 5, models.AttachmentKindDocument, "big.pdf", bytes.NewReader(append(testPDF, make([]byte, 1024)...)))
+	assert.ErrorIs(t, err, ErrAttachmentTooLarge)
+
+	_, err = service.Add(1, 5, "video", "a.pdf", bytes.NewReader(testPDF))
+	assert.ErrorIs(t, err, ErrAttachmentKind)
+}
+
+func TestAttachmentQuota(t *testing.T) {
+	_, service := newAttachmentTest(t)
+
+	_, err := service.Add(1, 5, models.AttachmentKindDocument, "a.pdf", bytes.NewReader(testPDF))
+	assert.NoError(t, err)
+	_, err = service.Add(1, 5, models.AttachmentKindDocument, "b.pdf", bytes.NewReader(testPDF))
+	assert.NoError(t, err)
+	// Третий файл не помещается ни по числу, ни по общему размеру
+	_, err = service.Add(1, 5, models.AttachmentKindDocument, "c.pdf", bytes.NewReader(testPDF))
+	assert.ErrorIs(t, err, ErrAttachmentQuota)
+
+	// Квота считается для каждой петиции отдельно
+	_, err = service.Add(2, 5, models.AttachmentKindDocument, "c.pdf", bytes.NewReader(testPDF))
+	assert.NoError(t, err)
+}
+
+func TestAttachmentCoverAndCleanup(t *testing.T) {
+	files, service := newAttachmentTest(t)
+
+	first, err := service.Add(1, 5, models.AttachmentKindCover, "cover.png", bytes.NewReader(testPNG(t, 10, 10)))
+	assert.NoError(t, err)
+	second, err := service.Add(1, 5, models.AttachmentKindCover, "cover2.png", bytes.NewReader(testPNG(t, 20, 20)))
+	assert.NoError(t, err)
+	_, err = service.Add(1, 5, models.AttachmentKindDocument, "a.pdf", bytes.NewReader(testPDF))
+	assert.NoError(t, err)
+
+	// Новая обложка заменяет старую
+	_, err = files.Open(first.StorageKey)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	urls, err := service.CoverURLs([]uint{1, 2})
+	assert.NoError(t, err)
+	assert.Equal(t, map[uint]string{1: second.DownloadURL}, urls)
+
+	all, err := service.GetAll(1)
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+	assert.Equal(t, models.AttachmentKindCover, all[0].Kind)
+
+	assert.NoError(t, service.DeleteAllForPetition(1))
+	all, err = service.GetAll(1)
+	assert.NoError(t, err)
+	assert.Empty(t, all)
+	_, err = files.Open(second.StorageKey)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+// gatedStorage Хранилище, которое сохраняет файлы только после того, как все загрузки прошли предварительную проверку
+type gatedStorage struct {
+	storage.Storage
+	arrived sync.WaitGroup
+}
+
+func (g *gatedStorage) Put(key string, body io.Reader, contentType string) error {
+	g.arrived.Done()
+	g.arrived.Wait()
+	return g.Storage.Put(key, body, contentType)
+}
+
+func TestAttachmentQuotaUnderConcurrentUploads(t *testing.T) {
+	const uploads = 8
+	db := testutil.OpenDB(t, models.Petition{}, models.PetitionAttachment{})
+	// SQLite не блокирует строки, поэтому транзакции выполняются по одной через единственное соединение
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	dir := t.TempDir()
+	files := &gatedStorage{Storage: storage.NewLocalStorage(dir, "/files")}
+	files.arrived.Add(2 * uploads)
+	service := NewPetitionAttachmentService(
+		repository.NewPetitionAttachmentRepository(db, testutil.Logger()),
+		files,
+		AttachmentQuota{MaxFileSize: 1024, MaxFiles: 2, MaxTotalSize: 150},
+		testutil.Logger(),
+	)
+
+	images := make([][]byte, uploads)
+	for i := range images {
+		images[i] = testPNG(t, 10+i, 10)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*uploads)
+	for i := 0; i < uploads; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			_, err := service.Add(1, 5, models.AttachmentKindDocument, fmt.Sprintf("%d.pdf", i), bytes.NewReader(testPDF))
+			errs <- err
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			_, err := service.Add(1, 5, models.AttachmentKindCover, fmt.Sprintf("%d.png", i), bytes.NewReader(images[i]))
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			assert.ErrorIs(t, err, ErrAttachmentQuota)
+		}
+	}
+
+	// Документов не больше квоты, обложка одна, а файлы отклоненных загрузок и замененных обложек удалены
+	all, err := service.GetAll(1)
+	assert.NoError(t, err)
+	var documents, covers int
+	for _, attachment := range all {
+		if attachment.Kind == models.AttachmentKindCover {
+			covers++
+		} else {
+			documents++
+		}
+	}
+	assert.Equal(t, 2, documents)
+	assert.Equal(t, 1, covers)
+	stored, err := filepath.Glob(filepath.Join(dir, "petitions", "1", "*"))
+	assert.NoError(t, err)
+	assert.Len(t, stored, len(all))
+}
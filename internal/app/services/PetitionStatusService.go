@@ -0,0 +1,296 @@
+package services
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"time"
+)
+
+var (
+	// ErrNotPetitionRecipient Пользователь не представляет адресата петиции
+	ErrNotPetitionRecipient = errors.New("user does not represent the petition recipient")
+	// ErrPetitionAlreadyAnswered Адресат уже ответил на петицию
+	ErrPetitionAlreadyAnswered = errors.New("petition already has an official response")
+)
+
+// StatusNotifier Получает события о смене статуса петиции
+type StatusNotifier interface {
+	// NotifyTargetReached Петиция набрала нужное число подписей.
+	// recipient и members пустые, если у петиции нет адресата из справочника
+	NotifyTargetReached(petition *models.Petition, recipient *models.Recipient, members []models.UserModel) error
+	// NotifyResponded Адресат опубликовал официальный ответ
+	NotifyResponded(petition *models.Petition, response *models.PetitionResponse) error
+	// NotifyMilestone Петиция впервые набрала долю цели из models.MilestonePercents
+	NotifyMilestone(petition *models.Petition, milestone *models.PetitionMilestone) error
+}
+
+// PetitionStatusService Переводит петиции по статусам: сбор подписей, ожидание ответа, ответ адресата
+type PetitionStatusService struct {
+	petitions  repository.PetitionRepository
+	votes      repository.VoteRepository
+	users      repository.UserRepository
+	recipients repository.RecipientRepository
+	responses  repository.PetitionResponseRepository
+	milestones repository.PetitionMilestoneRepository
+	events     repository.WebhookEventRepository
+	notifiers  []StatusNotifier
+	logger     *logrus.Logger
+}
+
+func NewPetitionStatusService(
+	petitions repository.PetitionRepository,
+	votes repository.VoteRepository,
+	users repository.UserRepository,
+	recipients repository.RecipientRepository,
+	responses repository.PetitionResponseRepository,
+	milestones repository.PetitionMilestoneRepository,
+	events repository.WebhookEventRepository,
+	logger *logrus.Logger,
+) *PetitionStatusService {
+	return &PetitionStatusService{
+		petitions:  petitions,
+		votes:      votes,
+		users:      users,
+		recipients: recipients,
+		responses:  responses,
+		milestones: milestones,
+		events:     events,
+		logger:     logger,
+	}
+}
+
+// AddNotifier добавляет получателя событий о смене статуса петиций
+func (s *PetitionStatusService) AddNotifier(notifier StatusNotifier) {
+	s.notifiers = append(s.notifiers, notifier)
+}
+
+// CheckTarget отмечает пройденные вехи сбора подписей и проверяет, набрала ли петиция TargetByVote подписей.
+// При первом наборе переводит ее в ожидание ответа и уведомляет адресата. Возвращает true, если цель достигнута этим вызовом.
+// Голоса проходят вехи в своей транзакции (VoteService.Vote), здесь вехи проверяются после правки цели
+func (s *PetitionStatusService) CheckTarget(petitionID uint) (bool, error) {
+	var petition *models.Petition
+	var count int64
+	var milestone *models.PetitionMilestone
+	// Подсчет и вехи под блокировкой строки петиции, чтобы параллельный голос не сохранил те же вехи со своим счетчиком
+	err := s.petitions.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if petition, err = s.petitions.GetForUpdateTx(tx, petitionID); err != nil {
+			return err
+		}
+		if count, err = s.votes.GetCountVoteByPetitionIDTx(tx, petitionID); err != nil {
+			return err
+		}
+		milestone, err = s.RecordMilestonesTx(tx, petition, count)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	if milestone != nil {
+		s.notifyMilestone(petition, milestone)
+	}
+	return s.checkTargetReached(petition, count)
+}
+
+// checkTargetReached Переводит открытую петицию с count подписями в ожидание ответа, если цель набрана впервые
+func (s *PetitionStatusService) checkTargetReached(petition *models.Petition, count int64) (bool, error) {
+	if petition.TargetByVote == 0 {
+		return false, nil
+	}
+	if petition.Status != models.PetitionStatusOpen || petition.TargetReachedAt != nil {
+		return false, nil
+	}
+	if count < int64(petition.TargetByVote) {
+		return false, nil
+	}
+
+	petitionID := petition.ID
+	now := time.Now()
+	var reached bool
+	// Смена статуса и событие для вебхуков сохраняются вместе
+	err := s.petitions.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if reached, err = s.petitions.MarkTargetReachedTx(tx, petitionID, now); err != nil || !reached {
+			return err
+		}
+		return s.events.CreateTx(tx, models.WebhookPetitionSucceeded, map[string]interface{}{
+			"petition_id":       petitionID,
+			"status":            models.PetitionStatusAwaitingResponse,
+			"target_by_vote":    petition.TargetByVote,
+			"vote_count":        count,
+			"target_reached_at": now,
+		})
+	})
+	if err != nil || !reached {
+		return false, err
+	}
+	petition.Status = models.PetitionStatusAwaitingResponse
+	petition.TargetReachedAt = &now
+	s.logger.Infof("Petition %d reached its target of %d signatures", petitionID, petition.TargetByVote)
+
+	s.notifyTargetReached(petition)
+	return true, nil
+}
+
+// RecordMilestonesTx Сохраняет в транзакции вехи, которые покрывает count подписей, и события для вебхуков о них.
+// Повторно веха не сохраняется благодаря уникальному индексу. Возвращает старшую новую веху или nil.
+// Если вызов прошел сразу несколько вех (например, автор уменьшил цель), вебхуки получают каждую, а уведомление - только старшую
+func (s *PetitionStatusService) RecordMilestonesTx(tx *gorm.DB, petition *models.Petition, count int64) (*models.PetitionMilestone, error) {
+	var latest *models.PetitionMilestone
+	now := time.Now()
+	for _, percent := range models.MilestonesReached(count, petition.TargetByVote) {
+		milestone := models.PetitionMilestone{
+			PetitionID: petition.ID,
+			Percent:    percent,
+			Target:     petition.TargetByVote,
+			VoteCount:  count,
+			ReachedAt:  now,
+		}
+		created, err := s.milestones.CreateTx(tx, &milestone)
+		if err != nil {
+			return nil, err
+		}
+		if !created {
+			continue
+		}
+		if err := s.events.CreateTx(tx, models.WebhookPetitionMilestone, milestone); err != nil {
+			return nil, err
+		}
+		latest = &milestone
+	}
+	return latest, nil
+}
+
+// notifyMilestone Рассылает сохраненную веху получателям уведомлений
+func (s *PetitionStatusService) notifyMilestone(petition *models.Petition, milestone *models.PetitionMilestone) {
+	s.logger.Infof("Petition %d reached %d%% of its target", petition.ID, milestone.Percent)
+	for _, notifier := range s.notifiers {
+		if err := notifier.NotifyMilestone(petition, milestone); err != nil {
+			s.logger.Errorf("Failed to notify about milestone of petition %d: %v", petition.ID, err)
+		}
+	}
+}
+
+// GetMilestones возвращает пройденные вехи петиции
+func (s *PetitionStatusService) GetMilestones(petitionID uint) ([]models.PetitionMilestone, error) {
+	return s.milestones.GetByPetitionID(petitionID)
+}
+
+// notifyTargetReached Рассылает событие всем получателям. Ошибка одного получателя не мешает остальным
+func (s *PetitionStatusService) notifyTargetReached(petition *models.Petition) {
+	var recipient *models.Recipient
+	var members []models.UserModel
+	if petition.RecipientID != nil {
+		var err error
+		if recipient, err = s.recipients.GetByID(*petition.RecipientID); err != nil {
+			s.logger.Errorf("Failed to get recipient of petition %d: %v", petition.ID, err)
+		} else if members, err = s.recipients.GetMembers(recipient.ID); err != nil {
+			s.logger.Errorf("Failed to get members of recipient %d: %v", recipient.ID, err)
+		}
+	}
+
+	for _, notifier := range s.notifiers {
+		if err := notifier.NotifyTargetReached(petition, recipient, members); err != nil {
+			s.logger.Errorf("Failed to notify about petition %d target: %v", petition.ID, err)
+		}
+	}
+}
+
+// GetResponse возвращает официальный ответ на петицию или nil, если ответа нет
+func (s *PetitionStatusService) GetResponse(petitionID uint) (*models.PetitionResponse, error) {
+	return s.responses.GetByPetitionID(petitionID)
+}
+
+// Respond публикует официальный ответ адресата и меняет статус петиции по решению.
+// Отвечать может только аккаунт, привязанный к адресату петиции, и только один раз
+func (s *PetitionStatusService) Respond(petition *models.Petition, userID uint, input models.PetitionResponseInput) (*models.PetitionResponse, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.RecipientID == nil || petition.RecipientID == nil || *user.RecipientID != *petition.RecipientID {
+		return nil, ErrNotPetitionRecipient
+	}
+
+	existing, err := s.responses.GetByPetitionID(petition.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrPetitionAlreadyAnswered
+	}
+
+	response := models.PetitionResponse{
+		PetitionID:  petition.ID,
+		RecipientID: *petition.RecipientID,
+		AuthorID:    userID,
+		Decision:    input.Decision,
+		Content:     input.Content,
+	}
+	status := models.PetitionStatusForDecision(input.Decision)
+	// Ответ, новый статус и событие для вебхуков сохраняются вместе, уникальный индекс по petition_id не даст ответить дважды
+	err = s.petitions.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.responses.CreateTx(tx, &response); err != nil {
+			return err
+		}
+		if err := s.petitions.SetStatusTx(tx, petition.ID, status); err != nil {
+			return err
+		}
+		return s.events.CreateTx(tx, models.WebhookPetitionResponded, map[string]interface{}{
+			"petition_id": petition.ID,
+			"status":      status,
+			"response":    response,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	petition.Status = status
+	petition.Response = &response
+
+	s.logger.Infof("Recipient %d responded to petition %d: %s", response.RecipientID, petition.ID, input.Decision)
+	for _, notifier := range s.notifiers {
+		if err := notifier.NotifyResponded(petition, &response); err != nil {
+			s.logger.Errorf("Failed to notify about response to petition %d: %v", petition.ID, err)
+		}
+	}
+	return &response, nil
+}
+
+// LogStatusNotifier Записывает в лог, кого из адресата нужно уведомить о наборе подписей
+type LogStatusNotifier struct {
+	logger *logrus.Logger
+}
+
+func NewLogStatusNotifier(logger *logrus.Logger) *LogStatusNotifier {
+	return &LogStatusNotifier{logger: logger}
+}
+
+func (n *LogStatusNotifier) NotifyTargetReached(petition *models.Petition, recipient *models.Recipient, members []models.UserModel) error {
+	if recipient == nil {
+		n.logger.Infof("Petition %d reached its target, recipient %q is not in the directory", petition.ID, petition.Recipient)
+		return nil
+	}
+	logins := make([]string, 0, len(members))
+	for _, member := range members {
+		logins = append(logins, member.Login)
+	}
+	n.logger.WithFields(logrus.Fields{
+		"petition_id":   petition.ID,
+		"recipient_id":  recipient.ID,
+		"contact_email": recipient.ContactEmail,
+		"members":       logins,
+	}).Info("Recipient notified: petition reached its target")
+	return nil
+}
+
+func (n *LogStatusNotifier) NotifyResponded(petition *models.Petition, response *models.PetitionResponse) error {
+	return nil
+}
+
+func (n *LogStatusNotifier) NotifyMilestone(petition *models.Petition, milestone *models.PetitionMilestone) error {
+	return nil
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/testutil"
+	"sync"
+	"testing"
+)
+
+type recordingNotifier struct {
+	mu         sync.Mutex
+	petitions  []uint
+	members    int
+	milestones []int
+}
+
+func (n *recordingNotifier) NotifyTargetReached(petition *models.Petition, recipient *models.Recipient, members []models.UserModel) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.petitions = append(n.petitions, petition.ID)
+	n.members += len(members)
+	return nil
+}
+
+func (n *recordingNotifier) NotifyResponded(petition *models.Petition, response *models.PetitionResponse) error {
+	return nil
+}
+
+func (n *recordingNotifier) NotifyMilestone(petition *models.Petition, milestone *models.PetitionMilestone) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.milestones = append(n.milestones, milestone.Percent)
+	return nil
+}
+
+func newPetitionStatusTest(t *testing.T) (*gorm.DB, *PetitionStatusService, *recordingNotifier) {
+	db := testutil.OpenDB(t, models.UserModel{}, models.Petition{}, models.Vote{}, models.Recipient{}, models.PetitionResponse{}, models.PetitionMilestone{}, models.WebhookEvent{})
+	logger := testutil.Logger()
+	service := NewPetitionStatusService(
+		repository.NewPetitionRepository(db, logger),
+		repository.NewVoteRepository(db, logger),
+		repository.NewUserRepository(db, logger),
+		repository.NewRecipientRepository(db, logger),
+		repository.NewPetitionResponseRepository(db, logger),
+		repository.NewPetitionMilestoneRepository(db, logger),
+		repository.NewWebhookEventRepository(db, logger),
+		logger,
+	)
+	notifier := &recordingNotifier{}
+	service.AddNotifier(notifier)
+	return db, service, notifier
+}
+
+func TestCheckTargetNotifiesOnce(t *testing.T) {
+	db, service, notifier := newPetitionStatusTest(t)
+	recipient := models.Recipient{Organisation: "Акимат Алматы"}
+	db.Create(&recipient)
+	member := createTestUser(t, db, "akimat", models.StatusActive)
+	db.Model(member).Update("recipient_id", recipient.ID)
+
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", TargetByVote: 2, UserID: 1, RecipientID: &recipient.ID}
+	db.Create(&petition)
+	db.Create(&models.Vote{PetitionID: petition.ID, UserID: 10})
+
+	reached, err := service.CheckTarget(petition.ID)
+	assert.NoError(t, err)
+	assert.False(t, reached)
+
+	db.Create(&models.Vote{PetitionID: petition.ID, UserID: 11})
+	// Одновременные проверки после последнего голоса уведомляют адресата только один раз
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = service.CheckTarget(petition.ID)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, []uint{petition.ID}, notifier.petitions)
+	assert.Equal(t, 1, notifier.members)
+	var stored models.Petition
+	db.First(&stored, petition.ID)
+	assert.Equal(t, models.PetitionStatusAwaitingResponse, stored.Status)
+	assert.NotNil(t, stored.TargetReachedAt)
+}
+
+func TestRespond(t *testing.T) {
+	db, service, _ := newPetitionStatusTest(t)
+	recipient := models.Recipient{Organisation: "Акимат Алматы"}
+	other := models.Recipient{Organisation: "Маслихат"}
+	db.Create(&recipient)
+	db.Create(&other)
+	member := createTestUser(t, db, "akimat", models.StatusActive)
+	db.Model(member).Update("recipient_id", recipient.ID)
+	stranger := createTestUser(t, db, "maslihat", models.StatusActive)
+	db.Model(stranger).Update("recipient_id", other.ID)
+
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", TargetByVote: 2, UserID: 1, RecipientID: &recipient.ID}
+	db.Create(&petition)
+	input := models.PetitionResponseInput{Decision: models.ResponseDecisionAccepted, Content: "Парк построим в 2027 году"}
+
+	_, err := service.Respond(&petition, stranger.ID, input)
+	assert.ErrorIs(t, err, ErrNotPetitionRecipient)
+
+	response, err := service.Respond(&petition, member.ID, input)
+	assert.NoError(t, err)
+	assert.Equal(t, recipient.ID, response.RecipientID)
+	assert.Equal(t, models.PetitionStatusAccepted, petition.Status)
+
+	_, err = service.Respond(&petition, member.ID, input)
+	assert.ErrorIs(t, err, ErrPetitionAlreadyAnswered)
+
+	stored, err := service.GetResponse(petition.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Парк построим в 2027 году", stored.Content)
+}
+
+func TestCheckTargetMilestones(t *testing.T) {
+	db, service, notifier := newPetitionStatusTest(t)
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", TargetByVote: 20, UserID: 1}
+	db.Create(&petition)
+
+	vote := func(userID uint) {
+		db.Create(&models.Vote{PetitionID: petition.ID, UserID: userID})
+	}
+	vote(10)
+	_, _ = service.CheckTarget(petition.ID)
+	assert.Empty(t, notifier.milestones)
+
+	vote(11)
+	// Одновременные голоса отмечают веху только один раз
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = service.CheckTarget(petition.ID)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, []int{10}, notifier.milestones)
+
+	// Отозванный и снова отданный голос не повторяет веху
+	db.Unscoped().Where("user_id = ?", 11).Delete(&models.Vote{})
+	_, _ = service.CheckTarget(petition.ID)
+	vote(11)
+	_, _ = service.CheckTarget(petition.ID)
+	assert.Equal(t, []int{10}, notifier.milestones)
+
+	// Уменьшение цели проходит сразу несколько вех, уведомление только о старшей
+	db.Model(&petition).Update("target_by_vote", 4)
+	_, _ = service.CheckTarget(petition.ID)
+	assert.Equal(t, []int{10, 50}, notifier.milestones)
+
+	milestones, err := service.GetMilestones(petition.ID)
+	assert.NoError(t, err)
+	assert.Len(t, milestones, 3)
+	assert.Equal(t, 50, milestones[2].Percent)
+	assert.Equal(t, uint(4), milestones[2].Target)
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/utils/pdf"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Разметка PDF со списком подписей, в пунктах
+const (
+	signaturesMargin     = 40.0
+	signaturesTop        = 50.0
+	signaturesBottom     = pdf.PageHeight - 50
+	signaturesRowHeight  = 14.0
+	signaturesFontSize   = 9.0
+	signaturesTextIndent = 4.0
+)
+
+// signatureColumns Колонки таблицы подписей: заголовок, левый край и ширина
+var signatureColumns = []struct {
+	title string
+	x     float64
+	width float64
+}{
+	{"№", signaturesMargin, 45},
+	{"Логин", signaturesMargin + 45, 125},
+	{"Фамилия, имя", signaturesMargin + 170, 215},
+	{"Дата подписи", signaturesMargin + 385, pdf.PageWidth - 2*signaturesMargin - 385},
+}
+
+// petitionStatusTitles Статусы петиции в PDF
+var petitionStatusTitles = map[string]string{
+	models.PetitionStatusOpen:             "идет сбор подписей",
+	models.PetitionStatusAwaitingResponse: "ожидает ответа адресата",
+	models.PetitionStatusAccepted:         "принята",
+	models.PetitionStatusRejected:         "отклонена",
+}
+
+// SignatureExportService Выгружает список подписантов петиции для адресата в CSV и PDF.
+// Подписи читаются из базы построчно и сразу пишутся в ответ
+type SignatureExportService struct {
+	votes  repository.VoteRepository
+	users  repository.UserRepository
+	roles  repository.RoleRepository
+	logger *logrus.Logger
+}
+
+func NewSignatureExportService(votes repository.VoteRepository, users repository.UserRepository, roles repository.RoleRepository, logger *logrus.Logger) *SignatureExportService {
+	return &SignatureExportService{votes: votes, users: users, roles: roles, logger: logger}
+}
+
+// CanExport может ли пользователь выгрузить подписи: автор петиции, администратор
+// или представитель адресата, которому она направлена. Роль берется из базы, а не из токена
+func (s *SignatureExportService) CanExport(petition *models.Petition, userID uint) (bool, error) {
+	if petition.UserID == userID {
+		return true, nil
+	}
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return false, err
+	}
+	if ok, err := s.roles.HasPermission(user.Role, models.PermUserManage); err != nil || ok {
+		return ok, err
+	}
+	ok, err := s.roles.HasPermission(user.Role, models.PermPetitionRespond)
+	if err != nil || !ok || petition.RecipientID == nil {
+		return false, err
+	}
+	return user.RecipientID != nil && *user.RecipientID == *petition.RecipientID, nil
+}
+
+// WriteCSV пишет подписи в CSV. В начале стоит BOM, чтобы Excel прочитал кириллицу
+func (s *SignatureExportService) WriteCSV(w io.Writer, petition *models.Petition) error {
+	if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"number", "login", "last_name", "first_name", "signed_at"}); err != nil {
+		return err
+	}
+	number := 0
+	err := s.votes.EachSignature(petition.ID, func(signature models.Signature) error {
+		number++
+		return writer.Write([]string{
+			strconv.Itoa(number),
+			csvCell(signature.Login),
+			csvCell(signature.LastName),
+			csvCell(signature.FirstName),
+			signature.SignedAt.UTC().Format(time.RFC3339),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvCell Экранирует значение, которое Excel и другие таблицы приняли бы за формулу:
+// перед =, +, -, @, табуляцией, возвратом каретки и переводом строки в начале ячейки ставится апостроф
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r\n", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// WritePDF пишет постраничный PDF: данные петиции, ее текст, итоги и таблицу подписантов
+func (s *SignatureExportService) WritePDF(w io.Writer, petition *models.Petition, now time.Time) error {
+	regular, err := pdf.Sans()
+	if err != nil {
+		return err
+	}
+	bold, err := pdf.SansBold()
+	if err != nil {
+		return err
+	}
+	total, err := s.votes.GetCountVoteByPetitionID(petition.ID)
+	if err != nil {
+		return err
+	}
+
+	doc := &signatureDocument{
+		pw:      pdf.NewWriter(w, fmt.Sprintf("Подписи под петицией №%d", petition.ID), regular, bold),
+		regular: regular,
+		bold:    bold,
+	}
+	doc.newPage()
+
+	doc.paragraph(bold, 16, "Список подписей под петицией")
+	doc.y += 4
+	doc.paragraph(bold, 12, petition.Title)
+	doc.y += 4
+
+	recipient := petition.Recipient
+	if recipient == "" {
+		recipient = "не указан"
+	}
+	status := petitionStatusTitles[petition.Status]
+	if status == "" {
+		status = petition.Status
+	}
+	for _, line := range []string{
+		fmt.Sprintf("Петиция №%d, создана %s", petition.ID, petition.CreatedAt.UTC().Format("02.01.2006")),
+		"Адресат: " + recipient,
+		fmt.Sprintf("Цель: %d подписей. Статус: %s", petition.TargetByVote, status),
+		fmt.Sprintf("Всего подписей: %d, из них обезличенных (удаленные аккаунты): %d", total, petition.AnonymousVotes),
+		"Список сформирован " + now.UTC().Format("02.01.2006 15:04") + " UTC",
+	} {
+		doc.paragraph(regular, signaturesFontSize+1, line)
+	}
+
+	doc.y += 8
+	doc.paragraph(bold, 11, "Текст петиции")
+	doc.paragraph(regular, signaturesFontSize+1, petition.Description)
+
+	doc.y += 10
+	doc.ensure(3 * signaturesRowHeight)
+	doc.tableHeader()
+	listed := 0
+	err = s.votes.EachSignature(petition.ID, func(signature models.Signature) error {
+		listed++
+		doc.row([]string{
+			strconv.Itoa(listed),
+			signature.Login,
+			signature.LastName + " " + signature.FirstName,
+			signature.SignedAt.UTC().Format("02.01.2006 15:04"),
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	doc.inTable = false
+	doc.y += 6
+	doc.paragraph(bold, signaturesFontSize+1, fmt.Sprintf("Итого в списке: %d", listed))
+	return doc.pw.Close()
+}
+
+// signatureDocument Текущее положение на странице PDF со списком подписей
+type signatureDocument struct {
+	pw      *pdf.Writer
+	regular *pdf.Font
+	bold    *pdf.Font
+	y       float64
+	inTable bool
+}
+
+// newPage Начинает страницу с номером внизу. Внутри таблицы повторяет ее шапку
+func (d *signatureDocument) newPage() {
+	d.pw.NewPage()
+	d.pw.SetFont(d.regular, 8)
+	label := fmt.Sprintf("Страница %d", d.pw.PageCount())
+	d.pw.Text(pdf.PageWidth-signaturesMargin-d.regular.TextWidth(label, 8), pdf.PageHeight-25, label)
+	d.y = signaturesTop
+	if d.inTable {
+		d.tableHeader()
+	}
+}
+
+// ensure Переходит на новую страницу, если до нижнего поля осталось меньше height
+func (d *signatureDocument) ensure(height float64) {
+	if d.y+height > signaturesBottom {
+		d.newPage()
+	}
+}
+
+// paragraph Выводит текст с переносами по ширине страницы
+func (d *signatureDocument) paragraph(font *pdf.Font, size float64, text string) {
+	lineHeight := size * 1.35
+	for _, line := range font.Wrap(text, size, pdf.PageWidth-2*signaturesMargin) {
+		d.ensure(lineHeight)
+		d.y += lineHeight
+		d.pw.SetFont(font, size)
+		d.pw.Text(signaturesMargin, d.y-size*0.3, line)
+	}
+}
+
+func (d *signatureDocument) tableHeader() {
+	d.inTable = true
+	d.pw.FillRect(signaturesMargin, d.y, pdf.PageWidth-2*signaturesMargin, signaturesRowHeight, 0.88)
+	titles := make([]string, len(signatureColumns))
+	for i, column := range signatureColumns {
+		titles[i] = column.title
+	}
+	d.cells(d.bold, titles)
+}
+
+// row Строка таблицы. Не помещающийся текст обрезается многоточием
+func (d *signatureDocument) row(values []string) {
+	d.ensure(signaturesRowHeight)
+	d.cells(d.regular, values)
+}
+
+func (d *signatureDocument) cells(font *pdf.Font, values []string) {
+	d.pw.SetFont(font, signaturesFontSize)
+	baseline := d.y + signaturesRowHeight - 4
+	for i, column := range signatureColumns {
+		d.pw.Text(column.x+signaturesTextIndent, baseline, fitText(font, values[i], column.width-2*signaturesTextIndent))
+	}
+	d.y += signaturesRowHeight
+	d.pw.Line(signaturesMargin, d.y, pdf.PageWidth-signaturesMargin, d.y, 0.3)
+}
+
+// fitText Обрезает текст до ширины width с многоточием в конце
+func fitText(font *pdf.Font, text string, width float64) string {
+	if font.TextWidth(text, signaturesFontSize) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && font.TextWidth(string(runes)+"…", signaturesFontSize) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
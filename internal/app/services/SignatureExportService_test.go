@@ -0,0 +1,155 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/testutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSignatureExportTest(t *testing.T) (*gorm.DB, *SignatureExportService) {
+	db := testutil.OpenDB(t, models.Permission{}, models.Role{}, models.UserModel{}, models.Petition{}, models.Vote{}, models.Recipient{})
+	logger := testutil.Logger()
+	roles := repository.NewRoleRepository(db, logger)
+	if err := roles.Seed(models.DefaultPermissions, models.DefaultRolePermissions); err != nil {
+		t.Fatal(err)
+	}
+	service := NewSignatureExportService(
+		repository.NewVoteRepository(db, logger),
+		repository.NewUserRepository(db, logger),
+		roles,
+		logger,
+	)
+	return db, service
+}
+
+// signTestPetition Создает подписантов с именами и их голоса
+func signTestPetition(t *testing.T, db *gorm.DB, petitionID uint, count int) {
+	for i := 0; i < count; i++ {
+		user := createTestUser(t, db, fmt.Sprintf("signer%d", i), models.StatusActive)
+		db.Model(user).Updates(map[string]interface{}{"first_name": "Айгерим", "last_name": fmt.Sprintf("Қасымова-%d", i)})
+		if err := db.Create(&models.Vote{Login: user.Login, UserID: user.ID, PetitionID: petitionID}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCanExportSignatures(t *testing.T) {
+	db, service := newSignatureExportTest(t)
+	recipient := models.Recipient{Organisation: "Акимат Алматы"}
+	other := models.Recipient{Organisation: "Маслихат"}
+	db.Create(&recipient)
+	db.Create(&other)
+
+	author := createTestUser(t, db, "author", models.StatusActive)
+	member := createTestUser(t, db, "akimat", models.StatusActive)
+	db.Model(member).Updates(map[string]interface{}{"recipient_id": recipient.ID, "role": models.RoleRecipient})
+	stranger := createTestUser(t, db, "maslihat", models.StatusActive)
+	db.Model(stranger).Updates(map[string]interface{}{"recipient_id": other.ID, "role": models.RoleRecipient})
+	user := createTestUser(t, db, "user", models.StatusActive)
+	admin := createTestUser(t, db, "admin", models.StatusActive)
+	db.Model(admin).Update("role", models.RoleAdmin)
+	moderator := createTestUser(t, db, "moderator", models.StatusActive)
+	db.Model(moderator).Update("role", models.RoleModerator)
+
+	petition := &models.Petition{Title: "Парк", UserID: author.ID, RecipientID: &recipient.ID}
+	for _, tc := range []struct {
+		userID uint
+		allow  bool
+	}{
+		{author.ID, true},
+		{admin.ID, true},
+		{member.ID, true},
+		{stranger.ID, false},
+		{user.ID, false},
+		{moderator.ID, false},
+	} {
+		ok, err := service.CanExport(petition, tc.userID)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.allow, ok, "user %d", tc.userID)
+	}
+
+	// Роль берется из базы: снятый с должности админ доступ теряет сразу
+	db.Model(admin).Update("role", models.RoleUser)
+	ok, err := service.CanExport(petition, admin.ID)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// Без адресата из справочника представители адресатов доступа не получают
+	ok, err = service.CanExport(&models.Petition{UserID: author.ID}, member.ID)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestWriteSignaturesCSV(t *testing.T) {
+	db, service := newSignatureExportTest(t)
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", TargetByVote: 10}
+	db.Create(&petition)
+	signTestPetition(t, db, petition.ID, 3)
+
+	var out bytes.Buffer
+	require.NoError(t, service.WriteCSV(&out, &petition))
+	assert.True(t, strings.HasPrefix(out.String(), "\uFEFF"))
+
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(out.String(), "\uFEFF"))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	assert.Equal(t, []string{"number", "login", "last_name", "first_name", "signed_at"}, records[0])
+	assert.Equal(t, []string{"1", "signer0", "Қасымова-0", "Айгерим"}, records[1][:4])
+	assert.Equal(t, "3", records[3][0])
+	signedAt, err := time.Parse(time.RFC3339, records[1][4])
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), signedAt, time.Minute)
+}
+
+func TestWriteSignaturesCSVEscapesFormulas(t *testing.T) {
+	db, service := newSignatureExportTest(t)
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", TargetByVote: 10}
+	db.Create(&petition)
+	for i, name := range []string{"=HYPERLINK(\"http://evil\")", "+7 777", "-1+1", "@SUM(A1)", "\n=1+1", "Айгерим"} {
+		user := models.UserModel{Login: fmt.Sprintf("u%d", i), FirstName: name, LastName: "Қасымова", Role: models.RoleUser, Status: models.StatusActive}
+		require.NoError(t, db.Create(&user).Error)
+		require.NoError(t, db.Create(&models.Vote{Login: user.Login, UserID: user.ID, PetitionID: petition.ID}).Error)
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, service.WriteCSV(&out, &petition))
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(out.String(), "\uFEFF"))).ReadAll()
+	require.NoError(t, err)
+	firstNames := make([]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		firstNames = append(firstNames, record[3])
+	}
+	assert.Equal(t, []string{"'=HYPERLINK(\"http://evil\")", "'+7 777", "'-1+1", "'@SUM(A1)", "'\n=1+1", "Айгерим"}, firstNames)
+}
+
+func TestWriteSignaturesPDF(t *testing.T) {
+	db, service := newSignatureExportTest(t)
+	petition := models.Petition{
+		Title:          "Построить парк на улице Абая",
+		Description:    strings.Repeat("Просим построить парк с детской площадкой и фонтаном. ", 40),
+		TargetByVote:   100,
+		Recipient:      "Акимат Алматы",
+		Status:         models.PetitionStatusOpen,
+		AnonymousVotes: 2,
+	}
+	db.Create(&petition)
+	signTestPetition(t, db, petition.ID, 120)
+
+	var out bytes.Buffer
+	require.NoError(t, service.WritePDF(&out, &petition, time.Now()))
+
+	data := out.String()
+	assert.True(t, strings.HasPrefix(data, "%PDF-"))
+	assert.True(t, strings.HasSuffix(data, "%%EOF\n"))
+	// 120 строк по 14 пунктов вместе с текстом петиции не помещаются на две страницы A4
+	assert.Contains(t, data, "/Count 3")
+}
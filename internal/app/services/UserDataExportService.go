@@ -0,0 +1,357 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"io"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"strconv"
+	"time"
+)
+
+// UserDataExportService Собирает все данные, которые хранятся о пользователе, в ZIP архив с JSON и CSV файлами
+type UserDataExportService struct {
+	users     repository.UserRepository
+	sessions  repository.SessionRepo
+	petitions repository.PetitionRepository
+	comments  repository.CommentRepository
+	votes     repository.VoteRepository
+	voteFlags repository.VoteFlagRepository
+	apiKeys   repository.APIKeyRepository
+	// notifications, preferences и subscriptions Уведомления пользователя и его настройки писем
+	notifications repository.NotificationRepository
+	preferences   repository.NotificationPreferenceRepository
+	subscriptions repository.SubscriptionRepository
+	webhooks      repository.WebhookRepository
+	bans          repository.UserBanRepository
+	logger        *logrus.Logger
+}
+
+func NewUserDataExportService(
+	users repository.UserRepository,
+	sessions repository.SessionRepo,
+	petitions repository.PetitionRepository,
+	comments repository.CommentRepository,
+	votes repository.VoteRepository,
+	voteFlags repository.VoteFlagRepository,
+	apiKeys repository.APIKeyRepository,
+	notifications repository.NotificationRepository,
+	preferences repository.NotificationPreferenceRepository,
+	subscriptions repository.SubscriptionRepository,
+	webhooks repository.WebhookRepository,
+	bans repository.UserBanRepository,
+	logger *logrus.Logger,
+) *UserDataExportService {
+	return &UserDataExportService{
+		users:         users,
+		sessions:      sessions,
+		petitions:     petitions,
+		comments:      comments,
+		votes:         votes,
+		voteFlags:     voteFlags,
+		apiKeys:       apiKeys,
+		notifications: notifications,
+		preferences:   preferences,
+		subscriptions: subscriptions,
+		webhooks:      webhooks,
+		bans:          bans,
+		logger:        logger,
+	}
+}
+
+// exportSession Сессия в выгрузке. Сам refresh токен не выгружается
+type exportSession struct {
+	ID          uint      `json:"id"`
+	UA          string    `json:"user_agent"`
+	IP          string    `json:"ip"`
+	Fingerprint string    `json:"fingerprint"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// exportVote Подпись в выгрузке. В отличие от ответов API, в ней есть адрес и браузер, с которых подписали
+type exportVote struct {
+	ID         uint      `json:"id"`
+	PetitionID uint      `json:"petition_id"`
+	Visibility string    `json:"visibility"`
+	Reason     string    `json:"reason"`
+	IP         string    `json:"ip"`
+	Subnet     string    `json:"subnet"`
+	UA         string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// exportInvalidatedVote Голос, который админ признал недействительным. Признаки подозрения и детали проверки
+// не выгружаются, чтобы выгрузка не раскрывала правила антифрода
+type exportInvalidatedVote struct {
+	VoteID        uint       `json:"vote_id"`
+	PetitionID    uint       `json:"petition_id"`
+	InvalidatedAt *time.Time `json:"invalidated_at"`
+}
+
+// exportNotificationSettings Язык и режимы писем пользователя
+type exportNotificationSettings struct {
+	models.NotificationSettings
+	Subscriptions []models.PetitionSubscription `json:"subscriptions"`
+}
+
+// CountRecords возвращает примерное число записей пользователя, чтобы решить, выгружать сразу или в фоне
+func (s *UserDataExportService) CountRecords(userID uint) (int64, error) {
+	petitions, err := s.petitions.CountByUserID(userID)
+	if err != nil {
+		return 0, err
+	}
+	comments, err := s.comments.CountByUserID(userID)
+	if err != nil {
+		return 0, err
+	}
+	votes, err := s.votes.CountByUserID(userID)
+	if err != nil {
+		return 0, err
+	}
+	notifications, err := s.notifications.CountByUserID(userID)
+	if err != nil {
+		return 0, err
+	}
+	return petitions + comments + votes + notifications, nil
+}
+
+// WriteArchive пишет ZIP архив с данными пользователя в w
+func (s *UserDataExportService) WriteArchive(w io.Writer, userID uint) error {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	sessions, err := s.sessions.FindAllByUserID(strconv.FormatUint(uint64(userID), 10))
+	if err != nil {
+		return err
+	}
+	petitions, err := s.petitions.GetAllByUserID(userID)
+	if err != nil {
+		return err
+	}
+	comments, err := s.comments.GetAllByUserID(userID)
+	if err != nil {
+		return err
+	}
+	votes, err := s.votes.GetAllByUserID(userID)
+	if err != nil {
+		return err
+	}
+	invalidated, err := s.voteFlags.GetInvalidatedByUserID(userID)
+	if err != nil {
+		return err
+	}
+	apiKeys, err := s.apiKeys.GetAllByUserID(userID)
+	if err != nil {
+		return err
+	}
+	notifications, err := s.notifications.GetAllByUserID(userID)
+	if err != nil {
+		return err
+	}
+	modes, err := s.preferences.GetModes(userID)
+	if err != nil {
+		return err
+	}
+	subscriptions, err := s.subscriptions.GetByUserID(userID)
+	if err != nil {
+		return err
+	}
+	webhooks, err := s.webhooks.GetByUserID(userID)
+	if err != nil {
+		return err
+	}
+	bans, err := s.bans.GetAllByUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	exportSessions := make([]exportSession, 0, len(sessions))
+	for _, session := range sessions {
+		exportSessions = append(exportSessions, exportSession{
+			ID:          session.ID,
+			UA:          session.UA,
+			IP:          session.IP,
+			Fingerprint: session.Fingerprint,
+			CreatedAt:   session.CreatedAt,
+			ExpiresAt:   time.Unix(session.ExpiresIn, 0),
+		})
+	}
+	exportVotes := make([]exportVote, 0, len(votes))
+	for _, vote := range votes {
+		exportVotes = append(exportVotes, exportVote{
+			ID:         vote.ID,
+			PetitionID: vote.PetitionID,
+			Visibility: vote.Visibility,
+			Reason:     vote.Reason,
+			IP:         vote.IP,
+			Subnet:     vote.Subnet,
+			UA:         vote.UA,
+			CreatedAt:  vote.CreatedAt,
+		})
+	}
+	// У голоса может быть несколько отметок, в выгрузке он один
+	invalidatedVotes := make([]exportInvalidatedVote, 0, len(invalidated))
+	seen := make(map[uint]bool, len(invalidated))
+	for _, flag := range invalidated {
+		if seen[flag.VoteID] {
+			continue
+		}
+		seen[flag.VoteID] = true
+		invalidatedVotes = append(invalidatedVotes, exportInvalidatedVote{
+			VoteID:        flag.VoteID,
+			PetitionID:    flag.PetitionID,
+			InvalidatedAt: flag.ReviewedAt,
+		})
+	}
+	exportKeys := make([]models.APIKeyView, 0, len(apiKeys))
+	for i := range apiKeys {
+		exportKeys = append(exportKeys, models.NewAPIKeyView(&apiKeys[i]))
+	}
+	// Секрет подписи вебхука в архив не попадает, как и refresh токены
+	exportWebhooks := make([]models.WebhookView, 0, len(webhooks))
+	for i := range webhooks {
+		exportWebhooks = append(exportWebhooks, models.NewWebhookView(&webhooks[i]))
+	}
+	settings := exportNotificationSettings{
+		NotificationSettings: models.NotificationSettings{Language: user.Language, Modes: modes},
+		Subscriptions:        subscriptions,
+	}
+	if settings.Subscriptions == nil {
+		settings.Subscriptions = []models.PetitionSubscription{}
+	}
+	if notifications == nil {
+		notifications = []models.Notification{}
+	}
+	if bans == nil {
+		bans = []models.UserBan{}
+	}
+
+	archive := zip.NewWriter(w)
+
+	if err := writeJSONFile(archive, "profile.json", models.NewUserPrivateView(user)); err != nil {
+		return err
+	}
+	if err := writeJSONFile(archive, "sessions.json", exportSessions); err != nil {
+		return err
+	}
+	if err := writeJSONFile(archive, "petitions.json", petitions); err != nil {
+		return err
+	}
+	if err := writeJSONFile(archive, "comments.json", comments); err != nil {
+		return err
+	}
+	if err := writeJSONFile(archive, "votes.json", exportVotes); err != nil {
+		return err
+	}
+	if err := writeJSONFile(archive, "invalidated_votes.json", invalidatedVotes); err != nil {
+		return err
+	}
+	if err := writeJSONFile(archive, "api_keys.json", exportKeys); err != nil {
+		return err
+	}
+	if err := writeJSONFile(archive, "notifications.json", notifications); err != nil {
+		return err
+	}
+	if err := writeJSONFile(archive, "notification_settings.json", settings); err != nil {
+		return err
+	}
+	if err := writeJSONFile(archive, "webhooks.json", exportWebhooks); err != nil {
+		return err
+	}
+	if err := writeJSONFile(archive, "bans.json", bans); err != nil {
+		return err
+	}
+
+	sessionRows := make([][]string, 0, len(exportSessions))
+	for _, session := range exportSessions {
+		sessionRows = append(sessionRows, []string{
+			formatUint(session.ID), session.UA, session.IP, session.Fingerprint,
+			formatTime(session.CreatedAt), formatTime(session.ExpiresAt),
+		})
+	}
+	if err := writeCSVFile(archive, "sessions.csv",
+		[]string{"id", "user_agent", "ip", "fingerprint", "created_at", "expires_at"}, sessionRows); err != nil {
+		return err
+	}
+
+	petitionRows := make([][]string, 0, len(petitions))
+	for _, petition := range petitions {
+		petitionRows = append(petitionRows, []string{
+			formatUint(petition.ID), petition.Title, petition.Description,
+			formatUint(petition.TargetByVote), petition.Recipient, formatTime(petition.CreatedAt),
+		})
+	}
+	if err := writeCSVFile(archive, "petitions.csv",
+		[]string{"id", "title", "description", "target_by_vote", "recipient", "created_at"}, petitionRows); err != nil {
+		return err
+	}
+
+	commentRows := make([][]string, 0, len(comments))
+	for _, comment := range comments {
+		commentRows = append(commentRows, []string{
+			formatUint(comment.ID), formatUint(comment.PetitionID), comment.Content, formatTime(comment.CreatedAt),
+		})
+	}
+	if err := writeCSVFile(archive, "comments.csv",
+		[]string{"id", "petition_id", "content", "created_at"}, commentRows); err != nil {
+		return err
+	}
+
+	voteRows := make([][]string, 0, len(exportVotes))
+	for _, vote := range exportVotes {
+		voteRows = append(voteRows, []string{
+			formatUint(vote.ID), formatUint(vote.PetitionID), vote.Visibility, vote.Reason,
+			vote.IP, vote.Subnet, vote.UA, formatTime(vote.CreatedAt),
+		})
+	}
+	if err := writeCSVFile(archive, "votes.csv",
+		[]string{"id", "petition_id", "visibility", "reason", "ip", "subnet", "user_agent", "created_at"}, voteRows); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+func writeJSONFile(archive *zip.Writer, name string, value interface{}) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func writeCSVFile(archive *zip.Writer, name string, header []string, rows [][]string) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(file)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		for i := range row {
+			row[i] = csvCell(row[i])
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatUint(value uint) string {
+	return strconv.FormatUint(uint64(value), 10)
+}
+
+func formatTime(value time.Time) string {
+	return value.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"sort"
+	"time"
+)
+
+// ErrVoteChainBroken Цепочка журнала голосов нарушена, дописывать в нее нельзя
+var ErrVoteChainBroken = errors.New("vote audit chain is broken")
+
+// VoteAuditService Проверка журнала голосов: цепочка хэшей не нарушена, а подписи,
+// которые по журналу должны быть в таблице votes, совпадают с ней
+type VoteAuditService struct {
+	audit  repository.VoteAuditRepository
+	votes  repository.VoteRepository
+	logger *logrus.Logger
+}
+
+func NewVoteAuditService(audit repository.VoteAuditRepository, votes repository.VoteRepository, logger *logrus.Logger) *VoteAuditService {
+	return &VoteAuditService{audit: audit, votes: votes, logger: logger}
+}
+
+// VerifyPetition Проверяет цепочку петиции и сверяет ее с таблицей votes.
+// Расхождение - не ошибка: отчет возвращается с Valid = false и описанием Problem
+func (s *VoteAuditService) VerifyPetition(petitionID uint) (*models.VoteChainReport, error) {
+	report, live, err := s.replay(petitionID)
+	if err != nil {
+		return nil, err
+	}
+	voters, err := s.votes.GetVotersByPetitionID(petitionID)
+	if err != nil {
+		return nil, err
+	}
+	report.TableVotes = int64(len(voters))
+	if report.Problem != "" {
+		return report, nil
+	}
+
+	for _, id := range sortedIDs(voters) {
+		userID, ok := live[id]
+		if !ok {
+			report.Problem = fmt.Sprintf("vote %d is missing from the audit log", id)
+			return report, nil
+		}
+		if userID != voters[id] {
+			report.Problem = fmt.Sprintf("vote %d belongs to another user than in the audit log", id)
+			return report, nil
+		}
+	}
+	for _, id := range sortedIDs(live) {
+		if _, ok := voters[id]; !ok {
+			report.Problem = fmt.Sprintf("vote %d was removed without an audit entry", id)
+			return report, nil
+		}
+	}
+	report.Valid = true
+	return report, nil
+}
+
+// VerifyAll Проверяет все петиции, у которых есть голоса или записи в журнале, и передает отчеты в fn
+func (s *VoteAuditService) VerifyAll(fn func(*models.VoteChainReport)) error {
+	petitionIDs, err := s.petitionIDs()
+	if err != nil {
+		return err
+	}
+	for _, petitionID := range petitionIDs {
+		report, err := s.VerifyPetition(petitionID)
+		if err != nil {
+			return err
+		}
+		fn(report)
+	}
+	return nil
+}
+
+// Backfill Дописывает в журнал записи import для голосов, поданных до появления журнала.
+// Если цепочка петиции уже есть, импортируются только голоса старше ее первой записи: голос, поданный позже
+// и не попавший в журнал, вставлен в обход сервиса. Такую петицию, как и нарушенную цепочку, Backfill не трогает
+// и возвращает ErrVoteChainBroken. Возвращает число добавленных записей
+func (s *VoteAuditService) Backfill(petitionID uint, now time.Time) (int, error) {
+	report, live, err := s.replay(petitionID)
+	if err != nil {
+		return 0, err
+	}
+	if report.Problem != "" {
+		return 0, fmt.Errorf("%w: petition %d: %s", ErrVoteChainBroken, petitionID, report.Problem)
+	}
+	voters, err := s.votes.GetVotersByPetitionID(petitionID)
+	if err != nil {
+		return 0, err
+	}
+	var missing []uint
+	for _, id := range sortedIDs(voters) {
+		if _, ok := live[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return 0, nil
+	}
+	first, err := s.audit.GetFirst(petitionID)
+	if err != nil {
+		return 0, err
+	}
+	if first != nil {
+		late, err := s.votes.GetIDsCreatedSince(missing, first.CreatedAt)
+		if err != nil {
+			return 0, err
+		}
+		if len(late) > 0 {
+			return 0, fmt.Errorf("%w: petition %d: vote %d was cast after the audit log started but is missing from it",
+				ErrVoteChainBroken, petitionID, late[0])
+		}
+	}
+
+	err = s.audit.DB.Transaction(func(tx *gorm.DB) error {
+		for _, id := range missing {
+			vote := models.Vote{Model: gorm.Model{ID: id}, UserID: voters[id], PetitionID: petitionID}
+			if _, err := s.audit.AppendTx(tx, &vote, models.VoteAuditImported, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.logger.Infof("Imported %d votes of petition %d into the audit log", len(missing), petitionID)
+	return len(missing), nil
+}
+
+// BackfillAll Дописывает в журнал голоса всех петиций. Петиции, которые Backfill отклонил с ErrVoteChainBroken, пропускаются
+func (s *VoteAuditService) BackfillAll(now time.Time) (int, error) {
+	petitionIDs, err := s.petitionIDs()
+	if err != nil {
+		return 0, err
+	}
+	var total int
+	for _, petitionID := range petitionIDs {
+		imported, err := s.Backfill(petitionID, now)
+		if errors.Is(err, ErrVoteChainBroken) {
+			s.logger.Error(err)
+			continue
+		}
+		if err != nil {
+			return total, err
+		}
+		total += imported
+	}
+	return total, nil
+}
+
+// replay Проходит цепочку петиции: проверяет порядок записей, ссылки на предыдущую запись и хэши,
+// считает действия и собирает голоса, которые по журналу должны быть в таблице votes, вместе с их владельцами.
+// Первое нарушение записывается в report.Problem, дальше цепочка не проверяется
+func (s *VoteAuditService) replay(petitionID uint) (*models.VoteChainReport, map[uint]uint, error) {
+	report := &models.VoteChainReport{PetitionID: petitionID, Head: models.VoteAuditGenesisHash}
+	live := map[uint]uint{}
+	errStop := errors.New("stop")
+
+	err := s.audit.EachEntry(petitionID, func(entry models.VoteAuditEntry) error {
+		report.Entries++
+		switch {
+		case entry.Seq != uint(report.Entries):
+			report.Problem = fmt.Sprintf("entry %d: expected seq %d", entry.Seq, report.Entries)
+		case entry.PrevHash != report.Head:
+			report.Problem = fmt.Sprintf("entry %d: previous hash does not match", entry.Seq)
+		case entry.Hash != entry.ComputeHash():
+			report.Problem = fmt.Sprintf("entry %d: hash does not match its contents", entry.Seq)
+		}
+		if report.Problem != "" {
+			return errStop
+		}
+		report.Head = entry.Hash
+
+		switch entry.Action {
+		case models.VoteAuditVoted, models.VoteAuditImported:
+			if _, ok := live[entry.VoteID]; ok {
+				report.Problem = fmt.Sprintf("entry %d: vote %d is already counted", entry.Seq, entry.VoteID)
+				return errStop
+			}
+			live[entry.VoteID] = entry.UserID
+			if entry.Action == models.VoteAuditVoted {
+				report.Voted++
+			} else {
+				report.Imported++
+			}
+		case models.VoteAuditUnvoted, models.VoteAuditInvalidated, models.VoteAuditAnonymised:
+			userID, ok := live[entry.VoteID]
+			if !ok {
+				report.Problem = fmt.Sprintf("entry %d: vote %d is not counted", entry.Seq, entry.VoteID)
+				return errStop
+			}
+			if userID != entry.UserID {
+				report.Problem = fmt.Sprintf("entry %d: vote %d belongs to another user", entry.Seq, entry.VoteID)
+				return errStop
+			}
+			delete(live, entry.VoteID)
+			switch entry.Action {
+			case models.VoteAuditUnvoted:
+				report.Unvoted++
+			case models.VoteAuditInvalidated:
+				report.Invalidated++
+			default:
+				report.Anonymised++
+			}
+		default:
+			report.Problem = fmt.Sprintf("entry %d: unknown action %q", entry.Seq, entry.Action)
+			return errStop
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStop) {
+		return nil, nil, err
+	}
+	report.ChainVotes = int64(len(live))
+	return report, live, nil
+}
+
+// petitionIDs Петиции, у которых есть голоса или записи в журнале
+func (s *VoteAuditService) petitionIDs() ([]uint, error) {
+	fromVotes, err := s.votes.GetPetitionIDs()
+	if err != nil {
+		return nil, err
+	}
+	fromAudit, err := s.audit.GetPetitionIDs()
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[uint]uint, len(fromVotes)+len(fromAudit))
+	for _, id := range append(fromVotes, fromAudit...) {
+		ids[id] = id
+	}
+	return sortedIDs(ids), nil
+}
+
+// sortedIDs Ключи отображения по возрастанию
+func sortedIDs(set map[uint]uint) []uint {
+	ids := make([]uint, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
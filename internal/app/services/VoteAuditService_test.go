@@ -0,0 +1,178 @@
+package services
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/testutil"
+	"testing"
+	"time"
+)
+
+func newVoteAuditTest(t *testing.T) (*gorm.DB, *VoteService, *VoteAuditService, *models.Petition) {
+	db, votes, _ := newVoteServiceTest(t)
+	logger := testutil.Logger()
+	audit := NewVoteAuditService(repository.NewVoteAuditRepository(db, logger), votes.votes, logger)
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", TargetByVote: 100}
+	db.Create(&petition)
+	return db, votes, audit, &petition
+}
+
+func TestVoteAuditChain(t *testing.T) {
+	db, votes, audit, petition := newVoteAuditTest(t)
+
+	report, err := audit.VerifyPetition(petition.ID)
+	require.NoError(t, err)
+	assert.True(t, report.Valid)
+	assert.Equal(t, models.VoteAuditGenesisHash, report.Head)
+
+	first := voteFrom(t, db, votes, petition.ID, "first", "10.0.0.1")
+	second := voteFrom(t, db, votes, petition.ID, "second", "10.0.0.2")
+	voteFrom(t, db, votes, petition.ID, "third", "10.0.0.3")
+	require.NoError(t, votes.Unvote(first.UserID, petition.ID))
+	_, err = votes.Invalidate(second.ID)
+	require.NoError(t, err)
+
+	report, err = audit.VerifyPetition(petition.ID)
+	require.NoError(t, err)
+	assert.True(t, report.Valid, report.Problem)
+	assert.Equal(t, int64(5), report.Entries)
+	assert.Equal(t, int64(3), report.Voted)
+	assert.Equal(t, int64(1), report.Unvoted)
+	assert.Equal(t, int64(1), report.Invalidated)
+	assert.Equal(t, int64(1), report.ChainVotes)
+	assert.Equal(t, int64(1), report.TableVotes)
+
+	var head models.VoteAuditEntry
+	db.Where("petition_id = ?", petition.ID).Order("seq DESC").First(&head)
+	assert.Equal(t, head.Hash, report.Head)
+	assert.Equal(t, models.VoteAuditInvalidated, head.Action)
+
+	// Голос, удаленный мимо журнала, обнаруживается
+	db.Unscoped().Where("petition_id = ?", petition.ID).Delete(&models.Vote{})
+	report, err = audit.VerifyPetition(petition.ID)
+	require.NoError(t, err)
+	assert.False(t, report.Valid)
+	assert.Contains(t, report.Problem, "removed without an audit entry")
+}
+
+func TestVoteAuditDetectsTampering(t *testing.T) {
+	db, votes, audit, petition := newVoteAuditTest(t)
+	voteFrom(t, db, votes, petition.ID, "first", "10.0.0.1")
+	voteFrom(t, db, votes, petition.ID, "second", "10.0.0.2")
+
+	// Подмена голоса в записи ломает ее хэш
+	db.Model(&models.VoteAuditEntry{}).Where("petition_id = ? AND seq = ?", petition.ID, 1).Update("vote_id", 999)
+	report, err := audit.VerifyPetition(petition.ID)
+	require.NoError(t, err)
+	assert.False(t, report.Valid)
+	assert.Equal(t, "entry 1: hash does not match its contents", report.Problem)
+
+	// Пересчитанный хэш не совпадает со ссылкой в следующей записи
+	var entry models.VoteAuditEntry
+	db.Where("petition_id = ? AND seq = ?", petition.ID, 1).First(&entry)
+	db.Model(&entry).Update("hash", entry.ComputeHash())
+	report, err = audit.VerifyPetition(petition.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "entry 2: previous hash does not match", report.Problem)
+
+	_, err = audit.Backfill(petition.ID, time.Now())
+	assert.ErrorIs(t, err, ErrVoteChainBroken)
+}
+
+func TestVoteAuditBackfill(t *testing.T) {
+	db, votes, audit, petition := newVoteAuditTest(t)
+	voteFrom(t, db, votes, petition.ID, "logged", "10.0.0.1")
+
+	// Голос, поданный до появления журнала
+	user := createTestUser(t, db, "legacy", models.StatusActive)
+	legacy := models.Vote{Login: user.Login, UserID: user.ID, PetitionID: petition.ID}
+	legacy.CreatedAt = time.Now().Add(-24 * time.Hour)
+	require.NoError(t, db.Create(&legacy).Error)
+	report, err := audit.VerifyPetition(petition.ID)
+	require.NoError(t, err)
+	assert.False(t, report.Valid)
+	assert.Contains(t, report.Problem, "missing from the audit log")
+
+	imported, err := audit.BackfillAll(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, imported)
+
+	var reports []*models.VoteChainReport
+	require.NoError(t, audit.VerifyAll(func(r *models.VoteChainReport) { reports = append(reports, r) }))
+	require.Len(t, reports, 1)
+	assert.True(t, reports[0].Valid, reports[0].Problem)
+	assert.Equal(t, int64(1), reports[0].Imported)
+	assert.Equal(t, int64(2), reports[0].TableVotes)
+
+	imported, err = audit.Backfill(petition.ID, time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, imported)
+}
+
+func TestVoteAuditBackfillRefusesVotesAfterChainStart(t *testing.T) {
+	db, votes, audit, petition := newVoteAuditTest(t)
+	voteFrom(t, db, votes, petition.ID, "logged", "10.0.0.1")
+
+	// Голос, вставленный в таблицу в обход сервиса после появления журнала, не импортируется
+	old := createTestUser(t, db, "old", models.StatusActive)
+	legacy := models.Vote{Login: old.Login, UserID: old.ID, PetitionID: petition.ID}
+	legacy.CreatedAt = time.Now().Add(-24 * time.Hour)
+	require.NoError(t, db.Create(&legacy).Error)
+	user := createTestUser(t, db, "injected", models.StatusActive)
+	require.NoError(t, db.Create(&models.Vote{Login: user.Login, UserID: user.ID, PetitionID: petition.ID}).Error)
+
+	imported, err := audit.Backfill(petition.ID, time.Now())
+	assert.ErrorIs(t, err, ErrVoteChainBroken)
+	assert.Zero(t, imported)
+	imported, err = audit.BackfillAll(time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, imported)
+
+	report, err := audit.VerifyPetition(petition.ID)
+	require.NoError(t, err)
+	assert.False(t, report.Valid)
+	assert.Equal(t, int64(0), report.Imported)
+}
+
+func TestVoteAuditDetectsReassignedVote(t *testing.T) {
+	db, votes, audit, petition := newVoteAuditTest(t)
+	vote := voteFrom(t, db, votes, petition.ID, "first", "10.0.0.1")
+	other := createTestUser(t, db, "other", models.StatusActive)
+
+	db.Model(&models.Vote{}).Where("id = ?", vote.ID).Update("user_id", other.ID)
+	report, err := audit.VerifyPetition(petition.ID)
+	require.NoError(t, err)
+	assert.False(t, report.Valid)
+	assert.Contains(t, report.Problem, "belongs to another user")
+
+	// Подмена владельца в записи журнала ломает ее хэш
+	db.Model(&models.VoteAuditEntry{}).Where("vote_id = ?", vote.ID).Update("user_id", other.ID)
+	report, err = audit.VerifyPetition(petition.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "entry 1: hash does not match its contents", report.Problem)
+}
+
+func TestVoteAuditCountsSoftDeletedRows(t *testing.T) {
+	db, votes, audit, petition := newVoteAuditTest(t)
+	vote := voteFrom(t, db, votes, petition.ID, "first", "10.0.0.1")
+
+	// Строка с deleted_at остается голосом: она занимает уникальный индекс и есть в журнале
+	db.Delete(&models.Vote{}, vote.ID)
+	report, err := audit.VerifyPetition(petition.ID)
+	require.NoError(t, err)
+	assert.True(t, report.Valid, report.Problem)
+	assert.Equal(t, int64(1), report.TableVotes)
+
+	exists, err := votes.votes.VoteExist(petition.ID, vote.UserID)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.ErrorIs(t, votes.Vote(&models.Vote{Login: vote.Login, UserID: vote.UserID, PetitionID: petition.ID}), ErrAlreadyVoted)
+
+	require.NoError(t, votes.Unvote(vote.UserID, petition.ID))
+	report, err = audit.VerifyPetition(petition.ID)
+	require.NoError(t, err)
+	assert.True(t, report.Valid, report.Problem)
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"time"
+)
+
+var (
+	// ErrVoteFlagNotFound Отметки о подозрительном голосе нет
+	ErrVoteFlagNotFound = errors.New("vote flag not found")
+	// ErrVoteFlagReviewed Голос уже проверен
+	ErrVoteFlagReviewed = errors.New("vote flag has already been reviewed")
+)
+
+// FraudPolicy Пороги поиска накруток. Нулевой порог отключает проверку
+type FraudPolicy struct {
+	// Window За какое время считаются голоса с одного адреса или от новых аккаунтов
+	Window time.Duration
+	// MaxVotesPerIP Сколько голосов за петицию с одного IP за окно считаются нормой
+	MaxVotesPerIP int
+	// MaxVotesPerSubnet Сколько голосов за петицию из одной подсети за окно считаются нормой
+	MaxVotesPerSubnet int
+	// NewAccountAge Аккаунт считается новым, если подписал раньше, чем через NewAccountAge после регистрации
+	NewAccountAge time.Duration
+	// MaxNewAccountVotes Сколько голосов новых аккаунтов за петицию за окно считаются нормой
+	MaxNewAccountVotes int
+}
+
+// VoteFraudService Ищет накрутки подписей. Подозрительные голоса только отмечаются и продолжают учитываться,
+// пока админ не признает их недействительными. Получает новые голоса как VoteNotifier
+type VoteFraudService struct {
+	flags  repository.VoteFlagRepository
+	votes  repository.VoteRepository
+	voting *VoteService
+	policy FraudPolicy
+	logger *logrus.Logger
+}
+
+func NewVoteFraudService(
+	flags repository.VoteFlagRepository,
+	votes repository.VoteRepository,
+	voting *VoteService,
+	policy FraudPolicy,
+	logger *logrus.Logger,
+) *VoteFraudService {
+	return &VoteFraudService{flags: flags, votes: votes, voting: voting, policy: policy, logger: logger}
+}
+
+// NotifyVoted Проверяет новый голос. Ошибки проверки только пишутся в лог, голос уже сохранен
+func (s *VoteFraudService) NotifyVoted(vote *models.Vote) error {
+	flags, err := s.Inspect(vote)
+	if err != nil {
+		return err
+	}
+	created, err := s.flags.CreateMany(flags)
+	if err != nil {
+		return err
+	}
+	if created > 0 {
+		s.logger.Warnf("Flagged %d suspicious votes on petition %d", created, vote.PetitionID)
+	}
+	return nil
+}
+
+// NotifyUnvoted Отзыв подписи не проверяется
+func (s *VoteFraudService) NotifyUnvoted(petitionID uint) error {
+	return nil
+}
+
+// NotifyInvalidated Удаленный админом голос уже отмечен
+func (s *VoteFraudService) NotifyInvalidated(vote *models.Vote) error {
+	return nil
+}
+
+// Inspect Возвращает отметки для голоса и для голосов той же волны, если голос похож на накрутку.
+// Окно отсчитывается от времени сервера, а не от времени голоса
+func (s *VoteFraudService) Inspect(vote *models.Vote) ([]models.VoteFlag, error) {
+	since := time.Now().Add(-s.policy.Window)
+	var flags []models.VoteFlag
+
+	if vote.IP != "" && s.policy.MaxVotesPerIP > 0 {
+		burst, err := s.votes.GetRecentByIP(vote.PetitionID, vote.IP, since)
+		if err != nil {
+			return nil, err
+		}
+		if len(burst) > s.policy.MaxVotesPerIP {
+			details := fmt.Sprintf("%d votes from %s within %s", len(burst), vote.IP, s.policy.Window)
+			flags = appendVoteFlags(flags, burst, models.VoteFlagSameIP, details)
+		}
+	}
+
+	if vote.Subnet != "" && s.policy.MaxVotesPerSubnet > 0 {
+		burst, err := s.votes.GetRecentBySubnet(vote.PetitionID, vote.Subnet, since)
+		if err != nil {
+			return nil, err
+		}
+		if len(burst) > s.policy.MaxVotesPerSubnet {
+			details := fmt.Sprintf("%d votes from %s within %s", len(burst), vote.Subnet, s.policy.Window)
+			flags = appendVoteFlags(flags, burst, models.VoteFlagSameSubnet, details)
+		}
+	}
+
+	if s.policy.MaxNewAccountVotes > 0 {
+		fresh, err := s.freshAccountVotes(vote.PetitionID, since)
+		if err != nil {
+			return nil, err
+		}
+		if len(fresh) > s.policy.MaxNewAccountVotes {
+			details := fmt.Sprintf("%d votes from accounts younger than %s within %s", len(fresh), s.policy.NewAccountAge, s.policy.Window)
+			flags = appendVoteFlags(flags, fresh, models.VoteFlagNewAccounts, details)
+		}
+	}
+
+	invalidated, err := s.flags.HasInvalidated(vote.UserID, vote.PetitionID)
+	if err != nil {
+		return nil, err
+	}
+	if invalidated {
+		flags = appendVoteFlags(flags, []models.Vote{*vote}, models.VoteFlagRevote, "signed again after the previous vote was invalidated")
+	}
+	return flags, nil
+}
+
+// freshAccountVotes Голоса за петицию с начала окна от аккаунтов, подписавших раньше NewAccountAge после регистрации
+func (s *VoteFraudService) freshAccountVotes(petitionID uint, since time.Time) ([]models.Vote, error) {
+	candidates, err := s.votes.GetRecentFromAccountsSince(petitionID, since, since.Add(-s.policy.NewAccountAge))
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate.SignedAt.Sub(candidate.AccountCreatedAt) < s.policy.NewAccountAge {
+			ids = append(ids, candidate.VoteID)
+		}
+	}
+	if len(ids) <= s.policy.MaxNewAccountVotes {
+		return nil, nil
+	}
+	return s.votes.GetByIDs(ids)
+}
+
+// List Отметки для проверки админом
+func (s *VoteFraudService) List(filter models.VoteFlagFilter) (*models.VoteFlagPage, error) {
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = 20
+	}
+	flags, total, err := s.flags.Search(filter)
+	if err != nil {
+		return nil, err
+	}
+	return &models.VoteFlagPage{Items: flags, Total: total, Page: filter.Page, PageSize: filter.PageSize}, nil
+}
+
+// Dismiss Признает голос честным. Закрываются все отметки голоса
+func (s *VoteFraudService) Dismiss(flagID uint, reviewerID uint, now time.Time) error {
+	flag, err := s.pendingFlag(flagID)
+	if err != nil {
+		return err
+	}
+	return s.flags.ResolveByVoteID(flag.VoteID, models.VoteFlagDismissed, reviewerID, now)
+}
+
+// Invalidate Признает голос недействительным: голос удаляется, счетчик петиции пересчитывается и рассылается
+func (s *VoteFraudService) Invalidate(flagID uint, reviewerID uint, now time.Time) error {
+	flag, err := s.pendingFlag(flagID)
+	if err != nil {
+		return err
+	}
+	// Голос мог быть уже отозван самим пользователем, тогда отметки просто закрываются
+	if _, err := s.voting.Invalidate(flag.VoteID); err != nil && !errors.Is(err, ErrVoteNotFound) {
+		return err
+	}
+	if err := s.flags.ResolveByVoteID(flag.VoteID, models.VoteFlagInvalidated, reviewerID, now); err != nil {
+		return err
+	}
+	s.logger.Infof("Vote %d of user %d on petition %d invalidated by %d", flag.VoteID, flag.UserID, flag.PetitionID, reviewerID)
+	return nil
+}
+
+// pendingFlag Находит непроверенную отметку
+func (s *VoteFraudService) pendingFlag(flagID uint) (*models.VoteFlag, error) {
+	flag, err := s.flags.GetByID(flagID)
+	if err != nil {
+		return nil, ErrVoteFlagNotFound
+	}
+	if flag.Status != models.VoteFlagPending {
+		return nil, ErrVoteFlagReviewed
+	}
+	return flag, nil
+}
+
+// appendVoteFlags Добавляет отметки с причиной reason для каждого голоса
+func appendVoteFlags(flags []models.VoteFlag, votes []models.Vote, reason string, details string) []models.VoteFlag {
+	for _, vote := range votes {
+		flags = append(flags, models.VoteFlag{
+			VoteID:     vote.ID,
+			Reason:     reason,
+			PetitionID: vote.PetitionID,
+			UserID:     vote.UserID,
+			Login:      vote.Login,
+			Details:    details,
+			IP:         vote.IP,
+			UA:         vote.UA,
+			Status:     models.VoteFlagPending,
+		})
+	}
+	return flags
+}
+
+// SubnetOf Подсеть адреса для поиска накруток: /24 для IPv4 и /64 для IPv6. Пусто, если адрес не разобран
+func SubnetOf(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(64, 128)), Mask: net.CIDRMask(64, 128)}).String()
+}
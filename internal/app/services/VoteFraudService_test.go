@@ -0,0 +1,135 @@
+package services
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/testutil"
+	"testing"
+	"time"
+)
+
+func newVoteFraudTest(t *testing.T, policy FraudPolicy) (*gorm.DB, *VoteService, *VoteFraudService, *recordingVoteNotifier) {
+	db, votes, notifier := newVoteServiceTest(t)
+	if err := db.AutoMigrate(models.VoteFlag{}); err != nil {
+		t.Fatal(err)
+	}
+	logger := testutil.Logger()
+	fraud := NewVoteFraudService(repository.NewVoteFlagRepository(db, logger), votes.votes, votes, policy, logger)
+	votes.AddNotifier(fraud)
+	return db, votes, fraud, notifier
+}
+
+// voteFrom Подписывает петицию новым пользователем с адреса ip
+func voteFrom(t *testing.T, db *gorm.DB, votes *VoteService, petitionID uint, login string, ip string) *models.Vote {
+	user := createTestUser(t, db, login, models.StatusActive)
+	vote := models.Vote{Login: user.Login, UserID: user.ID, PetitionID: petitionID, IP: ip, UA: "test-agent"}
+	require.NoError(t, votes.Vote(&vote))
+	return &vote
+}
+
+func pendingFlags(t *testing.T, fraud *VoteFraudService, petitionID uint) []models.VoteFlag {
+	page, err := fraud.List(models.VoteFlagFilter{Status: models.VoteFlagPending, PetitionID: petitionID})
+	require.NoError(t, err)
+	return page.Items
+}
+
+func TestVoteFraudFlagsSameIP(t *testing.T) {
+	db, votes, fraud, notifier := newVoteFraudTest(t, FraudPolicy{Window: 10 * time.Minute, MaxVotesPerIP: 2, MaxVotesPerSubnet: 3})
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", TargetByVote: 100}
+	db.Create(&petition)
+
+	first := voteFrom(t, db, votes, petition.ID, "bot1", "10.0.0.7")
+	voteFrom(t, db, votes, petition.ID, "bot2", "10.0.0.7")
+	voteFrom(t, db, votes, petition.ID, "neighbour", "10.0.0.8")
+	assert.Empty(t, pendingFlags(t, fraud, petition.ID))
+
+	// Третий голос с того же IP отмечает всю волну, четвертый из подсети - всю подсеть
+	voteFrom(t, db, votes, petition.ID, "bot3", "10.0.0.7")
+	flags := pendingFlags(t, fraud, petition.ID)
+	reasons := map[string]int{}
+	for _, flag := range flags {
+		reasons[flag.Reason]++
+		assert.Equal(t, "test-agent", flag.UA)
+	}
+	assert.Equal(t, map[string]int{models.VoteFlagSameIP: 3, models.VoteFlagSameSubnet: 4}, reasons)
+
+	// Голоса не блокируются и учитываются до проверки
+	count, err := votes.votes.GetCountVoteByPetitionID(petition.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+
+	var firstFlag models.VoteFlag
+	db.Where("vote_id = ? AND reason = ?", first.ID, models.VoteFlagSameIP).First(&firstFlag)
+	require.NoError(t, fraud.Invalidate(firstFlag.ID, 1, time.Now()))
+	assert.Equal(t, []uint{first.ID}, notifier.invalidated)
+	count, err = votes.votes.GetCountVoteByPetitionID(petition.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	// Закрываются все отметки голоса, повторная проверка не нужна
+	var resolved []models.VoteFlag
+	db.Where("vote_id = ?", first.ID).Find(&resolved)
+	require.Len(t, resolved, 2)
+	for _, flag := range resolved {
+		assert.Equal(t, models.VoteFlagInvalidated, flag.Status)
+	}
+	assert.ErrorIs(t, fraud.Invalidate(firstFlag.ID, 1, time.Now()), ErrVoteFlagReviewed)
+
+	var neighbourFlag models.VoteFlag
+	db.Where("login = ?", "neighbour").First(&neighbourFlag)
+	require.NoError(t, fraud.Dismiss(neighbourFlag.ID, 1, time.Now()))
+	assert.Len(t, pendingFlags(t, fraud, petition.ID), 4)
+
+	// Повторная подпись после признания голоса недействительным тоже отмечается
+	again := models.Vote{Login: first.Login, UserID: first.UserID, PetitionID: petition.ID, IP: "192.168.1.1"}
+	require.NoError(t, votes.Vote(&again))
+	var revote models.VoteFlag
+	require.NoError(t, db.Where("vote_id = ?", again.ID).First(&revote).Error)
+	assert.Equal(t, models.VoteFlagRevote, revote.Reason)
+}
+
+func TestVoteFraudFlagsNewAccounts(t *testing.T) {
+	db, votes, fraud, _ := newVoteFraudTest(t, FraudPolicy{Window: time.Hour, NewAccountAge: 24 * time.Hour, MaxNewAccountVotes: 2})
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", TargetByVote: 100}
+	db.Create(&petition)
+
+	old := createTestUser(t, db, "old", models.StatusActive)
+	db.Model(old).Update("created_at", time.Now().AddDate(0, -1, 0))
+	require.NoError(t, votes.Vote(&models.Vote{Login: old.Login, UserID: old.ID, PetitionID: petition.ID, IP: "10.1.0.1"}))
+
+	for i := 0; i < 3; i++ {
+		voteFrom(t, db, votes, petition.ID, fmt.Sprintf("fresh%d", i), fmt.Sprintf("10.%d.0.1", i+2))
+	}
+	flags := pendingFlags(t, fraud, petition.ID)
+	require.Len(t, flags, 3)
+	for _, flag := range flags {
+		assert.Equal(t, models.VoteFlagNewAccounts, flag.Reason)
+		assert.NotEqual(t, old.ID, flag.UserID)
+	}
+}
+
+func TestVoteFraudIgnoresBackdatedVotes(t *testing.T) {
+	db, votes, fraud, _ := newVoteFraudTest(t, FraudPolicy{Window: 10 * time.Minute, MaxVotesPerIP: 1})
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", TargetByVote: 100}
+	db.Create(&petition)
+
+	// Время голоса из запроса не сдвигает окно проверки и не сохраняется
+	past := time.Now().AddDate(-1, 0, 0)
+	for _, login := range []string{"bot1", "bot2"} {
+		user := createTestUser(t, db, login, models.StatusActive)
+		vote := models.Vote{Login: user.Login, UserID: user.ID, PetitionID: petition.ID, IP: "10.0.0.7"}
+		vote.CreatedAt = past
+		require.NoError(t, votes.Vote(&vote))
+		assert.WithinDuration(t, time.Now(), vote.CreatedAt, time.Minute)
+	}
+	assert.Len(t, pendingFlags(t, fraud, petition.ID), 2)
+}
+
+func TestSubnetOf(t *testing.T) {
+	assert.Equal(t, "192.168.5.0/24", SubnetOf("192.168.5.77"))
+	assert.Equal(t, "2001:db8:1:2::/64", SubnetOf("2001:db8:1:2:aaaa::1"))
+	assert.Equal(t, "", SubnetOf("unknown"))
+}
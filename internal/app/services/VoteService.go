@@ -0,0 +1,302 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// ErrAlreadyVoted Пользователь уже подписал петицию
+	ErrAlreadyVoted = errors.New("user already signed this petition")
+	// ErrVoteNotFound Пользователь не подписывал петицию
+	ErrVoteNotFound = errors.New("vote not found")
+	// ErrInvalidVisibility Неизвестное значение видимости подписи
+	ErrInvalidVisibility = errors.New("invalid visibility")
+	// ErrReasonTooLong Причина подписи длиннее models.MaxVoteReasonLength символов
+	ErrReasonTooLong = fmt.Errorf("reason is longer than %d characters", models.MaxVoteReasonLength)
+	// ErrPetitionNotFound Петиции нет или она удалена
+	ErrPetitionNotFound = errors.New("petition not found")
+	// ErrPetitionClosed Сбор подписей по петиции завершен
+	ErrPetitionClosed = errors.New("petition is not collecting signatures")
+)
+
+// VoteNotifier Получает события о подписях, уже сохраненных в базе
+type VoteNotifier interface {
+	// NotifyVoted Петицию подписали
+	NotifyVoted(vote *models.Vote) error
+	// NotifyUnvoted Подпись отозвана
+	NotifyUnvoted(petitionID uint) error
+	// NotifyInvalidated Админ признал подпись недействительной и удалил ее
+	NotifyInvalidated(vote *models.Vote) error
+}
+
+// VoteService Подписи петиций: общий путь для вебсокета и REST.
+// Голос сохраняется вместе с событием для вебхуков, записью журнала голосов и пройденными вехами, затем проверяется цель петиции
+type VoteService struct {
+	petitions   repository.PetitionRepository
+	votes       repository.VoteRepository
+	events      repository.WebhookEventRepository
+	audit       repository.VoteAuditRepository
+	statuses    *PetitionStatusService
+	eligibility *EligibilityService
+	accounts    *AccountStatusService
+	filter      *ContentFilter
+	notifiers   []VoteNotifier
+	logger      *logrus.Logger
+}
+
+func NewVoteService(
+	petitions repository.PetitionRepository,
+	votes repository.VoteRepository,
+	events repository.WebhookEventRepository,
+	audit repository.VoteAuditRepository,
+	statuses *PetitionStatusService,
+	eligibility *EligibilityService,
+	accounts *AccountStatusService,
+	filter *ContentFilter,
+	logger *logrus.Logger,
+) *VoteService {
+	return &VoteService{
+		petitions:   petitions,
+		votes:       votes,
+		events:      events,
+		audit:       audit,
+		statuses:    statuses,
+		eligibility: eligibility,
+		accounts:    accounts,
+		filter:      filter,
+		logger:      logger,
+	}
+}
+
+// AddNotifier добавляет получателя событий о подписях
+func (s *VoteService) AddNotifier(notifier VoteNotifier) {
+	s.notifiers = append(s.notifiers, notifier)
+}
+
+// Vote сохраняет подпись. Без выбора видимости подпись анонимная, причина проверяется фильтром комментариев.
+// Отключенные и заблокированные пользователи не подписывают (ошибки AccountStatusService).
+// Если пользователь не соответствует условиям подписи петиции, возвращает *IneligibleError.
+// Неизвестная петиция - ErrPetitionNotFound, петиция с завершенным сбором - ErrPetitionClosed
+func (s *VoteService) Vote(vote *models.Vote) error {
+	if err := s.accounts.CheckAccount(vote.UserID); err != nil {
+		return err
+	}
+	// Идентификатор и время голоса всегда задает база
+	vote.Model = gorm.Model{}
+	if vote.Visibility == "" {
+		vote.Visibility = models.VoteVisibilityAnonymous
+	}
+	if !models.ValidVoteVisibility(vote.Visibility) {
+		return ErrInvalidVisibility
+	}
+	reason, err := s.checkReason(vote.Reason)
+	if err != nil {
+		return err
+	}
+	vote.Reason = reason
+	vote.Subnet = SubnetOf(vote.IP)
+	vote.UA = truncate(vote.UA, models.MaxVoteUALength)
+
+	exists, err := s.votes.VoteExist(vote.PetitionID, vote.UserID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrAlreadyVoted
+	}
+	if err := s.eligibility.Require(vote.UserID, vote.PetitionID, time.Now()); err != nil {
+		return err
+	}
+
+	// Голос, событие для вебхуков, запись журнала и пройденные голосом вехи сохраняются вместе.
+	// Строка петиции блокируется, чтобы статус не сменился между проверкой и сохранением голоса
+	var petition *models.Petition
+	var count int64
+	var milestone *models.PetitionMilestone
+	err = s.votes.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		petition, err = s.petitions.GetForUpdateTx(tx, vote.PetitionID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPetitionNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !petition.CollectsSignatures() {
+			return ErrPetitionClosed
+		}
+		if err := s.votes.CreateTx(tx, vote); err != nil {
+			return err
+		}
+		if _, err := s.audit.AppendTx(tx, vote, models.VoteAuditVoted, time.Now()); err != nil {
+			return err
+		}
+		if err := s.recordVoteEvent(tx, models.WebhookVoteCreated, vote.PetitionID); err != nil {
+			return err
+		}
+		if count, err = s.votes.GetCountVoteByPetitionIDTx(tx, vote.PetitionID); err != nil {
+			return err
+		}
+		milestone, err = s.statuses.RecordMilestonesTx(tx, petition, count)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, notifier := range s.notifiers {
+		if err := notifier.NotifyVoted(vote); err != nil {
+			s.logger.Errorf("Failed to notify about vote %d: %v", vote.ID, err)
+		}
+	}
+	if milestone != nil {
+		s.statuses.notifyMilestone(petition, milestone)
+	}
+	// Голос уже сохранен, поэтому ошибка проверки цели не возвращается
+	if _, err := s.statuses.checkTargetReached(petition, count); err != nil {
+		s.logger.Errorf("Failed to check target of petition %d: %v", vote.PetitionID, err)
+	}
+	return nil
+}
+
+// Unvote отзывает подпись пользователя. Возвращает ErrVoteNotFound, если подписи не было
+func (s *VoteService) Unvote(userID uint, petitionID uint) error {
+	if err := s.accounts.CheckAccount(userID); err != nil {
+		return err
+	}
+	err := s.votes.DB.Transaction(func(tx *gorm.DB) error {
+		vote, err := s.votes.GetByUserIDAndPetitionIDTx(tx, userID, petitionID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrVoteNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.votes.DeleteByIDTx(tx, vote.ID); err != nil {
+			return err
+		}
+		if _, err := s.audit.AppendTx(tx, vote, models.VoteAuditUnvoted, time.Now()); err != nil {
+			return err
+		}
+		return s.recordVoteEvent(tx, models.WebhookVoteDeleted, petitionID)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, notifier := range s.notifiers {
+		if err := notifier.NotifyUnvoted(petitionID); err != nil {
+			s.logger.Errorf("Failed to notify about unvote of petition %d: %v", petitionID, err)
+		}
+	}
+	return nil
+}
+
+// Invalidate удаляет голос, признанный недействительным, и рассылает новый счетчик.
+// Возвращает ErrVoteNotFound, если голоса уже нет
+func (s *VoteService) Invalidate(voteID uint) (*models.Vote, error) {
+	var vote *models.Vote
+	err := s.votes.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		vote, err = s.votes.GetByIDTx(tx, voteID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrVoteNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.votes.DeleteByIDTx(tx, vote.ID); err != nil {
+			return err
+		}
+		if _, err := s.audit.AppendTx(tx, vote, models.VoteAuditInvalidated, time.Now()); err != nil {
+			return err
+		}
+		return s.recordVoteEvent(tx, models.WebhookVoteDeleted, vote.PetitionID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, notifier := range s.notifiers {
+		if err := notifier.NotifyInvalidated(vote); err != nil {
+			s.logger.Errorf("Failed to notify about invalidated vote %d: %v", vote.ID, err)
+		}
+	}
+	return vote, nil
+}
+
+// UpdateReason меняет причину подписи. Пустая причина удаляет ее
+func (s *VoteService) UpdateReason(userID uint, petitionID uint, reason string) (*models.Vote, error) {
+	if err := s.accounts.CheckAccount(userID); err != nil {
+		return nil, err
+	}
+	reason, err := s.checkReason(reason)
+	if err != nil {
+		return nil, err
+	}
+	vote, err := s.votes.GetByUserIDAndPetitionID(userID, petitionID)
+	if err != nil {
+		return nil, ErrVoteNotFound
+	}
+	if err := s.votes.UpdateReason(vote.ID, reason); err != nil {
+		return nil, err
+	}
+	vote.Reason = reason
+	return vote, nil
+}
+
+// Reasons Причины подписи из публичного списка петиции, новые первыми
+func (s *VoteService) Reasons(petitionID uint, filter models.PublicSignatureFilter) (*models.PublicSignaturePage, error) {
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = 20
+	}
+	signatures, total, err := s.votes.GetReasons(petitionID, filter.Page, filter.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]models.PublicSignature, 0, len(signatures))
+	for i := range signatures {
+		items = append(items, signatures[i].Public())
+	}
+	return &models.PublicSignaturePage{
+		Items:    items,
+		Total:    total,
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+	}, nil
+}
+
+// checkReason Обрезает пробелы и проверяет длину и содержание причины подписи
+func (s *VoteService) checkReason(reason string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if utf8.RuneCountInString(reason) > models.MaxVoteReasonLength {
+		return "", ErrReasonTooLong
+	}
+	if err := s.filter.Check(reason); err != nil {
+		return "", err
+	}
+	return reason, nil
+}
+
+// recordVoteEvent Кладет событие голоса в очередь вебхуков. Кто голосовал, не передается
+func (s *VoteService) recordVoteEvent(tx *gorm.DB, eventType string, petitionID uint) error {
+	count, err := s.votes.GetCountVoteByPetitionIDTx(tx, petitionID)
+	if err != nil {
+		return err
+	}
+	return s.events.CreateTx(tx, eventType, map[string]interface{}{
+		"petition_id": petitionID,
+		"vote_count":  count,
+	})
+}
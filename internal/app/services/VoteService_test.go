@@ -0,0 +1,210 @@
+package services
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/testutil"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type recordingVoteNotifier struct {
+	mu          sync.Mutex
+	voted       []uint
+	unvoted     []uint
+	invalidated []uint
+}
+
+func (n *recordingVoteNotifier) NotifyVoted(vote *models.Vote) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.voted = append(n.voted, vote.ID)
+	return nil
+}
+
+func (n *recordingVoteNotifier) NotifyUnvoted(petitionID uint) error {
+	n.unvoted = append(n.unvoted, petitionID)
+	return nil
+}
+
+func (n *recordingVoteNotifier) NotifyInvalidated(vote *models.Vote) error {
+	n.invalidated = append(n.invalidated, vote.ID)
+	return nil
+}
+
+func newVoteServiceTest(t *testing.T) (*gorm.DB, *VoteService, *recordingVoteNotifier) {
+	db, statuses, _ := newPetitionStatusTest(t)
+	if err := db.AutoMigrate(models.PetitionEligibility{}, models.VoteAuditEntry{}, models.UserBan{}); err != nil {
+		t.Fatal(err)
+	}
+	logger := testutil.Logger()
+	votes := repository.NewVoteRepository(db, logger)
+	users := repository.NewUserRepository(db, logger)
+	accounts := NewAccountStatusService(users, repository.NewUserBanRepository(db, logger), logger)
+	eligibility := NewEligibilityService(repository.NewPetitionEligibilityRepository(db, logger), users, votes, logger)
+	service := NewVoteService(repository.NewPetitionRepository(db, logger), votes, repository.NewWebhookEventRepository(db, logger), repository.NewVoteAuditRepository(db, logger), statuses, eligibility, accounts, NewContentFilter([]string{"дурак", "спам*"}), logger)
+	notifier := &recordingVoteNotifier{}
+	service.AddNotifier(notifier)
+	return db, service, notifier
+}
+
+func TestContentFilter(t *testing.T) {
+	filter := NewContentFilter([]string{" Дурак ", "спам*", "ёж", ""})
+
+	assert.NoError(t, filter.Check("Поддерживаю, нужен парк"))
+	assert.ErrorIs(t, filter.Check("Сам ДУРАК!"), ErrContentRejected)
+	assert.ErrorIs(t, filter.Check("купите спамер"), ErrContentRejected)
+	assert.ErrorIs(t, filter.Check("еж"), ErrContentRejected)
+	// Слово ищется целиком, а не как часть другого слова
+	assert.NoError(t, filter.Check("придурковатый"))
+	assert.NoError(t, NewContentFilter(nil).Check("дурак"))
+}
+
+func TestVoteWithReason(t *testing.T) {
+	db, service, notifier := newVoteServiceTest(t)
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", TargetByVote: 10}
+	db.Create(&petition)
+	user := createTestUser(t, db, "signer", models.StatusActive)
+
+	vote := models.Vote{Login: user.Login, UserID: user.ID, PetitionID: petition.ID, Reason: "  Детям негде гулять  "}
+	require.NoError(t, service.Vote(&vote))
+	assert.Equal(t, models.VoteVisibilityAnonymous, vote.Visibility)
+	assert.Equal(t, "Детям негде гулять", vote.Reason)
+	assert.Equal(t, []uint{vote.ID}, notifier.voted)
+
+	var events int64
+	db.Model(&models.WebhookEvent{}).Where("type = ?", models.WebhookVoteCreated).Count(&events)
+	assert.Equal(t, int64(1), events)
+
+	assert.ErrorIs(t, service.Vote(&models.Vote{Login: user.Login, UserID: user.ID, PetitionID: petition.ID}), ErrAlreadyVoted)
+
+	page, err := service.Reasons(petition.ID, models.PublicSignatureFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), page.Total)
+	assert.Equal(t, "Детям негде гулять", page.Items[0].Reason)
+	assert.True(t, page.Items[0].Anonymous)
+
+	updated, err := service.UpdateReason(user.ID, petition.ID, "Нужна площадка")
+	require.NoError(t, err)
+	assert.Equal(t, "Нужна площадка", updated.Reason)
+
+	_, err = service.UpdateReason(user.ID, petition.ID, "")
+	require.NoError(t, err)
+	page, err = service.Reasons(petition.ID, models.PublicSignatureFilter{})
+	require.NoError(t, err)
+	assert.Zero(t, page.Total)
+
+	require.NoError(t, service.Unvote(user.ID, petition.ID))
+	assert.Equal(t, []uint{petition.ID}, notifier.unvoted)
+	assert.ErrorIs(t, service.Unvote(user.ID, petition.ID), ErrVoteNotFound)
+	_, err = service.UpdateReason(user.ID, petition.ID, "Снова")
+	assert.ErrorIs(t, err, ErrVoteNotFound)
+}
+
+// TestVoteRecordsMilestones Вехи сохраняются в транзакции голоса и получают точный счетчик даже при одновременных голосах
+func TestVoteRecordsMilestones(t *testing.T) {
+	db, service, _ := newVoteServiceTest(t)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", TargetByVote: 20}
+	db.Create(&petition)
+	users := make([]*models.UserModel, 20)
+	for i := range users {
+		users[i] = createTestUser(t, db, fmt.Sprintf("signer%d", i), models.StatusActive)
+	}
+
+	var wg sync.WaitGroup
+	for _, user := range users {
+		wg.Add(1)
+		go func(user *models.UserModel) {
+			defer wg.Done()
+			assert.NoError(t, service.Vote(&models.Vote{Login: user.Login, UserID: user.ID, PetitionID: petition.ID}))
+		}(user)
+	}
+	wg.Wait()
+
+	var milestones []models.PetitionMilestone
+	require.NoError(t, db.Where("petition_id = ?", petition.ID).Order("percent").Find(&milestones).Error)
+	require.Len(t, milestones, len(models.MilestonePercents))
+	for i, milestone := range milestones {
+		assert.Equal(t, models.MilestonePercents[i], milestone.Percent)
+		assert.Equal(t, int64(milestone.Percent*20/100), milestone.VoteCount)
+	}
+	var events int64
+	db.Model(&models.WebhookEvent{}).Where("type = ?", models.WebhookPetitionMilestone).Count(&events)
+	assert.Equal(t, int64(len(models.MilestonePercents)), events)
+
+	var stored models.Petition
+	require.NoError(t, db.First(&stored, petition.ID).Error)
+	assert.Equal(t, models.PetitionStatusAwaitingResponse, stored.Status)
+}
+
+func TestVoteRejectsInvalidInput(t *testing.T) {
+	db, service, notifier := newVoteServiceTest(t)
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", TargetByVote: 10}
+	db.Create(&petition)
+	user := createTestUser(t, db, "signer", models.StatusActive)
+
+	for _, tc := range []struct {
+		vote models.Vote
+		err  error
+	}{
+		{models.Vote{Visibility: "everyone"}, ErrInvalidVisibility},
+		{models.Vote{Reason: strings.Repeat("а", models.MaxVoteReasonLength+1)}, ErrReasonTooLong},
+		{models.Vote{Reason: "Автор дурак"}, ErrContentRejected},
+	} {
+		tc.vote.UserID, tc.vote.PetitionID, tc.vote.Login = user.ID, petition.ID, user.Login
+		assert.ErrorIs(t, service.Vote(&tc.vote), tc.err)
+	}
+	assert.Empty(t, notifier.voted)
+
+	var count int64
+	db.Model(&models.Vote{}).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestVoteRejectsClosedPetitions(t *testing.T) {
+	db, service, notifier := newVoteServiceTest(t)
+	user := createTestUser(t, db, "signer", models.StatusActive)
+	accepted := models.Petition{Title: "Парк", Description: "Построить парк", TargetByVote: 10, Status: models.PetitionStatusAccepted}
+	db.Create(&accepted)
+	deleted := models.Petition{Title: "Сквер", Description: "Построить сквер", TargetByVote: 10}
+	db.Create(&deleted)
+	db.Delete(&deleted)
+
+	assert.ErrorIs(t, service.Vote(&models.Vote{Login: user.Login, UserID: user.ID, PetitionID: 999}), ErrPetitionNotFound)
+	assert.ErrorIs(t, service.Vote(&models.Vote{Login: user.Login, UserID: user.ID, PetitionID: deleted.ID}), ErrPetitionNotFound)
+	assert.ErrorIs(t, service.Vote(&models.Vote{Login: user.Login, UserID: user.ID, PetitionID: accepted.ID}), ErrPetitionClosed)
+	assert.Empty(t, notifier.voted)
+
+	var count int64
+	db.Model(&models.Vote{}).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestVoteRejectsDisabledAccounts(t *testing.T) {
+	db, service, notifier := newVoteServiceTest(t)
+	petition := models.Petition{Title: "Парк", Description: "Построить парк", TargetByVote: 10}
+	db.Create(&petition)
+	passive := createTestUser(t, db, "passive", models.StatusPassive)
+	banned := createTestUser(t, db, "banned", models.StatusActive)
+	db.Create(&models.UserBan{UserID: banned.ID, BannedByID: 1, Reason: "spam"})
+
+	err := service.Vote(&models.Vote{Login: passive.Login, UserID: passive.ID, PetitionID: petition.ID})
+	assert.ErrorIs(t, err, ErrAccountPassive)
+	err = service.Vote(&models.Vote{Login: banned.Login, UserID: banned.ID, PetitionID: petition.ID})
+	assert.ErrorIs(t, err, ErrAccountBanned)
+	assert.Empty(t, notifier.voted)
+
+	// Подпись, оставленная до блокировки, не отзывается и не меняется от имени заблокированного
+	db.Create(&models.Vote{Login: banned.Login, UserID: banned.ID, PetitionID: petition.ID})
+	assert.ErrorIs(t, service.Unvote(banned.ID, petition.ID), ErrAccountBanned)
+	_, err = service.UpdateReason(banned.ID, petition.ID, "Причина")
+	assert.ErrorIs(t, err, ErrAccountBanned)
+}
@@ -0,0 +1,433 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/utils/auth"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Заголовки запроса вебхука
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// webhookBatchSize Сколько событий и доставок обрабатывается за один проход
+const webhookBatchSize = 100
+
+var (
+	// ErrWebhookLimit У пользователя уже максимальное число вебхуков
+	ErrWebhookLimit = errors.New("webhook limit reached")
+	// ErrInvalidWebhookURL Адрес вебхука не http(s)
+	ErrInvalidWebhookURL = errors.New("webhook url must use http or https")
+	// ErrWebhookDeliveryPending Доставка еще не завершена, повторять нечего
+	ErrWebhookDeliveryPending = errors.New("webhook delivery is still pending")
+	// errPrivateAddress Вебхук ведет во внутреннюю сеть
+	errPrivateAddress = errors.New("webhook address is not public")
+)
+
+// WebhookPolicy Ограничения и расписание повторов доставки
+type WebhookPolicy struct {
+	// MaxPerUser Сколько вебхуков может создать пользователь
+	MaxPerUser int
+	// MaxAttempts После стольких неудачных попыток доставка считается мертвой
+	MaxAttempts int
+	// RetryBase Пауза после первой неудачи, дальше она удваивается
+	RetryBase time.Duration
+	// RetryMax Верхняя граница паузы между попытками
+	RetryMax time.Duration
+	// Timeout Сколько ждать ответа получателя
+	Timeout time.Duration
+	// Retention Сколько хранить журнал завершенных доставок
+	Retention time.Duration
+}
+
+// NewWebhookHTTPClient Клиент для доставки вебхуков. Если allowPrivate выключен, клиент не подключается
+// к локальным и внутренним адресам, в том числе когда на них указывает DNS имя получателя
+func NewWebhookHTTPClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+				ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		// Перенаправления не выполняются, чтобы подписанное событие не ушло на другой адрес
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// WebhookSignature Подпись тела запроса: HMAC-SHA256 от "timestamp.body" в hex с префиксом sha256=
+func WebhookSignature(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookEnvelope Тело запроса вебхука
+type webhookEnvelope struct {
+	ID        uint            `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// WebhookService Управляет вебхуками и доставляет им события из исходящей очереди
+type WebhookService struct {
+	webhooks   repository.WebhookRepository
+	events     repository.WebhookEventRepository
+	deliveries repository.WebhookDeliveryRepository
+	client     *http.Client
+	policy     WebhookPolicy
+	logger     *logrus.Logger
+}
+
+func NewWebhookService(
+	webhooks repository.WebhookRepository,
+	events repository.WebhookEventRepository,
+	deliveries repository.WebhookDeliveryRepository,
+	client *http.Client,
+	policy WebhookPolicy,
+	logger *logrus.Logger,
+) *WebhookService {
+	return &WebhookService{
+		webhooks:   webhooks,
+		events:     events,
+		deliveries: deliveries,
+		client:     client,
+		policy:     policy,
+		logger:     logger,
+	}
+}
+
+// Create создает вебхук пользователя со случайным секретом
+func (s *WebhookService) Create(userID uint, input models.WebhookInput) (*models.Webhook, error) {
+	if err := validateWebhookURL(input.URL); err != nil {
+		return nil, err
+	}
+	count, err := s.webhooks.CountByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if s.policy.MaxPerUser > 0 && count >= int64(s.policy.MaxPerUser) {
+		return nil, ErrWebhookLimit
+	}
+	secret, err := auth.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := models.Webhook{
+		UserID: userID,
+		URL:    input.URL,
+		Secret: "whsec_" + secret,
+		Events: strings.Join(uniqueValues(input.Events), ","),
+		Active: input.Active == nil || *input.Active,
+	}
+	if err := s.webhooks.Create(&webhook); err != nil {
+		return nil, err
+	}
+	s.logger.Infof("User %d created webhook %d", userID, webhook.ID)
+	return &webhook, nil
+}
+
+// Update меняет адрес, события и включенность вебхука
+func (s *WebhookService) Update(webhook *models.Webhook, input models.WebhookInput) error {
+	if err := validateWebhookURL(input.URL); err != nil {
+		return err
+	}
+	webhook.URL = input.URL
+	webhook.Events = strings.Join(uniqueValues(input.Events), ",")
+	if input.Active != nil {
+		webhook.Active = *input.Active
+	}
+	return s.webhooks.Update(webhook)
+}
+
+// Get возвращает вебхук пользователя
+func (s *WebhookService) Get(id uint, userID uint) (*models.Webhook, error) {
+	return s.webhooks.GetByIDAndUserID(id, userID)
+}
+
+// List возвращает вебхуки пользователя
+func (s *WebhookService) List(userID uint) ([]models.Webhook, error) {
+	return s.webhooks.GetByUserID(userID)
+}
+
+// Delete удаляет вебхук и журнал его доставок
+func (s *WebhookService) Delete(webhook *models.Webhook) error {
+	return s.webhooks.DeleteByID(webhook.ID)
+}
+
+// Deliveries возвращает журнал доставок вебхука
+func (s *WebhookService) Deliveries(webhook *models.Webhook, filter models.WebhookDeliveryFilter) (*models.WebhookDeliveryPage, error) {
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = 20
+	}
+	items, total, err := s.deliveries.GetByWebhookID(webhook.ID, filter.Status, filter.Page, filter.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &models.WebhookDeliveryPage{
+		Items:    items,
+		Total:    total,
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+	}, nil
+}
+
+// Redeliver ставит завершенную доставку в очередь заново с новым счетчиком попыток
+func (s *WebhookService) Redeliver(webhook *models.Webhook, deliveryID uint) (*models.WebhookDelivery, error) {
+	delivery, err := s.deliveries.GetByIDAndWebhookID(deliveryID, webhook.ID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.Status == models.WebhookDeliveryPending {
+		return nil, ErrWebhookDeliveryPending
+	}
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	delivery.LastError = ""
+	delivery.LastStatusCode = 0
+	if err := s.deliveries.Update(delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// ProcessDue раскладывает новые события по вебхукам, отправляет доставки, время которых наступило,
+// и чистит старый журнал
+func (s *WebhookService) ProcessDue(now time.Time) {
+	s.dispatch(now)
+	s.deliverDue(now)
+
+	if s.policy.Retention <= 0 {
+		return
+	}
+	cutoff := now.Add(-s.policy.Retention)
+	if _, err := s.deliveries.DeleteFinishedBefore(cutoff); err != nil {
+		s.logger.Errorf("Failed to delete old webhook deliveries: %v", err)
+		return
+	}
+	if _, err := s.events.DeleteDispatchedBefore(cutoff); err != nil {
+		s.logger.Errorf("Failed to delete old webhook events: %v", err)
+	}
+}
+
+// dispatch Создает доставки для каждого вебхука, подписанного на событие.
+// Событие отмечается разложенным в той же транзакции, поэтому доставки не создаются дважды
+func (s *WebhookService) dispatch(now time.Time) {
+	for {
+		events, err := s.events.GetUndispatched(webhookBatchSize)
+		if err != nil {
+			s.logger.Errorf("Failed to get webhook events: %v", err)
+			return
+		}
+		for _, event := range events {
+			err := s.events.DB.Transaction(func(tx *gorm.DB) error {
+				claimed, err := s.events.MarkDispatchedTx(tx, event.ID, now)
+				if err != nil || !claimed {
+					return err
+				}
+				webhooks, err := s.webhooks.GetActiveByEventTx(tx, event.Type)
+				if err != nil {
+					return err
+				}
+				deliveries := make([]models.WebhookDelivery, 0, len(webhooks))
+				for _, webhook := range webhooks {
+					deliveries = append(deliveries, models.WebhookDelivery{
+						WebhookID:     webhook.ID,
+						EventID:       event.ID,
+						EventType:     event.Type,
+						Status:        models.WebhookDeliveryPending,
+						NextAttemptAt: now,
+					})
+				}
+				return s.deliveries.CreateBatchTx(tx, deliveries)
+			})
+			if err != nil {
+				s.logger.Errorf("Failed to dispatch webhook event %d: %v", event.ID, err)
+				return
+			}
+		}
+		if len(events) < webhookBatchSize {
+			return
+		}
+	}
+}
+
+// deliverDue Отправляет доставки, время которых наступило
+func (s *WebhookService) deliverDue(now time.Time) {
+	deliveries, err := s.deliveries.GetDue(now, webhookBatchSize)
+	if err != nil {
+		s.logger.Errorf("Failed to get due webhook deliveries: %v", err)
+		return
+	}
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		// Пока идет попытка, доставка не видна другим экземплярам сервера
+		claimed, err := s.deliveries.Claim(delivery.ID, now, now.Add(2*s.policy.Timeout))
+		if err != nil {
+			s.logger.Errorf("Failed to claim webhook delivery %d: %v", delivery.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		s.deliver(delivery, now)
+	}
+}
+
+// deliver Делает одну попытку доставки и сохраняет ее результат
+func (s *WebhookService) deliver(delivery *models.WebhookDelivery, now time.Time) {
+	delivery.Attempts++
+	if !delivery.Webhook.Active {
+		s.finish(delivery, models.WebhookDeliveryDead, 0, "webhook is disabled")
+		return
+	}
+
+	statusCode, err := s.send(delivery)
+	switch {
+	case err == nil:
+		delivery.DeliveredAt = &now
+		s.finish(delivery, models.WebhookDeliverySucceeded, statusCode, "")
+	case delivery.Attempts >= s.policy.MaxAttempts:
+		s.logger.Warnf("Webhook delivery %d is dead after %d attempts: %v", delivery.ID, delivery.Attempts, err)
+		s.finish(delivery, models.WebhookDeliveryDead, statusCode, err.Error())
+	default:
+		delivery.NextAttemptAt = now.Add(s.retryDelay(delivery.Attempts))
+		s.finish(delivery, models.WebhookDeliveryPending, statusCode, err.Error())
+	}
+}
+
+func (s *WebhookService) finish(delivery *models.WebhookDelivery, status string, statusCode int, lastError string) {
+	delivery.Status = status
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = truncate(lastError, 300)
+	if err := s.deliveries.Update(delivery); err != nil {
+		s.logger.Errorf("Failed to save webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// retryDelay Пауза перед следующей попыткой: RetryBase, затем вдвое больше после каждой неудачи, но не больше RetryMax
+func (s *WebhookService) retryDelay(attempts int) time.Duration {
+	delay := s.policy.RetryBase
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= s.policy.RetryMax {
+			return s.policy.RetryMax
+		}
+	}
+	return delay
+}
+
+// send Отправляет подписанное событие. Успехом считается любой ответ 2xx
+func (s *WebhookService) send(delivery *models.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(webhookEnvelope{
+		ID:        delivery.Event.ID,
+		Type:      delivery.Event.Type,
+		CreatedAt: delivery.Event.CreatedAt,
+		Data:      json.RawMessage(delivery.Event.Payload),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.policy.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "petition-api-webhooks")
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, WebhookSignature(delivery.Webhook.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Тело ответа не нужно, но его дочитывание позволяет переиспользовать соединение
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// validateWebhookURL Разрешены только http и https адреса с хостом
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+	return nil
+}
+
+// uniqueValues убирает повторы, сохраняя порядок
+func uniqueValues(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// truncate Обрезает строку до limit символов
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit])
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"petition_api/internal/app/models"
+	repository "petition_api/internal/app/repositories"
+	"petition_api/internal/app/testutil"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver Получатель вебхуков, который проверяет подпись и отвечает заданным кодом
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	secret   string
+	bodies   []map[string]interface{}
+	badSigns int
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	body, _ := io.ReadAll(req.Body)
+	if req.Header.Get(WebhookSignatureHeader) != WebhookSignature(r.secret, req.Header.Get(WebhookTimestampHeader), body) {
+		r.badSigns++
+	}
+	var envelope map[string]interface{}
+	_ = json.Unmarshal(body, &envelope)
+	r.bodies = append(r.bodies, envelope)
+	w.WriteHeader(r.status)
+}
+
+func newWebhookTest(t *testing.T, status int) (*gorm.DB, *WebhookService, *webhookReceiver, *models.Webhook) {
+	db := testutil.OpenDB(t, models.Webhook{}, models.WebhookEvent{}, models.WebhookDelivery{})
+	receiver := &webhookReceiver{status: status}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	logger := testutil.Logger()
+	service := NewWebhookService(
+		repository.NewWebhookRepository(db, logger),
+		repository.NewWebhookEventRepository(db, logger),
+		repository.NewWebhookDeliveryRepository(db, logger),
+		NewWebhookHTTPClient(time.Second, true),
+		WebhookPolicy{MaxPerUser: 2, MaxAttempts: 3, RetryBase: time.Minute, RetryMax: time.Hour, Timeout: time.Second},
+		logger,
+	)
+	webhook, err := service.Create(1, models.WebhookInput{URL: server.URL, Events: []string{models.WebhookVoteCreated}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver.secret = webhook.Secret
+	return db, service, receiver, webhook
+}
+
+func recordEvent(t *testing.T, db *gorm.DB, eventType string, data interface{}) {
+	events := repository.NewWebhookEventRepository(db, testutil.Logger())
+	if err := events.CreateTx(db, eventType, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	db, service, receiver, webhook := newWebhookTest(t, http.StatusNoContent)
+	recordEvent(t, db, models.WebhookVoteCreated, map[string]interface{}{"petition_id": 7, "vote_count": 3})
+	// На это событие вебхук не подписан
+	recordEvent(t, db, models.WebhookPetitionCreated, map[string]interface{}{"id": 7})
+	// Событие из откатившейся транзакции не уходит
+	events := repository.NewWebhookEventRepository(db, testutil.Logger())
+	_ = db.Transaction(func(tx *gorm.DB) error {
+		_ = events.CreateTx(tx, models.WebhookVoteCreated, map[string]interface{}{"petition_id": 8})
+		return errors.New("rollback")
+	})
+
+	service.ProcessDue(time.Now())
+	assert.Len(t, receiver.bodies, 1)
+	assert.Equal(t, 0, receiver.badSigns)
+	assert.Equal(t, models.WebhookVoteCreated, receiver.bodies[0]["type"])
+	data := receiver.bodies[0]["data"].(map[string]interface{})
+	assert.Equal(t, float64(3), data["vote_count"])
+
+	// Повторная обработка ничего не отправляет заново
+	service.ProcessDue(time.Now())
+	assert.Len(t, receiver.bodies, 1)
+
+	page, err := service.Deliveries(webhook, models.WebhookDeliveryFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), page.Total)
+	assert.Equal(t, models.WebhookDeliverySucceeded, page.Items[0].Status)
+	assert.Equal(t, http.StatusNoContent, page.Items[0].LastStatusCode)
+	assert.NotNil(t, page.Items[0].DeliveredAt)
+}
+
+func TestWebhookRetryAndDeadLetter(t *testing.T) {
+	db, service, receiver, webhook := newWebhookTest(t, http.StatusInternalServerError)
+	recordEvent(t, db, models.WebhookVoteCreated, map[string]interface{}{"petition_id": 7, "vote_count": 1})
+
+	now := time.Now()
+	service.ProcessDue(now)
+	assert.Len(t, receiver.bodies, 1)
+
+	// Следующая попытка через RetryBase, затем пауза удваивается
+	service.ProcessDue(now.Add(30 * time.Second))
+	assert.Len(t, receiver.bodies, 1)
+	service.ProcessDue(now.Add(time.Minute))
+	assert.Len(t, receiver.bodies, 2)
+	service.ProcessDue(now.Add(2 * time.Minute))
+	assert.Len(t, receiver.bodies, 2)
+	service.ProcessDue(now.Add(3 * time.Minute))
+	assert.Len(t, receiver.bodies, 3)
+
+	page, _ := service.Deliveries(webhook, models.WebhookDeliveryFilter{Status: models.WebhookDeliveryDead})
+	assert.Equal(t, int64(1), page.Total)
+	dead := page.Items[0]
+	assert.Equal(t, 3, dead.Attempts)
+	assert.Equal(t, http.StatusInternalServerError, dead.LastStatusCode)
+	assert.Contains(t, dead.LastError, "500")
+
+	// Мертвые доставки больше не отправляются, пока их не повторят вручную
+	service.ProcessDue(now.Add(time.Hour))
+	assert.Len(t, receiver.bodies, 3)
+
+	receiver.status = http.StatusOK
+	_, err := service.Redeliver(webhook, dead.ID)
+	assert.NoError(t, err)
+	_, err = service.Redeliver(webhook, dead.ID)
+	assert.ErrorIs(t, err, ErrWebhookDeliveryPending)
+	service.ProcessDue(time.Now().Add(time.Second))
+	assert.Len(t, receiver.bodies, 4)
+	page, _ = service.Deliveries(webhook, models.WebhookDeliveryFilter{Status: models.WebhookDeliverySucceeded})
+	assert.Equal(t, int64(1), page.Total)
+}
+
+func TestWebhookValidation(t *testing.T) {
+	_, service, _, _ := newWebhookTest(t, http.StatusOK)
+
+	_, err := service.Create(1, models.WebhookInput{URL: "ftp://example.com/hook", Events: []string{models.WebhookVoteCreated}})
+	assert.ErrorIs(t, err, ErrInvalidWebhookURL)
+
+	_, err = service.Create(1, models.WebhookInput{URL: "https://example.com/hook", Events: []string{models.WebhookVoteCreated, models.WebhookVoteCreated}})
+	assert.NoError(t, err)
+	_, err = service.Create(1, models.WebhookInput{URL: "https://example.com/other", Events: []string{models.WebhookVoteCreated}})
+	assert.ErrorIs(t, err, ErrWebhookLimit)
+
+	hooks, _ := service.List(1)
+	assert.Equal(t, []string{models.WebhookVoteCreated}, hooks[1].EventList())
+}
+
+func TestWebhookClientBlocksPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := NewWebhookHTTPClient(time.Second, false).Get(server.URL)
+	assert.ErrorIs(t, err, errPrivateAddress)
+	resp, err := NewWebhookHTTPClient(time.Second, true).Get(server.URL)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"io"
+	"strings"
+)
+
+// S3Client Минимальный набор операций S3-совместимого хранилища (AWS S3, MinIO и т.п.).
+// Реализуется оберткой над SDK, в тестах - заглушкой
+type S3Client interface {
+	PutObject(bucket string, key string, body io.Reader, contentType string) error
+	// GetObject возвращает ErrNotFound, если объекта нет
+	GetObject(bucket string, key string) (io.ReadCloser, error)
+	DeleteObject(bucket string, key string) error
+}
+
+// S3Storage Хранит файлы в бакете S3-совместимого хранилища
+type S3Storage struct {
+	client S3Client
+	bucket string
+	// publicURL Адрес, по которому файлы бакета доступны снаружи, например https://cdn.example.kz/petitions
+	publicURL string
+}
+
+func NewS3Storage(client S3Client, bucket string, publicURL string) *S3Storage {
+	return &S3Storage{client: client, bucket: bucket, publicURL: strings.TrimSuffix(publicURL, "/")}
+}
+
+func (s *S3Storage) Put(key string, body io.Reader, contentType string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	return s.client.PutObject(s.bucket, key, body, contentType)
+}
+
+func (s *S3Storage) Open(key string) (io.ReadCloser, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	return s.client.GetObject(s.bucket, key)
+}
+
+func (s *S3Storage) Delete(key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	return s.client.DeleteObject(s.bucket, key)
+}
+
+func (s *S3Storage) URL(key string) string {
+	return s.publicURL + "/" + key
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrNotFound Файла с таким ключом нет в хранилище
+var ErrNotFound = errors.New("file not found")
+
+// ErrInvalidKey Ключ файла пустой или выходит за пределы хранилища
+var ErrInvalidKey = errors.New("invalid file key")
+
+// Storage Хранилище загруженных файлов (аватары, вложения).
+// Ключ - относительный путь вида "avatars/7/abc_64.jpg"
+type Storage interface {
+	// Put сохраняет файл, перезаписывая существующий с тем же ключом
+	Put(key string, body io.Reader, contentType string) error
+	// Open открывает файл на чтение. Если файла нет, возвращает ErrNotFound
+	Open(key string) (io.ReadCloser, error)
+	// Delete удаляет файл. Отсутствие файла не считается ошибкой
+	Delete(key string) error
+	// URL возвращает публичную ссылку на файл
+	URL(key string) string
+}
+
+// validateKey Проверяет, что ключ относительный и не содержит переходов вверх по папкам
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+// fakeS3Client Хранит объекты в памяти вместо настоящего S3
+type fakeS3Client struct {
+	objects map[string][]byte
+}
+
+func (f *fakeS3Client) PutObject(bucket string, key string, body io.Reader, contentType string) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	f.objects[bucket+"/"+key] = data
+	return nil
+}
+
+func (f *fakeS3Client) GetObject(bucket string, key string) (io.ReadCloser, error) {
+	data, ok := f.objects[bucket+"/"+key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (f *fakeS3Client) DeleteObject(bucket string, key string) error {
+	delete(f.objects, bucket+"/"+key)
+	return nil
+}
+
+func testStorage(t *testing.T, s Storage) {
+	assert.NoError(t, s.Put("avatars/1/a.jpg", strings.NewReader("image"), "image/jpeg"))
+
+	file, err := s.Open("avatars/1/a.jpg")
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(file)
+	file.Close()
+	assert.Equal(t, "image", string(data))
+
+	assert.NoError(t, s.Delete("avatars/1/a.jpg"))
+	_, err = s.Open("avatars/1/a.jpg")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, s.Delete("avatars/1/a.jpg"))
+
+	for _, key := range []string{"", "/etc/passwd", "../secret", "a/../../b", "a//b"} {
+		assert.ErrorIs(t, s.Put(key, strings.NewReader("x"), "text/plain"), ErrInvalidKey, key)
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	s := NewLocalStorage(t.TempDir(), "/files/")
+	testStorage(t, s)
+	assert.Equal(t, "/files/avatars/1/a.jpg", s.URL("avatars/1/a.jpg"))
+}
+
+func TestS3Storage(t *testing.T) {
+	s := NewS3Storage(&fakeS3Client{objects: map[string][]byte{}}, "petitions", "https://cdn.example.kz/petitions")
+	testStorage(t, s)
+	assert.Equal(t, "https://cdn.example.kz/petitions/avatars/1/a.jpg", s.URL("avatars/1/a.jpg"))
+}
@@ -0,0 +1,43 @@
+// Package testutil Общие помощники для тестов: временная база SQLite, логгер и ключ подписи JWT
+package testutil
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"github.com/glebarez/sqlite"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"path/filepath"
+	"petition_api/utils/auth"
+	"testing"
+)
+
+// OpenDB Открывает пустую базу SQLite во временной папке теста и создает таблицы моделей
+func OpenDB(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// Logger Логгер для тестов, пишет только предупреждения и ошибки
+func Logger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	return logger
+}
+
+// SetAuthKey Задает новый ключ подписи JWT для теста
+func SetAuthKey(t testing.TB) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.SetPrivateKey(key)
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"petition_api/internal/app/models"
+	"petition_api/utils/auth"
+	"time"
+)
+
+// APIKeyHeader Заголовок, в котором интеграции передают ключ
+const APIKeyHeader = "X-API-Key"
+
+// lastUsedResolution Время последнего использования ключа обновляется не чаще этого интервала
+const lastUsedResolution = time.Minute
+
+// APIKeyStore Хранилище API ключей
+type APIKeyStore interface {
+	GetByPrefix(prefix string) (*models.APIKey, error)
+	TouchLastUsed(id uint, at time.Time) error
+}
+
+// NewAPIKeyMiddleware возвращает middleware для роутов интеграций. Запрос проходит с ключом X-API-Key,
+// у которого есть все переданные скоупы, или с access токеном вошедшего пользователя (как NewAuthMiddleware).
+// Без ключа и токена отвечает 401. Ключи заблокированных пользователей не принимаются
+func NewAPIKeyMiddleware(store APIKeyStore, accounts AccountChecker, logger *logrus.Logger, scopes ...string) gin.HandlerFunc {
+	sessionMiddleware := NewAuthMiddleware(logger, accounts)
+	return func(c *gin.Context) {
+		rawKey := c.GetHeader(APIKeyHeader)
+		if rawKey == "" {
+			if _, ok := AccessTokenFromRequest(c); !ok {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API key or access token is required"})
+				return
+			}
+			sessionMiddleware(c)
+			return
+		}
+
+		prefix, secret, ok := auth.ParseAPIKey(rawKey)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+			return
+		}
+
+		key, err := store.GetByPrefix(prefix)
+		if err != nil || !auth.CheckAPIKeySecret(secret, key.SecretHash) {
+			logger.Warn("Invalid API key used. Prefix: ", prefix)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+			return
+		}
+
+		now := time.Now()
+		if key.IsExpired(now) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API key is expired"})
+			return
+		}
+
+		for _, scope := range scopes {
+			if !key.HasScope(scope) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API key has no scope " + scope})
+				return
+			}
+		}
+
+		role := ""
+		if accounts != nil {
+			if err := accounts.CheckAccount(key.UserID); err != nil {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if role, err = accounts.CurrentRole(key.UserID); err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check account"})
+				return
+			}
+		}
+
+		if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > lastUsedResolution {
+			if err := store.TouchLastUsed(key.ID, now); err != nil {
+				logger.Errorf("Failed to update api key last usage: %v", err)
+			}
+		}
+
+		c.Set("ID", key.UserID)
+		c.Set("Role", role)
+		c.Set("APIKeyID", key.ID)
+		c.Next()
+	}
+}
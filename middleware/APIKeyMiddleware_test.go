@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"petition_api/internal/app/models"
+	"petition_api/utils/auth"
+	"testing"
+	"time"
+)
+
+// fakeAPIKeys Хранилище ключей в памяти
+type fakeAPIKeys map[string]*models.APIKey
+
+func (f fakeAPIKeys) GetByPrefix(prefix string) (*models.APIKey, error) {
+	if key, ok := f[prefix]; ok {
+		return key, nil
+	}
+	return nil, errors.New("api key not found")
+}
+
+func (f fakeAPIKeys) TouchLastUsed(id uint, at time.Time) error {
+	return nil
+}
+
+// newTestAPIKey Создает ключ пользователя userID со скоупами и возвращает его полное значение
+func newTestAPIKey(t *testing.T, store fakeAPIKeys, userID uint, scopes string) string {
+	raw, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store[prefix] = &models.APIKey{UserID: userID, Prefix: prefix, SecretHash: hash, Scopes: scopes}
+	return raw
+}
+
+func TestAPIKeyMiddlewareEnforcesScopes(t *testing.T) {
+	router := newAuthTestRouter(t, nil)
+	store := fakeAPIKeys{}
+	router.GET("/count", NewAPIKeyMiddleware(store, blockedAccounts{9: true}, logrus.New(), models.ScopeVotesRead), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"id": c.Value("ID")})
+	})
+	votesKey := newTestAPIKey(t, store, 7, models.ScopeVotesRead)
+	petitionsKey := newTestAPIKey(t, store, 7, models.ScopePetitionsRead)
+	blockedKey := newTestAPIKey(t, store, 9, models.ScopeVotesRead)
+	token, err := auth.CreateAccessToken(8, "User")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		header string
+		value  string
+		code   int
+	}{
+		{"no credentials", "", "", http.StatusUnauthorized},
+		{"invalid key", APIKeyHeader, "pk_abc.def", http.StatusUnauthorized},
+		{"key without scope", APIKeyHeader, petitionsKey, http.StatusForbidden},
+		{"key of blocked user", APIKeyHeader, blockedKey, http.StatusForbidden},
+		{"key with scope", APIKeyHeader, votesKey, http.StatusOK},
+		{"session", "Authorization", "Bearer " + token, http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/count", nil)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tc.code, w.Code)
+		})
+	}
+}
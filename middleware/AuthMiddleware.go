@@ -6,20 +6,30 @@ import (
 	"github.com/sirupsen/logrus"
 	"net/http"
 	"petition_api/utils/auth"
+	"strings"
 )
 
-// NewAuthMiddleware возвращает middleware функцию для аутентификации
-func NewAuthMiddleware(logger *logrus.Logger) gin.HandlerFunc {
+// AccountChecker Проверяет, может ли пользователь пользоваться API (например, не заблокирован ли он)
+type AccountChecker interface {
+	CheckAccount(userID uint) error
+	// CurrentRole Текущая роль пользователя. Роль из токена не используется, чтобы смена роли применялась сразу
+	CurrentRole(userID uint) (string, error)
+}
+
+// NewAuthMiddleware возвращает middleware функцию для аутентификации.
+// Токен берется из заголовка Authorization: Bearer, а если его нет - из куки access_token.
+// Если передан accounts, запросы заблокированных пользователей отклоняются даже с действующим токеном,
+// а роль берется из базы, а не из токена
+func NewAuthMiddleware(logger *logrus.Logger, accounts AccountChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authCookie, err := c.Request.Cookie("access_token") // Чтение куки access_token из запроса
-		if err != nil || authCookie == nil {
-			logger.Warn("Access token cookie is missing or invalid")
-			c.JSON(http.StatusForbidden, gin.H{"error": "Access token cookie is missing or invalid"})
+		tokenString, ok := AccessTokenFromRequest(c)
+		if !ok {
+			logger.Warn("Access token is missing or invalid")
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access token is missing or invalid"})
 			c.Abort()
 			return
 		}
 
-		tokenString := authCookie.Value
 		// Валидация токена
 		claims, code, err := auth.ValidateAccessToken(tokenString)
 		if err != nil {
@@ -28,10 +38,49 @@ func NewAuthMiddleware(logger *logrus.Logger) gin.HandlerFunc {
 			return
 		}
 
-		logger.Debug(fmt.Sprintf("Authorized user: ID: %v : Role %v", claims.ID, claims.Role))
+		role := claims.Role
+		if accounts != nil {
+			if err := accounts.CheckAccount(claims.ID); err != nil {
+				logger.Warnf("Rejected request of user %d: %v", claims.ID, err)
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				c.Abort()
+				return
+			}
+			if role, err = accounts.CurrentRole(claims.ID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check account"})
+				c.Abort()
+				return
+			}
+		}
+
+		logger.Debug(fmt.Sprintf("Authorized user: ID: %v : Role %v", claims.ID, role))
 
 		c.Set("ID", claims.ID)
-		c.Set("Role", claims.Role)
+		c.Set("Role", role)
 		c.Next()
 	}
 }
+
+// AccessTokenFromRequest Возвращает access токен из заголовка Authorization: Bearer или из куки access_token
+func AccessTokenFromRequest(c *gin.Context) (string, bool) {
+	if token, ok := BearerToken(c); ok {
+		return token, true
+	}
+
+	authCookie, err := c.Request.Cookie("access_token") // Чтение куки access_token из запроса
+	if err != nil || authCookie == nil || authCookie.Value == "" {
+		return "", false
+	}
+	return authCookie.Value, true
+}
+
+// BearerToken Возвращает токен из заголовка Authorization: Bearer
+func BearerToken(c *gin.Context) (string, bool) {
+	header := c.GetHeader("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
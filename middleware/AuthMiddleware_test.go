@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"petition_api/utils/auth"
+	"testing"
+)
+
+func newAuthTestRouter(t *testing.T, accounts AccountChecker) *gin.Engine {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.SetPrivateKey(key)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/me", NewAuthMiddleware(logrus.New(), accounts), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"id": c.Value("ID"), "role": c.Value("Role")})
+	})
+	return router
+}
+
+func TestAuthMiddlewareBearer(t *testing.T) {
+	router := newAuthTestRouter(t, nil)
+	token, err := auth.CreateAccessToken(7, "User")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":7,"role":"User"}`, w.Body.String())
+}
+
+func TestAuthMiddlewareCookie(t *testing.T) {
+	router := newAuthTestRouter(t, nil)
+	token, err := auth.CreateAccessToken(3, "Admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":3,"role":"Admin"}`, w.Body.String())
+}
+
+func TestAuthMiddlewareRejects(t *testing.T) {
+	router := newAuthTestRouter(t, nil)
+
+	cases := map[string]string{
+		"missing":      "",
+		"wrong scheme": "Basic abc",
+		"bad token":    "Bearer not-a-jwt",
+	}
+	for name, header := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			if header != "" {
+				req.Header.Set("Authorization", header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		})
+	}
+}
+
+// blockedAccounts Блокирует пользователей из списка
+type blockedAccounts map[uint]bool
+
+func (b blockedAccounts) CheckAccount(userID uint) error {
+	if b[userID] {
+		return errors.New("account is banned")
+	}
+	return nil
+}
+
+func (b blockedAccounts) CurrentRole(userID uint) (string, error) {
+	return "User", nil
+}
+
+func TestAuthMiddlewareRejectsBlockedAccount(t *testing.T) {
+	router := newAuthTestRouter(t, blockedAccounts{5: true})
+
+	for id, want := range map[uint]int{5: http.StatusForbidden, 6: http.StatusOK} {
+		token, err := auth.CreateAccessToken(id, "User")
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, want, w.Code)
+	}
+}
+
+func TestAuthMiddlewareUsesCurrentRole(t *testing.T) {
+	router := newAuthTestRouter(t, blockedAccounts{})
+	// Токен выдан, когда пользователь был админом, а в базе он уже User
+	token, err := auth.CreateAccessToken(7, "Admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":7,"role":"User"}`, w.Body.String())
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// PermissionChecker Проверяет права ролей
+type PermissionChecker interface {
+	HasPermission(role string, permission string) (bool, error)
+}
+
+// RequirePermission возвращает middleware, которое пропускает только пользователей,
+// у роли которых есть все переданные права. Ставится после NewAuthMiddleware
+func RequirePermission(checker PermissionChecker, logger *logrus.Logger, permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Value("Role").(string)
+		if role == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Role is empty"})
+			return
+		}
+
+		for _, permission := range permissions {
+			ok, err := checker.HasPermission(role, permission)
+			if err != nil {
+				logger.Errorf("Failed to check permission %s for role %s: %v", permission, role, err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+				return
+			}
+			if !ok {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "No access to this resource"})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// HasPermission Проверяет право у роли текущего пользователя в обработчиках
+func HasPermission(c *gin.Context, checker PermissionChecker, permission string) bool {
+	role, _ := c.Value("Role").(string)
+	if role == "" {
+		return false
+	}
+	ok, err := checker.HasPermission(role, permission)
+	return err == nil && ok
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakePermissions map[string][]string
+
+func (f fakePermissions) HasPermission(role string, permission string) (bool, error) {
+	for _, p := range f[role] {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestRequirePermission(t *testing.T) {
+	checker := fakePermissions{
+		"Moderator": {"comment.delete.any"},
+		"Admin":     {"comment.delete.any", "user.manage"},
+	}
+
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		role string
+		code int
+	}{
+		{"", http.StatusForbidden},
+		{"User", http.StatusForbidden},
+		{"Moderator", http.StatusForbidden},
+		{"Admin", http.StatusOK},
+	}
+	for _, tc := range cases {
+		router := gin.New()
+		router.GET("/", func(c *gin.Context) {
+			c.Set("Role", tc.role)
+		}, RequirePermission(checker, logrus.New(), "comment.delete.any", "user.manage"), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, tc.code, w.Code, tc.role)
+	}
+}
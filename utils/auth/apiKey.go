@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+// apiKeyPrefixTag Начало каждого API ключа, чтобы ключ легко узнавался в логах и секрет-сканерах
+const apiKeyPrefixTag = "pk_"
+
+// GenerateAPIKey Создает новый API ключ вида pk_<prefix>.<secret>.
+// Возвращает полный ключ для пользователя, префикс для поиска и хэш секрета для хранения
+func GenerateAPIKey() (key string, prefix string, secretHash string, err error) {
+	prefixBytes := make([]byte, 6)
+	if _, err = rand.Read(prefixBytes); err != nil {
+		return "", "", "", err
+	}
+	secretBytes := make([]byte, 32)
+	if _, err = rand.Read(secretBytes); err != nil {
+		return "", "", "", err
+	}
+
+	prefix = apiKeyPrefixTag + hex.EncodeToString(prefixBytes)
+	secret := hex.EncodeToString(secretBytes)
+	return prefix + "." + secret, prefix, HashAPIKeySecret(secret), nil
+}
+
+// ParseAPIKey Разбирает ключ на префикс и секрет
+func ParseAPIKey(key string) (prefix string, secret string, ok bool) {
+	prefix, secret, ok = strings.Cut(strings.TrimSpace(key), ".")
+	if !ok || !strings.HasPrefix(prefix, apiKeyPrefixTag) || secret == "" {
+		return "", "", false
+	}
+	return prefix, secret, true
+}
+
+// HashAPIKeySecret Хэширует секрет ключа. Секрет случайный и длинный, поэтому достаточно SHA-256
+func HashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckAPIKeySecret Сравнивает секрет с хэшем за постоянное время
+func CheckAPIKeySecret(secret string, secretHash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashAPIKeySecret(secret)), []byte(secretHash)) == 1
+}
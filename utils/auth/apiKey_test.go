@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAPIKeyRoundTrip(t *testing.T) {
+	key, prefix, hash, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsedPrefix, secret, ok := ParseAPIKey(key)
+	assert.True(t, ok)
+	assert.Equal(t, prefix, parsedPrefix)
+	assert.True(t, CheckAPIKeySecret(secret, hash))
+	assert.False(t, CheckAPIKeySecret(secret+"x", hash))
+}
+
+func TestParseAPIKeyInvalid(t *testing.T) {
+	for _, key := range []string{"", "pk_abc", "abc.def", "pk_abc."} {
+		_, _, ok := ParseAPIKey(key)
+		assert.False(t, ok, key)
+	}
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"crypto/rsa"
 	"errors"
 	"petition_api/utils/RSAKeyFunc"
 	"time"
@@ -8,22 +9,62 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
+const (
+	// AccessTokenTTL Время жизни access токена
+	AccessTokenTTL = 60 * time.Minute
+	// RefreshTokenTTL Время жизни refresh токена
+	RefreshTokenTTL = time.Hour * 24 * 7
+
+	// TokenTypeAccess, TokenTypeRefresh Значения claim typ: refresh токен нельзя использовать вместо access и наоборот
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
 var (
-	privateKey, _ = RSAKeyFunc.LoadPrivateKeyFromFile("C:\\Users\\Жандарбек\\GolandProjects\\petition_api\\configs\\private_key.pem")
-	publicKey     = &privateKey.PublicKey
+	privateKey *rsa.PrivateKey
+	publicKey  *rsa.PublicKey
 )
 
+// LoadKeys Загружает RSA ключ для подписи токенов из pem файла
+func LoadKeys(filename string) error {
+	key, err := RSAKeyFunc.LoadPrivateKeyFromFile(filename)
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return errors.New("invalid private key file: " + filename)
+	}
+	SetPrivateKey(key)
+	return nil
+}
+
+// SetPrivateKey Устанавливает RSA ключ для подписи и проверки токенов
+func SetPrivateKey(key *rsa.PrivateKey) {
+	privateKey = key
+	publicKey = &key.PublicKey
+}
+
 type Claims struct {
 	ID   uint   `json:"id"`
 	Role string `json:"role"`
+	// Type Тип токена: TokenTypeAccess или TokenTypeRefresh
+	Type string `json:"typ"`
 	jwt.StandardClaims
 }
 
-// ValidateAccessToken Проверяет действительность токена
-func ValidateAccessToken(accessTokenString string) (*Claims, int, error) {
-	token, err := jwt.ParseWithClaims(accessTokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+// parseToken Проверяет подпись токена. Принимается только RS256, чтобы нельзя было подменить алгоритм
+func parseToken(tokenString string) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
 		return publicKey, nil
 	})
+}
+
+// ValidateAccessToken Проверяет действительность токена
+func ValidateAccessToken(accessTokenString string) (*Claims, int, error) {
+	token, err := parseToken(accessTokenString)
 
 	if err != nil {
 		var ve *jwt.ValidationError
@@ -41,54 +82,50 @@ func ValidateAccessToken(accessTokenString string) (*Claims, int, error) {
 
 	// Получение данных из токена
 	claims, ok := token.Claims.(*Claims)
-	if !ok {
+	if !ok || claims.Type != TokenTypeAccess {
 		return nil, 403, errors.New("invalid access token claims")
 	}
 
 	return claims, 0, nil
 }
 
-func CreateAccessToken(userID uint, userRole string) (string, error) {
-	// Создание access токена с истечением срока действия через 60 минут
-	claims := &Claims{
-		ID:   userID,
-		Role: userRole,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(60 * time.Minute).Unix(),
-			IssuedAt:  time.Now().Unix(),
-		},
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	accessToken, err := token.SignedString(privateKey)
+// createToken Подписывает токен типа tokenType со сроком жизни ttl.
+// Случайный jti делает уникальными даже токены, выпущенные в одну секунду, сессии ищутся по строке токена
+func createToken(userID uint, userRole string, tokenType string, ttl time.Duration) (string, error) {
+	jti, err := RandomToken(16)
 	if err != nil {
 		return "", err
 	}
-	return accessToken, nil
-}
-
-func CreateRefreshToken(userID uint, userRole string) (string, error) {
-	// Создание refresh токена с истечением срока действия через 7 дней
+	now := time.Now()
 	claims := &Claims{
 		ID:   userID,
 		Role: userRole,
+		Type: tokenType,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Hour * 24 * 7).Unix(),
-			IssuedAt:  time.Now().Unix(),
+			Id:        jti,
+			ExpiresAt: now.Add(ttl).Unix(),
+			IssuedAt:  now.Unix(),
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	refreshToken, err := token.SignedString(privateKey)
-	if err != nil {
-		return "", err
-	}
-	return refreshToken, nil
+	return token.SignedString(privateKey)
 }
 
-func RefreshTokens(refreshTokenString string) (string, string, error) {
+// CreateAccessToken Создает access токен с истечением срока действия через 60 минут
+func CreateAccessToken(userID uint, userRole string) (string, error) {
+	return createToken(userID, userRole, TokenTypeAccess, AccessTokenTTL)
+}
+
+// CreateRefreshToken Создает refresh токен с истечением срока действия через 7 дней
+func CreateRefreshToken(userID uint, userRole string) (string, error) {
+	return createToken(userID, userRole, TokenTypeRefresh, RefreshTokenTTL)
+}
+
+// RefreshTokens Создает новую пару токенов на основе refresh токена.
+// Роль передается отдельно, чтобы смена роли в базе применялась при следующем обновлении токенов
+func RefreshTokens(refreshTokenString string, userRole string) (string, string, error) {
 	// Распаковка refresh токена
-	refreshToken, err := jwt.Parse(refreshTokenString, func(token *jwt.Token) (interface{}, error) {
-		return publicKey, nil
-	})
+	refreshToken, err := parseToken(refreshTokenString)
 	if err != nil {
 		return "", "", err
 	}
@@ -100,11 +137,10 @@ func RefreshTokens(refreshTokenString string) (string, string, error) {
 
 	// Получение данных пользователя из refresh токена
 	claims, ok := refreshToken.Claims.(*Claims)
-	if !ok {
+	if !ok || claims.Type != TokenTypeRefresh {
 		return "", "", errors.New("invalid refresh token claims")
 	}
 	userID := claims.ID
-	userRole := claims.Role
 
 	// Создание новых access и refresh токенов для пользователя
 	newAccessToken, err := CreateAccessToken(userID, userRole)
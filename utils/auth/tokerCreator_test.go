@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func setTestKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetPrivateKey(key)
+}
+
+func TestRefreshTokenIsNotAccessToken(t *testing.T) {
+	setTestKey(t)
+	access, err := CreateAccessToken(1, "User")
+	require.NoError(t, err)
+	refresh, err := CreateRefreshToken(1, "User")
+	require.NoError(t, err)
+
+	claims, _, err := ValidateAccessToken(access)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), claims.ID)
+	assert.Equal(t, TokenTypeAccess, claims.Type)
+
+	_, code, err := ValidateAccessToken(refresh)
+	assert.Error(t, err)
+	assert.Equal(t, 403, code)
+
+	_, _, err = RefreshTokens(access, "User")
+	assert.Error(t, err)
+	newAccess, newRefresh, err := RefreshTokens(refresh, "Admin")
+	require.NoError(t, err)
+	claims, _, err = ValidateAccessToken(newAccess)
+	require.NoError(t, err)
+	assert.Equal(t, "Admin", claims.Role)
+	assert.NotEqual(t, refresh, newRefresh)
+}
+
+func TestTokensAreUnique(t *testing.T) {
+	setTestKey(t)
+	first, err := CreateRefreshToken(1, "User")
+	require.NoError(t, err)
+	second, err := CreateRefreshToken(1, "User")
+	require.NoError(t, err)
+	// Токены одной секунды отличаются благодаря jti
+	assert.NotEqual(t, first, second)
+}
@@ -0,0 +1,61 @@
+package imaging
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Thumbnail Обрезает изображение по центру до квадрата и масштабирует до size x size.
+// При уменьшении цвет пикселя - среднее по всем попавшим в него пикселям исходника
+func Thumbnail(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	// Квадрат из центра исходного изображения
+	crop := image.Rect(0, 0, side, side)
+	offset := image.Pt(bounds.Min.X+(bounds.Dx()-side)/2, bounds.Min.Y+(bounds.Dy()-side)/2)
+	square := image.NewRGBA(crop)
+	draw.Draw(square, crop, src, offset, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	if side == 0 {
+		return dst
+	}
+	for y := 0; y < size; y++ {
+		y0, y1 := sourceSpan(y, size, side)
+		for x := 0; x < size; x++ {
+			x0, x1 := sourceSpan(x, size, side)
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				row := square.Pix[sy*square.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint32(p[0])
+					g += uint32(p[1])
+					b += uint32(p[2])
+					a += uint32(p[3])
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// sourceSpan Возвращает диапазон пикселей исходника [from, to), который попадает в пиксель i результата.
+// При увеличении диапазон состоит из одного ближайшего пикселя
+func sourceSpan(i int, dstSize int, srcSize int) (int, int) {
+	from := i * srcSize / dstSize
+	to := (i + 1) * srcSize / dstSize
+	if to <= from {
+		to = from + 1
+	}
+	return from, to
+}
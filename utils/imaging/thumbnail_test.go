@@ -0,0 +1,42 @@
+package imaging
+
+import (
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestThumbnailCropsToCenterSquare(t *testing.T) {
+	// Слева и справа красные полосы, в центре синий квадрат 100x100
+	src := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 200; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 50 && x < 150 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			src.SetRGBA(x, y, c)
+		}
+	}
+
+	thumb := Thumbnail(src, 10)
+	assert.Equal(t, image.Rect(0, 0, 10, 10), thumb.Bounds())
+	assert.Equal(t, color.RGBA{B: 255, A: 255}, thumb.RGBAAt(0, 0))
+	assert.Equal(t, color.RGBA{B: 255, A: 255}, thumb.RGBAAt(9, 9))
+}
+
+func TestThumbnailAveragesAndUpscales(t *testing.T) {
+	// Шахматка 2x2 из черного и белого
+	src := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	src.SetRGBA(0, 0, color.RGBA{A: 255})
+	src.SetRGBA(1, 1, color.RGBA{A: 255})
+	src.SetRGBA(1, 0, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	src.SetRGBA(0, 1, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+
+	assert.Equal(t, color.RGBA{R: 127, G: 127, B: 127, A: 255}, Thumbnail(src, 1).RGBAAt(0, 0))
+
+	big := Thumbnail(src, 4)
+	assert.Equal(t, color.RGBA{A: 255}, big.RGBAAt(0, 0))
+	assert.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, big.RGBAAt(3, 0))
+}
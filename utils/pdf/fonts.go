@@ -0,0 +1,41 @@
+package pdf
+
+import (
+	_ "embed"
+	"sync"
+)
+
+// Встроенные шрифты DejaVu Sans Condensed: кириллица, казахские буквы и латиница. Лицензия в fonts/LICENSE
+var (
+	//go:embed fonts/DejaVuSansCondensed.ttf
+	sansData []byte
+	//go:embed fonts/DejaVuSansCondensed-Bold.ttf
+	sansBoldData []byte
+
+	sansOnce     sync.Once
+	sans         *Font
+	sansBold     *Font
+	sansFontsErr error
+)
+
+// Sans Обычное начертание встроенного шрифта
+func Sans() (*Font, error) {
+	loadSans()
+	return sans, sansFontsErr
+}
+
+// SansBold Полужирное начертание встроенного шрифта
+func SansBold() (*Font, error) {
+	loadSans()
+	return sansBold, sansFontsErr
+}
+
+// loadSans Разбирает встроенные шрифты один раз на весь процесс
+func loadSans() {
+	sansOnce.Do(func() {
+		if sans, sansFontsErr = ParseFont(sansData); sansFontsErr != nil {
+			return
+		}
+		sansBold, sansFontsErr = ParseFont(sansBoldData)
+	})
+}
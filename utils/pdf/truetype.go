@@ -0,0 +1,443 @@
+package pdf
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrInvalidFont Файл не похож на шрифт TrueType с нужными таблицами
+var ErrInvalidFont = errors.New("invalid or unsupported truetype font")
+
+// Font Разобранный шрифт TrueType. Только для чтения, один шрифт можно использовать в нескольких документах
+type Font struct {
+	// Name PostScript имя шрифта
+	Name       string
+	data       []byte
+	tables     map[string][]byte
+	unitsPerEm int
+	ascent     int
+	descent    int
+	capHeight  int
+	bbox       [4]int
+	advances   []uint16
+	cmap       map[rune]uint16
+	numGlyphs  int
+	longLoca   bool
+}
+
+// ParseFont Разбирает TrueType шрифт (glyf outlines). Шрифты CFF (OpenType .otf) не поддерживаются
+func ParseFont(data []byte) (*Font, error) {
+	if len(data) < 12 || binary.BigEndian.Uint32(data) != 0x00010000 {
+		return nil, ErrInvalidFont
+	}
+	f := &Font{data: data, tables: make(map[string][]byte)}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		record := 12 + 16*i
+		if record+16 > len(data) {
+			return nil, ErrInvalidFont
+		}
+		tag := string(data[record : record+4])
+		offset := int(binary.BigEndian.Uint32(data[record+8:]))
+		length := int(binary.BigEndian.Uint32(data[record+12:]))
+		if offset+length > len(data) {
+			return nil, ErrInvalidFont
+		}
+		f.tables[tag] = data[offset : offset+length]
+	}
+	for _, tag := range []string{"head", "hhea", "hmtx", "maxp", "cmap", "loca", "glyf"} {
+		if f.tables[tag] == nil {
+			return nil, ErrInvalidFont
+		}
+	}
+
+	head := f.tables["head"]
+	if len(head) < 54 {
+		return nil, ErrInvalidFont
+	}
+	f.unitsPerEm = int(binary.BigEndian.Uint16(head[18:]))
+	for i := range f.bbox {
+		f.bbox[i] = int(int16(binary.BigEndian.Uint16(head[36+2*i:])))
+	}
+	f.longLoca = binary.BigEndian.Uint16(head[50:]) == 1
+
+	hhea := f.tables["hhea"]
+	if len(hhea) < 36 || f.unitsPerEm == 0 {
+		return nil, ErrInvalidFont
+	}
+	f.ascent = int(int16(binary.BigEndian.Uint16(hhea[4:])))
+	f.descent = int(int16(binary.BigEndian.Uint16(hhea[6:])))
+	f.capHeight = f.ascent
+	if os2 := f.tables["OS/2"]; len(os2) >= 90 && binary.BigEndian.Uint16(os2) >= 2 {
+		f.capHeight = int(int16(binary.BigEndian.Uint16(os2[88:])))
+	}
+
+	f.numGlyphs = int(binary.BigEndian.Uint16(f.tables["maxp"][4:]))
+	numMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	hmtx := f.tables["hmtx"]
+	if numMetrics == 0 || len(hmtx) < 4*numMetrics {
+		return nil, ErrInvalidFont
+	}
+	f.advances = make([]uint16, f.numGlyphs)
+	for i := range f.advances {
+		// Глифы после numberOfHMetrics берут ширину последней записи
+		m := i
+		if m >= numMetrics {
+			m = numMetrics - 1
+		}
+		f.advances[i] = binary.BigEndian.Uint16(hmtx[4*m:])
+	}
+
+	cmap, err := parseCmap(f.tables["cmap"])
+	if err != nil {
+		return nil, err
+	}
+	f.cmap = cmap
+	f.Name = parsePostScriptName(f.tables["name"])
+	return f, nil
+}
+
+// parseCmap Читает юникодную таблицу символов: формат 12 (весь Unicode) или формат 4 (BMP)
+func parseCmap(table []byte) (map[rune]uint16, error) {
+	if len(table) < 4 {
+		return nil, ErrInvalidFont
+	}
+	var format4, format12 []byte
+	count := int(binary.BigEndian.Uint16(table[2:]))
+	for i := 0; i < count && 4+8*i+8 <= len(table); i++ {
+		platform := binary.BigEndian.Uint16(table[4+8*i:])
+		encoding := binary.BigEndian.Uint16(table[4+8*i+2:])
+		offset := int(binary.BigEndian.Uint32(table[4+8*i+4:]))
+		if offset+4 > len(table) {
+			continue
+		}
+		sub := table[offset:]
+		unicode := platform == 0 || (platform == 3 && (encoding == 1 || encoding == 10))
+		if !unicode {
+			continue
+		}
+		switch binary.BigEndian.Uint16(sub) {
+		case 4:
+			format4 = sub
+		case 12:
+			format12 = sub
+		}
+	}
+
+	result := make(map[rune]uint16)
+	switch {
+	case format12 != nil && len(format12) >= 16:
+		groups := int(binary.BigEndian.Uint32(format12[12:]))
+		for i := 0; i < groups && 16+12*i+12 <= len(format12); i++ {
+			group := format12[16+12*i:]
+			start := binary.BigEndian.Uint32(group)
+			end := binary.BigEndian.Uint32(group[4:])
+			glyph := binary.BigEndian.Uint32(group[8:])
+			for c := start; c <= end && c <= 0x10FFFF; c++ {
+				result[rune(c)] = uint16(glyph + c - start)
+			}
+		}
+	case format4 != nil && len(format4) >= 14:
+		segCount := int(binary.BigEndian.Uint16(format4[6:])) / 2
+		ends := 14
+		starts := ends + 2*segCount + 2
+		deltas := starts + 2*segCount
+		rangeOffsets := deltas + 2*segCount
+		if rangeOffsets+2*segCount > len(format4) {
+			return nil, ErrInvalidFont
+		}
+		for s := 0; s < segCount; s++ {
+			end := int(binary.BigEndian.Uint16(format4[ends+2*s:]))
+			start := int(binary.BigEndian.Uint16(format4[starts+2*s:]))
+			delta := int(binary.BigEndian.Uint16(format4[deltas+2*s:]))
+			rangeOffset := int(binary.BigEndian.Uint16(format4[rangeOffsets+2*s:]))
+			for c := start; c <= end && c != 0xFFFF; c++ {
+				var glyph int
+				if rangeOffset == 0 {
+					glyph = (c + delta) & 0xFFFF
+				} else {
+					// Смещение считается от самого поля idRangeOffset
+					pos := rangeOffsets + 2*s + rangeOffset + 2*(c-start)
+					if pos+2 > len(format4) {
+						continue
+					}
+					glyph = int(binary.BigEndian.Uint16(format4[pos:]))
+					if glyph != 0 {
+						glyph = (glyph + delta) & 0xFFFF
+					}
+				}
+				if glyph != 0 {
+					result[rune(c)] = uint16(glyph)
+				}
+			}
+		}
+	default:
+		return nil, ErrInvalidFont
+	}
+	return result, nil
+}
+
+// parsePostScriptName Имя шрифта из таблицы name (nameID 6)
+func parsePostScriptName(table []byte) string {
+	if len(table) < 6 {
+		return "Font"
+	}
+	count := int(binary.BigEndian.Uint16(table[2:]))
+	storage := int(binary.BigEndian.Uint16(table[4:]))
+	for i := 0; i < count && 6+12*i+12 <= len(table); i++ {
+		record := table[6+12*i:]
+		platform := binary.BigEndian.Uint16(record)
+		nameID := binary.BigEndian.Uint16(record[6:])
+		length := int(binary.BigEndian.Uint16(record[8:]))
+		offset := storage + int(binary.BigEndian.Uint16(record[10:]))
+		if nameID != 6 || offset+length > len(table) {
+			continue
+		}
+		raw := table[offset : offset+length]
+		if platform == 1 {
+			return string(raw)
+		}
+		units := make([]uint16, len(raw)/2)
+		for j := range units {
+			units[j] = binary.BigEndian.Uint16(raw[2*j:])
+		}
+		return string(utf16.Decode(units))
+	}
+	return "Font"
+}
+
+// GlyphIndex Номер глифа символа. 0 - символа в шрифте нет
+func (f *Font) GlyphIndex(r rune) uint16 {
+	return f.cmap[r]
+}
+
+// TextWidth Ширина строки в пунктах при размере шрифта size
+func (f *Font) TextWidth(s string, size float64) float64 {
+	var units int
+	for _, r := range s {
+		units += int(f.advances[f.GlyphIndex(r)])
+	}
+	return float64(units) * size / float64(f.unitsPerEm)
+}
+
+// scaled Переводит единицы шрифта в тысячные доли кегля, как их ждет PDF
+func (f *Font) scaled(units int) int {
+	return units * 1000 / f.unitsPerEm
+}
+
+// glyphData Контуры глифа из таблицы glyf
+func (f *Font) glyphData(glyph int) []byte {
+	loca := f.tables["loca"]
+	var start, end int
+	if f.longLoca {
+		if 4*glyph+8 > len(loca) {
+			return nil
+		}
+		start = int(binary.BigEndian.Uint32(loca[4*glyph:]))
+		end = int(binary.BigEndian.Uint32(loca[4*glyph+4:]))
+	} else {
+		if 2*glyph+4 > len(loca) {
+			return nil
+		}
+		start = 2 * int(binary.BigEndian.Uint16(loca[2*glyph:]))
+		end = 2 * int(binary.BigEndian.Uint16(loca[2*glyph+2:]))
+	}
+	glyf := f.tables["glyf"]
+	if start >= end || end > len(glyf) {
+		return nil
+	}
+	return glyf[start:end]
+}
+
+// Флаги составного глифа
+const (
+	compositeArgsAreWords = 0x0001
+	compositeHaveScale    = 0x0008
+	compositeMore         = 0x0020
+	compositeXYScale      = 0x0040
+	compositeTwoByTwo     = 0x0080
+)
+
+// components Глифы, из которых собран составной глиф
+func components(data []byte) []int {
+	if len(data) < 10 || int16(binary.BigEndian.Uint16(data)) >= 0 {
+		return nil
+	}
+	var result []int
+	pos := 10
+	for pos+4 <= len(data) {
+		flags := binary.BigEndian.Uint16(data[pos:])
+		result = append(result, int(binary.BigEndian.Uint16(data[pos+2:])))
+		pos += 4
+		if flags&compositeArgsAreWords != 0 {
+			pos += 4
+		} else {
+			pos += 2
+		}
+		switch {
+		case flags&compositeHaveScale != 0:
+			pos += 2
+		case flags&compositeXYScale != 0:
+			pos += 4
+		case flags&compositeTwoByTwo != 0:
+			pos += 8
+		}
+		if flags&compositeMore == 0 {
+			break
+		}
+	}
+	return result
+}
+
+// Subset Собирает копию шрифта, в которой остались контуры только нужных глифов.
+// Номера глифов не меняются, поэтому тексты в PDF ссылаются на них напрямую
+func (f *Font) Subset(glyphs map[uint16]bool) []byte {
+	keep := make(map[int]bool, len(glyphs)+1)
+	// Глиф 0 (.notdef) обязателен в любом шрифте
+	queue := []int{0}
+	for g := range glyphs {
+		queue = append(queue, int(g))
+	}
+	for len(queue) > 0 {
+		g := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if keep[g] || g >= f.numGlyphs {
+			continue
+		}
+		keep[g] = true
+		queue = append(queue, components(f.glyphData(g))...)
+	}
+
+	var glyf []byte
+	loca := make([]byte, 4*(f.numGlyphs+1))
+	for g := 0; g < f.numGlyphs; g++ {
+		binary.BigEndian.PutUint32(loca[4*g:], uint32(len(glyf)))
+		if keep[g] {
+			glyf = append(glyf, f.glyphData(g)...)
+			for len(glyf)%4 != 0 {
+				glyf = append(glyf, 0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(loca[4*f.numGlyphs:], uint32(len(glyf)))
+
+	head := append([]byte(nil), f.tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)
+	binary.BigEndian.PutUint16(head[50:], 1)
+
+	tables := map[string][]byte{
+		"head": head,
+		"hhea": f.tables["hhea"],
+		"hmtx": f.tables["hmtx"],
+		"maxp": f.tables["maxp"],
+		"cmap": f.tables["cmap"],
+		"loca": loca,
+		"glyf": glyf,
+	}
+	// Инструкции хинтинга нужны, чтобы глифы отрисовывались так же, как в исходном шрифте.
+	// cmap PDF не читает, но без нее часть программ считает шрифт поврежденным
+	for _, tag := range []string{"cvt ", "fpgm", "prep"} {
+		if table := f.tables[tag]; table != nil {
+			tables[tag] = table
+		}
+	}
+	font := buildFont(tables)
+	binary.BigEndian.PutUint32(font[headOffset(font):][8:], 0xB1B0AFBA-checksum(font))
+	return font
+}
+
+// buildFont Записывает таблицы в файл TrueType
+func buildFont(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	numTables := len(tags)
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= numTables {
+		entrySelector++
+	}
+	searchRange := 16 << entrySelector
+
+	header := make([]byte, 12+16*numTables)
+	binary.BigEndian.PutUint32(header, 0x00010000)
+	binary.BigEndian.PutUint16(header[4:], uint16(numTables))
+	binary.BigEndian.PutUint16(header[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(header[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(header[10:], uint16(16*numTables-searchRange))
+
+	body := make([]byte, 0)
+	for i, tag := range tags {
+		table := tables[tag]
+		record := header[12+16*i:]
+		copy(record, tag)
+		binary.BigEndian.PutUint32(record[4:], checksum(table))
+		binary.BigEndian.PutUint32(record[8:], uint32(len(header)+len(body)))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(table)))
+		body = append(body, table...)
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+	}
+	return append(header, body...)
+}
+
+// headOffset Смещение таблицы head в собранном файле
+func headOffset(font []byte) int {
+	numTables := int(binary.BigEndian.Uint16(font[4:]))
+	for i := 0; i < numTables; i++ {
+		record := font[12+16*i:]
+		if string(record[:4]) == "head" {
+			return int(binary.BigEndian.Uint32(record[8:]))
+		}
+	}
+	return 0
+}
+
+// checksum Контрольная сумма TrueType: сумма 32-битных слов
+func checksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+// Wrap Разбивает текст на строки не шире width пунктов. Переносы строк в тексте сохраняются,
+// слово длиннее строки режется по символам
+func (f *Font) Wrap(s string, size, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if f.TextWidth(candidate, size) <= width {
+				line = candidate
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			line = ""
+			for _, r := range word {
+				if line != "" && f.TextWidth(line+string(r), size) > width {
+					lines = append(lines, line)
+					line = ""
+				}
+				line += string(r)
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
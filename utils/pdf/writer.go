@@ -0,0 +1,320 @@
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// Размер страницы A4 в пунктах
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Зарезервированные номера объектов
+const (
+	catalogID = 1
+	pagesID   = 2
+	infoID    = 3
+)
+
+// fontResource Шрифт документа: номера его объектов и использованные глифы
+type fontResource struct {
+	font *Font
+	name string
+	// type0ID, cidID, descriptorID, fileID, toUnicodeID Объекты шрифта, пишутся при закрытии документа
+	type0ID      int
+	cidID        int
+	descriptorID int
+	fileID       int
+	toUnicodeID  int
+	used         map[uint16]rune
+}
+
+// Writer Пишет PDF постранично прямо в поток: в памяти хранится только текущая страница
+// и номера уже записанных объектов. Шрифты встраиваются подмножеством использованных глифов
+type Writer struct {
+	out     *bufio.Writer
+	written int64
+	offsets map[int]int64
+	nextID  int
+	fonts   []*fontResource
+	pageIDs []int
+	content bytes.Buffer
+	inPage  bool
+	current *fontResource
+	size    float64
+	title   string
+	err     error
+}
+
+// NewWriter Начинает документ. Все шрифты, которые понадобятся, передаются сразу
+func NewWriter(w io.Writer, title string, fonts ...*Font) *Writer {
+	pw := &Writer{
+		out:     bufio.NewWriterSize(w, 32<<10),
+		offsets: make(map[int]int64),
+		nextID:  infoID + 1,
+		title:   title,
+	}
+	for i, font := range fonts {
+		pw.fonts = append(pw.fonts, &fontResource{
+			font:         font,
+			name:         fmt.Sprintf("F%d", i+1),
+			type0ID:      pw.allocate(),
+			cidID:        pw.allocate(),
+			descriptorID: pw.allocate(),
+			fileID:       pw.allocate(),
+			toUnicodeID:  pw.allocate(),
+			used:         make(map[uint16]rune),
+		})
+	}
+	// Двоичные байты в комментарии подсказывают программам, что файл не текстовый
+	pw.write("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	return pw
+}
+
+// NewPage Завершает текущую страницу и начинает новую
+func (pw *Writer) NewPage() {
+	pw.finishPage()
+	pw.inPage = true
+	pw.current = nil
+}
+
+// PageCount Сколько страниц уже начато
+func (pw *Writer) PageCount() int {
+	count := len(pw.pageIDs)
+	if pw.inPage {
+		count++
+	}
+	return count
+}
+
+// SetFont Выбирает шрифт, переданный в NewWriter, и его размер
+func (pw *Writer) SetFont(font *Font, size float64) {
+	for _, resource := range pw.fonts {
+		if resource.font == font {
+			pw.current = resource
+			pw.size = size
+			return
+		}
+	}
+	pw.fail(fmt.Errorf("pdf: font %s is not registered", font.Name))
+}
+
+// Text Выводит строку текущим шрифтом. x - от левого края, y - базовая линия от верхнего края страницы
+func (pw *Writer) Text(x, y float64, s string) {
+	if pw.current == nil {
+		pw.fail(fmt.Errorf("pdf: font is not set"))
+		return
+	}
+	var glyphs strings.Builder
+	for _, r := range s {
+		glyph := pw.current.font.GlyphIndex(r)
+		if glyph != 0 {
+			pw.current.used[glyph] = r
+		}
+		fmt.Fprintf(&glyphs, "%04X", glyph)
+	}
+	fmt.Fprintf(&pw.content, "BT /%s %s Tf %s %s Td <%s> Tj ET\n",
+		pw.current.name, number(pw.size), number(x), number(PageHeight-y), glyphs.String())
+}
+
+// Line Рисует отрезок толщиной width
+func (pw *Writer) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&pw.content, "q %s w %s %s m %s %s l S Q\n",
+		number(width), number(x1), number(PageHeight-y1), number(x2), number(PageHeight-y2))
+}
+
+// FillRect Закрашивает прямоугольник оттенком серого: 0 - черный, 1 - белый. y - верхний край
+func (pw *Writer) FillRect(x, y, width, height, gray float64) {
+	fmt.Fprintf(&pw.content, "q %s g %s %s %s %s re f Q\n",
+		number(gray), number(x), number(PageHeight-y-height), number(width), number(height))
+}
+
+// Close Дописывает шрифты, дерево страниц и таблицу ссылок. Поток w не закрывается
+func (pw *Writer) Close() error {
+	pw.finishPage()
+	if len(pw.pageIDs) == 0 {
+		// В PDF должна быть хотя бы одна страница
+		pw.inPage = true
+		pw.finishPage()
+	}
+	for _, resource := range pw.fonts {
+		pw.writeFont(resource)
+	}
+
+	kids := make([]string, len(pw.pageIDs))
+	for i, id := range pw.pageIDs {
+		kids[i] = fmt.Sprintf("%d 0 R", id)
+	}
+	pw.object(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+	pw.object(catalogID, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID))
+	pw.object(infoID, fmt.Sprintf("<< /Title %s /Producer (petition_api) >>", textString(pw.title)))
+
+	xref := pw.written
+	pw.write(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", pw.nextID))
+	for id := 1; id < pw.nextID; id++ {
+		pw.write(fmt.Sprintf("%010d 00000 n \n", pw.offsets[id]))
+	}
+	pw.write(fmt.Sprintf("trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		pw.nextID, catalogID, infoID, xref))
+
+	if pw.err == nil {
+		pw.err = pw.out.Flush()
+	}
+	return pw.err
+}
+
+// finishPage Записывает содержимое и объект текущей страницы
+func (pw *Writer) finishPage() {
+	if !pw.inPage {
+		return
+	}
+	pw.inPage = false
+	contentID := pw.allocate()
+	pw.stream(contentID, "", pw.content.Bytes())
+	pw.content.Reset()
+
+	fonts := make([]string, len(pw.fonts))
+	for i, resource := range pw.fonts {
+		fonts[i] = fmt.Sprintf("/%s %d 0 R", resource.name, resource.type0ID)
+	}
+	pageID := pw.allocate()
+	pw.object(pageID, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /Font << %s >> >> /Contents %d 0 R >>",
+		pagesID, number(PageWidth), number(PageHeight), strings.Join(fonts, " "), contentID))
+	pw.pageIDs = append(pw.pageIDs, pageID)
+}
+
+// writeFont Встраивает шрифт как CIDFontType2 с кодировкой Identity-H: код символа в тексте - номер глифа
+func (pw *Writer) writeFont(resource *fontResource) {
+	font := resource.font
+	glyphs := make([]int, 0, len(resource.used))
+	subset := make(map[uint16]bool, len(resource.used))
+	for glyph := range resource.used {
+		glyphs = append(glyphs, int(glyph))
+		subset[glyph] = true
+	}
+	sort.Ints(glyphs)
+
+	// Метка подмножества из шести заглавных букв обязательна для встроенных частей шрифтов
+	tag := []byte("PETAAA")
+	for i, n := len(tag)-1, resource.type0ID; n > 0 && i >= 3; i, n = i-1, n/26 {
+		tag[i] = byte('A' + n%26)
+	}
+	baseName := string(tag) + "+" + strings.ReplaceAll(font.Name, " ", "")
+
+	widths := make([]string, 0, len(glyphs))
+	for _, glyph := range glyphs {
+		widths = append(widths, fmt.Sprintf("%d [%d]", glyph, font.scaled(int(font.advances[glyph]))))
+	}
+
+	pw.object(resource.type0ID, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		baseName, resource.cidID, resource.toUnicodeID))
+	pw.object(resource.cidID, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /DW %d /W [%s] /CIDToGIDMap /Identity >>",
+		baseName, resource.descriptorID, font.scaled(int(font.advances[0])), strings.Join(widths, " ")))
+	pw.object(resource.descriptorID, fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 4 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		baseName, font.scaled(font.bbox[0]), font.scaled(font.bbox[1]), font.scaled(font.bbox[2]), font.scaled(font.bbox[3]),
+		font.scaled(font.ascent), font.scaled(font.descent), font.scaled(font.capHeight), resource.fileID))
+	data := font.Subset(subset)
+	pw.stream(resource.fileID, fmt.Sprintf("/Length1 %d", len(data)), data)
+	pw.stream(resource.toUnicodeID, "", toUnicodeCMap(glyphs, resource.used))
+}
+
+// toUnicodeCMap Таблица обратного перевода глифов в символы, чтобы текст из PDF можно было копировать и искать
+func toUnicodeCMap(glyphs []int, used map[uint16]rune) []byte {
+	var b bytes.Buffer
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	// В одном блоке bfchar может быть не больше 100 записей
+	for start := 0; start < len(glyphs); start += 100 {
+		end := start + 100
+		if end > len(glyphs) {
+			end = len(glyphs)
+		}
+		fmt.Fprintf(&b, "%d beginbfchar\n", end-start)
+		for _, glyph := range glyphs[start:end] {
+			var unicode strings.Builder
+			for _, unit := range utf16.Encode([]rune{used[uint16(glyph)]}) {
+				fmt.Fprintf(&unicode, "%04X", unit)
+			}
+			fmt.Fprintf(&b, "<%04X> <%s>\n", glyph, unicode.String())
+		}
+		b.WriteString("endbfchar\n")
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return b.Bytes()
+}
+
+func (pw *Writer) allocate() int {
+	id := pw.nextID
+	pw.nextID++
+	return id
+}
+
+// object Записывает объект и запоминает его смещение для таблицы ссылок
+func (pw *Writer) object(id int, body string) {
+	pw.offsets[id] = pw.written
+	pw.write(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", id, body))
+}
+
+// stream Записывает сжатый поток. extra - дополнительные ключи словаря
+func (pw *Writer) stream(id int, extra string, data []byte) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(data); err != nil {
+		pw.fail(err)
+	}
+	if err := zw.Close(); err != nil {
+		pw.fail(err)
+	}
+	pw.offsets[id] = pw.written
+	pw.write(fmt.Sprintf("%d 0 obj\n<< /Length %d /Filter /FlateDecode %s>>\nstream\n", id, compressed.Len(), extra+" "))
+	pw.writeBytes(compressed.Bytes())
+	pw.write("\nendstream\nendobj\n")
+}
+
+func (pw *Writer) write(s string) {
+	pw.writeBytes([]byte(s))
+}
+
+func (pw *Writer) writeBytes(data []byte) {
+	if pw.err != nil {
+		return
+	}
+	n, err := pw.out.Write(data)
+	pw.written += int64(n)
+	pw.fail(err)
+}
+
+// fail Запоминает первую ошибку, после нее запись прекращается
+func (pw *Writer) fail(err error) {
+	if pw.err == nil && err != nil {
+		pw.err = err
+	}
+}
+
+// number Число без лишних нулей, как принято в PDF
+func number(v float64) string {
+	s := fmt.Sprintf("%.2f", v)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// textString Строка PDF в UTF-16BE с меткой порядка байт
+func textString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, unit := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", unit)
+	}
+	b.WriteString(">")
+	return b.String()
+}
@@ -0,0 +1,101 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestWriterProducesValidStructure(t *testing.T) {
+	regular, err := Sans()
+	require.NoError(t, err)
+	bold, err := SansBold()
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	pw := NewWriter(&out, "Подписи", regular, bold)
+	for page := 0; page < 3; page++ {
+		pw.NewPage()
+		pw.SetFont(bold, 14)
+		pw.Text(40, 60, "Список подписей")
+		pw.SetFont(regular, 10)
+		pw.Text(40, 80, "Әлем, Ғалым, Қазақстан, Өскемен, Ұлы, Үміт, Һ, І")
+		pw.Line(40, 90, 555, 90, 0.5)
+		pw.FillRect(40, 100, 100, 20, 0.9)
+	}
+	require.NoError(t, pw.Close())
+	assert.Equal(t, 3, pw.PageCount())
+
+	data := out.Bytes()
+	assert.True(t, bytes.HasPrefix(data, []byte("%PDF-1.7\n")))
+	assert.True(t, bytes.HasSuffix(data, []byte("%%EOF\n")))
+
+	// startxref указывает на таблицу, а каждая запись таблицы - на начало своего объекта
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(data)
+	require.NotNil(t, startxref)
+	xref, _ := strconv.Atoi(string(startxref[1]))
+	require.True(t, bytes.HasPrefix(data[xref:], []byte("xref\n")))
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(data[xref:], -1)
+	require.NotEmpty(t, entries)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		assert.True(t, bytes.HasPrefix(data[offset:], []byte(strconv.Itoa(i+1)+" 0 obj\n")), "object %d", i+1)
+	}
+	assert.Contains(t, string(data), "/Count 3")
+
+	// Текст переводится обратно в юникод через ToUnicode
+	glyph := regular.GlyphIndex('Қ')
+	require.NotZero(t, glyph)
+	var cmaps bytes.Buffer
+	for _, stream := range regexp.MustCompile(`(?s)stream\n(.*?)\nendstream`).FindAllSubmatch(data, -1) {
+		zr, err := zlib.NewReader(bytes.NewReader(stream[1]))
+		require.NoError(t, err)
+		decoded, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		cmaps.Write(decoded)
+	}
+	assert.Contains(t, cmaps.String(), "<"+hex4(glyph)+"> <049A>")
+}
+
+func TestSubsetKeepsGlyphIDs(t *testing.T) {
+	regular, err := Sans()
+	require.NoError(t, err)
+
+	used := map[uint16]bool{regular.GlyphIndex('Ж'): true, regular.GlyphIndex('ү'): true}
+	data := regular.Subset(used)
+	assert.Less(t, len(data), len(sansData)/4)
+
+	subset, err := ParseFont(data)
+	require.NoError(t, err)
+	assert.Equal(t, regular.numGlyphs, subset.numGlyphs)
+	assert.Equal(t, regular.GlyphIndex('Ж'), subset.GlyphIndex('Ж'))
+	assert.Equal(t, regular.glyphData(int(regular.GlyphIndex('Ж'))), subset.glyphData(int(subset.GlyphIndex('Ж'))))
+	assert.Empty(t, subset.glyphData(int(regular.GlyphIndex('Z'))))
+}
+
+func TestWrap(t *testing.T) {
+	regular, err := Sans()
+	require.NoError(t, err)
+
+	lines := regular.Wrap("Просим построить парк на улице Абая\nс детской площадкой", 10, 120)
+	assert.Greater(t, len(lines), 2)
+	assert.Equal(t, "с детской площадкой", lines[len(lines)-1])
+	for _, line := range lines {
+		assert.LessOrEqual(t, regular.TextWidth(line, 10), 120.0)
+	}
+	assert.Equal(t, []string{""}, regular.Wrap("", 10, 100))
+}
+
+func hex4(glyph uint16) string {
+	s := strconv.FormatUint(uint64(glyph), 16)
+	for len(s) < 4 {
+		s = "0" + s
+	}
+	return string(bytes.ToUpper([]byte(s)))
+}
@@ -0,0 +1,30 @@
+package textdiff
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	from := "Просим построить парк\nна улице Абая\nс детской площадкой"
+	to := "Просим построить парк\nна улице Сатпаева\nс детской площадкой\nи фонтаном"
+
+	assert.Equal(t, []Line{
+		{Op: OpEqual, Text: "Просим построить парк"},
+		{Op: OpDelete, Text: "на улице Абая"},
+		{Op: OpInsert, Text: "на улице Сатпаева"},
+		{Op: OpEqual, Text: "с детской площадкой"},
+		{Op: OpInsert, Text: "и фонтаном"},
+	}, Lines(from, to))
+}
+
+func TestLinesEdgeCases(t *testing.T) {
+	assert.Empty(t, Lines("", ""))
+	assert.Equal(t, []Line{{Op: OpInsert, Text: "a"}, {Op: OpInsert, Text: "b"}}, Lines("", "a\nb"))
+	assert.Equal(t, []Line{{Op: OpDelete, Text: "a"}}, Lines("a", ""))
+
+	same := Lines("a\r\nb\n", "a\nb")
+	assert.False(t, HasChanges(same))
+	assert.Len(t, same, 2)
+	assert.True(t, HasChanges(Lines("a", "b")))
+}